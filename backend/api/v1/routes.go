@@ -3,46 +3,157 @@ package v1
 import (
 	"database/sql"
 	"linguaforge/config"
+	"linguaforge/internal/analytics"
+	"linguaforge/internal/challenge"
+	"linguaforge/internal/classroom"
 	"linguaforge/internal/content"
+	"linguaforge/internal/duel"
+	"linguaforge/internal/events"
 	"linguaforge/internal/game"
+	"linguaforge/internal/guardian"
+	"linguaforge/internal/homework"
 	"linguaforge/internal/leaderboard"
+	"linguaforge/internal/notifications"
+	"linguaforge/internal/placement"
+	"linguaforge/internal/ratelimit"
+	"linguaforge/internal/reminders"
+	"linguaforge/internal/scheduler"
+	"linguaforge/internal/social"
 	"linguaforge/internal/user"
+	"linguaforge/internal/webhooks"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 )
 
-func SetupRoutes(router *gin.Engine, db *sql.DB, redis *redis.Client, cfg *config.Config) {
+// SetupRoutes 初始化各模块并注册路由；各模块的定时任务注册到 jobs、事件订阅注册到 bus，由调用方启动
+func SetupRoutes(router *gin.Engine, db *sql.DB, redis *redis.Client, cfg *config.Config,
+	jobs *scheduler.Scheduler, bus *events.Dispatcher, hooks *webhooks.Worker) {
 	// 初始化服务
-	userService := user.NewService(db, cfg)
+	userService := user.NewService(db, redis, cfg)
 	userHandlers := user.NewHandlers(userService)
 
 	contentService := content.NewService(db)
 	contentHandlers := content.NewHandlers(contentService)
 
-	gameService := game.NewService(db)
+	gameService := game.NewService(db, contentService)
 	gameHandlers := game.NewHandlers(gameService)
 
 	leaderboardService := leaderboard.NewService(db, redis)
 	leaderboardHandlers := leaderboard.NewHandlers(leaderboardService)
 
+	duelService := duel.NewService(db, redis, gameService)
+	duelHandlers := duel.NewHandlers(duelService)
+	socialService := social.NewService(db)
+	socialHandlers := social.NewHandlers(socialService)
+
+	challengeService := challenge.NewService(db, gameService, socialService)
+	challengeHandlers := challenge.NewHandlers(challengeService)
+	challengeService.RegisterJobs(jobs)
+
+	classroomService := classroom.NewService(db, leaderboardService)
+	classroomHandlers := classroom.NewHandlers(classroomService)
+
+	homeworkService := homework.NewService(db, classroomService)
+	homeworkHandlers := homework.NewHandlers(homeworkService)
+	homeworkService.Subscribe(bus)
+
+	webhookService := webhooks.NewService(db, classroomService, hooks)
+	webhookHandlers := webhooks.NewHandlers(webhookService)
+	webhookService.Subscribe(bus)
+	webhookService.RegisterJobs(jobs)
+
+	guardianService := guardian.NewService(db, guardian.NewNotifier(cfg.Notifier))
+	guardianHandlers := guardian.NewHandlers(guardianService)
+	guardianService.RegisterJobs(jobs)
+
+	analyticsService := analytics.NewService(db)
+	analyticsHandlers := analytics.NewHandlers(analyticsService)
+	analyticsService.RegisterJobs(jobs)
+
+	notificationService := notifications.NewService(db, redis)
+	notificationHandlers := notifications.NewHandlers(notificationService)
+	notificationService.Subscribe(bus)
+	notificationService.RegisterJobs(jobs)
+
+	reminderService := reminders.NewService(db, reminders.DefaultSenders(cfg.Notifier, notificationService))
+	reminderHandlers := reminders.NewHandlers(reminderService)
+	reminderService.RegisterJobs(jobs)
+
+	placementService := placement.NewService(db)
+	placementHandlers := placement.NewHandlers(placementService)
+
+	schedulerHandlers := scheduler.NewHandlers(jobs)
+	eventHandlers := events.NewHandlers(bus)
+	bus.RegisterJobs(jobs)
+
+	// 限流（令牌桶，配额保存在 Redis 中由各实例共享）：认证接口按 IP，登录后的接口按用户，
+	// 游戏接口和提交分数另有更严格的配额
+	limiter := ratelimit.NewLimiter(redis)
+	authLimit := limiter.Limit(ratelimit.Rule{Name: "auth", Limit: 20, Per: time.Minute, Burst: 10, By: ratelimit.PerIP})
+	apiLimit := limiter.Limit(ratelimit.Rule{Name: "api", Limit: 600, Per: time.Minute, Burst: 120, By: ratelimit.PerUser})
+	gameLimit := limiter.Limit(ratelimit.Rule{Name: "games", Limit: 120, Per: time.Minute, Burst: 60, By: ratelimit.PerUser})
+	submitLimit := limiter.Limit(ratelimit.Rule{Name: "games_submit", Limit: 10, Per: time.Minute, Burst: 5, By: ratelimit.PerUser})
+
 	// API v1 路由组
 	v1 := router.Group("/api/v1")
 	{
 		// 用户相关路由（无需认证）
 		auth := v1.Group("/auth")
+		auth.Use(authLimit)
 		{
 			auth.POST("/register", userHandlers.Register)
 			auth.POST("/login", userHandlers.Login)
+			auth.POST("/2fa/verify", userHandlers.VerifyTwoFactor)
+			auth.POST("/wechat", userHandlers.WeChatLogin)
+			auth.GET("/oidc/providers", userHandlers.OIDCProviders)
+			auth.GET("/oidc/:provider/login", userHandlers.OIDCLogin)
+			auth.GET("/oidc/:provider/callback", userHandlers.OIDCCallback)
+			auth.POST("/oidc/exchange", userHandlers.ExchangeLoginCode)
 		}
 
+		// 通知推送（SSE）：浏览器 EventSource 无法设置请求头，可先用 POST /notifications/stream-ticket 换取一次性凭证
+		v1.GET("/notifications/stream", userHandlers.StreamAuthMiddleware(), notificationHandlers.Stream)
+
 		// 需要认证的路由
 		authenticated := v1.Group("")
-		authenticated.Use(userHandlers.AuthMiddleware())
+		authenticated.Use(userHandlers.AuthMiddleware(), apiLimit)
 		{
 			// 用户资料
 			authenticated.GET("/profile", userHandlers.GetProfile)
 			authenticated.PUT("/profile", userHandlers.UpdateProfile)
+			authenticated.PUT("/profile/privacy", socialHandlers.UpdatePrivacy)
+			authenticated.GET("/profile/wechat", userHandlers.GetWeChatBinding)
+			authenticated.POST("/profile/wechat", userHandlers.BindWeChat)
+			authenticated.DELETE("/profile/wechat", userHandlers.UnbindWeChat)
+			authenticated.GET("/profile/2fa", userHandlers.GetTwoFactorStatus)
+			authenticated.POST("/profile/2fa/setup", userHandlers.SetupTwoFactor)
+			authenticated.POST("/profile/2fa/confirm", userHandlers.ConfirmTwoFactor)
+			authenticated.POST("/profile/2fa/recovery-codes", userHandlers.RegenerateRecoveryCodes)
+			authenticated.DELETE("/profile/2fa", userHandlers.DisableTwoFactor)
+			authenticated.GET("/profile/identities", userHandlers.ListIdentities)
+			authenticated.POST("/profile/identities/:provider", userHandlers.LinkIdentity)
+			authenticated.DELETE("/profile/identities/:provider", userHandlers.UnlinkIdentity)
+
+			// 社交关系与好友动态
+			authenticated.GET("/users/search", socialHandlers.SearchUsers)
+			authenticated.GET("/feed", socialHandlers.GetFeed)
+			friends := authenticated.Group("/friends")
+			{
+				friends.GET("", socialHandlers.ListFriends)
+				friends.DELETE("/:id", socialHandlers.RemoveFriend)
+				friends.GET("/requests", socialHandlers.ListFriendRequests)
+				friends.POST("/requests", socialHandlers.SendFriendRequest)
+				friends.POST("/requests/:id/respond", socialHandlers.RespondFriendRequest)
+			}
+			authenticated.GET("/following", socialHandlers.ListFollowing)
+			authenticated.POST("/following", socialHandlers.Follow)
+			authenticated.DELETE("/following/:id", socialHandlers.Unfollow)
+			authenticated.GET("/followers", socialHandlers.ListFollowers)
+			authenticated.GET("/blocks", socialHandlers.ListBlocked)
+			authenticated.POST("/blocks", socialHandlers.Block)
+			authenticated.DELETE("/blocks/:id", socialHandlers.Unblock)
 
 			// 词库相关
 			words := authenticated.Group("/words")
@@ -52,24 +163,190 @@ func SetupRoutes(router *gin.Engine, db *sql.DB, redis *redis.Client, cfg *confi
 				words.POST("/progress", contentHandlers.UpdateProgress)
 				words.GET("/progress", contentHandlers.GetUserProgress)
 				words.GET("/categories", contentHandlers.GetCategories)
+
+				// 错题本
+				words.GET("/mistakes", contentHandlers.GetMistakes)
+				words.DELETE("/mistakes/:id", contentHandlers.RemoveMistake)
 			}
 
 			// 游戏相关
 			games := authenticated.Group("/games")
+			games.Use(gameLimit)
 			{
 				// 通用游戏接口
-				games.POST("/start", gameHandlers.StartGame)
-				games.POST("/submit", gameHandlers.SubmitScore)
+				games.POST("/start", guardianHandlers.EnforceTimeLimit(), gameHandlers.StartGame)
+				games.POST("/submit", submitLimit, gameHandlers.SubmitScore)
 				games.GET("/history", gameHandlers.GetGameHistory)
+				games.POST("/answers", gameHandlers.RecordAnswers)
 
 				// 冒险游戏
-				games.GET("/adventure/start", gameHandlers.GetAdventureData)
+				games.GET("/adventure/start", guardianHandlers.EnforceTimeLimit(), gameHandlers.GetAdventureData)
+				games.GET("/adventure/chapters", gameHandlers.ListChapters)
+				games.POST("/adventure/chapters/:id/play", guardianHandlers.EnforceTimeLimit(), gameHandlers.PlayChapter)
+				games.POST("/adventure/chapters/:id/advance", gameHandlers.AdvanceChapter)
 
 				// 塔防游戏
-				games.POST("/defense/submit", gameHandlers.SubmitDefenseScore)
+				games.POST("/defense/submit", submitLimit, gameHandlers.SubmitDefenseScore)
 
 				// 配音游戏
 				games.POST("/dubbing/upload", gameHandlers.HandleDubbingSubmission)
+
+				// 拼写挑战
+				games.POST("/spelling/hint", gameHandlers.GetSpellingHint)
+				games.POST("/spelling/answer", gameHandlers.SubmitSpellingAnswer)
+
+				// 听力练习
+				games.POST("/listening/play", gameHandlers.PlayListeningRound)
+				games.POST("/listening/answer", gameHandlers.SubmitListeningAnswer)
+
+				// 连连看
+				games.POST("/matching/claim", gameHandlers.ClaimMatchingPair)
+
+				// 连词成句
+				games.POST("/sentence/answer", gameHandlers.SubmitSentenceAnswer)
+
+				// 实时对战（WebSocket）
+				games.GET("/duel", guardianHandlers.EnforceTimeLimit(), duelHandlers.Connect)
+				games.GET("/duel/rating", duelHandlers.GetRating)
+			}
+
+			// 好友挑战（异步对战）
+			challenges := authenticated.Group("/challenges")
+			{
+				challenges.POST("", guardianHandlers.EnforceTimeLimit(), challengeHandlers.Create)
+				challenges.GET("", challengeHandlers.List)
+				challenges.GET("/:id", challengeHandlers.Get)
+				challenges.POST("/:id/play", guardianHandlers.EnforceTimeLimit(), challengeHandlers.Play)
+				challenges.POST("/:id/answer", challengeHandlers.Answer)
+				challenges.POST("/:id/decline", challengeHandlers.Decline)
+			}
+
+			// 学习分析
+			analytics := authenticated.Group("/analytics")
+			{
+				analytics.GET("/time", analyticsHandlers.GetTimeSeries)
+				analytics.GET("/vocabulary", analyticsHandlers.GetVocabularyGrowth)
+				analytics.GET("/accuracy", analyticsHandlers.GetAccuracy)
+				analytics.GET("/retention", analyticsHandlers.GetRetention)
+				analytics.GET("/hardest-words", analyticsHandlers.GetHardestWords)
+			}
+
+			// 分级测试
+			placement := authenticated.Group("/placement")
+			{
+				placement.POST("/start", placementHandlers.StartTest)
+				placement.POST("/answer", placementHandlers.SubmitAnswer)
+				placement.GET("/result", placementHandlers.GetResult)
+			}
+
+			// 班级（学生）
+			classes := authenticated.Group("/classes")
+			{
+				classes.GET("", classroomHandlers.ListClasses)
+				classes.POST("/join", classroomHandlers.JoinClass)
+				classes.GET("/invites", classroomHandlers.ListInvites)
+				classes.POST("/invites/:id/respond", classroomHandlers.RespondInvite)
+				classes.GET("/:id", classroomHandlers.GetClass)
+				classes.POST("/:id/leave", classroomHandlers.LeaveClass)
+				classes.GET("/:id/leaderboard", classroomHandlers.GetLeaderboard)
+			}
+
+			// 站内通知
+			notices := authenticated.Group("/notifications")
+			{
+				notices.GET("", notificationHandlers.List)
+				notices.GET("/unread-count", notificationHandlers.UnreadCount)
+				notices.POST("/:id/read", notificationHandlers.MarkRead)
+				notices.POST("/read-all", notificationHandlers.MarkAllRead)
+				notices.POST("/stream-ticket", userHandlers.StreamTicket)
+			}
+
+			// 学习提醒设置
+			authenticated.GET("/reminders/preferences", reminderHandlers.GetPreferences)
+			authenticated.PUT("/reminders/preferences", reminderHandlers.UpdatePreferences)
+			authenticated.GET("/reminders/history", reminderHandlers.GetHistory)
+
+			// 我的作业（学生）
+			authenticated.GET("/homework", homeworkHandlers.ListMine)
+
+			// 我的监护人（学习者）
+			authenticated.GET("/guardians", guardianHandlers.ListMyGuardians)
+			authenticated.POST("/guardians/:id/respond", guardianHandlers.RespondLink)
+			authenticated.DELETE("/guardians/:id", guardianHandlers.RevokeLink)
+			authenticated.GET("/time-limit", guardianHandlers.GetTimeLimit)
+
+			// 监护人接口
+			guardians := authenticated.Group("/guardian")
+			guardians.Use(userHandlers.RequireRole(user.RoleGuardian))
+			{
+				guardians.GET("/links", guardianHandlers.ListLinks)
+				guardians.POST("/links", guardianHandlers.RequestLink)
+				guardians.PUT("/links/:id/limit", guardianHandlers.SetDailyLimit)
+				guardians.DELETE("/links/:id", guardianHandlers.RevokeLink)
+				guardians.GET("/learners/:id", guardianHandlers.GetOverview)
+				guardians.GET("/learners/:id/reports", guardianHandlers.GetReports)
+			}
+
+			// 教师接口
+			teacher := authenticated.Group("/teacher")
+			teacher.Use(userHandlers.RequireRole(user.RoleTeacher, user.RoleAdmin))
+			{
+				teacher.POST("/classes", classroomHandlers.CreateClass)
+				teacher.PUT("/classes/:id", classroomHandlers.UpdateClass)
+				teacher.DELETE("/classes/:id", classroomHandlers.DeleteClass)
+				teacher.POST("/classes/:id/join-code", classroomHandlers.ResetJoinCode)
+				teacher.GET("/classes/:id/students", classroomHandlers.GetRoster)
+				teacher.POST("/classes/:id/students", classroomHandlers.AddStudent)
+				teacher.GET("/classes/:id/invites", classroomHandlers.ListClassInvites)
+				teacher.DELETE("/classes/:id/students/:student_id", classroomHandlers.RemoveStudent)
+				teacher.GET("/classes/:id/dashboard", classroomHandlers.GetDashboard)
+
+				// 作业
+				teacher.GET("/classes/:id/assignments", homeworkHandlers.ListForClass)
+				teacher.POST("/classes/:id/assignments", homeworkHandlers.Create)
+				teacher.GET("/classes/:id/assignments/export", homeworkHandlers.ExportCSV)
+				teacher.PUT("/assignments/:id", homeworkHandlers.Update)
+				teacher.DELETE("/assignments/:id", homeworkHandlers.Delete)
+				teacher.GET("/assignments/:id/results", homeworkHandlers.Results)
+				teacher.POST("/assignments/:id/remind", homeworkHandlers.Remind)
+
+				// Webhook 订阅（教师按班级，管理员可订阅全部学生）
+				teacher.GET("/webhooks", webhookHandlers.List)
+				teacher.POST("/webhooks", webhookHandlers.Create)
+				teacher.GET("/webhooks/:id", webhookHandlers.Get)
+				teacher.PUT("/webhooks/:id", webhookHandlers.Update)
+				teacher.DELETE("/webhooks/:id", webhookHandlers.Delete)
+				teacher.POST("/webhooks/:id/ping", webhookHandlers.Ping)
+				teacher.GET("/webhooks/:id/deliveries", webhookHandlers.ListDeliveries)
+				teacher.GET("/webhooks/:id/deliveries/:delivery_id", webhookHandlers.GetDelivery)
+				teacher.POST("/webhooks/:id/deliveries/:delivery_id/replay", webhookHandlers.Replay)
+			}
+
+			// 管理接口（仅管理员）
+			admin := authenticated.Group("/admin")
+			admin.Use(userHandlers.RequireRole(user.RoleAdmin))
+			{
+				admin.PUT("/users/:id/role", userHandlers.SetRole)
+				admin.DELETE("/users/:id/2fa", userHandlers.ResetTwoFactor)
+				admin.GET("/2fa-policy", userHandlers.GetTwoFactorPolicy)
+				admin.PUT("/2fa-policy", userHandlers.SetTwoFactorPolicy)
+
+				// 定时任务
+				admin.GET("/jobs", schedulerHandlers.ListJobs)
+				admin.GET("/jobs/:name/runs", schedulerHandlers.ListRuns)
+				admin.POST("/jobs/:name/run", schedulerHandlers.TriggerJob)
+				admin.POST("/jobs/:name/pause", schedulerHandlers.PauseJob)
+				admin.POST("/jobs/:name/resume", schedulerHandlers.ResumeJob)
+
+				// 领域事件死信
+				admin.GET("/events/dead", eventHandlers.ListDeadLetters)
+				admin.POST("/events/:id/retry", eventHandlers.RetryDeadLetter)
+
+				// 冒险剧情编辑
+				admin.POST("/adventure/chapters/validate", gameHandlers.ValidateChapter)
+				admin.POST("/adventure/chapters", gameHandlers.CreateChapter)
+				admin.GET("/adventure/chapters/:id", gameHandlers.GetChapterGraph)
+				admin.PUT("/adventure/chapters/:id", gameHandlers.UpdateChapter)
 			}
 
 			// 排行榜相关
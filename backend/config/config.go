@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -10,10 +11,18 @@ import (
 type Config struct {
 	Environment string
 	Port        string
-	Database    DatabaseConfig
-	Redis       RedisConfig
-	JWT         JWTConfig
-	AWS         AWSConfig
+	// TrustedProxies 可信的反向代理地址（IP 或 CIDR），只有来自它们的 X-Forwarded-For 才会被用作客户端 IP；
+	// 为空时不信任任何代理，客户端 IP 为连接的对端地址（部署在反向代理后必须配置，否则所有请求都来自代理 IP）
+	TrustedProxies []string
+	Database       DatabaseConfig
+	Redis          RedisConfig
+	JWT            JWTConfig
+	AWS            AWSConfig
+	Notifier       NotifierConfig
+	Events         EventsConfig
+	Webhooks       WebhooksConfig
+	WeChat         WeChatConfig
+	OIDC           OIDCConfig
 }
 
 type DatabaseConfig struct {
@@ -44,6 +53,56 @@ type AWSConfig struct {
 	Endpoint        string
 }
 
+// NotifierConfig 通知发送配置；Driver 为 log（只写日志）或 smtp
+type NotifierConfig struct {
+	Driver       string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+}
+
+// EventsConfig 领域事件投递配置；RedisStream 为空时只投递给进程内订阅者
+type EventsConfig struct {
+	RedisStream string
+	MaxAttempts int
+}
+
+// WeChatConfig 微信小程序登录配置；APIBase 可指向本地模拟服务
+// 未配置 AppID 时关闭微信登录，只有显式设置 Fake（WECHAT_FAKE=true）才在非生产环境使用模拟登录
+type WeChatConfig struct {
+	AppID     string
+	AppSecret string
+	APIBase   string
+	Fake      bool
+}
+
+// OIDCConfig 第三方 OIDC 登录配置
+// 回调地址为 {RedirectBase}/api/v1/auth/oidc/{provider}/callback；FrontendURL 为空时回调直接返回 JSON
+type OIDCConfig struct {
+	Providers    []OIDCProviderConfig
+	RedirectBase string
+	FrontendURL  string
+}
+
+// OIDCProviderConfig 单个 OIDC 提供方；TrustEmail 为 true 时，已验证的邮箱可自动关联同邮箱的已有账号
+type OIDCProviderConfig struct {
+	ID           string
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	TrustEmail   bool
+}
+
+// WebhooksConfig 外发 Webhook 配置；默认禁止投递到内网地址，本地联调时可打开
+type WebhooksConfig struct {
+	AllowPrivateNetworks bool
+	TimeoutSeconds       int
+}
+
 func Load() *Config {
 	// 尝试加载.env文件（如果存在）
 	godotenv.Load()
@@ -51,6 +110,9 @@ func Load() *Config {
 	return &Config{
 		Environment: getEnv("ENVIRONMENT", "development"),
 		Port:        getEnv("PORT", "8080"),
+		TrustedProxies: strings.FieldsFunc(getEnv("TRUSTED_PROXIES", ""), func(r rune) bool {
+			return r == ',' || r == ' '
+		}),
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "3306"),
@@ -75,9 +137,69 @@ func Load() *Config {
 			BucketName:      getEnv("AWS_BUCKET_NAME", ""),
 			Endpoint:        getEnv("AWS_ENDPOINT", ""),
 		},
+		Notifier: NotifierConfig{
+			Driver:       getEnv("NOTIFIER_DRIVER", "log"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUser:     getEnv("SMTP_USER", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("NOTIFIER_FROM", "noreply@linguaforge.local"),
+		},
+		Events: EventsConfig{
+			RedisStream: getEnv("EVENTS_REDIS_STREAM", ""),
+			MaxAttempts: getEnvAsInt("EVENTS_MAX_ATTEMPTS", 10),
+		},
+		WeChat: WeChatConfig{
+			AppID:     getEnv("WECHAT_APP_ID", ""),
+			AppSecret: getEnv("WECHAT_APP_SECRET", ""),
+			APIBase:   getEnv("WECHAT_API_BASE", "https://api.weixin.qq.com"),
+			Fake:      getEnvAsBool("WECHAT_FAKE", false),
+		},
+		OIDC: OIDCConfig{
+			Providers:    loadOIDCProviders(),
+			RedirectBase: strings.TrimSuffix(getEnv("OIDC_REDIRECT_BASE", "http://localhost:8080"), "/"),
+			FrontendURL:  getEnv("OIDC_FRONTEND_URL", ""),
+		},
+		Webhooks: WebhooksConfig{
+			AllowPrivateNetworks: getEnvAsBool("WEBHOOKS_ALLOW_PRIVATE_NETWORKS", false),
+			TimeoutSeconds:       getEnvAsInt("WEBHOOKS_TIMEOUT_SECONDS", 10),
+		},
 	}
 }
 
+// wellKnownIssuers 常用提供方的默认 Issuer
+var wellKnownIssuers = map[string]string{
+	"google":    "https://accounts.google.com",
+	"microsoft": "https://login.microsoftonline.com/common/v2.0",
+}
+
+// loadOIDCProviders 读取 OIDC_PROVIDERS（逗号分隔的提供方 ID）及每个提供方的 OIDC_<ID>_* 配置，
+// 缺少 Issuer 或 ClientID 的提供方会被忽略
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, id := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(id, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			ID:           id,
+			Name:         getEnv(prefix+"NAME", id),
+			Issuer:       strings.TrimSuffix(getEnv(prefix+"ISSUER", wellKnownIssuers[id]), "/"),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+			TrustEmail:   getEnvAsBool(prefix+"TRUST_EMAIL", false),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -93,3 +215,12 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,112 @@
+package analytics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handlers struct {
+	service *Service
+}
+
+func NewHandlers(service *Service) *Handlers {
+	return &Handlers{
+		service: service,
+	}
+}
+
+// GetTimeSeries 学习时长趋势（按天或按周）
+func (h *Handlers) GetTimeSeries(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	period := Period(c.DefaultQuery("period", string(PeriodDay)))
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+
+	series, err := h.service.GetTimeSeries(userID.(int), period, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetVocabularyGrowth 词汇量增长
+func (h *Handlers) GetVocabularyGrowth(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+
+	growth, err := h.service.GetVocabularyGrowth(userID.(int), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, growth)
+}
+
+// GetAccuracy 按游戏类型和分类的正确率
+func (h *Handlers) GetAccuracy(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+
+	breakdown, err := h.service.GetAccuracy(userID.(int), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// GetRetention 记忆保持曲线
+func (h *Handlers) GetRetention(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	retention, err := h.service.GetRetention(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, retention)
+}
+
+// GetHardestWords 最难的单词
+func (h *Handlers) GetHardestWords(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	words, err := h.service.GetHardestWords(userID.(int), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"words": words})
+}
@@ -0,0 +1,15 @@
+package analytics
+
+import (
+	"context"
+	"linguaforge/internal/scheduler"
+	"time"
+)
+
+// RegisterJobs 注册学习分析的定时任务
+func (s *Service) RegisterJobs(jobs *scheduler.Scheduler) {
+	jobs.Register("analytics.rollups", "*/10 * * * *", "增量刷新学习分析的预聚合统计", 30*time.Minute,
+		func(ctx context.Context) error {
+			return s.RunRollups(ctx, time.Now())
+		})
+}
@@ -0,0 +1,94 @@
+package analytics
+
+import "time"
+
+// Period 时间序列的聚合粒度
+type Period string
+
+const (
+	PeriodDay  Period = "day"
+	PeriodWeek Period = "week" // 以周一为一周的开始
+)
+
+// TimePoint 一个时间段内的学习时长
+type TimePoint struct {
+	Date      string `json:"date"` // 日或周一，2006-01-02
+	Games     int    `json:"games"`
+	TimeSpent int    `json:"time_spent"` // 秒
+	Answers   int    `json:"answers"`
+	Correct   int    `json:"correct"`
+}
+
+// TimeSeries 学习时长趋势
+type TimeSeries struct {
+	Period      Period      `json:"period"`
+	Points      []TimePoint `json:"points"`
+	TotalTime   int         `json:"total_time"`
+	RefreshedAt *time.Time  `json:"refreshed_at,omitempty"` // 统计数据的更新时间
+}
+
+// VocabularyPoint 某天新学的单词数与累计单词数
+type VocabularyPoint struct {
+	Date       string `json:"date"`
+	NewWords   int    `json:"new_words"`
+	TotalWords int    `json:"total_words"`
+}
+
+// VocabularyGrowth 词汇量增长
+type VocabularyGrowth struct {
+	Points        []VocabularyPoint `json:"points"`
+	TotalWords    int               `json:"total_words"`
+	MasteredWords int               `json:"mastered_words"` // 掌握程度达到 80% 的单词
+	RefreshedAt   *time.Time        `json:"refreshed_at,omitempty"`
+}
+
+// AccuracyItem 某个游戏类型或分类的正确率
+type AccuracyItem struct {
+	Key      string  `json:"key"`
+	Answers  int     `json:"answers"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// AccuracyBreakdown 按游戏类型和单词分类的正确率
+type AccuracyBreakdown struct {
+	Days        int            `json:"days"`
+	Overall     AccuracyItem   `json:"overall"`
+	ByGameType  []AccuracyItem `json:"by_game_type"`
+	ByCategory  []AccuracyItem `json:"by_category"`
+	RefreshedAt *time.Time     `json:"refreshed_at,omitempty"`
+}
+
+// RetentionPoint 距上次作答间隔某一档内的正确率
+type RetentionPoint struct {
+	Interval string  `json:"interval"`
+	Answers  int     `json:"answers"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// RetentionBucket 某个掌握程度分档的记忆保持曲线
+type RetentionBucket struct {
+	Mastery   string           `json:"mastery"`   // 如 "40-60%"
+	Retention float64          `json:"retention"` // 间隔一天以上的复习正确率，作为保持率估计
+	Answers   int              `json:"answers"`
+	Curve     []RetentionPoint `json:"curve"`
+}
+
+// Retention 记忆保持分析
+type Retention struct {
+	Buckets     []RetentionBucket `json:"buckets"`
+	RefreshedAt *time.Time        `json:"refreshed_at,omitempty"`
+}
+
+// HardWord 错误率最高的单词
+type HardWord struct {
+	WordID       int       `json:"word_id"`
+	English      string    `json:"english"`
+	Chinese      string    `json:"chinese"`
+	Category     string    `json:"category"`
+	Attempts     int       `json:"attempts"`
+	Correct      int       `json:"correct"`
+	Accuracy     float64   `json:"accuracy"`
+	MasteryLevel float64   `json:"mastery_level"`
+	LastAnswered time.Time `json:"last_answered"`
+}
@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"linguaforge/internal/common"
+	"time"
+)
+
+const (
+	rollupName = "learning"
+	// retentionWindow 记忆保持统计回看的天数
+	retentionWindow = 180
+)
+
+// RunRollups 增量刷新预聚合统计：重新聚合上次刷新所在日期至今的数据
+// 每次都按整天重算并覆盖，重复执行结果不变
+func (s *Service) RunRollups(ctx context.Context, now time.Time) error {
+	var watermark sql.NullTime
+	err := s.db.QueryRowContext(ctx, "SELECT watermark FROM analytics_rollup_state WHERE name = ?", rollupName).Scan(&watermark)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get rollup state: %w", err)
+	}
+
+	// 首次运行时全量回填；之后多回看一小时，覆盖写入稍晚提交的记录
+	since := time.Unix(0, 0)
+	if watermark.Valid {
+		since = common.StartOfDay(watermark.Time.Add(-time.Hour))
+	}
+
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{"game stats", `
+			INSERT INTO user_daily_stats (user_id, stat_date, games, time_spent, total_score)
+			SELECT user_id, DATE(completed_at), COUNT(*), COALESCE(SUM(time_spent), 0), COALESCE(SUM(score), 0)
+			FROM game_records
+			WHERE completed_at >= ?
+			GROUP BY user_id, DATE(completed_at)
+			ON DUPLICATE KEY UPDATE games = VALUES(games), time_spent = VALUES(time_spent), total_score = VALUES(total_score)
+		`},
+		{"answer stats", `
+			INSERT INTO user_daily_stats (user_id, stat_date, answers, correct)
+			SELECT user_id, DATE(answered_at), COUNT(*), COALESCE(SUM(is_correct), 0)
+			FROM answer_events
+			WHERE answered_at >= ?
+			GROUP BY user_id, DATE(answered_at)
+			ON DUPLICATE KEY UPDATE answers = VALUES(answers), correct = VALUES(correct)
+		`},
+		{"new words", `
+			INSERT INTO user_daily_stats (user_id, stat_date, new_words)
+			SELECT user_id, DATE(created_at), COUNT(*)
+			FROM user_progress
+			WHERE created_at >= ?
+			GROUP BY user_id, DATE(created_at)
+			ON DUPLICATE KEY UPDATE new_words = VALUES(new_words)
+		`},
+		{"accuracy breakdown", `
+			INSERT INTO user_answer_stats (user_id, stat_date, game_type, category, answers, correct)
+			SELECT ae.user_id, DATE(ae.answered_at), ae.game_type, COALESCE(w.category, ''),
+			       COUNT(*), COALESCE(SUM(ae.is_correct), 0)
+			FROM answer_events ae
+			JOIN words w ON w.id = ae.word_id
+			WHERE ae.answered_at >= ?
+			GROUP BY ae.user_id, DATE(ae.answered_at), ae.game_type, COALESCE(w.category, '')
+			ON DUPLICATE KEY UPDATE answers = VALUES(answers), correct = VALUES(correct)
+		`},
+	}
+	for _, step := range steps {
+		if _, err := s.db.ExecContext(ctx, step.query, since); err != nil {
+			return fmt.Errorf("failed to roll up %s: %w", step.name, err)
+		}
+	}
+
+	if err := s.rollupRetention(ctx, since, now); err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO analytics_rollup_state (name, watermark) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE watermark = VALUES(watermark)
+	`, rollupName, now)
+	if err != nil {
+		return fmt.Errorf("failed to save rollup state: %w", err)
+	}
+	return nil
+}
+
+// rollupRetention 重新计算 since 之后有作答的用户的记忆保持统计
+// 掌握程度按当前值分档，间隔为同一单词相邻两次作答的时间差
+func (s *Service) rollupRetention(ctx context.Context, since, now time.Time) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT user_id FROM answer_events WHERE answered_at >= ?", since)
+	if err != nil {
+		return fmt.Errorf("failed to query active users: %w", err)
+	}
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan active user: %w", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+
+	windowStart := now.AddDate(0, 0, -retentionWindow)
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.rollupUserRetention(ctx, userID, windowStart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) rollupUserRetention(ctx context.Context, userID int, windowStart time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_retention_stats WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to clear retention stats: %w", err)
+	}
+	// 间隔不足一小时的重复作答（同一局内）不计入
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_retention_stats (user_id, mastery_bucket, interval_bucket, answers, correct)
+		SELECT ?, mastery_bucket, interval_bucket, COUNT(*), SUM(is_correct)
+		FROM (
+			SELECT LEAST(4, FLOOR(COALESCE(up.mastery_level, 0) * 5)) AS mastery_bucket,
+			       CASE
+			           WHEN t.gap_hours < 24 THEN 0
+			           WHEN t.gap_hours < 72 THEN 1
+			           WHEN t.gap_hours < 168 THEN 2
+			           WHEN t.gap_hours < 336 THEN 3
+			           WHEN t.gap_hours < 720 THEN 4
+			           ELSE 5
+			       END AS interval_bucket,
+			       t.is_correct
+			FROM (
+				SELECT word_id, is_correct,
+				       TIMESTAMPDIFF(HOUR, LAG(answered_at) OVER (PARTITION BY word_id ORDER BY answered_at, id), answered_at) AS gap_hours
+				FROM answer_events
+				WHERE user_id = ? AND answered_at >= ?
+			) t
+			LEFT JOIN user_progress up ON up.user_id = ? AND up.word_id = t.word_id
+			WHERE t.gap_hours >= 1
+		) b
+		GROUP BY mastery_bucket, interval_bucket
+	`, userID, userID, windowStart, userID)
+	if err != nil {
+		return fmt.Errorf("failed to roll up retention stats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit retention stats: %w", err)
+	}
+	return nil
+}
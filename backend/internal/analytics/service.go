@@ -0,0 +1,334 @@
+package analytics
+
+import (
+	"database/sql"
+	"fmt"
+	"linguaforge/internal/common"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// maxDays 趋势查询的最大天数
+	maxDays = 365
+	// hardWordsWindow 统计难词时回看的天数
+	hardWordsWindow = 90
+	// hardWordsMinAttempts 至少作答几次才计入难词
+	hardWordsMinAttempts = 3
+	// masteredLevel 视为已掌握的掌握程度
+	masteredLevel = 0.8
+)
+
+// masteryBuckets 与 user_retention_stats.mastery_bucket 对应
+var masteryBuckets = []string{"0-20%", "20-40%", "40-60%", "60-80%", "80-100%"}
+
+// intervalBuckets 与 user_retention_stats.interval_bucket 对应
+var intervalBuckets = []string{"1h-1d", "1-3d", "3-7d", "7-14d", "14-30d", "30d+"}
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// GetTimeSeries 最近 days 天的学习时长，按天或按周汇总
+func (s *Service) GetTimeSeries(userID int, period Period, days int) (*TimeSeries, error) {
+	if period != PeriodWeek {
+		period = PeriodDay
+	}
+	from, to := dayRange(days)
+
+	rows, err := s.db.Query(`
+		SELECT stat_date, games, time_spent, answers, correct
+		FROM user_daily_stats
+		WHERE user_id = ? AND stat_date >= ? AND stat_date < ?
+	`, userID, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	byDate := map[string]TimePoint{}
+	for rows.Next() {
+		var date time.Time
+		var p TimePoint
+		if err := rows.Scan(&date, &p.Games, &p.TimeSpent, &p.Answers, &p.Correct); err != nil {
+			return nil, fmt.Errorf("failed to scan daily stats: %w", err)
+		}
+		p.Date = date.Format(dateLayout)
+		byDate[p.Date] = p
+	}
+
+	series := &TimeSeries{Period: period, Points: []TimePoint{}}
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		p := byDate[d.Format(dateLayout)]
+		key := d
+		if period == PeriodWeek {
+			key = common.WeekStart(d)
+		}
+		p.Date = key.Format(dateLayout)
+		series.TotalTime += p.TimeSpent
+
+		last := len(series.Points) - 1
+		if last >= 0 && series.Points[last].Date == p.Date {
+			series.Points[last].Games += p.Games
+			series.Points[last].TimeSpent += p.TimeSpent
+			series.Points[last].Answers += p.Answers
+			series.Points[last].Correct += p.Correct
+			continue
+		}
+		series.Points = append(series.Points, p)
+	}
+
+	series.RefreshedAt, err = s.refreshedAt()
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// GetVocabularyGrowth 最近 days 天每天新学的单词数和累计词汇量
+func (s *Service) GetVocabularyGrowth(userID int, days int) (*VocabularyGrowth, error) {
+	from, to := dayRange(days)
+
+	var before int
+	if err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(new_words), 0) FROM user_daily_stats
+		WHERE user_id = ? AND stat_date < ?
+	`, userID, from.Format(dateLayout)).Scan(&before); err != nil {
+		return nil, fmt.Errorf("failed to count earlier words: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT stat_date, new_words FROM user_daily_stats
+		WHERE user_id = ? AND stat_date >= ? AND stat_date < ? AND new_words > 0
+	`, userID, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query new words: %w", err)
+	}
+	defer rows.Close()
+
+	newWords := map[string]int{}
+	for rows.Next() {
+		var date time.Time
+		var n int
+		if err := rows.Scan(&date, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan new words: %w", err)
+		}
+		newWords[date.Format(dateLayout)] = n
+	}
+
+	growth := &VocabularyGrowth{Points: []VocabularyPoint{}}
+	total := before
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		date := d.Format(dateLayout)
+		total += newWords[date]
+		growth.Points = append(growth.Points, VocabularyPoint{Date: date, NewWords: newWords[date], TotalWords: total})
+	}
+
+	// 当前词汇量直接取自学习进度，不受刷新延迟影响
+	if err := s.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(mastery_level >= ?), 0) FROM user_progress WHERE user_id = ?
+	`, masteredLevel, userID).Scan(&growth.TotalWords, &growth.MasteredWords); err != nil {
+		return nil, fmt.Errorf("failed to count words: %w", err)
+	}
+
+	growth.RefreshedAt, err = s.refreshedAt()
+	if err != nil {
+		return nil, err
+	}
+	return growth, nil
+}
+
+// GetAccuracy 最近 days 天按游戏类型和单词分类的正确率
+func (s *Service) GetAccuracy(userID int, days int) (*AccuracyBreakdown, error) {
+	days = normalizeDays(days)
+	from, _ := dayRange(days)
+	breakdown := &AccuracyBreakdown{Days: days, Overall: AccuracyItem{Key: "overall"}}
+
+	var err error
+	breakdown.ByGameType, err = s.accuracyBy("game_type", userID, from)
+	if err != nil {
+		return nil, err
+	}
+	breakdown.ByCategory, err = s.accuracyBy("category", userID, from)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range breakdown.ByGameType {
+		breakdown.Overall.Answers += item.Answers
+		breakdown.Overall.Correct += item.Correct
+	}
+	breakdown.Overall.Accuracy = ratio(breakdown.Overall.Correct, breakdown.Overall.Answers)
+
+	breakdown.RefreshedAt, err = s.refreshedAt()
+	if err != nil {
+		return nil, err
+	}
+	return breakdown, nil
+}
+
+// accuracyBy 按指定列汇总作答统计，column 只能是内部传入的常量
+func (s *Service) accuracyBy(column string, userID int, from time.Time) ([]AccuracyItem, error) {
+	rows, err := s.db.Query(`
+		SELECT `+column+`, SUM(answers), SUM(correct)
+		FROM user_answer_stats
+		WHERE user_id = ? AND stat_date >= ?
+		GROUP BY `+column+`
+		ORDER BY SUM(answers) DESC
+	`, userID, from.Format(dateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query accuracy by %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	items := []AccuracyItem{}
+	for rows.Next() {
+		var item AccuracyItem
+		if err := rows.Scan(&item.Key, &item.Answers, &item.Correct); err != nil {
+			return nil, fmt.Errorf("failed to scan accuracy: %w", err)
+		}
+		if item.Key == "" {
+			item.Key = "uncategorized"
+		}
+		item.Accuracy = ratio(item.Correct, item.Answers)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetRetention 各掌握程度分档的记忆保持曲线
+func (s *Service) GetRetention(userID int) (*Retention, error) {
+	rows, err := s.db.Query(`
+		SELECT mastery_bucket, interval_bucket, answers, correct
+		FROM user_retention_stats
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention stats: %w", err)
+	}
+	defer rows.Close()
+
+	type cell struct{ answers, correct int }
+	cells := make([][]cell, len(masteryBuckets))
+	for i := range cells {
+		cells[i] = make([]cell, len(intervalBuckets))
+	}
+	for rows.Next() {
+		var m, i, answers, correct int
+		if err := rows.Scan(&m, &i, &answers, &correct); err != nil {
+			return nil, fmt.Errorf("failed to scan retention stats: %w", err)
+		}
+		if m >= 0 && m < len(masteryBuckets) && i >= 0 && i < len(intervalBuckets) {
+			cells[m][i] = cell{answers, correct}
+		}
+	}
+
+	retention := &Retention{Buckets: []RetentionBucket{}}
+	for m, label := range masteryBuckets {
+		bucket := RetentionBucket{Mastery: label, Curve: []RetentionPoint{}}
+		var delayedAnswers, delayedCorrect int
+		for i, interval := range intervalBuckets {
+			c := cells[m][i]
+			bucket.Answers += c.answers
+			// 一小时到一天内的重复作答更多反映短时记忆，不计入保持率
+			if i > 0 {
+				delayedAnswers += c.answers
+				delayedCorrect += c.correct
+			}
+			bucket.Curve = append(bucket.Curve, RetentionPoint{
+				Interval: interval,
+				Answers:  c.answers,
+				Accuracy: ratio(c.correct, c.answers),
+			})
+		}
+		bucket.Retention = ratio(delayedCorrect, delayedAnswers)
+		retention.Buckets = append(retention.Buckets, bucket)
+	}
+
+	retention.RefreshedAt, err = s.refreshedAt()
+	if err != nil {
+		return nil, err
+	}
+	return retention, nil
+}
+
+// GetHardestWords 最近一段时间错误率最高的单词
+func (s *Service) GetHardestWords(userID int, limit int) ([]HardWord, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	rows, err := s.db.Query(`
+		SELECT w.id, w.english, w.chinese, COALESCE(w.category, ''),
+		       a.attempts, a.correct, COALESCE(up.mastery_level, 0), a.last_answered
+		FROM (
+			SELECT word_id, COUNT(*) AS attempts, SUM(is_correct) AS correct, MAX(answered_at) AS last_answered
+			FROM answer_events
+			WHERE user_id = ? AND answered_at >= ?
+			GROUP BY word_id
+			HAVING COUNT(*) >= ?
+		) a
+		JOIN words w ON w.id = a.word_id
+		LEFT JOIN user_progress up ON up.user_id = ? AND up.word_id = a.word_id
+		ORDER BY a.correct / a.attempts ASC, a.attempts DESC, a.last_answered DESC
+		LIMIT ?
+	`, userID, time.Now().AddDate(0, 0, -hardWordsWindow), hardWordsMinAttempts, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hardest words: %w", err)
+	}
+	defer rows.Close()
+
+	words := []HardWord{}
+	for rows.Next() {
+		var w HardWord
+		if err := rows.Scan(&w.WordID, &w.English, &w.Chinese, &w.Category,
+			&w.Attempts, &w.Correct, &w.MasteryLevel, &w.LastAnswered); err != nil {
+			return nil, fmt.Errorf("failed to scan hard word: %w", err)
+		}
+		w.Accuracy = ratio(w.Correct, w.Attempts)
+		words = append(words, w)
+	}
+	return words, nil
+}
+
+// refreshedAt 统计数据最近一次刷新到的时间，尚未刷新过时为空
+func (s *Service) refreshedAt() (*time.Time, error) {
+	var watermark sql.NullTime
+	err := s.db.QueryRow("SELECT watermark FROM analytics_rollup_state WHERE name = ?", rollupName).Scan(&watermark)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get rollup state: %w", err)
+	}
+	if !watermark.Valid {
+		return nil, nil
+	}
+	return &watermark.Time, nil
+}
+
+func normalizeDays(days int) int {
+	if days <= 0 {
+		return 30
+	}
+	if days > maxDays {
+		return maxDays
+	}
+	return days
+}
+
+// dayRange 包含今天在内最近 days 天的 [from, to)
+func dayRange(days int) (time.Time, time.Time) {
+	days = normalizeDays(days)
+	today := common.StartOfDay(time.Now())
+	return today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1)
+}
+
+func ratio(correct, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+}
@@ -0,0 +1,16 @@
+package challenge
+
+import (
+	"context"
+	"linguaforge/internal/scheduler"
+	"time"
+)
+
+// RegisterJobs 注册好友挑战的定时任务
+func (s *Service) RegisterJobs(jobs *scheduler.Scheduler) {
+	// 查看挑战时也会过期处理，定时任务保证对方长期不登录时状态同样正确
+	jobs.Register("challenge.expire", "0 * * * *", "将超时未应战的挑战标记为过期", time.Minute,
+		func(ctx context.Context) error {
+			return s.expireChallenges()
+		})
+}
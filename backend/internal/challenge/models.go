@@ -0,0 +1,119 @@
+package challenge
+
+import "time"
+
+// Status 挑战状态
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 发起者尚未玩完
+	StatusSent      Status = "sent"      // 已发给对方，等待对方应战
+	StatusAccepted  Status = "accepted"  // 对方已开始
+	StatusCompleted Status = "completed" // 双方都已完成
+	StatusDeclined  Status = "declined"
+	StatusExpired   Status = "expired"
+)
+
+// Box 挑战列表类型
+type Box string
+
+const (
+	BoxIncoming Box = "incoming"
+	BoxOutgoing Box = "outgoing"
+)
+
+// CreateRequest 发起挑战请求，对手可用ID或用户名指定
+type CreateRequest struct {
+	OpponentID       int    `json:"opponent_id"`
+	OpponentUsername string `json:"opponent_username"`
+	Level            int    `json:"level"`
+}
+
+// Game 一局挑战的题目，双方拿到的完全相同
+type Game struct {
+	ChallengeID int     `json:"challenge_id"`
+	SessionID   int     `json:"session_id"`
+	Level       int     `json:"level"`
+	Rounds      []Round `json:"rounds"`
+	Answered    []int   `json:"answered"` // 已作答的轮次（断点续玩）
+	Score       int     `json:"score"`
+}
+
+// Round 挑战的单轮题目：看英文选释义
+type Round struct {
+	Round   int      `json:"round"`
+	WordID  int      `json:"word_id"`
+	English string   `json:"english"`
+	Options []string `json:"options"`
+}
+
+// AnswerRequest 挑战作答请求
+type AnswerRequest struct {
+	Round          int    `json:"round" binding:"required,min=1"`
+	Answer         string `json:"answer"`
+	ResponseTimeMs int    `json:"response_time_ms"`
+}
+
+// AnswerResult 挑战作答结果；本方完成全部题目时 Finished 为 true
+type AnswerResult struct {
+	Round    int         `json:"round"`
+	Correct  bool        `json:"correct"`
+	Points   int         `json:"points"`
+	Expected string      `json:"expected"`
+	Score    int         `json:"score"`
+	Finished bool        `json:"finished"`
+	Result   *HeadToHead `json:"result,omitempty"`
+}
+
+// Challenge 挑战列表项
+type Challenge struct {
+	ID              int        `json:"id"`
+	ChallengerID    int        `json:"challenger_id"`
+	ChallengerName  string     `json:"challenger_name"`
+	OpponentID      int        `json:"opponent_id"`
+	OpponentName    string     `json:"opponent_name"`
+	Level           int        `json:"level"`
+	Status          Status     `json:"status"`
+	ChallengerScore *int       `json:"challenger_score,omitempty"`
+	OpponentScore   *int       `json:"opponent_score,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+}
+
+// HeadToHead 双方对比结果
+type HeadToHead struct {
+	ChallengeID int           `json:"challenge_id"`
+	Status      Status        `json:"status"`
+	WinnerID    int           `json:"winner_id,omitempty"` // 平局或未结束时为 0
+	Players     []PlayerBoard `json:"players"`
+}
+
+// PlayerBoard 一方的成绩；对方的逐题作答在挑战结束后才公开
+type PlayerBoard struct {
+	UserID   int           `json:"user_id"`
+	Username string        `json:"username"`
+	Started  bool          `json:"started"`
+	Finished bool          `json:"finished"`
+	Score    int           `json:"score"`
+	Correct  int           `json:"correct"`
+	Answers  []RoundAnswer `json:"answers,omitempty"`
+}
+
+// RoundAnswer 单轮作答
+type RoundAnswer struct {
+	Round          int    `json:"round"`
+	English        string `json:"english"`
+	Answer         string `json:"answer"`
+	Expected       string `json:"expected"`
+	Correct        bool   `json:"correct"`
+	Points         int    `json:"points"`
+	ResponseTimeMs int    `json:"response_time_ms"`
+}
+
+// frozenRound 冻结保存的题目（含答案，只在服务端使用）
+type frozenRound struct {
+	WordID  int      `json:"word_id"`
+	English string   `json:"english"`
+	Options []string `json:"options"`
+	Answer  string   `json:"answer"`
+}
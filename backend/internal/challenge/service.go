@@ -0,0 +1,615 @@
+package challenge
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linguaforge/internal/events"
+	"linguaforge/internal/game"
+	"linguaforge/internal/social"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// 好友挑战参数
+const (
+	challengeRoundCount  = 10
+	challengeExpireDays  = 7 // 发出后多少天内未应战则过期
+	challengeBasePoints  = 10
+	challengeFastAnswer  = 3000 // 毫秒，快速作答奖励阈值
+	challengeQuickAnswer = 6000
+)
+
+type Service struct {
+	db     *sql.DB
+	games  *game.Service
+	social *social.Service
+}
+
+func NewService(db *sql.DB, games *game.Service, social *social.Service) *Service {
+	return &Service{
+		db:     db,
+		games:  games,
+		social: social,
+	}
+}
+
+// Create 发起挑战：用新种子生成一局题目并冻结，发起者先玩，玩完后挑战才会发给对方
+// 只能挑战好友，任意一方拉黑了另一方时拒绝
+func (s *Service) Create(userID int, req *CreateRequest) (*Game, error) {
+	opponentID, err := s.resolveOpponent(req)
+	if err != nil {
+		return nil, err
+	}
+	if opponentID == userID {
+		return nil, errors.New("cannot challenge yourself")
+	}
+	if err := s.social.CheckNotBlocked(userID, opponentID); err != nil {
+		return nil, err
+	}
+	friends, err := s.social.IsFriend(userID, opponentID)
+	if err != nil {
+		return nil, err
+	}
+	if !friends {
+		return nil, errors.New("can only challenge friends")
+	}
+
+	level := req.Level
+	if level <= 0 {
+		if err := s.db.QueryRow("SELECT preferred_level FROM users WHERE id = ?", userID).Scan(&level); err != nil || level <= 0 {
+			level = 1
+		}
+	}
+
+	seed := rand.Int63()
+	generated, err := s.games.BuildSeededRounds(seed, level, challengeRoundCount)
+	if err != nil {
+		return nil, err
+	}
+	if len(generated) == 0 {
+		return nil, fmt.Errorf("no words available for level %d", level)
+	}
+	rounds := make([]frozenRound, 0, len(generated))
+	for _, r := range generated {
+		rounds = append(rounds, frozenRound{WordID: r.WordID, English: r.English, Options: r.Options, Answer: r.Answer})
+	}
+	data, err := json.Marshal(rounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode challenge rounds: %w", err)
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO challenges (challenger_id, opponent_id, level, seed, rounds, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, opponentID, level, seed, string(data), StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge ID: %w", err)
+	}
+
+	return s.Play(userID, int(id))
+}
+
+// Play 开始或继续挑战：发起者在发出前、对方在应战后，拿到的都是冻结的同一局题目
+func (s *Service) Play(userID int, challengeID int) (*Game, error) {
+	if err := s.expireChallenges(); err != nil {
+		return nil, err
+	}
+	c, err := s.getChallenge(challengeID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case userID == c.challengerID:
+		if c.status != StatusPending {
+			return nil, errors.New("challenge already played")
+		}
+	case userID == c.opponentID:
+		if c.status != StatusSent && c.status != StatusAccepted {
+			return nil, fmt.Errorf("challenge is %s", c.status)
+		}
+	default:
+		return nil, errors.New("challenge not found")
+	}
+
+	attempt, err := s.getAttempt(challengeID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		attempt, err = s.startAttempt(c, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if attempt.finished {
+		return nil, errors.New("challenge already played")
+	}
+	if userID == c.opponentID && c.status == StatusSent {
+		if _, err := s.db.Exec("UPDATE challenges SET status = ? WHERE id = ?", StatusAccepted, challengeID); err != nil {
+			return nil, fmt.Errorf("failed to accept challenge: %w", err)
+		}
+	}
+
+	answered, err := s.answeredRounds(attempt.id)
+	if err != nil {
+		return nil, err
+	}
+	g := &Game{
+		ChallengeID: challengeID,
+		SessionID:   attempt.sessionID,
+		Level:       c.level,
+		Rounds:      make([]Round, 0, len(c.rounds)),
+		Answered:    answered,
+		Score:       attempt.score,
+	}
+	for i, r := range c.rounds {
+		g.Rounds = append(g.Rounds, Round{Round: i + 1, WordID: r.WordID, English: r.English, Options: r.Options})
+	}
+	return g, nil
+}
+
+// Answer 提交一轮作答；本方答完全部题目后结算：发起者完成则发出挑战，对方完成则挑战结束
+func (s *Service) Answer(userID int, challengeID int, req *AnswerRequest) (*AnswerResult, error) {
+	if req.ResponseTimeMs < 0 {
+		return nil, errors.New("invalid response time")
+	}
+	c, err := s.getChallenge(challengeID)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := s.getAttempt(challengeID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		return nil, errors.New("challenge not started")
+	}
+	if attempt.finished {
+		return nil, errors.New("challenge already played")
+	}
+	if req.Round > len(c.rounds) {
+		return nil, errors.New("invalid round")
+	}
+
+	round := c.rounds[req.Round-1]
+	result := &AnswerResult{
+		Round:    req.Round,
+		Correct:  strings.TrimSpace(req.Answer) == round.Answer,
+		Expected: round.Answer,
+	}
+	if result.Correct {
+		result.Points = challengeBasePoints
+		switch {
+		case req.ResponseTimeMs > 0 && req.ResponseTimeMs < challengeFastAnswer:
+			result.Points += 5
+		case req.ResponseTimeMs > 0 && req.ResponseTimeMs < challengeQuickAnswer:
+			result.Points += 2
+		}
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO challenge_answers (attempt_id, round, answer, is_correct, points, response_time_ms)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, attempt.id, req.Round, truncate(req.Answer, 200), result.Correct, result.Points, req.ResponseTimeMs)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return nil, errors.New("round already answered")
+		}
+		return nil, fmt.Errorf("failed to save challenge answer: %w", err)
+	}
+
+	_, err = s.games.RecordAnswers(userID, &game.RecordAnswersRequest{
+		SessionID: attempt.sessionID,
+		GameType:  game.GameTypeChallenge,
+		Answers: []game.AnswerInput{{
+			WordID:         round.WordID,
+			ChosenOption:   req.Answer,
+			IsCorrect:      result.Correct,
+			ResponseTimeMs: req.ResponseTimeMs,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var answered int
+	err = s.db.QueryRow(`
+		SELECT COALESCE(SUM(points), 0), COALESCE(SUM(is_correct), 0), COUNT(*)
+		FROM challenge_answers WHERE attempt_id = ?
+	`, attempt.id).Scan(&attempt.score, &attempt.correct, &answered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count challenge answers: %w", err)
+	}
+	_, err = s.db.Exec("UPDATE challenge_attempts SET score = ?, correct_count = ? WHERE id = ?",
+		attempt.score, attempt.correct, attempt.id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update challenge attempt: %w", err)
+	}
+	result.Score = attempt.score
+
+	if answered < len(c.rounds) {
+		return result, nil
+	}
+	if err := s.finishAttempt(c, userID, attempt); err != nil {
+		return nil, err
+	}
+	result.Finished = true
+	result.Result, err = s.Get(userID, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Decline 拒绝挑战
+func (s *Service) Decline(userID int, challengeID int) error {
+	result, err := s.db.Exec(`
+		UPDATE challenges SET status = ? WHERE id = ? AND opponent_id = ? AND status = ?
+	`, StatusDeclined, challengeID, userID, StatusSent)
+	if err != nil {
+		return fmt.Errorf("failed to decline challenge: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("challenge not found or already accepted")
+	}
+	return nil
+}
+
+// List 列出收到的或发出的挑战；收到的挑战不包含对方尚未玩完（未发出）的
+func (s *Service) List(userID int, box Box, limit int) ([]Challenge, error) {
+	if err := s.expireChallenges(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	where := "c.challenger_id = ?"
+	if box == BoxIncoming {
+		where = "c.opponent_id = ? AND c.status <> 'pending'"
+	}
+	rows, err := s.db.Query(`
+		SELECT c.id, c.challenger_id, cu.username, c.opponent_id, ou.username, c.level, c.status,
+		       ca.score, ca.finished_at, oa.score, oa.finished_at, c.created_at, c.expires_at
+		FROM challenges c
+		JOIN users cu ON cu.id = c.challenger_id
+		JOIN users ou ON ou.id = c.opponent_id
+		LEFT JOIN challenge_attempts ca ON ca.challenge_id = c.id AND ca.user_id = c.challenger_id
+		LEFT JOIN challenge_attempts oa ON oa.challenge_id = c.id AND oa.user_id = c.opponent_id
+		WHERE `+where+`
+		ORDER BY c.created_at DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query challenges: %w", err)
+	}
+	defer rows.Close()
+
+	var challenges []Challenge
+	for rows.Next() {
+		var ch Challenge
+		var challengerScore, opponentScore sql.NullInt64
+		var challengerDone, opponentDone, expires sql.NullTime
+		if err := rows.Scan(&ch.ID, &ch.ChallengerID, &ch.ChallengerName, &ch.OpponentID, &ch.OpponentName,
+			&ch.Level, &ch.Status, &challengerScore, &challengerDone, &opponentScore, &opponentDone,
+			&ch.CreatedAt, &expires); err != nil {
+			return nil, fmt.Errorf("failed to scan challenge: %w", err)
+		}
+		// 只展示已完成一方的分数；对方未完成前不透露发起者分数以外的信息
+		if challengerDone.Valid {
+			score := int(challengerScore.Int64)
+			ch.ChallengerScore = &score
+		}
+		if opponentDone.Valid {
+			score := int(opponentScore.Int64)
+			ch.OpponentScore = &score
+		}
+		if expires.Valid {
+			ch.ExpiresAt = &expires.Time
+		}
+		challenges = append(challenges, ch)
+	}
+	return challenges, nil
+}
+
+// Get 获取双方对比结果；对方的逐题作答在挑战结束后才公开
+func (s *Service) Get(userID int, challengeID int) (*HeadToHead, error) {
+	if err := s.expireChallenges(); err != nil {
+		return nil, err
+	}
+	c, err := s.getChallenge(challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if userID != c.challengerID && userID != c.opponentID {
+		return nil, errors.New("challenge not found")
+	}
+	if userID == c.opponentID && c.status == StatusPending {
+		return nil, errors.New("challenge not found")
+	}
+
+	result := &HeadToHead{ChallengeID: challengeID, Status: c.status}
+	for _, playerID := range []int{c.challengerID, c.opponentID} {
+		board := PlayerBoard{UserID: playerID}
+		if err := s.db.QueryRow("SELECT username FROM users WHERE id = ?", playerID).Scan(&board.Username); err != nil {
+			return nil, fmt.Errorf("failed to get player: %w", err)
+		}
+		attempt, err := s.getAttempt(challengeID, playerID)
+		if err != nil {
+			return nil, err
+		}
+		if attempt != nil {
+			board.Started = true
+			board.Finished = attempt.finished
+			if attempt.finished || playerID == userID {
+				board.Score = attempt.score
+				board.Correct = attempt.correct
+			}
+			if playerID == userID || c.status == StatusCompleted {
+				board.Answers, err = s.roundAnswers(attempt.id, c.rounds)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		result.Players = append(result.Players, board)
+	}
+
+	if c.status == StatusCompleted {
+		switch {
+		case result.Players[0].Score > result.Players[1].Score:
+			result.WinnerID = c.challengerID
+		case result.Players[1].Score > result.Players[0].Score:
+			result.WinnerID = c.opponentID
+		}
+	}
+	return result, nil
+}
+
+// challengeRecord 数据库中的挑战
+type challengeRecord struct {
+	id           int
+	challengerID int
+	opponentID   int
+	level        int
+	status       Status
+	rounds       []frozenRound
+}
+
+// attemptRecord 一方的挑战记录
+type attemptRecord struct {
+	id        int
+	sessionID int
+	score     int
+	correct   int
+	elapsed   int
+	finished  bool
+}
+
+func (s *Service) getChallenge(challengeID int) (*challengeRecord, error) {
+	c := &challengeRecord{id: challengeID}
+	var rounds string
+	err := s.db.QueryRow(`
+		SELECT challenger_id, opponent_id, level, status, rounds FROM challenges WHERE id = ?
+	`, challengeID).Scan(&c.challengerID, &c.opponentID, &c.level, &c.status, &rounds)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("challenge not found")
+		}
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	if err := json.Unmarshal([]byte(rounds), &c.rounds); err != nil {
+		return nil, fmt.Errorf("failed to decode challenge rounds: %w", err)
+	}
+	return c, nil
+}
+
+// getAttempt 获取一方的挑战记录，尚未开始时返回 nil
+func (s *Service) getAttempt(challengeID int, userID int) (*attemptRecord, error) {
+	a := &attemptRecord{}
+	var finishedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT id, session_id, score, correct_count, TIMESTAMPDIFF(SECOND, started_at, NOW()), finished_at
+		FROM challenge_attempts WHERE challenge_id = ? AND user_id = ?
+	`, challengeID, userID).Scan(&a.id, &a.sessionID, &a.score, &a.correct, &a.elapsed, &finishedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge attempt: %w", err)
+	}
+	a.finished = finishedAt.Valid
+	return a, nil
+}
+
+// startAttempt 为一方创建挑战记录和游戏会话（会话单词即冻结的题目单词）
+func (s *Service) startAttempt(c *challengeRecord, userID int) (*attemptRecord, error) {
+	words := make([]game.AdventureWord, 0, len(c.rounds))
+	for _, r := range c.rounds {
+		words = append(words, game.AdventureWord{ID: r.WordID, English: r.English, Chinese: r.Answer})
+	}
+	sessionID, err := s.games.CreateFixedSession(userID, game.GameTypeChallenge, c.level, words)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO challenge_attempts (challenge_id, user_id, session_id) VALUES (?, ?, ?)
+	`, c.id, userID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start challenge attempt: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge attempt ID: %w", err)
+	}
+	return &attemptRecord{id: int(id), sessionID: sessionID}, nil
+}
+
+// finishAttempt 一方答完全部题目：写入游戏记录并推进挑战状态
+func (s *Service) finishAttempt(c *challengeRecord, userID int, attempt *attemptRecord) error {
+	result, err := s.db.Exec(`
+		UPDATE challenge_attempts SET finished_at = NOW() WHERE id = ? AND finished_at IS NULL
+	`, attempt.id)
+	if err != nil {
+		return fmt.Errorf("failed to finish challenge attempt: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+
+	err = s.games.SubmitScore(userID, &game.SubmitScoreRequest{
+		GameType:     game.GameTypeChallenge,
+		Score:        attempt.score,
+		LevelReached: c.level,
+		TimeSpent:    attempt.elapsed,
+		SessionID:    attempt.sessionID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if userID == c.challengerID {
+		if err := s.send(c, attempt.score); err != nil {
+			return err
+		}
+		c.status = StatusSent
+		return nil
+	}
+
+	if _, err := s.db.Exec(`
+		UPDATE challenges SET status = ?, completed_at = NOW() WHERE id = ?
+	`, StatusCompleted, c.id); err != nil {
+		return fmt.Errorf("failed to update challenge status: %w", err)
+	}
+	c.status = StatusCompleted
+	return nil
+}
+
+// send 发起者完成后把挑战发给对手，同时写入挑战事件
+func (s *Service) send(c *challengeRecord, score int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE challenges SET status = ?, sent_at = NOW(), expires_at = DATE_ADD(NOW(), INTERVAL ? DAY)
+		WHERE id = ?
+	`, StatusSent, challengeExpireDays, c.id); err != nil {
+		return fmt.Errorf("failed to update challenge status: %w", err)
+	}
+	var expiresAt time.Time
+	if err := tx.QueryRow("SELECT expires_at FROM challenges WHERE id = ?", c.id).Scan(&expiresAt); err != nil {
+		return fmt.Errorf("failed to get challenge expiry: %w", err)
+	}
+	if err := events.Write(tx, events.ChallengeSent, c.challengerID, &events.ChallengeSentPayload{
+		ChallengeID: c.id,
+		OpponentID:  c.opponentID,
+		Level:       c.level,
+		Score:       score,
+		ExpiresAt:   expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit challenge: %w", err)
+	}
+	return nil
+}
+
+// answeredRounds 已作答的轮次
+func (s *Service) answeredRounds(attemptID int) ([]int, error) {
+	rows, err := s.db.Query("SELECT round FROM challenge_answers WHERE attempt_id = ? ORDER BY round", attemptID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query challenge answers: %w", err)
+	}
+	defer rows.Close()
+
+	answered := []int{}
+	for rows.Next() {
+		var round int
+		if err := rows.Scan(&round); err != nil {
+			return nil, fmt.Errorf("failed to scan challenge answer: %w", err)
+		}
+		answered = append(answered, round)
+	}
+	return answered, nil
+}
+
+// roundAnswers 一方的逐题作答
+func (s *Service) roundAnswers(attemptID int, rounds []frozenRound) ([]RoundAnswer, error) {
+	rows, err := s.db.Query(`
+		SELECT round, answer, is_correct, points, response_time_ms
+		FROM challenge_answers WHERE attempt_id = ? ORDER BY round
+	`, attemptID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query challenge answers: %w", err)
+	}
+	defer rows.Close()
+
+	var answers []RoundAnswer
+	for rows.Next() {
+		var a RoundAnswer
+		var answer sql.NullString
+		if err := rows.Scan(&a.Round, &answer, &a.Correct, &a.Points, &a.ResponseTimeMs); err != nil {
+			return nil, fmt.Errorf("failed to scan challenge answer: %w", err)
+		}
+		a.Answer = answer.String
+		if a.Round >= 1 && a.Round <= len(rounds) {
+			a.English = rounds[a.Round-1].English
+			a.Expected = rounds[a.Round-1].Answer
+		}
+		answers = append(answers, a)
+	}
+	return answers, nil
+}
+
+// resolveOpponent 按ID或用户名找到对手
+func (s *Service) resolveOpponent(req *CreateRequest) (int, error) {
+	var id int
+	var err error
+	switch {
+	case req.OpponentID > 0:
+		err = s.db.QueryRow("SELECT id FROM users WHERE id = ?", req.OpponentID).Scan(&id)
+	case req.OpponentUsername != "":
+		err = s.db.QueryRow("SELECT id FROM users WHERE username = ?", req.OpponentUsername).Scan(&id)
+	default:
+		return 0, errors.New("opponent_id or opponent_username is required")
+	}
+	if err == sql.ErrNoRows {
+		return 0, errors.New("opponent not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get opponent: %w", err)
+	}
+	return id, nil
+}
+
+// expireChallenges 把超过期限仍未应战的挑战标记为过期
+func (s *Service) expireChallenges() error {
+	_, err := s.db.Exec(`
+		UPDATE challenges SET status = ? WHERE status = ? AND expires_at < NOW()
+	`, StatusExpired, StatusSent)
+	if err != nil {
+		return fmt.Errorf("failed to expire challenges: %w", err)
+	}
+	return nil
+}
+
+// truncate 按字符截断字符串
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,99 @@
+package classroom
+
+import (
+	"linguaforge/internal/leaderboard"
+	"time"
+)
+
+// Class 班级
+type Class struct {
+	ID           int       `json:"id"`
+	TeacherID    int       `json:"teacher_id"`
+	TeacherName  string    `json:"teacher_name"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	JoinCode     string    `json:"join_code,omitempty"` // 只返回给班级教师
+	Archived     bool      `json:"archived"`
+	StudentCount int       `json:"student_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ClassRequest 创建/更新班级请求
+type ClassRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Archived    bool   `json:"archived"`
+}
+
+// JoinRequest 学生加入班级请求
+type JoinRequest struct {
+	JoinCode string `json:"join_code" binding:"required"`
+}
+
+// AddStudentRequest 教师按ID或用户名邀请学生加入班级
+type AddStudentRequest struct {
+	StudentID int    `json:"student_id"`
+	Username  string `json:"username"`
+}
+
+// InviteStatus 班级邀请状态
+type InviteStatus string
+
+const (
+	InvitePending  InviteStatus = "pending" // 等待学生同意
+	InviteAccepted InviteStatus = "accepted"
+	InviteDeclined InviteStatus = "declined"
+	InviteRevoked  InviteStatus = "revoked" // 教师撤回
+)
+
+// Invite 班级邀请
+type Invite struct {
+	ID          int          `json:"id"`
+	ClassID     int          `json:"class_id"`
+	ClassName   string       `json:"class_name"`
+	TeacherName string       `json:"teacher_name"`
+	StudentID   int          `json:"student_id"`
+	StudentName string       `json:"student_name"`
+	Status      InviteStatus `json:"status"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// RespondInviteRequest 学生处理班级邀请
+type RespondInviteRequest struct {
+	Accept bool `json:"accept"`
+}
+
+// Student 班级名单中的学生
+type Student struct {
+	ID       int       `json:"id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Level    int       `json:"level"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// StudentSummary 教师看板中单个学生的学习概况
+type StudentSummary struct {
+	StudentID    int        `json:"student_id"`
+	Username     string     `json:"username"`
+	Level        int        `json:"level"`
+	WordsStudied int        `json:"words_studied"`
+	Answers      int        `json:"answers"`
+	Accuracy     float64    `json:"accuracy"` // 0-1，没有作答时为 0
+	GamesPlayed  int        `json:"games_played"`
+	TimeSpent    int        `json:"time_spent"` // 秒，来自 game_records.time_spent
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+}
+
+// Dashboard 教师看板
+type Dashboard struct {
+	ClassID  int              `json:"class_id"`
+	Days     int              `json:"days"` // 统计最近多少天，0 表示全部
+	Students []StudentSummary `json:"students"`
+}
+
+// LeaderboardResponse 班级排行榜
+type LeaderboardResponse struct {
+	ClassID int `json:"class_id"`
+	*leaderboard.LeaderboardResponse
+}
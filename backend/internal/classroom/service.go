@@ -0,0 +1,567 @@
+package classroom
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"linguaforge/internal/leaderboard"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	joinCodeLength   = 6
+	joinCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789" // 去掉容易混淆的 0/O、1/I/L
+	joinCodeAttempts = 5
+)
+
+type Service struct {
+	db          *sql.DB
+	leaderboard *leaderboard.Service
+}
+
+func NewService(db *sql.DB, leaderboard *leaderboard.Service) *Service {
+	return &Service{
+		db:          db,
+		leaderboard: leaderboard,
+	}
+}
+
+// CreateClass 教师创建班级，自动生成邀请码
+func (s *Service) CreateClass(teacherID int, req *ClassRequest) (*Class, error) {
+	for i := 0; i < joinCodeAttempts; i++ {
+		code, err := newJoinCode()
+		if err != nil {
+			return nil, err
+		}
+		result, err := s.db.Exec(`
+			INSERT INTO classes (teacher_id, name, description, join_code) VALUES (?, ?, ?, ?)
+		`, teacherID, req.Name, req.Description, code)
+		if err != nil {
+			if isDuplicate(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to create class: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get class ID: %w", err)
+		}
+		return s.getClass(int(id), true)
+	}
+	return nil, errors.New("failed to generate a unique join code")
+}
+
+// UpdateClass 更新班级名称、简介和归档状态
+func (s *Service) UpdateClass(teacherID int, classID int, req *ClassRequest) (*Class, error) {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+	_, err := s.db.Exec(`
+		UPDATE classes SET name = ?, description = ?, is_archived = ? WHERE id = ?
+	`, req.Name, req.Description, req.Archived, classID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update class: %w", err)
+	}
+	return s.getClass(classID, true)
+}
+
+// DeleteClass 删除班级（成员关系一并删除）
+func (s *Service) DeleteClass(teacherID int, classID int) error {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("DELETE FROM classes WHERE id = ?", classID); err != nil {
+		return fmt.Errorf("failed to delete class: %w", err)
+	}
+	return nil
+}
+
+// ResetJoinCode 重新生成邀请码，旧邀请码立即失效
+func (s *Service) ResetJoinCode(teacherID int, classID int) (*Class, error) {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+	for i := 0; i < joinCodeAttempts; i++ {
+		code, err := newJoinCode()
+		if err != nil {
+			return nil, err
+		}
+		_, err = s.db.Exec("UPDATE classes SET join_code = ? WHERE id = ?", code, classID)
+		if err != nil {
+			if isDuplicate(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to reset join code: %w", err)
+		}
+		return s.getClass(classID, true)
+	}
+	return nil, errors.New("failed to generate a unique join code")
+}
+
+// ListClasses 我任教的班级和我加入的班级
+func (s *Service) ListClasses(userID int) (teaching []Class, enrolled []Class, err error) {
+	teaching, err = s.queryClasses("c.teacher_id = ?", true, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	enrolled, err = s.queryClasses("c.id IN (SELECT class_id FROM class_members WHERE student_id = ?)", false, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return teaching, enrolled, nil
+}
+
+// GetClass 获取班级详情，仅班级教师和成员可见
+func (s *Service) GetClass(userID int, classID int) (*Class, error) {
+	isTeacher, err := s.checkAccess(userID, classID)
+	if err != nil {
+		return nil, err
+	}
+	return s.getClass(classID, isTeacher)
+}
+
+// JoinClass 学生通过邀请码加入班级
+func (s *Service) JoinClass(studentID int, joinCode string) (*Class, error) {
+	var classID, teacherID int
+	var archived bool
+	err := s.db.QueryRow(`
+		SELECT id, teacher_id, is_archived FROM classes WHERE join_code = ?
+	`, strings.ToUpper(strings.TrimSpace(joinCode))).Scan(&classID, &teacherID, &archived)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid join code")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class: %w", err)
+	}
+	if archived {
+		return nil, errors.New("class is archived")
+	}
+	if teacherID == studentID {
+		return nil, errors.New("cannot join your own class")
+	}
+
+	if err := s.addMember(classID, studentID); err != nil {
+		return nil, err
+	}
+	return s.getClass(classID, false)
+}
+
+// LeaveClass 学生退出班级
+func (s *Service) LeaveClass(studentID int, classID int) error {
+	result, err := s.db.Exec("DELETE FROM class_members WHERE class_id = ? AND student_id = ?", classID, studentID)
+	if err != nil {
+		return fmt.Errorf("failed to leave class: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("not a member of this class")
+	}
+	return nil
+}
+
+// GetRoster 班级名单
+func (s *Service) GetRoster(teacherID int, classID int) ([]Student, error) {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT u.id, u.username, COALESCE(u.email, ''), u.level, m.joined_at
+		FROM class_members m
+		JOIN users u ON u.id = m.student_id
+		WHERE m.class_id = ?
+		ORDER BY u.username
+	`, classID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roster: %w", err)
+	}
+	defer rows.Close()
+
+	students := []Student{}
+	for rows.Next() {
+		var st Student
+		if err := rows.Scan(&st.ID, &st.Username, &st.Email, &st.Level, &st.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan student: %w", err)
+		}
+		students = append(students, st)
+	}
+	return students, nil
+}
+
+// AddStudent 教师按ID或用户名邀请学生，学生同意后才加入班级（加入前教师看不到该学生的任何数据）
+func (s *Service) AddStudent(teacherID int, classID int, req *AddStudentRequest) (*Invite, error) {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+
+	var studentID int
+	var err error
+	switch {
+	case req.StudentID > 0:
+		err = s.db.QueryRow("SELECT id FROM users WHERE id = ?", req.StudentID).Scan(&studentID)
+	case req.Username != "":
+		err = s.db.QueryRow("SELECT id FROM users WHERE username = ?", req.Username).Scan(&studentID)
+	default:
+		return nil, errors.New("student_id or username is required")
+	}
+	if err == sql.ErrNoRows {
+		return nil, errors.New("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if studentID == teacherID {
+		return nil, errors.New("cannot add yourself to your own class")
+	}
+
+	var archived, member bool
+	err = s.db.QueryRow(`
+		SELECT c.is_archived, EXISTS(SELECT 1 FROM class_members WHERE class_id = c.id AND student_id = ?)
+		FROM classes c WHERE c.id = ?
+	`, studentID, classID).Scan(&archived, &member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class: %w", err)
+	}
+	if archived {
+		return nil, errors.New("class is archived")
+	}
+	if member {
+		return nil, errors.New("student is already in this class")
+	}
+
+	// 已拒绝、已撤回或学生退出后可以重新邀请
+	_, err = s.db.Exec(`
+		INSERT INTO class_invites (class_id, student_id, invited_by, status)
+		VALUES (?, ?, ?, 'pending')
+		ON DUPLICATE KEY UPDATE
+		    invited_by = VALUES(invited_by),
+		    created_at = IF(status = 'pending', created_at, NOW()),
+		    responded_at = NULL,
+		    status = 'pending'
+	`, classID, studentID, teacherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create class invite: %w", err)
+	}
+
+	invites, err := s.queryInvites("i.class_id = ? AND i.student_id = ?", classID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(invites) == 0 {
+		return nil, errors.New("invite not found")
+	}
+	return &invites[0], nil
+}
+
+// ListClassInvites 教师查看班级中等待学生同意的邀请
+func (s *Service) ListClassInvites(teacherID int, classID int) ([]Invite, error) {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+	return s.queryInvites("i.class_id = ? AND i.status = 'pending'", classID)
+}
+
+// ListInvites 学生收到的待处理班级邀请
+func (s *Service) ListInvites(studentID int) ([]Invite, error) {
+	return s.queryInvites("i.student_id = ? AND i.status = 'pending'", studentID)
+}
+
+// RespondInvite 学生同意或拒绝班级邀请，同意后加入班级
+func (s *Service) RespondInvite(studentID int, inviteID int, accept bool) (*Class, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var classID int
+	var archived bool
+	err = tx.QueryRow(`
+		SELECT i.class_id, c.is_archived
+		FROM class_invites i JOIN classes c ON c.id = i.class_id
+		WHERE i.id = ? AND i.student_id = ? AND i.status = 'pending'
+		FOR UPDATE
+	`, inviteID, studentID).Scan(&classID, &archived)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invite not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class invite: %w", err)
+	}
+	if accept && archived {
+		return nil, errors.New("class is archived")
+	}
+
+	status := InviteDeclined
+	if accept {
+		status = InviteAccepted
+	}
+	if _, err := tx.Exec("UPDATE class_invites SET status = ?, responded_at = NOW() WHERE id = ?", status, inviteID); err != nil {
+		return nil, fmt.Errorf("failed to respond class invite: %w", err)
+	}
+	if accept {
+		if _, err := tx.Exec("INSERT IGNORE INTO class_members (class_id, student_id) VALUES (?, ?)", classID, studentID); err != nil {
+			return nil, fmt.Errorf("failed to add class member: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit class invite: %w", err)
+	}
+	if !accept {
+		return nil, nil
+	}
+	return s.getClass(classID, false)
+}
+
+// RemoveStudent 把学生移出班级，或撤回尚未处理的邀请
+func (s *Service) RemoveStudent(teacherID int, classID int, studentID int) error {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return err
+	}
+	result, err := s.db.Exec(`
+		UPDATE class_invites SET status = 'revoked', responded_at = NOW()
+		WHERE class_id = ? AND student_id = ? AND status = 'pending'
+	`, classID, studentID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke class invite: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		return nil
+	}
+	return s.LeaveClass(studentID, classID)
+}
+
+// GetLeaderboard 班级排行榜，复用全站排行榜的查询，只统计班级学生
+func (s *Service) GetLeaderboard(userID int, classID int, req *leaderboard.LeaderboardRequest) (*LeaderboardResponse, error) {
+	if _, err := s.checkAccess(userID, classID); err != nil {
+		return nil, err
+	}
+	studentIDs, err := s.StudentIDs(classID)
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.leaderboard.GetScopedLeaderboard(req, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &LeaderboardResponse{ClassID: classID, LeaderboardResponse: board}, nil
+}
+
+// GetDashboard 教师看板：每个学生的学习单词数、正确率、游戏时长和最近活跃时间
+// days > 0 时只统计最近 days 天的学习数据，最近活跃时间始终按全部记录计算
+func (s *Service) GetDashboard(teacherID int, classID int, days int) (*Dashboard, error) {
+	if err := s.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+	if days < 0 {
+		days = 0
+	}
+	since := time.Unix(0, 0)
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT u.id, u.username, u.level,
+		       COALESCE(p.words, 0), COALESCE(a.answers, 0), COALESCE(a.correct, 0),
+		       COALESCE(g.games, 0), COALESCE(g.time_spent, 0),
+		       (SELECT MAX(answered_at) FROM answer_events WHERE user_id = u.id),
+		       (SELECT MAX(completed_at) FROM game_records WHERE user_id = u.id)
+		FROM class_members m
+		JOIN users u ON u.id = m.student_id
+		LEFT JOIN (
+			SELECT up.user_id, COUNT(*) AS words
+			FROM user_progress up
+			JOIN class_members cm ON cm.student_id = up.user_id AND cm.class_id = ?
+			WHERE up.last_studied >= ?
+			GROUP BY up.user_id
+		) p ON p.user_id = u.id
+		LEFT JOIN (
+			SELECT ae.user_id, COUNT(*) AS answers, SUM(ae.is_correct) AS correct
+			FROM answer_events ae
+			JOIN class_members cm ON cm.student_id = ae.user_id AND cm.class_id = ?
+			WHERE ae.answered_at >= ?
+			GROUP BY ae.user_id
+		) a ON a.user_id = u.id
+		LEFT JOIN (
+			SELECT gr.user_id, COUNT(*) AS games, SUM(gr.time_spent) AS time_spent
+			FROM game_records gr
+			JOIN class_members cm ON cm.student_id = gr.user_id AND cm.class_id = ?
+			WHERE gr.completed_at >= ?
+			GROUP BY gr.user_id
+		) g ON g.user_id = u.id
+		WHERE m.class_id = ?
+		ORDER BY u.username
+	`, classID, since, classID, since, classID, since, classID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query class dashboard: %w", err)
+	}
+	defer rows.Close()
+
+	dashboard := &Dashboard{ClassID: classID, Days: days, Students: []StudentSummary{}}
+	for rows.Next() {
+		var st StudentSummary
+		var correct int
+		var lastAnswer, lastGame sql.NullTime
+		if err := rows.Scan(&st.StudentID, &st.Username, &st.Level, &st.WordsStudied, &st.Answers, &correct,
+			&st.GamesPlayed, &st.TimeSpent, &lastAnswer, &lastGame); err != nil {
+			return nil, fmt.Errorf("failed to scan student summary: %w", err)
+		}
+		if st.Answers > 0 {
+			st.Accuracy = float64(correct) / float64(st.Answers)
+		}
+		for _, t := range []sql.NullTime{lastAnswer, lastGame} {
+			if t.Valid && (st.LastActiveAt == nil || t.Time.After(*st.LastActiveAt)) {
+				last := t.Time
+				st.LastActiveAt = &last
+			}
+		}
+		dashboard.Students = append(dashboard.Students, st)
+	}
+	return dashboard, nil
+}
+
+// StudentIDs 班级全部学生ID
+func (s *Service) StudentIDs(classID int) ([]int, error) {
+	rows, err := s.db.Query("SELECT student_id FROM class_members WHERE class_id = ?", classID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query class members: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan class member: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CheckTeacher 校验用户是班级的任课教师
+func (s *Service) CheckTeacher(teacherID int, classID int) error {
+	var owner int
+	err := s.db.QueryRow("SELECT teacher_id FROM classes WHERE id = ?", classID).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != teacherID) {
+		return errors.New("class not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get class: %w", err)
+	}
+	return nil
+}
+
+// checkAccess 校验用户是班级教师或成员，返回是否为教师
+func (s *Service) checkAccess(userID int, classID int) (bool, error) {
+	var isTeacher, isMember bool
+	err := s.db.QueryRow(`
+		SELECT c.teacher_id = ?,
+		       EXISTS(SELECT 1 FROM class_members WHERE class_id = c.id AND student_id = ?)
+		FROM classes c WHERE c.id = ?
+	`, userID, userID, classID).Scan(&isTeacher, &isMember)
+	if err == sql.ErrNoRows || (err == nil && !isTeacher && !isMember) {
+		return false, errors.New("class not found")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get class: %w", err)
+	}
+	return isTeacher, nil
+}
+
+func (s *Service) addMember(classID int, studentID int) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO class_members (class_id, student_id) VALUES (?, ?)", classID, studentID)
+	if err != nil {
+		return fmt.Errorf("failed to add class member: %w", err)
+	}
+	return nil
+}
+
+// queryInvites 按条件查询班级邀请
+func (s *Service) queryInvites(where string, args ...interface{}) ([]Invite, error) {
+	rows, err := s.db.Query(`
+		SELECT i.id, i.class_id, c.name, t.username, i.student_id, u.username, i.status, i.created_at
+		FROM class_invites i
+		JOIN classes c ON c.id = i.class_id
+		JOIN users t ON t.id = c.teacher_id
+		JOIN users u ON u.id = i.student_id
+		WHERE `+where+`
+		ORDER BY i.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query class invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []Invite{}
+	for rows.Next() {
+		var inv Invite
+		if err := rows.Scan(&inv.ID, &inv.ClassID, &inv.ClassName, &inv.TeacherName,
+			&inv.StudentID, &inv.StudentName, &inv.Status, &inv.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan class invite: %w", err)
+		}
+		invites = append(invites, inv)
+	}
+	return invites, nil
+}
+
+func (s *Service) getClass(classID int, withCode bool) (*Class, error) {
+	classes, err := s.queryClasses("c.id = ?", withCode, classID)
+	if err != nil {
+		return nil, err
+	}
+	if len(classes) == 0 {
+		return nil, errors.New("class not found")
+	}
+	return &classes[0], nil
+}
+
+// queryClasses 按条件查询班级；withCode 为 false 时不返回邀请码
+func (s *Service) queryClasses(where string, withCode bool, args ...interface{}) ([]Class, error) {
+	rows, err := s.db.Query(`
+		SELECT c.id, c.teacher_id, u.username, c.name, COALESCE(c.description, ''), c.join_code, c.is_archived,
+		       (SELECT COUNT(*) FROM class_members WHERE class_id = c.id), c.created_at
+		FROM classes c
+		JOIN users u ON u.id = c.teacher_id
+		WHERE `+where+`
+		ORDER BY c.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query classes: %w", err)
+	}
+	defer rows.Close()
+
+	classes := []Class{}
+	for rows.Next() {
+		var c Class
+		if err := rows.Scan(&c.ID, &c.TeacherID, &c.TeacherName, &c.Name, &c.Description, &c.JoinCode,
+			&c.Archived, &c.StudentCount, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan class: %w", err)
+		}
+		if !withCode {
+			c.JoinCode = ""
+		}
+		classes = append(classes, c)
+	}
+	return classes, nil
+}
+
+// newJoinCode 生成随机邀请码
+func newJoinCode() (string, error) {
+	code := make([]byte, joinCodeLength)
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate join code: %w", err)
+		}
+		code[i] = joinCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func isDuplicate(err error) bool {
+	return strings.Contains(err.Error(), "Duplicate entry")
+}
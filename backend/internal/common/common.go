@@ -0,0 +1,43 @@
+// Package common 各模块共用的小工具：随机令牌、Redis 锁释放和按自然日/周取整的时间计算
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RandomToken n 字节随机数的 base64url 编码（无填充），用于锁令牌、state、nonce 和一次性 code
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// releaseScript 只在值仍是自己写入的令牌时删除键
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// ReleaseLock 释放用 SET NX 获取的锁；锁已过期并被其他持有者获取时不会误删
+func ReleaseLock(ctx context.Context, client *redis.Client, key string, token string) error {
+	return releaseScript.Run(ctx, client, []string{key}, token).Err()
+}
+
+// StartOfDay t 所在自然日的零点（保留时区）
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// WeekStart t 所在自然周的周一零点
+func WeekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7 // 周一为 0
+	return StartOfDay(t).AddDate(0, 0, -offset)
+}
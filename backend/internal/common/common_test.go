@@ -0,0 +1,27 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeekStart(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	monday := time.Date(2024, 10, 14, 0, 0, 0, 0, loc)
+	for day := 0; day < 7; day++ {
+		at := monday.AddDate(0, 0, day).Add(23*time.Hour + 59*time.Minute)
+		if got := WeekStart(at); !got.Equal(monday) {
+			t.Errorf("WeekStart(%s) = %s, want %s", at.Format("Mon 2006-01-02 15:04"), got, monday)
+		}
+	}
+	if got := StartOfDay(monday.Add(-time.Minute)); !got.Equal(monday.AddDate(0, 0, -1)) {
+		t.Errorf("StartOfDay before midnight = %s", got)
+	}
+}
+
+func TestRandomToken(t *testing.T) {
+	a, b := RandomToken(16), RandomToken(16)
+	if len(a) != 22 || a == b {
+		t.Fatalf("RandomToken(16) = %q, %q", a, b)
+	}
+}
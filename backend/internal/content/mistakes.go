@@ -0,0 +1,97 @@
+package content
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// 错题连续答对该次数后自动移出错题本
+const mistakeClearStreak = 3
+
+// recordMistake 根据一次作答更新错题本：答错则记入，答对则累计连续答对次数
+func recordMistake(tx *sql.Tx, userID int, wordID int, correct bool, chosen string) error {
+	if !correct {
+		_, err := tx.Exec(`
+			INSERT INTO user_mistakes (user_id, word_id, wrong_count, correct_streak, last_wrong_answer, last_wrong_at)
+			VALUES (?, ?, 1, 0, ?, NOW())
+			ON DUPLICATE KEY UPDATE
+			    wrong_count = wrong_count + 1, correct_streak = 0,
+			    last_wrong_answer = VALUES(last_wrong_answer), last_wrong_at = NOW()
+		`, userID, wordID, chosen)
+		if err != nil {
+			return fmt.Errorf("failed to record mistake: %w", err)
+		}
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		UPDATE user_mistakes SET correct_streak = correct_streak + 1, updated_at = NOW()
+		WHERE user_id = ? AND word_id = ?
+	`, userID, wordID)
+	if err != nil {
+		return fmt.Errorf("failed to update mistake: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM user_mistakes
+		WHERE user_id = ? AND word_id = ? AND correct_streak >= ?
+	`, userID, wordID, mistakeClearStreak)
+	if err != nil {
+		return fmt.Errorf("failed to clear mistake: %w", err)
+	}
+
+	return nil
+}
+
+// GetMistakes 获取错题本（最近答错的在前）
+func (s *Service) GetMistakes(userID int, req *GetMistakesRequest) ([]Mistake, int, error) {
+	var total int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM user_mistakes WHERE user_id = ?", userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count mistakes: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT m.word_id, w.english, w.chinese, w.pronunciation,
+		       m.wrong_count, m.correct_streak, m.last_wrong_answer, m.last_wrong_at
+		FROM user_mistakes m
+		JOIN words w ON w.id = m.word_id
+		WHERE m.user_id = ?
+		ORDER BY m.last_wrong_at DESC
+		LIMIT ? OFFSET ?
+	`, userID, req.Limit, req.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query mistakes: %w", err)
+	}
+	defer rows.Close()
+
+	var mistakes []Mistake
+	for rows.Next() {
+		var m Mistake
+		var pronunciation, lastWrongAnswer sql.NullString
+		err := rows.Scan(
+			&m.WordID, &m.English, &m.Chinese, &pronunciation,
+			&m.WrongCount, &m.CorrectStreak, &lastWrongAnswer, &m.LastWrongAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan mistake: %w", err)
+		}
+		m.Pronunciation = pronunciation.String
+		m.LastWrongAnswer = lastWrongAnswer.String
+		mistakes = append(mistakes, m)
+	}
+
+	return mistakes, total, nil
+}
+
+// RemoveMistake 手动将单词移出错题本
+func (s *Service) RemoveMistake(userID int, wordID int) error {
+	result, err := s.db.Exec("DELETE FROM user_mistakes WHERE user_id = ? AND word_id = ?", userID, wordID)
+	if err != nil {
+		return fmt.Errorf("failed to remove mistake: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("mistake not found")
+	}
+	return nil
+}
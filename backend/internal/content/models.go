@@ -13,6 +13,7 @@ type Word struct {
 	AudioURL        string    `json:"audio_url" db:"audio_url"`
 	ImageURL        string    `json:"image_url" db:"image_url"`
 	Story           string    `json:"story" db:"story"`
+	ExampleSentence string    `json:"example_sentence" db:"example_sentence"`
 	DifficultyLevel int       `json:"difficulty_level" db:"difficulty_level"`
 	Category        string    `json:"category" db:"category"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
@@ -52,3 +53,21 @@ type UpdateProgressRequest struct {
 	StudyCount   int     `json:"study_count"`
 	MasteryLevel float64 `json:"mastery_level"`
 }
+
+// Mistake 错题本条目
+type Mistake struct {
+	WordID          int       `json:"word_id" db:"word_id"`
+	English         string    `json:"english"`
+	Chinese         string    `json:"chinese"`
+	Pronunciation   string    `json:"pronunciation"`
+	WrongCount      int       `json:"wrong_count" db:"wrong_count"`
+	CorrectStreak   int       `json:"correct_streak" db:"correct_streak"`
+	LastWrongAnswer string    `json:"last_wrong_answer" db:"last_wrong_answer"`
+	LastWrongAt     time.Time `json:"last_wrong_at" db:"last_wrong_at"`
+}
+
+// GetMistakesRequest 获取错题本请求
+type GetMistakesRequest struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
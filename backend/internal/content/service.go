@@ -3,6 +3,7 @@ package content
 import (
 	"database/sql"
 	"fmt"
+	"linguaforge/internal/events"
 )
 
 type Service struct {
@@ -18,7 +19,7 @@ func NewService(db *sql.DB) *Service {
 // GetWords 获取单词列表
 func (s *Service) GetWords(req *GetWordsRequest, userID int) ([]WordWithProgress, error) {
 	query := `
-		SELECT w.id, w.english, w.chinese, w.pronunciation, w.audio_url, w.image_url, w.story,
+		SELECT w.id, w.english, w.chinese, w.pronunciation, w.audio_url, w.image_url, w.story, w.example_sentence,
 		       w.difficulty_level, w.category, w.created_at, w.updated_at,
 		       up.id, up.user_id, up.word_id, up.study_count, up.mastery_level,
 		       up.last_studied, up.created_at as up_created_at, up.updated_at as up_updated_at
@@ -75,10 +76,10 @@ func (s *Service) GetWords(req *GetWordsRequest, userID int) ([]WordWithProgress
 		var progressUpdatedAt sql.NullTime
 
 		var pronunciation, audioURL, imageURL sql.NullString
-		var story sql.NullString
+		var story, exampleSentence sql.NullString
 		err := rows.Scan(
 			&word.ID, &word.English, &word.Chinese, &pronunciation,
-			&audioURL, &imageURL, &story, &word.DifficultyLevel, &word.Category,
+			&audioURL, &imageURL, &story, &exampleSentence, &word.DifficultyLevel, &word.Category,
 			&word.CreatedAt, &word.UpdatedAt,
 			&progressID, &progressUserID, &progressWordID, &studyCount,
 			&masteryLevel, &lastStudied, &progressCreatedAt, &progressUpdatedAt,
@@ -100,6 +101,9 @@ func (s *Service) GetWords(req *GetWordsRequest, userID int) ([]WordWithProgress
 		if story.Valid {
 			word.Story = story.String
 		}
+		if exampleSentence.Valid {
+			word.ExampleSentence = exampleSentence.String
+		}
 
 		// 如果有进度数据，则填充
 		if progressID.Valid {
@@ -124,15 +128,15 @@ func (s *Service) GetWords(req *GetWordsRequest, userID int) ([]WordWithProgress
 // GetWordByID 根据ID获取单词
 func (s *Service) GetWordByID(id int) (*Word, error) {
 	word := &Word{}
-	var pronunciation, audioURL, imageURL sql.NullString
+	var pronunciation, audioURL, imageURL, exampleSentence sql.NullString
 
 	err := s.db.QueryRow(`
-		SELECT id, english, chinese, pronunciation, audio_url, image_url, story,
+		SELECT id, english, chinese, pronunciation, audio_url, image_url, story, example_sentence,
 		       difficulty_level, category, created_at, updated_at
 		FROM words WHERE id = ?
 	`, id).Scan(
 		&word.ID, &word.English, &word.Chinese, &pronunciation,
-		&audioURL, &imageURL, &word.Story, &word.DifficultyLevel, &word.Category,
+		&audioURL, &imageURL, &word.Story, &exampleSentence, &word.DifficultyLevel, &word.Category,
 		&word.CreatedAt, &word.UpdatedAt,
 	)
 	if err != nil {
@@ -152,15 +156,24 @@ func (s *Service) GetWordByID(id int) (*Word, error) {
 	if imageURL.Valid {
 		word.ImageURL = imageURL.String
 	}
+	if exampleSentence.Valid {
+		word.ExampleSentence = exampleSentence.String
+	}
 
 	return word, nil
 }
 
 // UpdateUserProgress 更新用户学习进度
 func (s *Service) UpdateUserProgress(userID int, req *UpdateProgressRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// 检查是否已存在进度记录
 	var count int
-	err := s.db.QueryRow(`
+	err = tx.QueryRow(`
 		SELECT COUNT(*) FROM user_progress 
 		WHERE user_id = ? AND word_id = ?
 	`, userID, req.WordID).Scan(&count)
@@ -170,7 +183,7 @@ func (s *Service) UpdateUserProgress(userID int, req *UpdateProgressRequest) err
 
 	if count > 0 {
 		// 更新现有记录
-		_, err = s.db.Exec(`
+		_, err = tx.Exec(`
 			UPDATE user_progress 
 			SET study_count = study_count + ?, mastery_level = ?, 
 			    last_studied = NOW(), updated_at = NOW()
@@ -178,7 +191,7 @@ func (s *Service) UpdateUserProgress(userID int, req *UpdateProgressRequest) err
 		`, req.StudyCount, req.MasteryLevel, userID, req.WordID)
 	} else {
 		// 创建新记录
-		_, err = s.db.Exec(`
+		_, err = tx.Exec(`
 			INSERT INTO user_progress (user_id, word_id, study_count, mastery_level, last_studied)
 			VALUES (?, ?, ?, ?, NOW())
 		`, userID, req.WordID, req.StudyCount, req.MasteryLevel)
@@ -188,6 +201,18 @@ func (s *Service) UpdateUserProgress(userID int, req *UpdateProgressRequest) err
 		return fmt.Errorf("failed to update progress: %w", err)
 	}
 
+	mastery := req.MasteryLevel
+	if err := events.Write(tx, events.WordReviewed, userID, &events.WordReviewedPayload{
+		WordID:       req.WordID,
+		Source:       "study",
+		MasteryLevel: &mastery,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit progress: %w", err)
+	}
 	return nil
 }
 
@@ -244,3 +269,28 @@ func (s *Service) GetCategories() ([]string, error) {
 
 	return categories, nil
 }
+
+// 作答结果对掌握度的影响权重（指数滑动平均）
+const answerMasteryWeight = 0.3
+
+// RecordAnswer 根据游戏中的一次作答更新学习进度和错题本（在调用方的事务中执行，与作答记录一起提交）
+func (s *Service) RecordAnswer(tx *sql.Tx, userID int, wordID int, correct bool, chosen string) error {
+	outcome := 0.0
+	if correct {
+		outcome = 1.0
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO user_progress (user_id, word_id, study_count, mastery_level, last_studied)
+		VALUES (?, ?, 1, ?, NOW())
+		ON DUPLICATE KEY UPDATE
+		    study_count = study_count + 1,
+		    mastery_level = LEAST(1, GREATEST(0, mastery_level + ? * (? - mastery_level))),
+		    last_studied = NOW(), updated_at = NOW()
+	`, userID, wordID, answerMasteryWeight*outcome, answerMasteryWeight, outcome)
+	if err != nil {
+		return fmt.Errorf("failed to record answer progress: %w", err)
+	}
+
+	return recordMistake(tx, userID, wordID, correct, chosen)
+}
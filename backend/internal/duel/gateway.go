@@ -0,0 +1,164 @@
+package duel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"linguaforge/internal/common"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Serve 处理一条对战连接：排队（或重连进行中的对局），并在客户端与对局之间转发消息
+// 推送给玩家的消息统一走 Redis 频道，因此对局与连接可以在不同实例上
+func (s *Service) Serve(ws *websocket.Conn, userID int, level int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer ws.Close()
+
+	// 每个用户同时只允许一条对战连接
+	token := common.RandomToken(16)
+	ok, err := s.redis.SetNX(ctx, connKey(userID), token, duelIdleTimeout).Result()
+	if err != nil || !ok {
+		websocket.JSON.Send(ws, &ServerMessage{Type: MessageError, Error: "duel connection already open"})
+		return
+	}
+	defer common.ReleaseLock(context.Background(), s.redis, connKey(userID), token)
+
+	sub := s.redis.Subscribe(ctx, playerChannel(userID))
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		websocket.JSON.Send(ws, &ServerMessage{Type: MessageError, Error: "duel service unavailable"})
+		return
+	}
+
+	var matchID atomic.Int64
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for msg := range sub.Channel() {
+			var head struct {
+				Type    MessageType `json:"type"`
+				MatchID int         `json:"match_id"`
+			}
+			if err := json.Unmarshal([]byte(msg.Payload), &head); err != nil {
+				continue
+			}
+			if head.Type == MessageMatched {
+				matchID.Store(int64(head.MatchID))
+				ws.SetReadDeadline(time.Now().Add(duelIdleTimeout))
+			}
+			if err := websocket.Message.Send(ws, msg.Payload); err != nil {
+				return
+			}
+			if head.Type == MessageResult {
+				// 对局结束，关闭连接让读循环退出
+				ws.Close()
+				return
+			}
+		}
+	}()
+
+	// 有进行中的对局时直接重连，否则排队
+	active, err := s.activeMatch(ctx, userID)
+	if err != nil {
+		websocket.JSON.Send(ws, &ServerMessage{Type: MessageError, Error: "duel service unavailable"})
+		return
+	}
+	if active > 0 {
+		matchID.Store(int64(active))
+		received, err := s.forward(ctx, active, &matchEnvelope{UserID: userID, ClientMessage: ClientMessage{Type: messageRejoin}})
+		if err != nil || received == 0 {
+			// 对局所在实例已不存在
+			s.redis.Del(ctx, activeKey(userID))
+			matchID.Store(0)
+			active = 0
+		}
+	}
+	queued := false
+	if active == 0 {
+		if queued, err = s.enqueue(ctx, userID, level); err != nil {
+			websocket.JSON.Send(ws, &ServerMessage{Type: MessageError, Error: err.Error()})
+			return
+		}
+		if queued {
+			websocket.JSON.Send(ws, &ServerMessage{Type: MessageQueued, Level: level})
+			ws.SetReadDeadline(time.Now().Add(duelQueueTimeout))
+		}
+	}
+
+	for {
+		var msg ClientMessage
+		err := websocket.JSON.Receive(ws, &msg)
+		if err != nil {
+			current := int(matchID.Load())
+			var netErr interface{ Timeout() bool }
+			if current == 0 && errors.As(err, &netErr) && netErr.Timeout() {
+				// 排队超时；如果此时恰好已被对手取走，则继续等待对局开始
+				if still, _ := s.leaveQueue(ctx, userID, level); !still {
+					ws.SetReadDeadline(time.Now().Add(duelIdleTimeout))
+					continue
+				}
+				websocket.JSON.Send(ws, &ServerMessage{Type: MessageQueueTimeout})
+				return
+			}
+			if current > 0 {
+				s.forward(ctx, current, &matchEnvelope{UserID: userID, ClientMessage: ClientMessage{Type: messageLeave}})
+			} else if queued {
+				s.leaveQueue(ctx, userID, level)
+			}
+			if err != io.EOF {
+				select {
+				case <-finished:
+				default:
+					log.Printf("duel connection of user %d closed: %v", userID, err)
+				}
+			}
+			return
+		}
+
+		s.redis.Expire(ctx, connKey(userID), duelIdleTimeout)
+		if current := int(matchID.Load()); current > 0 {
+			ws.SetReadDeadline(time.Now().Add(duelIdleTimeout))
+		}
+
+		switch msg.Type {
+		case MessagePing:
+			websocket.JSON.Send(ws, &ServerMessage{Type: MessagePong})
+		case MessageAnswer, MessageForfeit:
+			current := int(matchID.Load())
+			if current == 0 {
+				websocket.JSON.Send(ws, &ServerMessage{Type: MessageError, Error: "not in a duel"})
+				continue
+			}
+			s.forward(ctx, current, &matchEnvelope{UserID: userID, ClientMessage: msg})
+		default:
+			websocket.JSON.Send(ws, &ServerMessage{Type: MessageError, Error: "unknown message type"})
+		}
+	}
+}
+
+// enqueue 排队匹配；匹配成功时在本实例启动对局，返回是否仍在排队
+func (s *Service) enqueue(ctx context.Context, userID int, level int) (bool, error) {
+	for {
+		opponentID, err := s.matchmake(ctx, userID, level)
+		if err != nil {
+			return false, err
+		}
+		if opponentID == 0 {
+			return true, nil
+		}
+		started, err := s.startMatch(ctx, userID, opponentID, level)
+		if err != nil {
+			return false, err
+		}
+		if started {
+			return false, nil
+		}
+		// 对手已离开，重新排队
+	}
+}
@@ -0,0 +1,276 @@
+package duel
+
+import (
+	"database/sql"
+	"fmt"
+	"linguaforge/internal/game"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-sql-driver/mysql"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/net/websocket"
+)
+
+// newTestDatabase 在 TEST_MYSQL_DSN 指定的服务器上新建一个临时库并执行全部迁移，测试结束后删除；
+// 未设置或连不上时跳过
+func newTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("parse TEST_MYSQL_DSN: %v", err)
+	}
+	cfg.DBName = ""
+	cfg.ParseTime = true
+	cfg.MultiStatements = true
+	admin, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if err := admin.Ping(); err != nil {
+		t.Skipf("mysql not available: %v", err)
+	}
+
+	name := fmt.Sprintf("linguaforge_duel_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + name + " CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci"); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name) })
+
+	cfg.DBName = name
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("find migrations: %v", err)
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		// 迁移固定使用 linguaforge 库，这里改为在临时库中执行
+		var lines []string
+		for _, line := range strings.Split(string(content), "\n") {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "USE linguaforge;" || strings.HasPrefix(trimmed, "CREATE DATABASE") {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		if _, err := db.Exec(strings.Join(lines, "\n")); err != nil {
+			t.Fatalf("apply %s: %v", filepath.Base(file), err)
+		}
+	}
+	return db
+}
+
+func createTestUser(t *testing.T, db *sql.DB, username string) int {
+	t.Helper()
+	result, err := db.Exec("INSERT INTO users (username, email, password_hash) VALUES (?, ?, 'x')", username, username+"@example.com")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+// duelClient 一条进程内的对战连接
+type duelClient struct {
+	t  *testing.T
+	ws *websocket.Conn
+}
+
+func dialDuel(t *testing.T, server *httptest.Server, userID int) *duelClient {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?user_id=" + strconv.Itoa(userID)
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatalf("dial duel for user %d: %v", userID, err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return &duelClient{t: t, ws: ws}
+}
+
+// next 读取下一条消息
+func (c *duelClient) next() *ServerMessage {
+	c.t.Helper()
+	c.ws.SetReadDeadline(time.Now().Add(duelReconnectGrace + duelRoundTimeout))
+	var msg ServerMessage
+	if err := websocket.JSON.Receive(c.ws, &msg); err != nil {
+		c.t.Fatalf("receive: %v", err)
+	}
+	if msg.Type == MessageError {
+		c.t.Fatalf("server error: %s", msg.Error)
+	}
+	return &msg
+}
+
+// expect 读取下一条消息并检查类型
+func (c *duelClient) expect(want MessageType) *ServerMessage {
+	c.t.Helper()
+	msg := c.next()
+	if msg.Type != want {
+		c.t.Fatalf("got %q message, want %q", msg.Type, want)
+	}
+	return msg
+}
+
+// until 跳过其他消息直到收到指定类型，返回收到的全部类型和该消息
+func (c *duelClient) until(want MessageType) ([]MessageType, *ServerMessage) {
+	c.t.Helper()
+	var seen []MessageType
+	for {
+		msg := c.next()
+		seen = append(seen, msg.Type)
+		if msg.Type == want {
+			return seen, msg
+		}
+	}
+}
+
+func (c *duelClient) send(msg *ClientMessage) {
+	c.t.Helper()
+	if err := websocket.JSON.Send(c.ws, msg); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+// TestDuelOverWebSocket 两个客户端经真实的排队脚本配对，打完一轮后一方断线，宽限期过后判负并按 Elo 结算
+func TestDuelOverWebSocket(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect grace period")
+	}
+	db := newTestDatabase(t)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	s := NewService(db, client, game.NewService(db, nil))
+
+	const level = 1
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		userID, _ := strconv.Atoi(ws.Request().URL.Query().Get("user_id"))
+		s.Serve(ws, userID, level)
+	}))
+	t.Cleanup(server.Close)
+
+	// 配对：先到的排队，后到的取走对手
+	a := dialDuel(t, server, alice)
+	if queued := a.expect(MessageQueued); queued.Level != level {
+		t.Fatalf("queued at level %d, want %d", queued.Level, level)
+	}
+	b := dialDuel(t, server, bob)
+	matchedA := a.expect(MessageMatched)
+	matchedB := b.expect(MessageMatched)
+	if matchedA.MatchID == 0 || matchedA.MatchID != matchedB.MatchID {
+		t.Fatalf("match IDs %d and %d", matchedA.MatchID, matchedB.MatchID)
+	}
+	if matchedA.Opponent.UserID != bob || matchedB.Opponent.UserID != alice {
+		t.Fatalf("opponents %d and %d", matchedA.Opponent.UserID, matchedB.Opponent.UserID)
+	}
+	if matchedA.Opponent.Rating != duelInitialRating {
+		t.Fatalf("initial rating %d, want %d", matchedA.Opponent.Rating, duelInitialRating)
+	}
+	if mr.Exists(queueKey(level)) {
+		t.Fatal("queue not empty after pairing")
+	}
+
+	// 完整的一轮：alice 答对，bob 答错
+	roundA := a.expect(MessageRound)
+	roundB := b.expect(MessageRound)
+	if roundA.Round != 1 || roundA.WordID != roundB.WordID {
+		t.Fatalf("round %d word %d vs word %d", roundA.Round, roundA.WordID, roundB.WordID)
+	}
+	var answer string
+	if err := db.QueryRow("SELECT chinese FROM words WHERE id = ?", roundA.WordID).Scan(&answer); err != nil {
+		t.Fatalf("get answer: %v", err)
+	}
+	wrong := ""
+	for _, option := range roundB.Options {
+		if option != answer {
+			wrong = option
+			break
+		}
+	}
+	a.send(&ClientMessage{Type: MessageAnswer, Round: 1, Answer: answer})
+	b.send(&ClientMessage{Type: MessageAnswer, Round: 1, Answer: wrong})
+
+	result := a.expect(MessageRoundResult)
+	b.expect(MessageRoundResult)
+	if result.Answer != answer {
+		t.Fatalf("round answer %q, want %q", result.Answer, answer)
+	}
+	scores := map[int]PlayerRound{}
+	for _, score := range result.Scores {
+		scores[score.UserID] = score
+	}
+	if got := scores[alice]; !got.Correct || got.Points <= duelBasePoints || got.Score != got.Points {
+		t.Fatalf("alice round result %+v", got)
+	}
+	if got := scores[bob]; !got.Answered || got.Correct || got.Score != 0 {
+		t.Fatalf("bob round result %+v", got)
+	}
+
+	// bob 断线且不重连：宽限期过后判负
+	a.expect(MessageRound)
+	b.expect(MessageRound)
+	b.ws.Close()
+	seen, final := a.until(MessageResult)
+	left := false
+	for _, typ := range seen {
+		left = left || typ == MessageOpponentLeft
+	}
+	if !left {
+		t.Fatalf("alice not told that bob left: %v", seen)
+	}
+	if final.ForfeitBy != bob || final.WinnerID != alice {
+		t.Fatalf("forfeit by %d, winner %d", final.ForfeitBy, final.WinnerID)
+	}
+
+	// 同分开局，K=32：胜者 +16，负者 -16
+	want := map[int]int{alice: 16, bob: -16}
+	for _, r := range final.Results {
+		if r.RatingChange != want[r.UserID] || r.Rating != duelInitialRating+want[r.UserID] {
+			t.Fatalf("user %d rating %d (%+d), want %+d", r.UserID, r.Rating, r.RatingChange, want[r.UserID])
+		}
+	}
+	for userID, change := range want {
+		rating, err := s.GetRating(userID)
+		if err != nil {
+			t.Fatalf("GetRating: %v", err)
+		}
+		if rating.Rating != duelInitialRating+change || rating.Wins+rating.Losses != 1 {
+			t.Fatalf("stored rating of user %d: %+v", userID, rating)
+		}
+	}
+	var status string
+	var winner, forfeit sql.NullInt64
+	err := db.QueryRow("SELECT status, winner_id, forfeit_by FROM duel_matches WHERE id = ?", final.MatchID).Scan(&status, &winner, &forfeit)
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	if status != "finished" || int(winner.Int64) != alice || int(forfeit.Int64) != bob {
+		t.Fatalf("stored match: status %s, winner %v, forfeit %v", status, winner, forfeit)
+	}
+	if mr.Exists(activeKey(alice)) || mr.Exists(activeKey(bob)) {
+		t.Fatal("active duel keys not cleared")
+	}
+}
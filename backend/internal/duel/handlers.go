@@ -0,0 +1,68 @@
+package duel
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+type Handlers struct {
+	service *Service
+}
+
+func NewHandlers(service *Service) *Handlers {
+	return &Handlers{
+		service: service,
+	}
+}
+
+// Connect 建立对战 WebSocket 连接；未指定难度时按用户推荐难度排队
+func (h *Handlers) Connect(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	level, err := strconv.Atoi(c.Query("level"))
+	if err != nil || level <= 0 {
+		level = h.service.userLevel(userID.(int))
+	}
+
+	// 不校验 Origin：连接已通过 AuthMiddleware 的 token 认证
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			h.service.Serve(ws, userID.(int), level)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// GetRating 获取对战积分和最近的对战记录
+func (h *Handlers) GetRating(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	rating, err := h.service.GetRating(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	matches, err := h.service.GetRecentMatches(userID.(int), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rating":  rating,
+		"matches": matches,
+	})
+}
@@ -0,0 +1,336 @@
+package duel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"linguaforge/internal/game"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// matchPlayer 对局中的玩家状态
+type matchPlayer struct {
+	*Player
+	sessionID    int
+	score        int
+	correct      int
+	connected    bool
+	disconnected time.Time
+}
+
+// match 一局对战，运行在撮合成功的那个实例上；玩家消息经 Redis 频道转发，双方可连接不同实例
+type match struct {
+	s         *Service
+	id        int
+	level     int
+	players   [2]*matchPlayer
+	rounds    []game.ChoiceRound
+	sub       *redis.PubSub
+	push      func(ctx context.Context, userID int, msg *ServerMessage) (int64, error) // 向玩家推送消息，默认 Service.publish
+	startedAt time.Time
+
+	// 当前轮
+	round      int
+	roundStart time.Time
+	answered   map[int]*PlayerRound
+}
+
+// startMatch 为两名玩家创建对局：选词、写入记录、订阅对局频道并通知双方
+// 对手的连接已不存在时返回 false，调用方应重新排队
+func (s *Service) startMatch(ctx context.Context, userID int, opponentID int, level int) (bool, error) {
+	players := [2]*matchPlayer{}
+	for i, id := range []int{opponentID, userID} {
+		p, err := s.loadPlayer(id)
+		if err != nil {
+			return false, err
+		}
+		players[i] = &matchPlayer{Player: p, connected: true}
+	}
+
+	matchID, err := s.createMatch(opponentID, userID, level)
+	if err != nil {
+		return false, err
+	}
+
+	selection, sessions, err := s.games.SelectSharedWords([]int{opponentID, userID}, game.GameTypeDuel, duelRoundCount, level)
+	if err != nil {
+		return false, err
+	}
+	rounds, err := s.games.BuildChoiceRounds(selection.Words)
+	if err != nil {
+		return false, err
+	}
+	if len(rounds) == 0 {
+		return false, fmt.Errorf("no words available for level %d", level)
+	}
+	for _, p := range players {
+		p.sessionID = sessions[p.UserID]
+	}
+
+	sub := s.redis.Subscribe(ctx, matchChannel(matchID))
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return false, fmt.Errorf("failed to subscribe duel match: %w", err)
+	}
+
+	m := &match{s: s, id: matchID, level: level, players: players, rounds: rounds, sub: sub, push: s.publish, startedAt: time.Now()}
+	for i, p := range players {
+		if err := s.redis.Set(ctx, activeKey(p.UserID), matchID, duelActiveTTL).Err(); err != nil {
+			sub.Close()
+			return false, fmt.Errorf("failed to save active duel: %w", err)
+		}
+		received, err := s.publish(ctx, p.UserID, m.matchedMessage(i))
+		if err != nil {
+			sub.Close()
+			return false, err
+		}
+		if received == 0 && p.UserID == opponentID {
+			// 对手在被取出队列前已断开：作废本局
+			s.redis.Del(ctx, activeKey(opponentID), activeKey(userID))
+			s.db.Exec("DELETE FROM duel_matches WHERE id = ?", matchID)
+			sub.Close()
+			return false, nil
+		}
+	}
+
+	go m.run()
+	return true, nil
+}
+
+// run 对局主循环：逐轮下发题目，收集作答，处理掉线/重连/认输，最后结算
+func (m *match) run() {
+	ctx := context.Background()
+	defer m.sub.Close()
+	messages := m.sub.Channel()
+	graceExpired := make(chan int, 8)
+
+	for i := range m.rounds {
+		m.round = i + 1
+		m.roundStart = time.Now()
+		m.answered = make(map[int]*PlayerRound, 2)
+		for idx := range m.players {
+			m.send(ctx, idx, m.roundMessage())
+		}
+
+		timer := time.NewTimer(duelRoundTimeout)
+	roundLoop:
+		for len(m.answered) < len(m.players) {
+			select {
+			case <-timer.C:
+				break roundLoop
+			case userID := <-graceExpired:
+				if m.graceElapsed(userID, time.Now()) {
+					timer.Stop()
+					m.finish(ctx, userID)
+					return
+				}
+			case msg, ok := <-messages:
+				if !ok {
+					timer.Stop()
+					m.finish(ctx, 0)
+					return
+				}
+				var env matchEnvelope
+				if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+					continue
+				}
+				if forfeitBy := m.handle(ctx, &env, graceExpired); forfeitBy != 0 {
+					timer.Stop()
+					m.finish(ctx, forfeitBy)
+					return
+				}
+			}
+		}
+		timer.Stop()
+
+		scores := make([]PlayerRound, 0, len(m.players))
+		for _, p := range m.players {
+			result, ok := m.answered[p.UserID]
+			if !ok {
+				result = &PlayerRound{UserID: p.UserID}
+			}
+			result.Score = p.score
+			scores = append(scores, *result)
+		}
+		for idx := range m.players {
+			m.send(ctx, idx, &ServerMessage{
+				Type:   MessageRoundResult,
+				Round:  m.round,
+				Answer: m.rounds[i].Answer,
+				Scores: scores,
+			})
+		}
+	}
+
+	m.finish(ctx, 0)
+}
+
+// handle 处理一条玩家消息：作答、认输、掉线和重连；返回非 0 表示该玩家认输
+// 掉线后经过 duelReconnectGrace 把玩家写入 graceExpired，由主循环用 graceElapsed 判断是否判负
+func (m *match) handle(ctx context.Context, env *matchEnvelope, graceExpired chan<- int) int {
+	idx := m.playerIndex(env.UserID)
+	if idx < 0 {
+		return 0
+	}
+	switch env.Type {
+	case MessageAnswer:
+		m.answer(idx, &env.ClientMessage)
+	case MessageForfeit:
+		return env.UserID
+	case messageLeave:
+		m.players[idx].connected = false
+		m.players[idx].disconnected = time.Now()
+		userID := env.UserID
+		time.AfterFunc(duelReconnectGrace, func() {
+			select {
+			case graceExpired <- userID:
+			default: // 对局已结束
+			}
+		})
+		m.send(ctx, 1-idx, &ServerMessage{Type: MessageOpponentLeft, GraceSeconds: int(duelReconnectGrace.Seconds())})
+	case messageRejoin:
+		m.players[idx].connected = true
+		m.send(ctx, idx, m.matchedMessage(idx))
+		if _, done := m.answered[env.UserID]; !done {
+			m.send(ctx, idx, m.roundMessage())
+		}
+		m.send(ctx, 1-idx, &ServerMessage{Type: MessageOpponentBack})
+	}
+	return 0
+}
+
+// graceElapsed 玩家掉线后在宽限期内没有重连（重连后再次掉线时从最后一次掉线算起）
+func (m *match) graceElapsed(userID int, now time.Time) bool {
+	idx := m.playerIndex(userID)
+	return idx >= 0 && !m.players[idx].connected && now.Sub(m.players[idx].disconnected) >= duelReconnectGrace
+}
+
+// answer 处理作答：以服务器收到的时间计分，每轮只接受第一次作答
+func (m *match) answer(idx int, msg *ClientMessage) {
+	p := m.players[idx]
+	if msg.Round != m.round {
+		return
+	}
+	if _, done := m.answered[p.UserID]; done {
+		return
+	}
+
+	round := m.rounds[m.round-1]
+	elapsed := time.Since(m.roundStart)
+	correct := strings.TrimSpace(msg.Answer) == round.Answer
+	result := &PlayerRound{UserID: p.UserID, Answered: true, Correct: correct}
+	if correct {
+		remaining := max(duelRoundTimeout-elapsed, 0)
+		result.Points = duelBasePoints + int(remaining/(100*time.Millisecond))
+		p.score += result.Points
+		p.correct++
+	}
+	m.answered[p.UserID] = result
+
+	_, err := m.s.games.RecordAnswers(p.UserID, &game.RecordAnswersRequest{
+		SessionID: p.sessionID,
+		GameType:  game.GameTypeDuel,
+		Answers: []game.AnswerInput{{
+			WordID:         round.WordID,
+			ChosenOption:   msg.Answer,
+			IsCorrect:      correct,
+			ResponseTimeMs: int(elapsed.Milliseconds()),
+		}},
+	})
+	if err != nil {
+		log.Printf("duel %d: failed to record answer: %v", m.id, err)
+	}
+}
+
+// finish 结算对局；forfeitBy 非 0 时该玩家判负
+func (m *match) finish(ctx context.Context, forfeitBy int) {
+	winnerID := m.winner(forfeitBy)
+	results, err := m.s.settleMatch(m.id, m.players, winnerID, forfeitBy)
+	if err != nil {
+		log.Printf("duel %d: %v", m.id, err)
+		for idx := range m.players {
+			m.send(ctx, idx, &ServerMessage{Type: MessageError, Error: "failed to settle duel"})
+		}
+	}
+
+	timeSpent := int(time.Since(m.startedAt).Seconds())
+	for _, p := range m.players {
+		err := m.s.games.SubmitScore(p.UserID, &game.SubmitScoreRequest{
+			GameType:     game.GameTypeDuel,
+			Score:        p.score,
+			LevelReached: m.level,
+			TimeSpent:    timeSpent,
+			SessionID:    p.sessionID,
+		})
+		if err != nil {
+			log.Printf("duel %d: failed to save game record for user %d: %v", m.id, p.UserID, err)
+		}
+		m.s.redis.Del(ctx, activeKey(p.UserID))
+	}
+
+	for idx := range m.players {
+		m.send(ctx, idx, &ServerMessage{
+			Type:      MessageResult,
+			MatchID:   m.id,
+			Results:   results[:],
+			WinnerID:  winnerID,
+			ForfeitBy: forfeitBy,
+		})
+	}
+}
+
+// winner 胜者ID，平局时为 0；认输或掉线超时的一方无论比分都判负
+func (m *match) winner(forfeitBy int) int {
+	switch {
+	case forfeitBy != 0:
+		return m.players[1-m.playerIndex(forfeitBy)].UserID
+	case m.players[0].score > m.players[1].score:
+		return m.players[0].UserID
+	case m.players[1].score > m.players[0].score:
+		return m.players[1].UserID
+	}
+	return 0
+}
+
+func (m *match) matchedMessage(idx int) *ServerMessage {
+	return &ServerMessage{
+		Type:     MessageMatched,
+		MatchID:  m.id,
+		Level:    m.level,
+		Total:    len(m.rounds),
+		Opponent: m.players[1-idx].Player,
+	}
+}
+
+func (m *match) roundMessage() *ServerMessage {
+	round := m.rounds[m.round-1]
+	return &ServerMessage{
+		Type:       MessageRound,
+		MatchID:    m.id,
+		Round:      m.round,
+		Total:      len(m.rounds),
+		WordID:     round.WordID,
+		Word:       round.English,
+		Options:    round.Options,
+		DeadlineMs: max(duelRoundTimeout-time.Since(m.roundStart), 0).Milliseconds(),
+	}
+}
+
+func (m *match) send(ctx context.Context, idx int, msg *ServerMessage) {
+	if _, err := m.push(ctx, m.players[idx].UserID, msg); err != nil {
+		log.Printf("duel %d: failed to push message: %v", m.id, err)
+	}
+}
+
+func (m *match) playerIndex(userID int) int {
+	for i, p := range m.players {
+		if p.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
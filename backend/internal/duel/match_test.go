@@ -0,0 +1,182 @@
+package duel
+
+import (
+	"context"
+	"linguaforge/internal/game"
+	"testing"
+	"time"
+)
+
+type pushed struct {
+	userID int
+	msg    *ServerMessage
+}
+
+// newTestMatch 两名玩家（1 和 2）进入第一轮的对局，推送的消息记录在返回的切片中
+func newTestMatch() (*match, *[]pushed) {
+	var out []pushed
+	m := &match{
+		id:    7,
+		level: 1,
+		players: [2]*matchPlayer{
+			{Player: &Player{UserID: 1, Username: "alice", Rating: 1200}, connected: true},
+			{Player: &Player{UserID: 2, Username: "bob", Rating: 1200}, connected: true},
+		},
+		rounds: []game.ChoiceRound{
+			{WordID: 10, English: "apple", Options: []string{"苹果", "香蕉"}, Answer: "苹果"},
+			{WordID: 11, English: "pear", Options: []string{"梨", "桃"}, Answer: "梨"},
+		},
+		push: func(ctx context.Context, userID int, msg *ServerMessage) (int64, error) {
+			out = append(out, pushed{userID, msg})
+			return 1, nil
+		},
+		startedAt:  time.Now(),
+		round:      1,
+		roundStart: time.Now(),
+		answered:   map[int]*PlayerRound{},
+	}
+	return m, &out
+}
+
+func messageTypes(out []pushed, userID int) []MessageType {
+	var types []MessageType
+	for _, p := range out {
+		if p.userID == userID {
+			types = append(types, p.msg.Type)
+		}
+	}
+	return types
+}
+
+func equalTypes(a, b []MessageType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMatchForfeit(t *testing.T) {
+	m, _ := newTestMatch()
+	m.players[1].score = 500 // 认输方比分领先也判负
+
+	forfeitBy := m.handle(context.Background(), &matchEnvelope{UserID: 2, ClientMessage: ClientMessage{Type: MessageForfeit}}, make(chan int, 1))
+	if forfeitBy != 2 {
+		t.Fatalf("handle(forfeit) = %d, want 2", forfeitBy)
+	}
+	if winner := m.winner(forfeitBy); winner != 1 {
+		t.Fatalf("winner = %d, want 1", winner)
+	}
+
+	if got := m.handle(context.Background(), &matchEnvelope{UserID: 99, ClientMessage: ClientMessage{Type: MessageForfeit}}, make(chan int, 1)); got != 0 {
+		t.Fatalf("forfeit from a stranger = %d, want 0", got)
+	}
+}
+
+func TestMatchWinnerByScore(t *testing.T) {
+	m, _ := newTestMatch()
+	if winner := m.winner(0); winner != 0 {
+		t.Fatalf("tied winner = %d, want 0", winner)
+	}
+	m.players[0].score = 120
+	if winner := m.winner(0); winner != 1 {
+		t.Fatalf("winner = %d, want 1", winner)
+	}
+}
+
+func TestMatchDisconnectAndReconnect(t *testing.T) {
+	m, out := newTestMatch()
+	ctx := context.Background()
+	grace := make(chan int, 1)
+
+	m.handle(ctx, &matchEnvelope{UserID: 1, ClientMessage: ClientMessage{Type: messageLeave}}, grace)
+	left := m.players[0].disconnected
+	if m.players[0].connected {
+		t.Fatal("player still connected after leave")
+	}
+	if got := messageTypes(*out, 2); !equalTypes(got, []MessageType{MessageOpponentLeft}) {
+		t.Fatalf("opponent got %v, want opponent_left", got)
+	}
+	if (*out)[0].msg.GraceSeconds != int(duelReconnectGrace.Seconds()) {
+		t.Fatalf("grace seconds = %d", (*out)[0].msg.GraceSeconds)
+	}
+	if m.graceElapsed(1, left.Add(duelReconnectGrace-time.Second)) {
+		t.Fatal("grace elapsed before the deadline")
+	}
+
+	*out = nil
+	m.handle(ctx, &matchEnvelope{UserID: 1, ClientMessage: ClientMessage{Type: messageRejoin}}, grace)
+	if !m.players[0].connected {
+		t.Fatal("player not connected after rejoin")
+	}
+	// 重连后重新下发对局信息和尚未作答的当前轮
+	if got := messageTypes(*out, 1); !equalTypes(got, []MessageType{MessageMatched, MessageRound}) {
+		t.Fatalf("rejoined player got %v, want matched and round", got)
+	}
+	if got := messageTypes(*out, 2); !equalTypes(got, []MessageType{MessageOpponentBack}) {
+		t.Fatalf("opponent got %v, want opponent_back", got)
+	}
+	if round := (*out)[1].msg; round.Round != 1 || round.Word != "apple" || round.DeadlineMs <= 0 {
+		t.Fatalf("unexpected round message %+v", round)
+	}
+	if m.graceElapsed(1, left.Add(duelReconnectGrace)) {
+		t.Fatal("reconnected player forfeited when the first grace timer fired")
+	}
+}
+
+func TestMatchRejoinAfterAnswering(t *testing.T) {
+	m, out := newTestMatch()
+	m.players[0].connected = false
+	m.answered[1] = &PlayerRound{UserID: 1, Answered: true, Correct: true}
+
+	m.handle(context.Background(), &matchEnvelope{UserID: 1, ClientMessage: ClientMessage{Type: messageRejoin}}, make(chan int, 1))
+	if got := messageTypes(*out, 1); !equalTypes(got, []MessageType{MessageMatched}) {
+		t.Fatalf("rejoined player got %v, want only matched", got)
+	}
+}
+
+func TestMatchGraceRestartsOnSecondDisconnect(t *testing.T) {
+	m, _ := newTestMatch()
+	ctx := context.Background()
+	grace := make(chan int, 4)
+
+	m.handle(ctx, &matchEnvelope{UserID: 2, ClientMessage: ClientMessage{Type: messageLeave}}, grace)
+	m.handle(ctx, &matchEnvelope{UserID: 2, ClientMessage: ClientMessage{Type: messageRejoin}}, grace)
+	m.players[1].disconnected = time.Now().Add(-duelReconnectGrace / 2) // 模拟第二次掉线发生在较晚的时间
+	m.players[1].connected = false
+	second := m.players[1].disconnected
+
+	// 第一次掉线的计时器先到期：从第二次掉线算起还在宽限期内
+	if m.graceElapsed(2, second.Add(duelReconnectGrace/2)) {
+		t.Fatal("grace elapsed counting from the first disconnect")
+	}
+	if !m.graceElapsed(2, second.Add(duelReconnectGrace)) {
+		t.Fatal("grace not elapsed after the second disconnect's deadline")
+	}
+	if winner := m.winner(2); winner != 1 {
+		t.Fatalf("winner after timeout = %d, want 1", winner)
+	}
+}
+
+func TestEloChange(t *testing.T) {
+	tests := []struct {
+		rating, opponent int
+		outcome          float64
+		want             int
+	}{
+		{1200, 1200, 1, 16},
+		{1200, 1200, 0, -16},
+		{1200, 1200, 0.5, 0},
+		{1400, 1200, 1, 8},
+		{1200, 1400, 1, 24},
+	}
+	for _, tt := range tests {
+		if got := eloChange(tt.rating, tt.opponent, tt.outcome); got != tt.want {
+			t.Errorf("eloChange(%d, %d, %v) = %d, want %d", tt.rating, tt.opponent, tt.outcome, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package duel
+
+import "time"
+
+// MessageType WebSocket 消息类型
+type MessageType string
+
+const (
+	// 客户端 → 服务器
+	MessageAnswer  MessageType = "answer"
+	MessageForfeit MessageType = "forfeit"
+	MessagePing    MessageType = "ping"
+
+	// 服务器 → 客户端
+	MessageQueued       MessageType = "queued"
+	MessageQueueTimeout MessageType = "queue_timeout"
+	MessageMatched      MessageType = "matched"
+	MessageRound        MessageType = "round"
+	MessageRoundResult  MessageType = "round_result"
+	MessageOpponentLeft MessageType = "opponent_left"
+	MessageOpponentBack MessageType = "opponent_back"
+	MessageResult       MessageType = "result"
+	MessagePong         MessageType = "pong"
+	MessageError        MessageType = "error"
+
+	// 网关 → 对局（不下发给客户端）
+	messageLeave  MessageType = "leave"
+	messageRejoin MessageType = "rejoin"
+)
+
+// ClientMessage 客户端发送的消息
+type ClientMessage struct {
+	Type   MessageType `json:"type"`
+	Round  int         `json:"round,omitempty"`
+	Answer string      `json:"answer,omitempty"`
+}
+
+// ServerMessage 服务器推送的消息，按 Type 使用其中部分字段
+type ServerMessage struct {
+	Type         MessageType    `json:"type"`
+	MatchID      int            `json:"match_id,omitempty"`
+	Level        int            `json:"level,omitempty"`
+	Opponent     *Player        `json:"opponent,omitempty"`
+	Round        int            `json:"round,omitempty"`
+	Total        int            `json:"total,omitempty"`
+	WordID       int            `json:"word_id,omitempty"`
+	Word         string         `json:"word,omitempty"`
+	Options      []string       `json:"options,omitempty"`
+	DeadlineMs   int64          `json:"deadline_ms,omitempty"` // 本轮剩余作答时间
+	Answer       string         `json:"answer,omitempty"`
+	Scores       []PlayerRound  `json:"scores,omitempty"`
+	Results      []PlayerResult `json:"results,omitempty"`
+	WinnerID     int            `json:"winner_id,omitempty"`
+	ForfeitBy    int            `json:"forfeit_by,omitempty"`
+	GraceSeconds int            `json:"grace_seconds,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// Player 对战玩家
+type Player struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+}
+
+// PlayerRound 单轮结算中某位玩家的结果
+type PlayerRound struct {
+	UserID   int  `json:"user_id"`
+	Answered bool `json:"answered"`
+	Correct  bool `json:"correct"`
+	Points   int  `json:"points"`
+	Score    int  `json:"score"`
+}
+
+// PlayerResult 对战结束时某位玩家的结果
+type PlayerResult struct {
+	UserID       int `json:"user_id"`
+	Score        int `json:"score"`
+	Correct      int `json:"correct"`
+	Rating       int `json:"rating"`
+	RatingChange int `json:"rating_change"`
+}
+
+// Rating 用户对战积分
+type Rating struct {
+	UserID int `json:"user_id"`
+	Rating int `json:"rating"`
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+	Draws  int `json:"draws"`
+}
+
+// Match 对战记录
+type Match struct {
+	ID            int        `json:"id"`
+	OpponentID    int        `json:"opponent_id"`
+	OpponentName  string     `json:"opponent_name"`
+	Level         int        `json:"level"`
+	Score         int        `json:"score"`
+	OpponentScore int        `json:"opponent_score"`
+	Result        string     `json:"result"` // win / loss / draw
+	Forfeit       bool       `json:"forfeit"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// matchEnvelope 网关转发给对局的消息，附带发送者
+type matchEnvelope struct {
+	UserID int `json:"user_id"`
+	ClientMessage
+}
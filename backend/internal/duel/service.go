@@ -0,0 +1,283 @@
+package duel
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linguaforge/internal/game"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 对战参数
+const (
+	duelRoundCount     = 10
+	duelRoundTimeout   = 10 * time.Second
+	duelQueueTimeout   = 60 * time.Second // 排队等待对手的最长时间
+	duelIdleTimeout    = 2 * time.Minute  // 连接无任何消息时断开
+	duelReconnectGrace = 15 * time.Second // 掉线后可重连的时间，超时判负
+	duelActiveTTL      = 30 * time.Minute
+	duelBasePoints     = 100 // 答对基础分，另加剩余时间奖励（每100ms 1分）
+	duelEloK           = 32
+	duelInitialRating  = 1200
+)
+
+// matchmakeScript 原子地取出一个等待中的对手；没有对手时把自己加入队列
+// KEYS[1] 队列；ARGV[1] 用户ID；ARGV[2] 当前时间(ms)；ARGV[3] 过期时间点(ms)
+var matchmakeScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[3])
+local waiting = redis.call('ZRANGE', KEYS[1], 0, -1)
+for _, id in ipairs(waiting) do
+  if id ~= ARGV[1] then
+    redis.call('ZREM', KEYS[1], id)
+    return id
+  end
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return false
+`)
+
+type Service struct {
+	db    *sql.DB
+	redis *redis.Client
+	games *game.Service
+}
+
+func NewService(db *sql.DB, redis *redis.Client, games *game.Service) *Service {
+	return &Service{
+		db:    db,
+		redis: redis,
+		games: games,
+	}
+}
+
+// GetRating 获取用户对战积分，未参加过对战时返回初始积分
+func (s *Service) GetRating(userID int) (*Rating, error) {
+	rating := &Rating{UserID: userID, Rating: duelInitialRating}
+	err := s.db.QueryRow(`
+		SELECT rating, wins, losses, draws FROM duel_ratings WHERE user_id = ?
+	`, userID).Scan(&rating.Rating, &rating.Wins, &rating.Losses, &rating.Draws)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get duel rating: %w", err)
+	}
+	return rating, nil
+}
+
+// GetRecentMatches 获取用户最近的对战记录
+func (s *Service) GetRecentMatches(userID int, limit int) ([]Match, error) {
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+	rows, err := s.db.Query(`
+		SELECT m.id, m.level, m.player1_id, m.player2_id, m.player1_score, m.player2_score,
+		       m.winner_id, m.forfeit_by, m.started_at, m.finished_at, u.username
+		FROM duel_matches m
+		JOIN users u ON u.id = IF(m.player1_id = ?, m.player2_id, m.player1_id)
+		WHERE (m.player1_id = ? OR m.player2_id = ?) AND m.status = 'finished'
+		ORDER BY m.started_at DESC
+		LIMIT ?
+	`, userID, userID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duel matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var player1, player2, score1, score2 int
+		var winner, forfeit sql.NullInt64
+		var finished sql.NullTime
+		if err := rows.Scan(&m.ID, &m.Level, &player1, &player2, &score1, &score2,
+			&winner, &forfeit, &m.StartedAt, &finished, &m.OpponentName); err != nil {
+			return nil, fmt.Errorf("failed to scan duel match: %w", err)
+		}
+		m.Score, m.OpponentScore, m.OpponentID = score1, score2, player2
+		if player2 == userID {
+			m.Score, m.OpponentScore, m.OpponentID = score2, score1, player1
+		}
+		switch {
+		case !winner.Valid:
+			m.Result = "draw"
+		case int(winner.Int64) == userID:
+			m.Result = "win"
+		default:
+			m.Result = "loss"
+		}
+		m.Forfeit = forfeit.Valid
+		if finished.Valid {
+			m.FinishedAt = &finished.Time
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// matchmake 按难度排队：有对手时返回对手ID，否则加入队列并返回 0
+func (s *Service) matchmake(ctx context.Context, userID int, level int) (int, error) {
+	now := time.Now()
+	opponent, err := matchmakeScript.Run(ctx, s.redis, []string{queueKey(level)},
+		userID, now.UnixMilli(), now.Add(-duelQueueTimeout).UnixMilli()).Text()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to join duel queue: %w", err)
+	}
+	return strconv.Atoi(opponent)
+}
+
+// leaveQueue 退出排队，返回是否仍在队列中（false 表示已被对手取走）
+func (s *Service) leaveQueue(ctx context.Context, userID int, level int) (bool, error) {
+	removed, err := s.redis.ZRem(ctx, queueKey(level), userID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to leave duel queue: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// loadPlayer 读取玩家用户名与对战积分
+func (s *Service) loadPlayer(userID int) (*Player, error) {
+	player := &Player{UserID: userID}
+	if err := s.db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&player.Username); err != nil {
+		return nil, fmt.Errorf("failed to get player: %w", err)
+	}
+	rating, err := s.GetRating(userID)
+	if err != nil {
+		return nil, err
+	}
+	player.Rating = rating.Rating
+	return player, nil
+}
+
+// userLevel 用户的推荐难度
+func (s *Service) userLevel(userID int) int {
+	level := 1
+	if err := s.db.QueryRow("SELECT preferred_level FROM users WHERE id = ?", userID).Scan(&level); err != nil || level < 1 {
+		return 1
+	}
+	return level
+}
+
+// createMatch 写入对战记录
+func (s *Service) createMatch(player1, player2, level int) (int, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO duel_matches (player1_id, player2_id, level) VALUES (?, ?, ?)
+	`, player1, player2, level)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create duel match: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get duel match ID: %w", err)
+	}
+	return int(id), nil
+}
+
+// settleMatch 结算对战记录并按 Elo 更新双方积分，返回双方新积分和变化值
+func (s *Service) settleMatch(matchID int, players [2]*matchPlayer, winnerID int, forfeitBy int) ([2]PlayerResult, error) {
+	var results [2]PlayerResult
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return results, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE duel_matches
+		SET status = 'finished', player1_score = ?, player2_score = ?, winner_id = ?, forfeit_by = ?, finished_at = NOW()
+		WHERE id = ?
+	`, players[0].score, players[1].score, nullID(winnerID), nullID(forfeitBy), matchID)
+	if err != nil {
+		return results, fmt.Errorf("failed to finish duel match: %w", err)
+	}
+
+	var ratings [2]int
+	for i, p := range players {
+		_, err := tx.Exec("INSERT IGNORE INTO duel_ratings (user_id, rating) VALUES (?, ?)", p.UserID, duelInitialRating)
+		if err != nil {
+			return results, fmt.Errorf("failed to init duel rating: %w", err)
+		}
+		if err := tx.QueryRow("SELECT rating FROM duel_ratings WHERE user_id = ? FOR UPDATE", p.UserID).Scan(&ratings[i]); err != nil {
+			return results, fmt.Errorf("failed to get duel rating: %w", err)
+		}
+	}
+
+	for i, p := range players {
+		opponent := ratings[1-i]
+		outcome, column := 0.5, "draws"
+		if winnerID == p.UserID {
+			outcome, column = 1, "wins"
+		} else if winnerID != 0 {
+			outcome, column = 0, "losses"
+		}
+		change := eloChange(ratings[i], opponent, outcome)
+		_, err := tx.Exec(`
+			UPDATE duel_ratings SET rating = rating + ?, `+column+` = `+column+` + 1 WHERE user_id = ?
+		`, change, p.UserID)
+		if err != nil {
+			return results, fmt.Errorf("failed to update duel rating: %w", err)
+		}
+		results[i] = PlayerResult{
+			UserID:       p.UserID,
+			Score:        p.score,
+			Correct:      p.correct,
+			Rating:       ratings[i] + change,
+			RatingChange: change,
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return results, fmt.Errorf("failed to commit duel result: %w", err)
+	}
+	return results, nil
+}
+
+// publish 向玩家推送消息，返回收到消息的连接数
+func (s *Service) publish(ctx context.Context, userID int, msg *ServerMessage) (int64, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	return s.redis.Publish(ctx, playerChannel(userID), payload).Result()
+}
+
+// forward 把玩家消息转发给对局，返回收到消息的对局数（0 表示对局已不存在）
+func (s *Service) forward(ctx context.Context, matchID int, env *matchEnvelope) (int64, error) {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return 0, err
+	}
+	return s.redis.Publish(ctx, matchChannel(matchID), payload).Result()
+}
+
+// activeMatch 用户正在进行的对战ID，没有时返回 0
+func (s *Service) activeMatch(ctx context.Context, userID int) (int, error) {
+	id, err := s.redis.Get(ctx, activeKey(userID)).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// eloChange 按 Elo 公式计算积分变化，outcome 为 1 胜 / 0.5 平 / 0 负
+func eloChange(rating, opponent int, outcome float64) int {
+	expected := 1 / (1 + math.Pow(10, float64(opponent-rating)/400))
+	return int(math.Round(duelEloK * (outcome - expected)))
+}
+
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+func queueKey(level int) string       { return fmt.Sprintf("duel:queue:%d", level) }
+func playerChannel(userID int) string { return fmt.Sprintf("duel:player:%d", userID) }
+func matchChannel(matchID int) string { return fmt.Sprintf("duel:match:%d", matchID) }
+func activeKey(userID int) string     { return fmt.Sprintf("duel:active:%d", userID) }
+func connKey(userID int) string       { return fmt.Sprintf("duel:conn:%d", userID) }
@@ -0,0 +1,360 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"linguaforge/config"
+	"linguaforge/internal/common"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// batchSize 每批认领的事件数
+	batchSize = 100
+	// pollInterval 没有待投递事件时的轮询间隔
+	pollInterval = time.Second
+	// lockDuration 认领后必须在该时间内处理完，否则其他实例可以重新认领
+	lockDuration = time.Minute
+	// maxBackoff 重试间隔上限
+	maxBackoff = time.Hour
+	// streamMaxLen Redis Stream 保留的大致条数
+	streamMaxLen = 100000
+	// streamSubscriber 写入 Redis Stream 视为一个订阅者，失败同样会重试
+	streamSubscriber = "redis-stream"
+	// deliveredRetentionDays 已投递事件的保留天数
+	deliveredRetentionDays = 7
+)
+
+var ErrEventNotFound = errors.New("event not found")
+
+// Handler 事件处理函数；返回错误时事件稍后重试，因此处理逻辑需要幂等
+type Handler func(ctx context.Context, e Event) error
+
+type subscription struct {
+	name    string
+	handler Handler
+}
+
+// DeadLetter 超过重试次数仍未投递成功的事件
+type DeadLetter struct {
+	Event
+	LastError string `json:"last_error"`
+}
+
+// Dispatcher 从发件箱读取事件并投递给订阅者
+type Dispatcher struct {
+	db          *sql.DB
+	redis       *redis.Client
+	stream      string
+	maxAttempts int
+
+	mu          sync.RWMutex
+	subscribers map[Type][]subscription
+	wake        chan struct{}
+}
+
+func NewDispatcher(db *sql.DB, redis *redis.Client, cfg config.EventsConfig) *Dispatcher {
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	return &Dispatcher{
+		db:          db,
+		redis:       redis,
+		stream:      cfg.RedisStream,
+		maxAttempts: maxAttempts,
+		subscribers: make(map[Type][]subscription),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Subscribe 订阅事件；name 在同一事件类型下唯一，用于记录投递进度，修改会导致历史事件被视为未处理
+func (d *Dispatcher) Subscribe(eventType Type, name string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, sub := range d.subscribers[eventType] {
+		if sub.name == name {
+			panic(fmt.Sprintf("events: subscriber %s registered twice for %s", name, eventType))
+		}
+	}
+	d.subscribers[eventType] = append(d.subscribers[eventType], subscription{name: name, handler: handler})
+}
+
+// Notify 提示有新事件写入，减少投递延迟；不调用也会按轮询间隔投递
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start 在后台持续投递事件，ctx 取消后停止
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		for {
+			n, err := d.DispatchBatch(ctx)
+			if err != nil {
+				log.Printf("events: %v", err)
+			}
+			if n > 0 && err == nil {
+				continue // 可能还有积压
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-d.wake:
+			case <-time.After(pollInterval):
+			}
+		}
+	}()
+}
+
+// DispatchBatch 认领一批到期的事件并投递，返回处理的事件数
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	token := common.RandomToken(16)
+	result, err := d.db.ExecContext(ctx, `
+		UPDATE event_outbox
+		SET locked_by = ?, locked_until = NOW(3) + INTERVAL ? SECOND
+		WHERE status = 'pending' AND next_attempt_at <= NOW(3)
+		  AND (locked_until IS NULL OR locked_until < NOW(3))
+		ORDER BY id
+		LIMIT ?
+	`, token, int(lockDuration.Seconds()), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim events: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, event_type, COALESCE(user_id, 0), payload, created_at, attempts
+		FROM event_outbox
+		WHERE locked_by = ?
+		ORDER BY id
+	`, token)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load claimed events: %w", err)
+	}
+	var batch []Event
+	for rows.Next() {
+		var e Event
+		var payload string
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &payload, &e.OccurredAt, &e.Attempts); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		e.Payload = []byte(payload)
+		batch = append(batch, e)
+	}
+	rows.Close()
+
+	for i := range batch {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		d.deliver(ctx, &batch[i])
+	}
+	return len(batch), nil
+}
+
+// deliver 把事件交给尚未成功处理它的订阅者，并更新发件箱状态
+func (d *Dispatcher) deliver(ctx context.Context, e *Event) {
+	consumed, err := d.consumedBy(ctx, e.ID)
+	if err != nil {
+		d.fail(e, err)
+		return
+	}
+
+	failures := handlePending(ctx, *e, d.targets(e.Type), consumed, func(subscriber string) error {
+		_, err := d.db.ExecContext(ctx, `
+			INSERT IGNORE INTO event_consumptions (event_id, subscriber) VALUES (?, ?)
+		`, e.ID, subscriber)
+		return err
+	})
+
+	if len(failures) > 0 {
+		d.fail(e, errors.New(strings.Join(failures, "; ")))
+		return
+	}
+	if _, err := d.db.Exec(`
+		UPDATE event_outbox
+		SET status = 'delivered', delivered_at = NOW(3), locked_by = NULL, locked_until = NULL, last_error = NULL
+		WHERE id = ?
+	`, e.ID); err != nil {
+		log.Printf("events: failed to mark event %d delivered: %v", e.ID, err)
+	}
+}
+
+// fail 记录失败并安排重试，超过次数后进入死信
+func (d *Dispatcher) fail(e *Event, cause error) {
+	attempts := e.Attempts + 1
+	status, backoff := nextAttempt(attempts, d.maxAttempts)
+	if status == "dead" {
+		log.Printf("events: event %d (%s) moved to dead letters after %d attempts: %v", e.ID, e.Type, attempts, cause)
+	}
+	_, err := d.db.Exec(`
+		UPDATE event_outbox
+		SET status = ?, attempts = ?, last_error = ?, next_attempt_at = NOW(3) + INTERVAL ? SECOND,
+		    locked_by = NULL, locked_until = NULL
+		WHERE id = ?
+	`, status, attempts, cause.Error(), int(backoff.Seconds()), e.ID)
+	if err != nil {
+		log.Printf("events: failed to record failure of event %d: %v", e.ID, err)
+	}
+}
+
+// handlePending 把事件交给 consumed 之外的订阅者，处理成功后调用 record 记录；返回失败信息，为空表示全部成功
+// 重新认领的事件只会投递给上次失败的订阅者
+func handlePending(ctx context.Context, e Event, subs []subscription, consumed map[string]bool, record func(subscriber string) error) []string {
+	var failures []string
+	for _, sub := range subs {
+		if consumed[sub.name] {
+			continue
+		}
+		if err := safeHandle(ctx, sub.handler, e); err != nil {
+			failures = append(failures, sub.name+": "+err.Error())
+			continue
+		}
+		if err := record(sub.name); err != nil {
+			failures = append(failures, sub.name+": failed to record consumption: "+err.Error())
+		}
+	}
+	return failures
+}
+
+// nextAttempt 第 attempts 次失败后的状态和重试间隔：达到 maxAttempts 进入死信，
+// 否则从 2 秒开始翻倍，不超过 maxBackoff
+func nextAttempt(attempts int, maxAttempts int) (string, time.Duration) {
+	status := "pending"
+	if attempts >= maxAttempts {
+		status = "dead"
+	}
+	return status, retryBackoff(attempts)
+}
+
+// retryBackoff 第 attempts 次失败后的重试间隔
+func retryBackoff(attempts int) time.Duration {
+	backoff := time.Duration(1<<uint(min(max(attempts, 0), 12))) * time.Second
+	return min(backoff, maxBackoff)
+}
+
+// targets 事件的全部订阅者，启用 Redis Stream 时包括写入 Stream
+func (d *Dispatcher) targets(eventType Type) []subscription {
+	d.mu.RLock()
+	subs := append([]subscription(nil), d.subscribers[eventType]...)
+	d.mu.RUnlock()
+	if d.stream != "" && d.redis != nil {
+		subs = append(subs, subscription{name: streamSubscriber, handler: d.publishToStream})
+	}
+	return subs
+}
+
+// publishToStream 把事件写入 Redis Stream，供其他服务消费
+func (d *Dispatcher) publishToStream(ctx context.Context, e Event) error {
+	return d.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: d.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"event_id":    strconv.FormatInt(e.ID, 10),
+			"type":        string(e.Type),
+			"user_id":     strconv.Itoa(e.UserID),
+			"payload":     string(e.Payload),
+			"occurred_at": e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+}
+
+func (d *Dispatcher) consumedBy(ctx context.Context, eventID int64) (map[string]bool, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT subscriber FROM event_consumptions WHERE event_id = ?", eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consumptions: %w", err)
+	}
+	defer rows.Close()
+
+	consumed := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan consumption: %w", err)
+		}
+		consumed[name] = true
+	}
+	return consumed, nil
+}
+
+// DeadLetters 死信列表（最新在前）
+func (d *Dispatcher) DeadLetters(limit int) ([]DeadLetter, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	rows, err := d.db.Query(`
+		SELECT id, event_type, COALESCE(user_id, 0), payload, created_at, attempts, COALESCE(last_error, '')
+		FROM event_outbox
+		WHERE status = 'dead'
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := []DeadLetter{}
+	for rows.Next() {
+		var l DeadLetter
+		var payload string
+		if err := rows.Scan(&l.ID, &l.Type, &l.UserID, &payload, &l.OccurredAt, &l.Attempts, &l.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		l.Payload = []byte(payload)
+		letters = append(letters, l)
+	}
+	return letters, nil
+}
+
+// Retry 把死信重新放回投递队列，已成功的订阅者不会重复收到
+func (d *Dispatcher) Retry(eventID int64) error {
+	result, err := d.db.Exec(`
+		UPDATE event_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW(3)
+		WHERE id = ? AND status = 'dead'
+	`, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to retry event: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrEventNotFound
+	}
+	d.Notify()
+	return nil
+}
+
+// prune 删除已投递的旧事件（死信保留，待人工处理）
+func (d *Dispatcher) prune(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `
+		DELETE FROM event_outbox WHERE status = 'delivered' AND delivered_at < NOW() - INTERVAL ? DAY
+	`, deliveredRetentionDays)
+	if err != nil {
+		return fmt.Errorf("failed to prune delivered events: %w", err)
+	}
+	return nil
+}
+
+// safeHandle 执行订阅者并把 panic 转为错误
+func safeHandle(ctx context.Context, handler Handler, e Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, e)
+}
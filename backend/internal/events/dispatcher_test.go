@@ -0,0 +1,142 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"linguaforge/config"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{5, 32 * time.Second},
+		{11, 2048 * time.Second},
+		{12, maxBackoff}, // 4096 秒超过上限
+		{40, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNextAttemptDeadLetter(t *testing.T) {
+	const maxAttempts = 3
+	tests := []struct {
+		attempts int
+		want     string
+	}{
+		{1, "pending"},
+		{2, "pending"},
+		{3, "dead"},
+		{4, "dead"}, // 死信重试后再次失败
+	}
+	for _, tt := range tests {
+		status, backoff := nextAttempt(tt.attempts, maxAttempts)
+		if status != tt.want {
+			t.Errorf("nextAttempt(%d, %d) status = %q, want %q", tt.attempts, maxAttempts, status, tt.want)
+		}
+		if backoff != retryBackoff(tt.attempts) {
+			t.Errorf("nextAttempt(%d, %d) backoff = %v, want %v", tt.attempts, maxAttempts, backoff, retryBackoff(tt.attempts))
+		}
+	}
+}
+
+func TestHandlePendingSkipsConsumedSubscribers(t *testing.T) {
+	var calls []string
+	failing := true
+	subs := []subscription{
+		{"a", func(ctx context.Context, e Event) error { calls = append(calls, "a"); return nil }},
+		{"b", func(ctx context.Context, e Event) error {
+			calls = append(calls, "b")
+			if failing {
+				return errors.New("boom")
+			}
+			return nil
+		}},
+		{"c", func(ctx context.Context, e Event) error { calls = append(calls, "c"); panic("bad handler") }},
+	}
+	consumed := map[string]bool{}
+	record := func(name string) error { consumed[name] = true; return nil }
+	e := Event{ID: 1, Type: "test.event"}
+
+	failures := handlePending(context.Background(), e, subs, consumed, record)
+	if len(failures) != 2 || !strings.HasPrefix(failures[0], "b: boom") || !strings.HasPrefix(failures[1], "c: panic: bad handler") {
+		t.Fatalf("failures = %q", failures)
+	}
+	if !reflect.DeepEqual(consumed, map[string]bool{"a": true}) {
+		t.Fatalf("consumed = %v, want only a", consumed)
+	}
+
+	// 重新认领后只投递给上次失败的订阅者
+	calls = nil
+	failing = false
+	subs = subs[:2]
+	if failures := handlePending(context.Background(), e, subs, consumed, record); failures != nil {
+		t.Fatalf("retry failures = %q", failures)
+	}
+	if !reflect.DeepEqual(calls, []string{"b"}) {
+		t.Fatalf("retry called %v, want only b", calls)
+	}
+
+	// 全部处理过的事件不再调用任何订阅者
+	calls = nil
+	if failures := handlePending(context.Background(), e, subs, consumed, record); failures != nil || calls != nil {
+		t.Fatalf("consumed event: failures %q, calls %v", failures, calls)
+	}
+}
+
+func TestHandlePendingRecordFailure(t *testing.T) {
+	subs := []subscription{{"a", func(ctx context.Context, e Event) error { return nil }}}
+	failures := handlePending(context.Background(), Event{ID: 1}, subs, map[string]bool{}, func(string) error {
+		return errors.New("db down")
+	})
+	// 处理成功但没记下来：事件重试，订阅者需要幂等
+	if len(failures) != 1 || !strings.Contains(failures[0], "failed to record consumption") {
+		t.Fatalf("failures = %q", failures)
+	}
+}
+
+func TestTargetsIncludeStream(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer client.Close()
+	handler := func(ctx context.Context, e Event) error { return nil }
+
+	names := func(subs []subscription) []string {
+		var out []string
+		for _, s := range subs {
+			out = append(out, s.name)
+		}
+		return out
+	}
+
+	d := NewDispatcher(nil, client, config.EventsConfig{})
+	d.Subscribe("test.event", "a", handler)
+	if got := names(d.targets("test.event")); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("targets without stream = %v", got)
+	}
+	if d.maxAttempts != 10 {
+		t.Fatalf("default maxAttempts = %d, want 10", d.maxAttempts)
+	}
+
+	d = NewDispatcher(nil, client, config.EventsConfig{RedisStream: "events", MaxAttempts: 3})
+	d.Subscribe("test.event", "a", handler)
+	if got := names(d.targets("test.event")); !reflect.DeepEqual(got, []string{"a", streamSubscriber}) {
+		t.Fatalf("targets with stream = %v", got)
+	}
+	if got := names(d.targets("other.event")); !reflect.DeepEqual(got, []string{streamSubscriber}) {
+		t.Fatalf("targets for an event without subscribers = %v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Type 事件类型
+type Type string
+
+const (
+	GameCompleted       Type = "game.completed"
+	WordReviewed        Type = "word.reviewed"
+	UserRegistered      Type = "user.registered"
+	LevelUp             Type = "user.level_up"
+	AssignmentCompleted Type = "assignment.completed"
+	ChallengeSent       Type = "challenge.sent"
+)
+
+// Event 从发件箱取出的事件；ID 可作为订阅者的幂等键
+type Event struct {
+	ID         int64           `json:"id"`
+	Type       Type            `json:"type"`
+	UserID     int             `json:"user_id"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Attempts   int             `json:"attempts"` // 之前已失败的次数
+}
+
+// Decode 解析事件内容
+func (e *Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode %s event %d: %w", e.Type, e.ID, err)
+	}
+	return nil
+}
+
+// GameCompletedPayload 一局游戏完成并记录成绩
+type GameCompletedPayload struct {
+	RecordID         int64  `json:"record_id"`
+	SessionID        int    `json:"session_id,omitempty"`
+	GameType         string `json:"game_type"`
+	Score            int    `json:"score"`
+	LevelReached     int    `json:"level_reached"`
+	TimeSpent        int    `json:"time_spent"`
+	CorrectCount     int    `json:"correct_count"`
+	TotalCount       int    `json:"total_count"`
+	ExperienceGained int    `json:"experience_gained"`
+	CoinsGained      int    `json:"coins_gained"`
+	Experience       int    `json:"experience"` // 奖励后的总经验值
+}
+
+// WordReviewedPayload 学习或作答了一个单词
+type WordReviewedPayload struct {
+	WordID       int      `json:"word_id"`
+	Source       string   `json:"source"`              // study（学习进度）或 game（游戏作答）
+	GameType     string   `json:"game_type,omitempty"` // 游戏作答时的游戏类型
+	Correct      *bool    `json:"correct,omitempty"`   // 游戏作答是否正确
+	MasteryLevel *float64 `json:"mastery_level,omitempty"`
+}
+
+// UserRegisteredPayload 新用户注册
+type UserRegisteredPayload struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Source   string `json:"source,omitempty"` // 注册来源，如 wechat；为空表示用户名密码注册
+}
+
+// LevelUpPayload 用户升级（一次跨多级时每级一条）
+type LevelUpPayload struct {
+	Level int `json:"level"`
+}
+
+// AssignmentCompletedPayload 学生首次完成作业
+type AssignmentCompletedPayload struct {
+	AssignmentID int       `json:"assignment_id"`
+	ClassID      int       `json:"class_id"`
+	Title        string    `json:"title"`
+	Kind         string    `json:"kind"`
+	Target       int       `json:"target"`
+	Progress     int       `json:"progress"`
+	DueAt        time.Time `json:"due_at"`
+	CompletedAt  time.Time `json:"completed_at"`
+	Late         bool      `json:"late"` // 截止后才完成
+}
+
+// ChallengeSentPayload 发起者完成挑战局，挑战发给对手
+type ChallengeSentPayload struct {
+	ChallengeID int       `json:"challenge_id"`
+	OpponentID  int       `json:"opponent_id"`
+	Level       int       `json:"level"`
+	Score       int       `json:"score"` // 发起者的得分
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Execer 可以是 *sql.DB 或 *sql.Tx；需要与业务数据保持一致时传入同一个事务
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Write 把事件写入发件箱，事务提交后由 Dispatcher 投递
+func Write(exec Execer, eventType Type, userID int, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	var user interface{}
+	if userID > 0 {
+		user = userID
+	}
+	if _, err := exec.Exec(`
+		INSERT INTO event_outbox (event_type, user_id, payload) VALUES (?, ?, ?)
+	`, eventType, user, string(data)); err != nil {
+		return fmt.Errorf("failed to write %s event: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handlers struct {
+	dispatcher *Dispatcher
+}
+
+func NewHandlers(dispatcher *Dispatcher) *Handlers {
+	return &Handlers{
+		dispatcher: dispatcher,
+	}
+}
+
+// ListDeadLetters 死信列表
+func (h *Handlers) ListDeadLetters(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	letters, err := h.dispatcher.DeadLetters(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": letters})
+}
+
+// RetryDeadLetter 重新投递死信
+func (h *Handlers) RetryDeadLetter(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	if err := h.dispatcher.Retry(eventID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrEventNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event requeued"})
+}
@@ -0,0 +1,11 @@
+package events
+
+import (
+	"linguaforge/internal/scheduler"
+	"time"
+)
+
+// RegisterJobs 注册事件相关的定时任务
+func (d *Dispatcher) RegisterJobs(jobs *scheduler.Scheduler) {
+	jobs.Register("events.prune", "45 3 * * *", "清理已投递的旧事件", 5*time.Minute, d.prune)
+}
@@ -0,0 +1,229 @@
+package game
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"linguaforge/internal/events"
+	"strings"
+)
+
+// ProgressRecorder 把单题作答结果同步到学习进度和错题本（由 content.Service 实现），在作答记录的事务中执行
+type ProgressRecorder interface {
+	RecordAnswer(tx *sql.Tx, userID int, wordID int, correct bool, chosen string) error
+}
+
+// reportableGameTypes 由客户端判题、需要通过 /games/answers 上报作答的游戏类型；
+// 其余游戏由服务端判题并自行记录作答
+var reportableGameTypes = map[GameType]bool{
+	GameTypeAdventure: true,
+	GameTypeDefense:   true,
+	GameTypeMistakes:  true,
+}
+
+// ErrWordAnswered 该局中这个单词已经作答过
+var ErrWordAnswered = errors.New("word already answered")
+
+// ReportAnswers 保存客户端上报的作答：校验游戏类型和单词，按单词释义重新判定对错，不采信客户端的 is_correct
+func (s *Service) ReportAnswers(userID int, req *RecordAnswersRequest) (int, error) {
+	if !reportableGameTypes[req.GameType] {
+		return 0, fmt.Errorf("invalid game type: %s", req.GameType)
+	}
+
+	wordIDs := make([]interface{}, 0, len(req.Answers)+1)
+	for _, a := range req.Answers {
+		wordIDs = append(wordIDs, a.WordID)
+	}
+	// 只接受该局抽到的单词
+	wordIDs = append(wordIDs, req.SessionID)
+	words, err := s.queryWords(`
+		SELECT w.id, w.english, w.chinese FROM words w
+		WHERE w.id IN (?`+strings.Repeat(", ?", len(req.Answers)-1)+`)
+		  AND w.id IN (SELECT word_id FROM game_session_words WHERE session_id = ?)
+	`, wordIDs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get words: %w", err)
+	}
+	byID := make(map[int]AdventureWord, len(words))
+	for _, w := range words {
+		byID[w.ID] = w
+	}
+
+	answers := make([]AnswerInput, len(req.Answers))
+	for i, a := range req.Answers {
+		w, ok := byID[a.WordID]
+		if !ok {
+			return 0, fmt.Errorf("word %d not in game session", a.WordID)
+		}
+		a.IsCorrect = isCorrectAnswer(w, a.ChosenOption)
+		answers[i] = a
+	}
+	return s.RecordAnswers(userID, &RecordAnswersRequest{
+		SessionID: req.SessionID,
+		GameType:  req.GameType,
+		Answers:   answers,
+	})
+}
+
+// isCorrectAnswer 选项与单词释义或英文一致即为答对（忽略首尾空白和英文大小写）
+func isCorrectAnswer(w AdventureWord, chosen string) bool {
+	chosen = strings.TrimSpace(chosen)
+	return chosen != "" && (chosen == strings.TrimSpace(w.Chinese) || strings.EqualFold(chosen, strings.TrimSpace(w.English)))
+}
+
+// RecordAnswers 批量保存已判定对错的作答记录，并在同一事务中更新学习进度
+func (s *Service) RecordAnswers(userID int, req *RecordAnswersRequest) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.recordAnswers(tx, userID, req); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit answer events: %w", err)
+	}
+	return len(req.Answers), nil
+}
+
+// recordAnswers 在调用方的事务中保存作答：该局须属于本人、游戏类型一致且尚未结算；
+// 每个单词须属于该局且只能作答一次，得分记在局内单词上，作答时间以服务器为准
+func (s *Service) recordAnswers(tx *sql.Tx, userID int, req *RecordAnswersRequest) error {
+	if err := lockLiveSession(tx, req.SessionID, userID, req.GameType); err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool, len(req.Answers))
+	placeholders := make([]string, 0, len(req.Answers))
+	args := make([]interface{}, 0, len(req.Answers)*7)
+	for _, a := range req.Answers {
+		if a.ResponseTimeMs < 0 {
+			return fmt.Errorf("invalid response time for word %d", a.WordID)
+		}
+		if seen[a.WordID] {
+			return fmt.Errorf("duplicate answer for word %d", a.WordID)
+		}
+		seen[a.WordID] = true
+
+		// 以未作答为条件更新，并发提交同一单词时只有一个请求能写入
+		result, err := tx.Exec(`
+			UPDATE game_session_words SET answered_at = NOW(), points = ?
+			WHERE session_id = ? AND word_id = ? AND answered_at IS NULL
+		`, a.Points, req.SessionID, a.WordID)
+		if err != nil {
+			return fmt.Errorf("failed to mark word answered: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			var exists bool
+			if err := tx.QueryRow(`
+				SELECT EXISTS(SELECT 1 FROM game_session_words WHERE session_id = ? AND word_id = ?)
+			`, req.SessionID, a.WordID).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to get session word: %w", err)
+			}
+			if !exists {
+				return fmt.Errorf("word %d not in game session", a.WordID)
+			}
+			return ErrWordAnswered
+		}
+
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, NOW())")
+		args = append(args, userID, req.SessionID, a.WordID, req.GameType,
+			a.ChosenOption, a.IsCorrect, a.ResponseTimeMs)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO answer_events
+		(user_id, session_id, word_id, game_type, chosen_option, is_correct, response_time_ms, answered_at)
+		VALUES `+strings.Join(placeholders, ", "), args...)
+	if err != nil {
+		return fmt.Errorf("failed to save answer events: %w", err)
+	}
+	for _, a := range req.Answers {
+		if s.progress != nil {
+			if err := s.progress.RecordAnswer(tx, userID, a.WordID, a.IsCorrect, a.ChosenOption); err != nil {
+				return fmt.Errorf("failed to update progress: %w", err)
+			}
+		}
+		correct := a.IsCorrect
+		if err := events.Write(tx, events.WordReviewed, userID, &events.WordReviewedPayload{
+			WordID:   a.WordID,
+			Source:   "game",
+			GameType: string(req.GameType),
+			Correct:  &correct,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockLiveSession 锁定游戏局并校验归属、游戏类型和结算状态
+func lockLiveSession(tx *sql.Tx, sessionID int, userID int, gameType GameType) error {
+	var owner int
+	var sessionType GameType
+	var finished bool
+	err := tx.QueryRow(`
+		SELECT user_id, game_type, finished_at IS NOT NULL FROM game_sessions WHERE id = ? FOR UPDATE
+	`, sessionID).Scan(&owner, &sessionType, &finished)
+	if err == sql.ErrNoRows || (err == nil && owner != userID) {
+		return fmt.Errorf("game session not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get game session: %w", err)
+	}
+	if sessionType != gameType {
+		return fmt.Errorf("game type does not match session")
+	}
+	if finished {
+		return ErrGameCompleted
+	}
+	return nil
+}
+
+// settleAnsweredSession 局内单词全部作答后，按服务端记在各单词上的得分结算本局；尚有单词未作答时返回 nil
+func (s *Service) settleAnsweredSession(userID int, sessionID int, gameType GameType) (*RoundsSummary, error) {
+	var remaining, score, level int
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(gsw.answered_at IS NULL), 0), COALESCE(SUM(gsw.points), 0), gs.difficulty_max
+		FROM game_sessions gs JOIN game_session_words gsw ON gsw.session_id = gs.id
+		WHERE gs.id = ?
+		GROUP BY gs.id, gs.difficulty_max
+	`, sessionID).Scan(&remaining, &score, &level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session words: %w", err)
+	}
+	if remaining > 0 {
+		return nil, nil
+	}
+
+	err = s.SubmitScore(userID, &SubmitScoreRequest{
+		GameType:     gameType,
+		Score:        score,
+		LevelReached: level,
+		SessionID:    sessionID,
+	})
+	// 最后两个单词并发作答时两个请求都会走到这里，只有一个能结算
+	if err != nil && !errors.Is(err, ErrGameCompleted) {
+		return nil, err
+	}
+	correct, total, err := s.sessionAnswerCounts(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &RoundsSummary{Score: score, CorrectCount: correct, TotalCount: total}, nil
+}
+
+// sessionAnswerCounts 统计某局已上报的答对数和总题数
+func (s *Service) sessionAnswerCounts(sessionID int) (int, int, error) {
+	var correct, total int
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(is_correct), 0), COUNT(*)
+		FROM answer_events WHERE session_id = ?
+	`, sessionID).Scan(&correct, &total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count session answers: %w", err)
+	}
+	return correct, total, nil
+}
@@ -0,0 +1,427 @@
+package game
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 冒险剧情参数
+const adventureCorrectPoints = 10 // 每答对一题的得分
+
+// storyNode 数据库中的剧情节点
+type storyNode struct {
+	Key         string
+	Kind        StoryNodeKind
+	Story       string
+	WordID      sql.NullInt64
+	English     sql.NullString
+	Chinese     sql.NullString
+	Next        sql.NullString
+	Wrong       sql.NullString
+	RewardScore int
+}
+
+// chapterProgress 用户在某章节的进度
+type chapterProgress struct {
+	ID          int
+	SessionID   sql.NullInt64
+	NodeKey     string
+	Status      ChapterStatus
+	Score       int
+	Completions int
+	Elapsed     int
+}
+
+// ListChapters 列出已发布的章节及用户的解锁状态和进度
+// 解锁规则：需先通关 unlock_chapter_id 指定的章节，且用户推荐难度不低于 unlock_level
+func (s *Service) ListChapters(userID int) ([]AdventureChapter, error) {
+	level := s.getUserPreferredLevel(userID)
+
+	rows, err := s.db.Query(`
+		SELECT c.id, c.title, c.description, c.sort_order, c.unlock_chapter_id, c.unlock_level,
+		       p.status, COALESCE(p.completions, 0), COALESCE(p.best_score, 0)
+		FROM adventure_chapters c
+		LEFT JOIN user_adventure_progress p ON p.chapter_id = c.id AND p.user_id = ?
+		WHERE c.is_published = TRUE
+		ORDER BY c.sort_order, c.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chapters: %w", err)
+	}
+	defer rows.Close()
+
+	var chapters []AdventureChapter
+	for rows.Next() {
+		var ch AdventureChapter
+		var description, status sql.NullString
+		var unlockChapter sql.NullInt64
+		if err := rows.Scan(&ch.ID, &ch.Title, &description, &ch.SortOrder, &unlockChapter, &ch.UnlockLevel,
+			&status, &ch.Completions, &ch.BestScore); err != nil {
+			return nil, fmt.Errorf("failed to scan chapter: %w", err)
+		}
+		ch.Description = description.String
+		if unlockChapter.Valid {
+			id := int(unlockChapter.Int64)
+			ch.UnlockChapterID = &id
+		}
+		switch {
+		case status.String == string(ChapterStatusInProgress):
+			ch.Status = ChapterStatusInProgress
+		case ch.Completions > 0:
+			ch.Status = ChapterStatusCompleted
+		default:
+			ch.Status = ChapterStatusAvailable
+		}
+		chapters = append(chapters, ch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chapters: %w", err)
+	}
+
+	// 已开始过的章节不再上锁，避免编辑调整解锁条件后用户进度丢失
+	completed := make(map[int]bool, len(chapters))
+	for _, ch := range chapters {
+		if ch.Completions > 0 {
+			completed[ch.ID] = true
+		}
+	}
+	for i := range chapters {
+		ch := &chapters[i]
+		if ch.Status != ChapterStatusAvailable {
+			continue
+		}
+		switch {
+		case ch.UnlockChapterID != nil && !completed[*ch.UnlockChapterID]:
+			ch.Status = ChapterStatusLocked
+			ch.LockReason = fmt.Sprintf("complete chapter %d first", *ch.UnlockChapterID)
+		case level < ch.UnlockLevel:
+			ch.Status = ChapterStatusLocked
+			ch.LockReason = fmt.Sprintf("requires level %d", ch.UnlockLevel)
+		}
+	}
+	return chapters, nil
+}
+
+// PlayChapter 进入章节：有未完成的进度时从保存的位置继续，否则（含重玩已通关章节）从起点开始新的一局
+func (s *Service) PlayChapter(userID int, chapterID int) (*AdventureState, error) {
+	chapter, err := s.findChapter(userID, chapterID)
+	if err != nil {
+		return nil, err
+	}
+	if chapter.Status == ChapterStatusLocked {
+		return nil, fmt.Errorf("chapter is locked: %s", chapter.LockReason)
+	}
+
+	progress, err := s.getChapterProgress(userID, chapterID)
+	if err != nil {
+		return nil, err
+	}
+	if progress != nil && progress.Status == ChapterStatusInProgress && progress.SessionID.Valid {
+		return s.adventureState(chapterID, int(progress.SessionID.Int64), progress.NodeKey, progress.Score)
+	}
+
+	var startNode string
+	if err := s.db.QueryRow("SELECT start_node_key FROM adventure_chapters WHERE id = ?", chapterID).Scan(&startNode); err != nil {
+		return nil, fmt.Errorf("failed to get chapter: %w", err)
+	}
+
+	// 本章的题目单词作为本局会话单词，作答记录和学习进度照常更新
+	selection, err := s.chapterSelection(chapterID)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := s.createSession(userID, GameTypeAdventure, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO user_adventure_progress (user_id, chapter_id, session_id, current_node_key, status, score)
+		VALUES (?, ?, ?, ?, ?, 0)
+		ON DUPLICATE KEY UPDATE session_id = VALUES(session_id), current_node_key = VALUES(current_node_key),
+		    status = VALUES(status), score = 0, ending_key = NULL, started_at = NOW(), completed_at = NULL
+	`, userID, chapterID, sessionID, startNode, ChapterStatusInProgress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save chapter progress: %w", err)
+	}
+
+	return s.adventureState(chapterID, sessionID, startNode, 0)
+}
+
+// AdvanceChapter 在当前节点作答或继续：题目按答对/答错走不同分支，到达结局即通关并写入游戏记录
+func (s *Service) AdvanceChapter(userID int, chapterID int, req *AdventureAdvanceRequest) (*AdventureAdvanceResult, error) {
+	progress, err := s.getChapterProgress(userID, chapterID)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil || progress.Status != ChapterStatusInProgress || !progress.SessionID.Valid {
+		return nil, fmt.Errorf("chapter not in progress")
+	}
+	if req.NodeKey != progress.NodeKey {
+		return nil, fmt.Errorf("node does not match current position")
+	}
+	sessionID := int(progress.SessionID.Int64)
+
+	node, err := s.getStoryNode(chapterID, progress.NodeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AdventureAdvanceResult{}
+	score := progress.Score
+	next := node.Next.String
+	var answer *AnswerInput
+	switch node.Kind {
+	case StoryNodeNarration:
+	case StoryNodeQuestion:
+		correct := strings.TrimSpace(req.Answer) == node.Chinese.String
+		result.Correct = &correct
+		result.Expected = node.Chinese.String
+		if correct {
+			score += adventureCorrectPoints
+		} else {
+			next = node.Wrong.String
+		}
+		answer = &AnswerInput{
+			WordID:         int(node.WordID.Int64),
+			ChosenOption:   req.Answer,
+			IsCorrect:      correct,
+			ResponseTimeMs: req.ResponseTimeMs,
+		}
+	default:
+		return nil, fmt.Errorf("chapter already reached an ending")
+	}
+
+	nextNode, err := s.getStoryNode(chapterID, next)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 先以当前位置为条件更新，同一节点被重复提交时只有一个请求能前进，其余请求不记录作答
+	update, err := tx.Exec(`
+		UPDATE user_adventure_progress SET current_node_key = ?, score = ?
+		WHERE id = ? AND current_node_key = ? AND status = ?
+	`, next, score, progress.ID, progress.NodeKey, ChapterStatusInProgress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save chapter progress: %w", err)
+	}
+	if affected, _ := update.RowsAffected(); affected == 0 {
+		return nil, fmt.Errorf("node already answered")
+	}
+	if answer != nil {
+		err = s.recordAnswers(tx, userID, &RecordAnswersRequest{
+			SessionID: sessionID,
+			GameType:  GameTypeAdventure,
+			Answers:   []AnswerInput{*answer},
+		})
+		// 同一单词在本章多个题目节点出现时只记录第一次作答
+		if err != nil && !errors.Is(err, ErrWordAnswered) {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit chapter progress: %w", err)
+	}
+
+	if nextNode.Kind == StoryNodeEnding {
+		unlocked, err := s.completeChapter(userID, chapterID, progress, nextNode, score+nextNode.RewardScore)
+		if err != nil {
+			return nil, err
+		}
+		score += nextNode.RewardScore
+		state, err := s.adventureState(chapterID, sessionID, next, score)
+		if err != nil {
+			return nil, err
+		}
+		state.Unlocked = unlocked
+		result.State = *state
+		return result, nil
+	}
+
+	state, err := s.adventureState(chapterID, sessionID, next, score)
+	if err != nil {
+		return nil, err
+	}
+	result.State = *state
+	return result, nil
+}
+
+// completeChapter 通关结算：更新进度、写入游戏记录，返回首次通关后新解锁的章节
+func (s *Service) completeChapter(userID int, chapterID int, progress *chapterProgress, ending *storyNode, score int) ([]AdventureChapter, error) {
+	before, err := s.ListChapters(userID)
+	if err != nil {
+		return nil, err
+	}
+	locked := make(map[int]bool, len(before))
+	for _, ch := range before {
+		if ch.Status == ChapterStatusLocked {
+			locked[ch.ID] = true
+		}
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE user_adventure_progress
+		SET status = ?, score = ?, best_score = GREATEST(best_score, ?), completions = completions + 1,
+		    ending_key = ?, completed_at = NOW()
+		WHERE id = ?
+	`, ChapterStatusCompleted, score, score, ending.Key, progress.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete chapter: %w", err)
+	}
+
+	var sortOrder int
+	if err := s.db.QueryRow("SELECT sort_order FROM adventure_chapters WHERE id = ?", chapterID).Scan(&sortOrder); err != nil {
+		return nil, fmt.Errorf("failed to get chapter: %w", err)
+	}
+	err = s.SubmitScore(userID, &SubmitScoreRequest{
+		GameType:     GameTypeAdventure,
+		Score:        score,
+		LevelReached: sortOrder,
+		TimeSpent:    progress.Elapsed,
+		SessionID:    int(progress.SessionID.Int64),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := s.ListChapters(userID)
+	if err != nil {
+		return nil, err
+	}
+	var unlocked []AdventureChapter
+	for _, ch := range after {
+		if locked[ch.ID] && ch.Status != ChapterStatusLocked {
+			unlocked = append(unlocked, ch)
+		}
+	}
+	return unlocked, nil
+}
+
+// adventureState 组装当前节点的游玩状态
+func (s *Service) adventureState(chapterID int, sessionID int, nodeKey string, score int) (*AdventureState, error) {
+	node, err := s.getStoryNode(chapterID, nodeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &AdventureState{
+		ChapterID: chapterID,
+		SessionID: sessionID,
+		Score:     score,
+		Completed: node.Kind == StoryNodeEnding,
+		Node: AdventureNodeView{
+			Key:   node.Key,
+			Kind:  node.Kind,
+			Story: node.Story,
+		},
+	}
+	err = s.db.QueryRow(
+		"SELECT difficulty_min, difficulty_max FROM game_sessions WHERE id = ?", sessionID,
+	).Scan(&state.Difficulty.Min, &state.Difficulty.Max)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game session: %w", err)
+	}
+
+	switch node.Kind {
+	case StoryNodeQuestion:
+		word := AdventureWord{ID: int(node.WordID.Int64), English: node.English.String, Chinese: node.Chinese.String}
+		pool, err := s.queryWords("SELECT w.id, w.english, w.chinese FROM words w WHERE w.id <> ? ORDER BY RAND() LIMIT 6", word.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get distractors: %w", err)
+		}
+		state.Node.WordID = word.ID
+		state.Node.English = word.English
+		state.Node.Options = meaningOptions(word, pool, nil)
+	case StoryNodeEnding:
+		state.Node.Reward = node.RewardScore
+	}
+	return state, nil
+}
+
+// chapterSelection 章节内题目节点用到的单词
+func (s *Service) chapterSelection(chapterID int) (*WordSelection, error) {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT w.id, w.english, w.chinese, w.difficulty_level
+		FROM adventure_nodes n JOIN words w ON w.id = n.word_id
+		WHERE n.chapter_id = ?
+	`, chapterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chapter words: %w", err)
+	}
+	defer rows.Close()
+
+	selection := &WordSelection{Sources: make(map[int]WordSource)}
+	for rows.Next() {
+		var w AdventureWord
+		var difficulty int
+		if err := rows.Scan(&w.ID, &w.English, &w.Chinese, &difficulty); err != nil {
+			return nil, fmt.Errorf("failed to scan chapter word: %w", err)
+		}
+		if selection.Band.Min == 0 || difficulty < selection.Band.Min {
+			selection.Band.Min = difficulty
+		}
+		selection.Band.Max = max(selection.Band.Max, difficulty)
+		selection.Words = append(selection.Words, w)
+		selection.Sources[w.ID] = WordSourceStory
+	}
+	if selection.Band.Min == 0 {
+		selection.Band = DifficultyBand{Min: 1, Max: 1}
+	}
+	return selection, nil
+}
+
+// findChapter 查找已发布章节及其解锁状态
+func (s *Service) findChapter(userID int, chapterID int) (*AdventureChapter, error) {
+	chapters, err := s.ListChapters(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chapters {
+		if chapters[i].ID == chapterID {
+			return &chapters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("chapter not found")
+}
+
+// getChapterProgress 获取用户章节进度，未开始时返回 nil
+func (s *Service) getChapterProgress(userID int, chapterID int) (*chapterProgress, error) {
+	p := &chapterProgress{}
+	err := s.db.QueryRow(`
+		SELECT id, session_id, current_node_key, status, score, completions,
+		       TIMESTAMPDIFF(SECOND, started_at, NOW())
+		FROM user_adventure_progress WHERE user_id = ? AND chapter_id = ?
+	`, userID, chapterID).Scan(&p.ID, &p.SessionID, &p.NodeKey, &p.Status, &p.Score, &p.Completions, &p.Elapsed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chapter progress: %w", err)
+	}
+	return p, nil
+}
+
+// getStoryNode 获取剧情节点（题目节点附带单词）
+func (s *Service) getStoryNode(chapterID int, key string) (*storyNode, error) {
+	n := &storyNode{}
+	err := s.db.QueryRow(`
+		SELECT n.node_key, n.kind, n.story, n.word_id, w.english, w.chinese, n.next_key, n.wrong_key, n.reward_score
+		FROM adventure_nodes n LEFT JOIN words w ON w.id = n.word_id
+		WHERE n.chapter_id = ? AND n.node_key = ?
+	`, chapterID, key).Scan(&n.Key, &n.Kind, &n.Story, &n.WordID, &n.English, &n.Chinese, &n.Next, &n.Wrong, &n.RewardScore)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("story node %q not found", key)
+		}
+		return nil, fmt.Errorf("failed to get story node: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,352 @@
+package game
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChapterGraph 章节及其完整剧情图（编辑用）
+type ChapterGraph struct {
+	ID int `json:"id"`
+	ChapterInput
+}
+
+// ValidateChapter 校验章节剧情图：节点引用完整、从起点可达所有节点、每个节点都能走到结局（无死路）
+// chapterID 为 0 表示新建章节
+func (s *Service) ValidateChapter(chapterID int, input *ChapterInput) (*ChapterValidation, error) {
+	errs := validateStoryGraph(input)
+
+	// 题目节点引用的单词必须存在
+	wordIDs := make([]int, 0)
+	for _, n := range input.Nodes {
+		if n.Kind == StoryNodeQuestion && n.WordID > 0 {
+			wordIDs = append(wordIDs, n.WordID)
+		}
+	}
+	if len(wordIDs) > 0 {
+		media, err := s.getWordMedia(wordIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check words: %w", err)
+		}
+		for _, n := range input.Nodes {
+			if n.Kind != StoryNodeQuestion || n.WordID <= 0 {
+				continue
+			}
+			if _, ok := media[n.WordID]; !ok {
+				errs = append(errs, fmt.Sprintf("node %q references unknown word %d", n.Key, n.WordID))
+			}
+		}
+	}
+
+	// 解锁章节必须存在，且解锁链不能成环
+	if input.UnlockChapterID != nil {
+		unlockErr, err := s.checkUnlockChain(chapterID, *input.UnlockChapterID)
+		if err != nil {
+			return nil, err
+		}
+		if unlockErr != "" {
+			errs = append(errs, unlockErr)
+		}
+	}
+	if input.UnlockLevel < 0 {
+		errs = append(errs, "unlock_level must not be negative")
+	}
+
+	return &ChapterValidation{Valid: len(errs) == 0, Errors: errs}, nil
+}
+
+// SaveChapter 创建（chapterID 为 0）或整体替换章节剧情图；校验不通过时不写入并返回校验结果
+// 替换后，停留在已删除节点上的用户进度回到起点重新开始
+func (s *Service) SaveChapter(userID int, chapterID int, input *ChapterInput) (int, *ChapterValidation, error) {
+	if chapterID > 0 {
+		if _, err := s.GetChapterGraph(chapterID); err != nil {
+			return 0, nil, err
+		}
+	}
+	validation, err := s.ValidateChapter(chapterID, input)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !validation.Valid {
+		return 0, validation, nil
+	}
+	if input.UnlockLevel == 0 {
+		input.UnlockLevel = 1
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var unlockChapter sql.NullInt64
+	if input.UnlockChapterID != nil {
+		unlockChapter = sql.NullInt64{Int64: int64(*input.UnlockChapterID), Valid: true}
+	}
+	if chapterID == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO adventure_chapters
+			(title, description, sort_order, start_node_key, unlock_chapter_id, unlock_level, is_published, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, input.Title, input.Description, input.SortOrder, input.StartNode, unlockChapter,
+			input.UnlockLevel, input.Published, userID)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create chapter: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get chapter ID: %w", err)
+		}
+		chapterID = int(id)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE adventure_chapters
+			SET title = ?, description = ?, sort_order = ?, start_node_key = ?,
+			    unlock_chapter_id = ?, unlock_level = ?, is_published = ?
+			WHERE id = ?
+		`, input.Title, input.Description, input.SortOrder, input.StartNode,
+			unlockChapter, input.UnlockLevel, input.Published, chapterID)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to update chapter: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM adventure_nodes WHERE chapter_id = ?", chapterID); err != nil {
+			return 0, nil, fmt.Errorf("failed to replace story nodes: %w", err)
+		}
+	}
+
+	placeholders := make([]string, 0, len(input.Nodes))
+	args := make([]interface{}, 0, len(input.Nodes)*8)
+	keys := make([]interface{}, 0, len(input.Nodes))
+	for _, n := range input.Nodes {
+		var wordID sql.NullInt64
+		if n.Kind == StoryNodeQuestion {
+			wordID = sql.NullInt64{Int64: int64(n.WordID), Valid: true}
+		}
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, chapterID, n.Key, n.Kind, n.Story, wordID,
+			nullString(n.Next), nullString(n.Wrong), n.RewardScore)
+		keys = append(keys, n.Key)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO adventure_nodes (chapter_id, node_key, kind, story, word_id, next_key, wrong_key, reward_score)
+		VALUES `+strings.Join(placeholders, ", "), args...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to save story nodes: %w", err)
+	}
+
+	args = append([]interface{}{input.StartNode, chapterID, ChapterStatusInProgress}, keys...)
+	_, err = tx.Exec(`
+		UPDATE user_adventure_progress SET current_node_key = ?, score = 0
+		WHERE chapter_id = ? AND status = ? AND current_node_key NOT IN (?`+strings.Repeat(", ?", len(keys)-1)+`)
+	`, args...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to reset chapter progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit chapter: %w", err)
+	}
+	return chapterID, validation, nil
+}
+
+// GetChapterGraph 获取章节的完整剧情图（含未发布章节）
+func (s *Service) GetChapterGraph(chapterID int) (*ChapterGraph, error) {
+	graph := &ChapterGraph{ID: chapterID}
+	var description sql.NullString
+	var unlockChapter sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT title, description, sort_order, start_node_key, unlock_chapter_id, unlock_level, is_published
+		FROM adventure_chapters WHERE id = ?
+	`, chapterID).Scan(&graph.Title, &description, &graph.SortOrder, &graph.StartNode,
+		&unlockChapter, &graph.UnlockLevel, &graph.Published)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chapter not found")
+		}
+		return nil, fmt.Errorf("failed to get chapter: %w", err)
+	}
+	graph.Description = description.String
+	if unlockChapter.Valid {
+		id := int(unlockChapter.Int64)
+		graph.UnlockChapterID = &id
+	}
+
+	rows, err := s.db.Query(`
+		SELECT node_key, kind, story, word_id, next_key, wrong_key, reward_score
+		FROM adventure_nodes WHERE chapter_id = ? ORDER BY id
+	`, chapterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get story nodes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n StoryNodeInput
+		var wordID sql.NullInt64
+		var next, wrong sql.NullString
+		if err := rows.Scan(&n.Key, &n.Kind, &n.Story, &wordID, &next, &wrong, &n.RewardScore); err != nil {
+			return nil, fmt.Errorf("failed to scan story node: %w", err)
+		}
+		n.WordID = int(wordID.Int64)
+		n.Next = next.String
+		n.Wrong = wrong.String
+		graph.Nodes = append(graph.Nodes, n)
+	}
+	return graph, nil
+}
+
+// checkUnlockChain 检查解锁章节存在，且沿解锁链不会回到当前章节
+func (s *Service) checkUnlockChain(chapterID int, unlockChapterID int) (string, error) {
+	if chapterID > 0 && unlockChapterID == chapterID {
+		return "chapter cannot unlock itself", nil
+	}
+	seen := map[int]bool{}
+	current := unlockChapterID
+	for {
+		var next sql.NullInt64
+		err := s.db.QueryRow("SELECT unlock_chapter_id FROM adventure_chapters WHERE id = ?", current).Scan(&next)
+		if err == sql.ErrNoRows {
+			if current == unlockChapterID {
+				return fmt.Sprintf("unlock chapter %d not found", unlockChapterID), nil
+			}
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check unlock chapter: %w", err)
+		}
+		seen[current] = true
+		if !next.Valid {
+			return "", nil
+		}
+		current = int(next.Int64)
+		if (chapterID > 0 && current == chapterID) || seen[current] {
+			return "unlock chapters form a cycle", nil
+		}
+	}
+}
+
+// validateStoryGraph 校验剧情图的结构，返回全部错误
+func validateStoryGraph(input *ChapterInput) []string {
+	var errs []string
+	nodes := make(map[string]StoryNodeInput, len(input.Nodes))
+	for _, n := range input.Nodes {
+		if _, dup := nodes[n.Key]; dup {
+			errs = append(errs, fmt.Sprintf("duplicate node key %q", n.Key))
+			continue
+		}
+		nodes[n.Key] = n
+	}
+	if _, ok := nodes[input.StartNode]; !ok {
+		errs = append(errs, fmt.Sprintf("start node %q not found", input.StartNode))
+	}
+
+	// 各类节点的字段要求与出边
+	edges := make(map[string][]string, len(nodes))
+	hasEnding := false
+	for _, n := range input.Nodes {
+		var targets []string
+		switch n.Kind {
+		case StoryNodeNarration:
+			if n.Next == "" {
+				errs = append(errs, fmt.Sprintf("narration node %q has no next node", n.Key))
+			}
+			if n.Wrong != "" || n.WordID != 0 {
+				errs = append(errs, fmt.Sprintf("narration node %q must not have a word or wrong branch", n.Key))
+			}
+			targets = []string{n.Next}
+		case StoryNodeQuestion:
+			if n.WordID <= 0 {
+				errs = append(errs, fmt.Sprintf("question node %q has no word", n.Key))
+			}
+			if n.Next == "" || n.Wrong == "" {
+				errs = append(errs, fmt.Sprintf("question node %q needs both next and wrong branches", n.Key))
+			}
+			targets = []string{n.Next, n.Wrong}
+		case StoryNodeEnding:
+			hasEnding = true
+			if n.Next != "" || n.Wrong != "" {
+				errs = append(errs, fmt.Sprintf("ending node %q must not have branches", n.Key))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("node %q has unknown kind %q", n.Key, n.Kind))
+		}
+		for _, t := range targets {
+			if t == "" {
+				continue
+			}
+			if _, ok := nodes[t]; !ok {
+				errs = append(errs, fmt.Sprintf("node %q points to unknown node %q", n.Key, t))
+				continue
+			}
+			edges[n.Key] = append(edges[n.Key], t)
+		}
+	}
+	if !hasEnding {
+		errs = append(errs, "chapter has no ending node")
+	}
+
+	// 从起点出发必须能到达所有节点
+	reachable := map[string]bool{}
+	if _, ok := nodes[input.StartNode]; ok {
+		queue := []string{input.StartNode}
+		reachable[input.StartNode] = true
+		for len(queue) > 0 {
+			key := queue[0]
+			queue = queue[1:]
+			for _, t := range edges[key] {
+				if !reachable[t] {
+					reachable[t] = true
+					queue = append(queue, t)
+				}
+			}
+		}
+	}
+
+	// 反向遍历：每个节点都必须能走到某个结局，否则就是死路（或无出口的循环）
+	reverse := make(map[string][]string, len(nodes))
+	for from, targets := range edges {
+		for _, t := range targets {
+			reverse[t] = append(reverse[t], from)
+		}
+	}
+	canFinish := map[string]bool{}
+	var queue []string
+	for _, n := range input.Nodes {
+		if n.Kind == StoryNodeEnding && !canFinish[n.Key] {
+			canFinish[n.Key] = true
+			queue = append(queue, n.Key)
+		}
+	}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, from := range reverse[key] {
+			if !canFinish[from] {
+				canFinish[from] = true
+				queue = append(queue, from)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(nodes))
+	for key := range nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(reachable) > 0 && !reachable[key] {
+			errs = append(errs, fmt.Sprintf("node %q is unreachable from the start node", key))
+		}
+		if hasEnding && !canFinish[key] {
+			errs = append(errs, fmt.Sprintf("node %q is a dead end: no ending can be reached from it", key))
+		}
+	}
+	return errs
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,56 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// BuildChoiceRounds 为一组单词生成"看英文选释义"题目，干扰释义从全词库随机抽取
+func (s *Service) BuildChoiceRounds(words []AdventureWord) ([]ChoiceRound, error) {
+	pool, err := s.queryWords("SELECT w.id, w.english, w.chinese FROM words w ORDER BY RAND() LIMIT ?", len(words)+6)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distractors: %w", err)
+	}
+	return choiceRounds(words, pool, nil), nil
+}
+
+// BuildSeededRounds 由种子确定性地生成一局"看英文选释义"题目：选词、干扰项和选项顺序都只取决于种子和词库
+// 词库按ID排序后再用种子抽取，同一种子在词库不变时总能得到同一局
+func (s *Service) BuildSeededRounds(seed int64, level int, count int) ([]ChoiceRound, error) {
+	if level <= 0 {
+		level = 1
+	}
+	candidates, err := s.queryWords("SELECT w.id, w.english, w.chinese FROM words w WHERE w.difficulty_level = ? ORDER BY w.id", level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get words: %w", err)
+	}
+	all, err := s.queryWords("SELECT w.id, w.english, w.chinese FROM words w ORDER BY w.id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get words: %w", err)
+	}
+	if len(candidates) < count {
+		// 该难度单词不足时放宽到全部难度
+		candidates = append([]AdventureWord{}, all...)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+	rng.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+	return choiceRounds(candidates, all, rng), nil
+}
+
+func choiceRounds(words []AdventureWord, pool []AdventureWord, rng *rand.Rand) []ChoiceRound {
+	rounds := make([]ChoiceRound, 0, len(words))
+	for _, w := range words {
+		rounds = append(rounds, ChoiceRound{
+			WordID:  w.ID,
+			English: w.English,
+			Options: meaningOptions(w, pool, rng),
+			Answer:  w.Chinese,
+		})
+	}
+	return rounds
+}
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -41,6 +42,16 @@ func (h *Handlers) StartGame(c *gin.Context) {
 		game, err = h.service.StartDefenseGame(userID.(int), req.Level)
 	case GameTypeDubbing:
 		game, err = h.service.StartDubbingGame(userID.(int), req.Level)
+	case GameTypeMistakes:
+		game, err = h.service.StartMistakesGame(userID.(int))
+	case GameTypeSpelling:
+		game, err = h.service.StartSpellingGame(userID.(int), req.Level)
+	case GameTypeListening:
+		game, err = h.service.StartListeningGame(userID.(int), req.Level)
+	case GameTypeMatching:
+		game, err = h.service.StartMatchingGame(userID.(int), req.Level)
+	case GameTypeSentence:
+		game, err = h.service.StartSentenceGame(userID.(int), req.Level)
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid game type"})
 		return
@@ -68,15 +79,41 @@ func (h *Handlers) SubmitScore(c *gin.Context) {
 		return
 	}
 
-	err := h.service.SubmitScore(userID.(int), &req)
+	err := h.service.ReportScore(userID.(int), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(submitErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Score submitted successfully"})
 }
 
+// RecordAnswers 批量上报逐题作答（对错由服务端判定）
+func (h *Handlers) RecordAnswers(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req RecordAnswersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recorded, err := h.service.ReportAnswers(userID.(int), &req)
+	if err != nil {
+		c.JSON(answerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Answers recorded successfully",
+		"recorded": recorded,
+	})
+}
+
 // SubmitDubbing 提交配音
 func (h *Handlers) SubmitDubbing(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -135,10 +172,10 @@ func (h *Handlers) GetAdventureData(c *gin.Context) {
 		return
 	}
 
-	levelStr := c.DefaultQuery("level", "1")
-	level, err := strconv.Atoi(levelStr)
+	// 未指定难度时使用用户的推荐难度
+	level, err := strconv.Atoi(c.Query("level"))
 	if err != nil {
-		level = 1
+		level = 0
 	}
 
 	game, err := h.service.StartAdventureGame(userID.(int), level)
@@ -166,9 +203,9 @@ func (h *Handlers) SubmitDefenseScore(c *gin.Context) {
 
 	req.GameType = GameTypeDefense
 
-	err := h.service.SubmitScore(userID.(int), &req)
+	err := h.service.ReportScore(userID.(int), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(submitErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -197,3 +234,308 @@ func (h *Handlers) HandleDubbingSubmission(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Dubbing submission received"})
 }
+
+// GetSpellingHint 购买拼写字母提示
+func (h *Handlers) GetSpellingHint(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SpellingHintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hint, err := h.service.GetSpellingHint(userID.(int), &req)
+	if err != nil {
+		c.JSON(answerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, hint)
+}
+
+// SubmitSpellingAnswer 提交拼写答案
+func (h *Handlers) SubmitSpellingAnswer(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SpellingAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.SubmitSpellingAnswer(userID.(int), &req)
+	if err != nil {
+		c.JSON(answerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// PlayListeningRound 播放（重播）听力题
+func (h *Handlers) PlayListeningRound(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req ListeningPlayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	play, err := h.service.PlayListeningRound(userID.(int), &req)
+	if err != nil {
+		c.JSON(answerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, play)
+}
+
+// SubmitListeningAnswer 提交听力作答
+func (h *Handlers) SubmitListeningAnswer(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req ListeningAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.SubmitListeningAnswer(userID.(int), &req)
+	if err != nil {
+		c.JSON(answerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ClaimMatchingPair 提交连连看配对
+func (h *Handlers) ClaimMatchingPair(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req MatchingClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.ClaimMatchingPair(userID.(int), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SubmitSentenceAnswer 提交连词成句答案
+func (h *Handlers) SubmitSentenceAnswer(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SentenceAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.SubmitSentenceAnswer(userID.(int), &req)
+	if err != nil {
+		c.JSON(answerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListChapters 冒险剧情：章节列表及解锁状态
+func (h *Handlers) ListChapters(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	chapters, err := h.service.ListChapters(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chapters": chapters})
+}
+
+// PlayChapter 冒险剧情：进入章节（有存档时继续）
+func (h *Handlers) PlayChapter(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	chapterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chapter ID"})
+		return
+	}
+
+	state, err := h.service.PlayChapter(userID.(int), chapterID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// AdvanceChapter 冒险剧情：在当前节点作答或继续
+func (h *Handlers) AdvanceChapter(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	chapterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chapter ID"})
+		return
+	}
+
+	var req AdventureAdvanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.AdvanceChapter(userID.(int), chapterID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ValidateChapter 剧情编辑：只校验不保存
+func (h *Handlers) ValidateChapter(c *gin.Context) {
+	var req ChapterInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chapterID, _ := strconv.Atoi(c.Query("chapter_id"))
+	validation, err := h.service.ValidateChapter(chapterID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, validation)
+}
+
+// CreateChapter 剧情编辑：创建章节
+func (h *Handlers) CreateChapter(c *gin.Context) {
+	h.saveChapter(c, 0)
+}
+
+// UpdateChapter 剧情编辑：整体替换章节及剧情图
+func (h *Handlers) UpdateChapter(c *gin.Context) {
+	chapterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chapter ID"})
+		return
+	}
+	h.saveChapter(c, chapterID)
+}
+
+// GetChapterGraph 剧情编辑：获取章节完整剧情图
+func (h *Handlers) GetChapterGraph(c *gin.Context) {
+	chapterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chapter ID"})
+		return
+	}
+
+	graph, err := h.service.GetChapterGraph(chapterID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, graph)
+}
+
+func (h *Handlers) saveChapter(c *gin.Context, chapterID int) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req ChapterInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, validation, err := h.service.SaveChapter(userID.(int), chapterID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validation.Valid {
+		c.JSON(http.StatusUnprocessableEntity, validation)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Chapter saved successfully",
+		"id":      id,
+	})
+}
+
+// submitErrorStatus 重复结算同一局返回 409，分数不被接受返回 400，其余为服务器错误
+func submitErrorStatus(err error) int {
+	if errors.Is(err, ErrGameCompleted) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, ErrScoreRejected) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// answerErrorStatus 重复作答或该局已结算返回 409，其余为请求错误
+func answerErrorStatus(err error) int {
+	if errors.Is(err, ErrWordAnswered) || errors.Is(err, ErrGameCompleted) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
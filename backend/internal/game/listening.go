@@ -0,0 +1,272 @@
+package game
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// 听力练习参数
+const (
+	listeningRoundCount   = 10
+	listeningMaxPoints    = 10
+	listeningFreeReplays  = 1    // 免费重播次数
+	listeningFastAnswerMs = 3000 // 快速作答奖励阈值
+	listeningSlowAnswerMs = 6000
+)
+
+// StartListeningGame 开始听力练习：优先选择有音频的单词，不足时用有音标的单词补齐；
+// 题目不含音频和音标，客户端通过 PlayListeningRound 播放，由服务器记录播放次数和开始时间
+func (s *Service) StartListeningGame(userID int, level int) (*ListeningGame, error) {
+	if level <= 0 {
+		level = s.getUserPreferredLevel(userID)
+	}
+
+	preferred, _ := s.getUserPreferredCategory(userID)
+	selection, err := s.selectWords(userID, listeningRoundCount, level, wordScope{category: preferred, requireAudio: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get words: %w", err)
+	}
+	if len(selection.Words) < listeningRoundCount {
+		rest, err := s.selectWords(userID, listeningRoundCount, level, wordScope{category: preferred, requireMedia: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get words: %w", err)
+		}
+		for _, w := range rest.Words {
+			if len(selection.Words) >= listeningRoundCount {
+				break
+			}
+			if _, ok := selection.Sources[w.ID]; ok {
+				continue
+			}
+			selection.Words = append(selection.Words, w)
+			selection.Sources[w.ID] = rest.Sources[w.ID]
+		}
+	}
+	if len(selection.Words) == 0 {
+		return nil, fmt.Errorf("no words available for level %d", level)
+	}
+	if err := s.saveSelection(userID, GameTypeListening, selection); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(selection.Words))
+	for _, w := range selection.Words {
+		ids = append(ids, w.ID)
+	}
+	media, err := s.getWordMedia(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get word media: %w", err)
+	}
+	pool, err := s.queryWords("SELECT w.id, w.english, w.chinese FROM words w ORDER BY RAND() LIMIT ?", len(ids)+3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distractors: %w", err)
+	}
+
+	rounds := make([]ListeningRound, 0, len(selection.Words))
+	for i, w := range selection.Words {
+		round := ListeningRound{
+			WordID:   w.ID,
+			Mode:     ListeningModeChoice,
+			HasAudio: media[w.ID].AudioURL != "",
+		}
+		// 有音频的单词交替使用"选释义"和"拼写"；没有音频时只能展示音标，固定为选释义
+		if round.HasAudio && i%2 == 1 {
+			round.Mode = ListeningModeType
+		}
+		if round.Mode == ListeningModeChoice {
+			round.Options = meaningOptions(w, pool, nil)
+		}
+
+		_, err := s.db.Exec(`
+			UPDATE game_session_words SET round_mode = ? WHERE session_id = ? AND word_id = ?
+		`, round.Mode, selection.SessionID, w.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save round mode: %w", err)
+		}
+		rounds = append(rounds, round)
+	}
+
+	return &ListeningGame{
+		ID:         selection.SessionID,
+		UserID:     userID,
+		Difficulty: selection.Band,
+		Rounds:     rounds,
+	}, nil
+}
+
+// PlayListeningRound 下发（或重播）一轮的音频或音标：首次播放时记录开始时间，每次播放累加次数
+func (s *Service) PlayListeningRound(userID int, req *ListeningPlayRequest) (*ListeningPlayResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockLiveSession(tx, req.SessionID, userID, GameTypeListening); err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec(`
+		UPDATE game_session_words SET issued_at = COALESCE(issued_at, NOW(3)), plays = plays + 1
+		WHERE session_id = ? AND word_id = ? AND answered_at IS NULL
+	`, req.SessionID, req.WordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save listening play: %w", err)
+	}
+
+	resp := &ListeningPlayResponse{WordID: req.WordID}
+	var answered bool
+	var pronunciation, audioURL sql.NullString
+	err = tx.QueryRow(`
+		SELECT gsw.plays, gsw.answered_at IS NOT NULL, w.pronunciation, w.audio_url
+		FROM game_session_words gsw JOIN words w ON w.id = gsw.word_id
+		WHERE gsw.session_id = ? AND gsw.word_id = ?
+	`, req.SessionID, req.WordID).Scan(&resp.Plays, &answered, &pronunciation, &audioURL)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("word not found in game session")
+		}
+		return nil, fmt.Errorf("failed to get session word: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 && answered {
+		return nil, ErrWordAnswered
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit listening play: %w", err)
+	}
+
+	// 有音频时只下发音频，没有音频时展示音标
+	resp.AudioURL = audioURL.String
+	if resp.AudioURL == "" {
+		resp.Pronunciation = pronunciation.String
+	}
+	return resp, nil
+}
+
+// SubmitListeningAnswer 评分听力作答：重播次数越多、作答越慢得分越低；
+// 重播次数和用时按服务器记录的播放计算，得分记在本局单词上，全部单词作答后按总分自动结算
+func (s *Service) SubmitListeningAnswer(userID int, req *ListeningAnswerRequest) (*ListeningAnswerResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockLiveSession(tx, req.SessionID, userID, GameTypeListening); err != nil {
+		return nil, err
+	}
+
+	var english, chinese string
+	var mode sql.NullString
+	var plays int
+	var elapsedMs sql.NullInt64
+	var answered bool
+	err = tx.QueryRow(`
+		SELECT w.english, w.chinese, gsw.round_mode, gsw.plays,
+		       TIMESTAMPDIFF(MICROSECOND, gsw.issued_at, NOW(3)) DIV 1000, gsw.answered_at IS NOT NULL
+		FROM game_session_words gsw JOIN words w ON w.id = gsw.word_id
+		WHERE gsw.session_id = ? AND gsw.word_id = ?
+		FOR UPDATE
+	`, req.SessionID, req.WordID).Scan(&english, &chinese, &mode, &plays, &elapsedMs, &answered)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("word not found in game session")
+		}
+		return nil, fmt.Errorf("failed to get session word: %w", err)
+	}
+	if answered {
+		return nil, ErrWordAnswered
+	}
+	if !elapsedMs.Valid {
+		return nil, fmt.Errorf("round not played yet")
+	}
+
+	result := &ListeningAnswerResult{
+		WordID:         req.WordID,
+		Mode:           ListeningMode(mode.String),
+		English:        english,
+		Chinese:        chinese,
+		Replays:        max(plays-1, 0),
+		ResponseTimeMs: int(max(elapsedMs.Int64, 0)),
+	}
+	if result.Mode == ListeningModeType {
+		target := normalizeSpelling(english)
+		distance := editDistance(normalizeSpelling(req.Answer), target)
+		result.Correct = distance == 0
+		result.Credit = math.Max(0, 1-float64(distance)/float64(len([]rune(target))))
+	} else {
+		result.Mode = ListeningModeChoice
+		result.Correct = req.Answer == chinese
+		if result.Correct {
+			result.Credit = 1
+		}
+	}
+
+	// 超出免费次数的重播每次扣1分，最多扣掉一半
+	base := result.Credit * listeningMaxPoints
+	penalty := math.Min(float64(max(result.Replays-listeningFreeReplays, 0)), base/2)
+	points := base - penalty
+	if result.Correct {
+		switch {
+		case result.ResponseTimeMs < listeningFastAnswerMs:
+			points += 3
+		case result.ResponseTimeMs < listeningSlowAnswerMs:
+			points++
+		}
+	}
+	result.Points = int(math.Round(points))
+	result.Credit = math.Round(result.Credit*100) / 100
+
+	err = s.recordAnswers(tx, userID, &RecordAnswersRequest{
+		SessionID: req.SessionID,
+		GameType:  GameTypeListening,
+		Answers: []AnswerInput{{
+			WordID:         req.WordID,
+			ChosenOption:   req.Answer,
+			IsCorrect:      result.Correct,
+			ResponseTimeMs: result.ResponseTimeMs,
+			Points:         result.Points,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit listening answer: %w", err)
+	}
+
+	result.Summary, err = s.settleAnsweredSession(userID, req.SessionID, GameTypeListening)
+	if err != nil {
+		return nil, err
+	}
+	result.Completed = result.Summary != nil
+	return result, nil
+}
+
+// meaningOptions 生成"选释义"选项：正确释义加3个不同的干扰释义；rng 为 nil 时使用全局随机源
+func meaningOptions(word AdventureWord, pool []AdventureWord, rng *rand.Rand) []string {
+	options := []string{word.Chinese}
+	seen := map[string]bool{word.Chinese: true}
+	for _, d := range pool {
+		if len(options) >= 4 {
+			break
+		}
+		if seen[d.Chinese] {
+			continue
+		}
+		seen[d.Chinese] = true
+		options = append(options, d.Chinese)
+	}
+	shuffle(rng, len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+	return options
+}
+
+// shuffle 用指定随机源打乱（可复现），rng 为 nil 时使用全局随机源
+func shuffle(rng *rand.Rand, n int, swap func(i, j int)) {
+	if rng == nil {
+		rand.Shuffle(n, swap)
+		return
+	}
+	rng.Shuffle(n, swap)
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+)
+
+// 连连看参数
+const (
+	matchingPairCount       = 6
+	matchingSecondsPerPair  = 10 // 每对卡片的限时
+	matchingPointsPerPair   = 10
+	matchingMismatchPenalty = 2
+)
+
+// StartMatchingGame 开始连连看：英文卡片和打乱顺序的中文卡片各一列
+func (s *Service) StartMatchingGame(userID int, level int) (*MatchingGame, error) {
+	if level <= 0 {
+		level = s.getUserPreferredLevel(userID)
+	}
+
+	preferred, _ := s.getUserPreferredCategory(userID)
+	selection, err := s.SelectWords(userID, GameTypeMatching, matchingPairCount, level, preferred)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get words: %w", err)
+	}
+	if len(selection.Words) == 0 {
+		return nil, fmt.Errorf("no words available for level %d", level)
+	}
+
+	// 中文卡片使用本局随机编号，避免客户端直接用单词ID配对
+	cardIDs := rand.Perm(len(selection.Words))
+	english := make([]MatchingCard, 0, len(selection.Words))
+	chinese := make([]MatchingCard, len(selection.Words))
+	for i, w := range selection.Words {
+		cardID := cardIDs[i] + 1
+		_, err := s.db.Exec(`
+			UPDATE game_session_words SET pair_card = ? WHERE session_id = ? AND word_id = ?
+		`, cardID, selection.SessionID, w.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save matching card: %w", err)
+		}
+		english = append(english, MatchingCard{ID: w.ID, Text: w.English})
+		chinese[cardIDs[i]] = MatchingCard{ID: cardID, Text: w.Chinese}
+	}
+	rand.Shuffle(len(english), func(i, j int) { english[i], english[j] = english[j], english[i] })
+
+	return &MatchingGame{
+		ID:           selection.SessionID,
+		UserID:       userID,
+		Difficulty:   selection.Band,
+		TimeLimit:    len(selection.Words) * matchingSecondsPerPair,
+		EnglishCards: english,
+		ChineseCards: chinese,
+	}, nil
+}
+
+// ClaimMatchingPair 校验客户端声明的一对卡片；全部配对完成或超时后自动结算
+// 锁定游戏局后在一个事务中完成校验、配对和作答记录，并发提交同一张卡片时只有一个请求能配对成功
+func (s *Service) ClaimMatchingPair(userID int, req *MatchingClaimRequest) (*MatchingClaimResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var elapsed, pairs, level int
+	var finished bool
+	err = tx.QueryRow(`
+		SELECT TIMESTAMPDIFF(SECOND, gs.created_at, NOW()), gs.difficulty_max, gs.finished_at IS NOT NULL,
+		       (SELECT COUNT(*) FROM game_session_words WHERE session_id = gs.id)
+		FROM game_sessions gs
+		WHERE gs.id = ? AND gs.user_id = ? AND gs.game_type = ?
+		FOR UPDATE
+	`, req.SessionID, userID, GameTypeMatching).Scan(&elapsed, &level, &finished, &pairs)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("game session not found")
+		}
+		return nil, fmt.Errorf("failed to get game session: %w", err)
+	}
+
+	if finished {
+		return nil, ErrGameCompleted
+	}
+
+	timeLimit := pairs * matchingSecondsPerPair
+	if elapsed > timeLimit {
+		tx.Rollback()
+		return s.finishMatching(userID, req.SessionID, level, timeLimit, timeLimit)
+	}
+
+	var meaning string
+	var ownCard, ownMismatches int
+	var leftMatched bool
+	err = tx.QueryRow(`
+		SELECT w.chinese, gsw.pair_card, gsw.mismatches, gsw.matched_at IS NOT NULL
+		FROM game_session_words gsw JOIN words w ON w.id = gsw.word_id
+		WHERE gsw.session_id = ? AND gsw.word_id = ?
+		FOR UPDATE
+	`, req.SessionID, req.WordID).Scan(&meaning, &ownCard, &ownMismatches, &leftMatched)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("word not found in game session")
+		}
+		return nil, fmt.Errorf("failed to get session word: %w", err)
+	}
+	if leftMatched {
+		return nil, fmt.Errorf("word already matched")
+	}
+
+	var cardWordID int
+	var cardText string
+	var cardMatched bool
+	err = tx.QueryRow(`
+		SELECT gsw.word_id, w.chinese, gsw.matched_at IS NOT NULL
+		FROM game_session_words gsw JOIN words w ON w.id = gsw.word_id
+		WHERE gsw.session_id = ? AND gsw.pair_card = ?
+		FOR UPDATE
+	`, req.SessionID, req.CardID).Scan(&cardWordID, &cardText, &cardMatched)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("card not found in game session")
+		}
+		return nil, fmt.Errorf("failed to get matching card: %w", err)
+	}
+	if cardMatched {
+		return nil, fmt.Errorf("card already matched")
+	}
+
+	// 释义相同的卡片（如 silent / mute 都是"静音"）也算配对成功：交换两个单词的中文卡片编号
+	matched := cardWordID == req.WordID || cardText == meaning
+	if matched && cardWordID != req.WordID {
+		_, err = tx.Exec(`
+			UPDATE game_session_words
+			SET pair_card = CASE word_id WHEN ? THEN ? ELSE ? END
+			WHERE session_id = ? AND word_id IN (?, ?)
+		`, req.WordID, req.CardID, ownCard, req.SessionID, req.WordID, cardWordID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to swap matching cards: %w", err)
+		}
+	}
+	if matched {
+		// 以未配对为条件更新，同一单词只能配对成功一次
+		result, err := tx.Exec(`
+			UPDATE game_session_words SET matched_at = NOW()
+			WHERE session_id = ? AND word_id = ? AND matched_at IS NULL
+		`, req.SessionID, req.WordID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update matching board: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return nil, fmt.Errorf("word already matched")
+		}
+		// 每个单词配对成功时记录一次作答，此前配错过则记为答错
+		err = s.recordAnswers(tx, userID, &RecordAnswersRequest{
+			SessionID: req.SessionID,
+			GameType:  GameTypeMatching,
+			Answers:   []AnswerInput{{WordID: req.WordID, ChosenOption: cardText, IsCorrect: ownMismatches == 0}},
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		_, err = tx.Exec(`
+			UPDATE game_session_words SET mismatches = mismatches + 1
+			WHERE session_id = ? AND word_id = ?
+		`, req.SessionID, req.WordID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update matching board: %w", err)
+		}
+	}
+
+	var remaining, mismatches int
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(matched_at IS NULL), 0), COALESCE(SUM(mismatches), 0)
+		FROM game_session_words WHERE session_id = ?
+	`, req.SessionID).Scan(&remaining, &mismatches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matching board: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit matching claim: %w", err)
+	}
+
+	if remaining == 0 {
+		result, err := s.finishMatching(userID, req.SessionID, level, elapsed, timeLimit)
+		if err != nil {
+			return nil, err
+		}
+		result.Matched = matched
+		return result, nil
+	}
+
+	return &MatchingClaimResult{
+		Matched:    matched,
+		Remaining:  remaining,
+		Mismatches: mismatches,
+	}, nil
+}
+
+// finishMatching 结算连连看并写入游戏记录：配对得分 - 错误扣分 + 剩余时间奖励
+func (s *Service) finishMatching(userID int, sessionID int, level int, elapsed int, timeLimit int) (*MatchingClaimResult, error) {
+	var pairs, remaining, mismatches int
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(matched_at IS NOT NULL), 0), COALESCE(SUM(matched_at IS NULL), 0), COALESCE(SUM(mismatches), 0)
+		FROM game_session_words WHERE session_id = ?
+	`, sessionID).Scan(&pairs, &remaining, &mismatches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matching board: %w", err)
+	}
+
+	summary := &MatchingResult{
+		Pairs:      pairs,
+		Mismatches: mismatches,
+		TimeSpent:  elapsed,
+	}
+	if remaining == 0 && elapsed < timeLimit {
+		summary.TimeBonus = timeLimit - elapsed
+	}
+	summary.Score = max(pairs*matchingPointsPerPair-mismatches*matchingMismatchPenalty, 0) + summary.TimeBonus
+
+	err = s.SubmitScore(userID, &SubmitScoreRequest{
+		GameType:     GameTypeMatching,
+		Score:        summary.Score,
+		LevelReached: level,
+		TimeSpent:    elapsed,
+		SessionID:    sessionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MatchingClaimResult{
+		Remaining:  remaining,
+		Mismatches: mismatches,
+		Completed:  true,
+		Summary:    summary,
+	}, nil
+}
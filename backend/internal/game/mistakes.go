@@ -0,0 +1,83 @@
+package game
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// 错题复习每局题数
+const mistakesRoundCount = 10
+
+// StartMistakesGame 开始错题复习：题目只从错题本中抽取，答错次数多、最近答错的优先
+func (s *Service) StartMistakesGame(userID int) (*MistakesGame, error) {
+	rows, err := s.db.Query(`
+		SELECT w.id, w.english, w.chinese, m.wrong_count, m.last_wrong_answer
+		FROM user_mistakes m
+		JOIN words w ON w.id = m.word_id
+		WHERE m.user_id = ?
+		ORDER BY m.wrong_count DESC, m.last_wrong_at DESC
+		LIMIT ?
+	`, userID, mistakesRoundCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mistakes: %w", err)
+	}
+	defer rows.Close()
+
+	var words []AdventureWord
+	var rounds []MistakeRound
+	for rows.Next() {
+		var w AdventureWord
+		var r MistakeRound
+		var lastWrong sql.NullString
+		if err := rows.Scan(&w.ID, &w.English, &w.Chinese, &r.WrongCount, &lastWrong); err != nil {
+			return nil, fmt.Errorf("failed to scan mistake: %w", err)
+		}
+		r.WordID = w.ID
+		r.Chinese = w.Chinese
+		r.LastWrongAnswer = lastWrong.String
+		words = append(words, w)
+		rounds = append(rounds, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mistakes: %w", err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("mistake notebook is empty")
+	}
+
+	// 干扰项从整个词库随机抽取，避免错题之间互相提示
+	pool, err := s.queryWords("SELECT w.id, w.english, w.chinese FROM words w ORDER BY RAND() LIMIT ?", len(words)+3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distractors: %w", err)
+	}
+
+	selection := &WordSelection{Words: words, Sources: make(map[int]WordSource)}
+	for i, w := range words {
+		selection.Sources[w.ID] = WordSourceMistake
+
+		w.Required = true
+		set := []AdventureWord{w}
+		for _, d := range pool {
+			if len(set) >= 4 {
+				break
+			}
+			if d.ID == w.ID || d.English == w.English {
+				continue
+			}
+			d.Required = false
+			set = append(set, d)
+		}
+		rounds[i].Options = s.generateOptions(set)
+	}
+
+	sessionID, err := s.createSession(userID, GameTypeMistakes, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MistakesGame{
+		ID:     sessionID,
+		UserID: userID,
+		Rounds: rounds,
+	}, nil
+}
@@ -11,6 +11,13 @@ const (
 	GameTypeAdventure GameType = "adventure"
 	GameTypeDefense   GameType = "defense"
 	GameTypeDubbing   GameType = "dubbing"
+	GameTypeMistakes  GameType = "mistakes"
+	GameTypeSpelling  GameType = "spelling"
+	GameTypeListening GameType = "listening"
+	GameTypeMatching  GameType = "matching"
+	GameTypeSentence  GameType = "sentence"
+	GameTypeDuel      GameType = "duel"
+	GameTypeChallenge GameType = "challenge"
 )
 
 // GameRecord 游戏记录
@@ -21,6 +28,8 @@ type GameRecord struct {
 	Score        int       `json:"score" db:"score"`
 	LevelReached int       `json:"level_reached" db:"level_reached"`
 	TimeSpent    int       `json:"time_spent" db:"time_spent"`
+	CorrectCount int       `json:"correct_count" db:"correct_count"`
+	TotalCount   int       `json:"total_count" db:"total_count"`
 	CompletedAt  time.Time `json:"completed_at" db:"completed_at"`
 }
 
@@ -29,6 +38,7 @@ type AdventureGame struct {
 	ID           int               `json:"id"`
 	UserID       int               `json:"user_id"`
 	CurrentLevel int               `json:"current_level"`
+	Difficulty   DifficultyBand    `json:"difficulty"`
 	Score        int               `json:"score"`
 	Story        string            `json:"story"`
 	Options      []AdventureOption `json:"options"`
@@ -61,6 +71,7 @@ type AdventureWord struct {
 type DefenseGame struct {
 	ID          int            `json:"id"`
 	UserID      int            `json:"user_id"`
+	Difficulty  DifficultyBand `json:"difficulty"`
 	CurrentWave int            `json:"current_wave"`
 	Score       int            `json:"score"`
 	Health      int            `json:"health"`
@@ -118,6 +129,326 @@ type DubbingScript struct {
 	Completed    bool   `json:"completed"`
 }
 
+// MistakesGame 错题复习游戏（题目只来自错题本）
+type MistakesGame struct {
+	ID     int            `json:"id"`
+	UserID int            `json:"user_id"`
+	Rounds []MistakeRound `json:"rounds"`
+}
+
+// MistakeRound 错题复习的单轮题目：看中文选英文
+type MistakeRound struct {
+	WordID          int               `json:"word_id"`
+	Chinese         string            `json:"chinese"`
+	WrongCount      int               `json:"wrong_count"`
+	LastWrongAnswer string            `json:"last_wrong_answer"`
+	Options         []AdventureOption `json:"options"`
+}
+
+// SpellingGame 拼写挑战：看中文释义、听发音，输入英文单词
+type SpellingGame struct {
+	ID         int             `json:"id"`
+	UserID     int             `json:"user_id"`
+	Difficulty DifficultyBand  `json:"difficulty"`
+	HintCost   int             `json:"hint_cost"`
+	Rounds     []SpellingRound `json:"rounds"`
+}
+
+// SpellingRound 拼写挑战的单轮题目
+type SpellingRound struct {
+	WordID        int    `json:"word_id"`
+	Chinese       string `json:"chinese"`
+	Pronunciation string `json:"pronunciation"`
+	AudioURL      string `json:"audio_url"`
+	Pattern       string `json:"pattern"` // 用下划线表示字母、保留空格，如 "________ ____"
+}
+
+// SpellingHintRequest 拼写提示请求
+type SpellingHintRequest struct {
+	SessionID int `json:"session_id" binding:"required"`
+	WordID    int `json:"word_id" binding:"required"`
+}
+
+// SpellingHintResponse 拼写提示响应
+type SpellingHintResponse struct {
+	WordID     int    `json:"word_id"`
+	Revealed   string `json:"revealed"`
+	HintsUsed  int    `json:"hints_used"`
+	CoinsSpent int    `json:"coins_spent"`
+}
+
+// SpellingAnswerRequest 拼写作答请求
+type SpellingAnswerRequest struct {
+	SessionID      int    `json:"session_id" binding:"required"`
+	WordID         int    `json:"word_id" binding:"required"`
+	Answer         string `json:"answer"`
+	ResponseTimeMs int    `json:"response_time_ms"`
+}
+
+// SpellingAnswerResult 拼写作答评分结果
+type SpellingAnswerResult struct {
+	WordID    int            `json:"word_id"`
+	Correct   bool           `json:"correct"`
+	Credit    float64        `json:"credit"` // 0~1，按编辑距离给部分分
+	Points    int            `json:"points"`
+	Distance  int            `json:"distance"`
+	HintsUsed int            `json:"hints_used"`
+	Expected  string         `json:"expected"`
+	Completed bool           `json:"completed"` // 本局全部作答完毕，已自动结算
+	Summary   *RoundsSummary `json:"summary,omitempty"`
+}
+
+// RoundsSummary 逐题判分游戏的结算：总分为各单词得分之和
+type RoundsSummary struct {
+	Score        int `json:"score"`
+	CorrectCount int `json:"correct_count"`
+	TotalCount   int `json:"total_count"`
+}
+
+// ListeningMode 听力题作答方式
+type ListeningMode string
+
+const (
+	ListeningModeChoice ListeningMode = "choice" // 听音选释义
+	ListeningModeType   ListeningMode = "type"   // 听音拼写
+)
+
+// ListeningGame 听力练习
+type ListeningGame struct {
+	ID         int              `json:"id"`
+	UserID     int              `json:"user_id"`
+	Difficulty DifficultyBand   `json:"difficulty"`
+	Rounds     []ListeningRound `json:"rounds"`
+}
+
+// ListeningRound 听力练习的单轮题目；音频和音标通过播放接口下发
+// 没有音频的单词（词库不足时用有音标的单词补齐）HasAudio 为 false，客户端改为展示音标
+type ListeningRound struct {
+	WordID   int           `json:"word_id"`
+	Mode     ListeningMode `json:"mode"`
+	HasAudio bool          `json:"has_audio"`
+	Options  []string      `json:"options,omitempty"`
+}
+
+// ListeningPlayRequest 播放（重播）一轮的请求
+type ListeningPlayRequest struct {
+	SessionID int `json:"session_id" binding:"required"`
+	WordID    int `json:"word_id" binding:"required"`
+}
+
+// ListeningPlayResponse 一轮的音频或音标（有音频时不下发音标）
+type ListeningPlayResponse struct {
+	WordID        int    `json:"word_id"`
+	AudioURL      string `json:"audio_url,omitempty"`
+	Pronunciation string `json:"pronunciation,omitempty"`
+	Plays         int    `json:"plays"`
+}
+
+// ListeningAnswerRequest 听力作答请求
+type ListeningAnswerRequest struct {
+	SessionID int    `json:"session_id" binding:"required"`
+	WordID    int    `json:"word_id" binding:"required"`
+	Answer    string `json:"answer"`
+}
+
+// ListeningAnswerResult 听力作答评分结果；重播次数和用时由服务器按播放记录计算
+type ListeningAnswerResult struct {
+	WordID         int            `json:"word_id"`
+	Mode           ListeningMode  `json:"mode"`
+	Correct        bool           `json:"correct"`
+	Credit         float64        `json:"credit"`
+	Points         int            `json:"points"`
+	Replays        int            `json:"replays"`
+	ResponseTimeMs int            `json:"response_time_ms"`
+	English        string         `json:"english"`
+	Chinese        string         `json:"chinese"`
+	Completed      bool           `json:"completed"` // 本局全部作答完毕，已自动结算
+	Summary        *RoundsSummary `json:"summary,omitempty"`
+}
+
+// MatchingGame 连连看：限时把英文卡片和中文卡片配对
+type MatchingGame struct {
+	ID           int            `json:"id"`
+	UserID       int            `json:"user_id"`
+	Difficulty   DifficultyBand `json:"difficulty"`
+	TimeLimit    int            `json:"time_limit"` // 秒
+	EnglishCards []MatchingCard `json:"english_cards"`
+	ChineseCards []MatchingCard `json:"chinese_cards"`
+}
+
+// MatchingCard 连连看卡片：英文卡片ID为单词ID，中文卡片ID为本局随机编号
+type MatchingCard struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// MatchingClaimRequest 配对请求
+type MatchingClaimRequest struct {
+	SessionID int `json:"session_id" binding:"required"`
+	WordID    int `json:"word_id" binding:"required"`
+	CardID    int `json:"card_id" binding:"required"`
+}
+
+// MatchingClaimResult 配对结果；全部配对完成时附带本局结算
+type MatchingClaimResult struct {
+	Matched    bool            `json:"matched"`
+	Remaining  int             `json:"remaining"`
+	Mismatches int             `json:"mismatches"`
+	Completed  bool            `json:"completed"`
+	Summary    *MatchingResult `json:"summary,omitempty"`
+}
+
+// MatchingResult 连连看结算
+type MatchingResult struct {
+	Score      int `json:"score"`
+	Pairs      int `json:"pairs"`
+	Mismatches int `json:"mismatches"`
+	TimeSpent  int `json:"time_spent"`
+	TimeBonus  int `json:"time_bonus"`
+}
+
+// SentenceGame 连词成句：把打乱的单词（含干扰词）还原成例句
+type SentenceGame struct {
+	ID         int             `json:"id"`
+	UserID     int             `json:"user_id"`
+	Difficulty DifficultyBand  `json:"difficulty"`
+	Rounds     []SentenceRound `json:"rounds"`
+}
+
+// SentenceRound 连词成句的单轮题目
+type SentenceRound struct {
+	WordID  int      `json:"word_id"`
+	English string   `json:"english"`
+	Chinese string   `json:"chinese"`
+	Length  int      `json:"length"` // 句子需要的单词数
+	Tokens  []string `json:"tokens"` // 打乱后的单词，包含干扰词
+}
+
+// SentenceAnswerRequest 连词成句作答请求
+type SentenceAnswerRequest struct {
+	SessionID      int      `json:"session_id" binding:"required"`
+	WordID         int      `json:"word_id" binding:"required"`
+	Tokens         []string `json:"tokens" binding:"required,min=1"`
+	ResponseTimeMs int      `json:"response_time_ms"`
+}
+
+// SentenceAnswerResult 连词成句评分结果
+type SentenceAnswerResult struct {
+	WordID    int            `json:"word_id"`
+	Correct   bool           `json:"correct"`
+	Credit    float64        `json:"credit"` // 按最长公共子序列计算的顺序正确比例
+	Points    int            `json:"points"`
+	Expected  string         `json:"expected"`
+	Completed bool           `json:"completed"` // 本局全部作答完毕，已自动结算
+	Summary   *RoundsSummary `json:"summary,omitempty"`
+}
+
+// ChoiceRound 看英文选释义的题目（实时对战等模式共用），Answer 只在服务端使用
+type ChoiceRound struct {
+	WordID  int      `json:"word_id"`
+	English string   `json:"english"`
+	Options []string `json:"options"`
+	Answer  string   `json:"-"`
+}
+
+// StoryNodeKind 剧情节点类型
+type StoryNodeKind string
+
+const (
+	StoryNodeNarration StoryNodeKind = "narration" // 旁白，直接进入 next
+	StoryNodeQuestion  StoryNodeKind = "question"  // 答题，答对进入 next，答错进入 wrong
+	StoryNodeEnding    StoryNodeKind = "ending"    // 结局，到达即通关
+)
+
+// ChapterStatus 用户章节进度状态
+type ChapterStatus string
+
+const (
+	ChapterStatusLocked     ChapterStatus = "locked"
+	ChapterStatusAvailable  ChapterStatus = "available"
+	ChapterStatusInProgress ChapterStatus = "in_progress"
+	ChapterStatusCompleted  ChapterStatus = "completed"
+)
+
+// AdventureChapter 冒险章节（含用户进度）
+type AdventureChapter struct {
+	ID              int           `json:"id"`
+	Title           string        `json:"title"`
+	Description     string        `json:"description"`
+	SortOrder       int           `json:"sort_order"`
+	UnlockChapterID *int          `json:"unlock_chapter_id,omitempty"`
+	UnlockLevel     int           `json:"unlock_level"`
+	Status          ChapterStatus `json:"status"`
+	LockReason      string        `json:"lock_reason,omitempty"`
+	Completions     int           `json:"completions"`
+	BestScore       int           `json:"best_score"`
+}
+
+// AdventureState 当前章节的游玩状态（断点续玩时原样返回）
+type AdventureState struct {
+	ChapterID  int                `json:"chapter_id"`
+	SessionID  int                `json:"session_id"`
+	Score      int                `json:"score"`
+	Completed  bool               `json:"completed"`
+	Node       AdventureNodeView  `json:"node"`
+	Difficulty DifficultyBand     `json:"difficulty"`
+	Unlocked   []AdventureChapter `json:"unlocked,omitempty"` // 通关后新解锁的章节
+}
+
+// AdventureNodeView 下发给客户端的剧情节点（不含分支走向和答案）
+type AdventureNodeView struct {
+	Key     string        `json:"key"`
+	Kind    StoryNodeKind `json:"kind"`
+	Story   string        `json:"story"`
+	WordID  int           `json:"word_id,omitempty"`
+	English string        `json:"english,omitempty"`
+	Options []string      `json:"options,omitempty"`
+	Reward  int           `json:"reward,omitempty"`
+}
+
+// AdventureAdvanceRequest 推进剧情请求；旁白节点无需 answer
+type AdventureAdvanceRequest struct {
+	NodeKey        string `json:"node_key" binding:"required"`
+	Answer         string `json:"answer"`
+	ResponseTimeMs int    `json:"response_time_ms"`
+}
+
+// AdventureAdvanceResult 推进剧情结果
+type AdventureAdvanceResult struct {
+	Correct  *bool          `json:"correct,omitempty"`
+	Expected string         `json:"expected,omitempty"`
+	State    AdventureState `json:"state"`
+}
+
+// StoryNodeInput 编辑剧情时提交的节点
+type StoryNodeInput struct {
+	Key         string        `json:"key" binding:"required,max=50"`
+	Kind        StoryNodeKind `json:"kind" binding:"required"`
+	Story       string        `json:"story" binding:"required"`
+	WordID      int           `json:"word_id,omitempty"`
+	Next        string        `json:"next,omitempty"`
+	Wrong       string        `json:"wrong,omitempty"`
+	RewardScore int           `json:"reward_score,omitempty"`
+}
+
+// ChapterInput 创建或整体替换章节的请求
+type ChapterInput struct {
+	Title           string           `json:"title" binding:"required,max=100"`
+	Description     string           `json:"description"`
+	SortOrder       int              `json:"sort_order"`
+	StartNode       string           `json:"start_node" binding:"required"`
+	UnlockChapterID *int             `json:"unlock_chapter_id,omitempty"`
+	UnlockLevel     int              `json:"unlock_level"`
+	Published       bool             `json:"published"`
+	Nodes           []StoryNodeInput `json:"nodes" binding:"required,min=1,dive"`
+}
+
+// ChapterValidation 剧情图校验结果
+type ChapterValidation struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
 // GameRequest 游戏请求
 type GameRequest struct {
 	GameType GameType `json:"game_type" binding:"required"`
@@ -135,6 +466,9 @@ type SubmitScoreRequest struct {
 	Score        int      `json:"score"`
 	LevelReached int      `json:"level_reached"`
 	TimeSpent    int      `json:"time_spent"`
+	SessionID    int      `json:"session_id,omitempty"`
+	CorrectCount int      `json:"correct_count,omitempty"`
+	TotalCount   int      `json:"total_count,omitempty"`
 	GameData     string   `json:"game_data,omitempty"`
 }
 
@@ -145,3 +479,32 @@ type SubmitDubbingRequest struct {
 	AudioData string `json:"audio_data" binding:"required"`
 	TimeSpent int    `json:"time_spent"`
 }
+
+// AnswerEvent 单题作答记录
+type AnswerEvent struct {
+	ID             int64     `json:"id" db:"id"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	SessionID      int       `json:"session_id,omitempty" db:"session_id"`
+	WordID         int       `json:"word_id" db:"word_id"`
+	GameType       GameType  `json:"game_type" db:"game_type"`
+	ChosenOption   string    `json:"chosen_option" db:"chosen_option"`
+	IsCorrect      bool      `json:"is_correct" db:"is_correct"`
+	ResponseTimeMs int       `json:"response_time_ms" db:"response_time_ms"`
+	AnsweredAt     time.Time `json:"answered_at" db:"answered_at"`
+}
+
+// AnswerInput 客户端上报的单题作答
+type AnswerInput struct {
+	WordID         int    `json:"word_id" binding:"required"`
+	ChosenOption   string `json:"chosen_option"`
+	IsCorrect      bool   `json:"is_correct"` // 客户端上报时忽略，由服务端根据 chosen_option 判定
+	ResponseTimeMs int    `json:"response_time_ms"`
+	Points         int    `json:"-"` // 服务端判分的游戏记在局内单词上的得分
+}
+
+// RecordAnswersRequest 批量上报作答请求
+type RecordAnswersRequest struct {
+	SessionID int           `json:"session_id" binding:"required"`
+	GameType  GameType      `json:"game_type" binding:"required"`
+	Answers   []AnswerInput `json:"answers" binding:"required,min=1,max=200,dive"`
+}
@@ -0,0 +1,463 @@
+package game
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// 自适应选词参数
+const (
+	selectorRecentSessions = 3    // 最近N局出现过的单词不再重复出题
+	selectorRecentGames    = 5    // 取最近N局游戏记录计算正确率
+	selectorReviewShare    = 0.3  // 到期复习词占比上限
+	selectorWeakShare      = 0.3  // 薄弱词占比上限
+	selectorNewShare       = 0.4  // 新词占比上限
+	selectorWeakMastery    = 0.5  // 掌握度低于该值视为薄弱词
+	selectorRaiseAccuracy  = 0.85 // 近期正确率不低于该值时难度区间上探一级
+	selectorLowerAccuracy  = 0.6  // 近期正确率低于该值时难度区间下探一级
+)
+
+// WordSource 选词来源
+type WordSource string
+
+const (
+	WordSourceReview  WordSource = "review"
+	WordSourceWeak    WordSource = "weak"
+	WordSourceNew     WordSource = "new"
+	WordSourceFill    WordSource = "fill"
+	WordSourceMistake WordSource = "mistake"
+	WordSourceStory   WordSource = "story" // 冒险剧情章节中的题目单词
+)
+
+// DifficultyBand 难度区间（闭区间）
+type DifficultyBand struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// WordSelection 一局游戏的选词结果
+type WordSelection struct {
+	SessionID int                `json:"session_id"`
+	Band      DifficultyBand     `json:"band"`
+	Words     []AdventureWord    `json:"words"`
+	Sources   map[int]WordSource `json:"sources"`
+}
+
+// wordScope 选词范围
+type wordScope struct {
+	category        string
+	exclude         []int
+	requireAudio    bool // 只选有音频的单词
+	requireMedia    bool // 只选有音频或音标的单词
+	requireSentence bool // 只选有英文例句（或故事中含英文句子）的单词
+}
+
+// SelectWords 自适应选词并创建游戏会话
+// 按"到期复习词 → 薄弱词 → 新词（限额）"的顺序挑选，不足时用难度区间内的其他单词补齐；
+// 最近几局出现过的单词优先排除，实在不够时才放开限制。
+func (s *Service) SelectWords(userID int, gameType GameType, count int, level int, category string) (*WordSelection, error) {
+	selection, err := s.selectWords(userID, count, level, wordScope{category: category})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveSelection(userID, gameType, selection); err != nil {
+		return nil, err
+	}
+	return selection, nil
+}
+
+// SelectSharedWords 为多名玩家选出同一组单词（如实时对战），并为每名玩家各自创建会话
+// 只按指定难度随机选词，不参考任何一方的学习记录，保证双方题目公平；返回 用户ID → 会话ID
+func (s *Service) SelectSharedWords(userIDs []int, gameType GameType, count int, level int) (*WordSelection, map[int]int, error) {
+	if level <= 0 {
+		level = 1
+	}
+	band := DifficultyBand{Min: level, Max: level}
+	words, err := s.queryBandWords(band, wordScope{}, count)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get words: %w", err)
+	}
+	if len(words) < count {
+		// 该难度单词不足时放宽到全部难度
+		band = DifficultyBand{Min: 1, Max: level}
+		if err := s.db.QueryRow("SELECT COALESCE(MAX(difficulty_level), 1) FROM words").Scan(&band.Max); err != nil {
+			return nil, nil, fmt.Errorf("failed to get max difficulty: %w", err)
+		}
+		words, err = s.queryBandWords(band, wordScope{}, count)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get words: %w", err)
+		}
+	}
+
+	selection := &WordSelection{Band: band, Words: words, Sources: make(map[int]WordSource, len(words))}
+	for _, w := range words {
+		selection.Sources[w.ID] = WordSourceFill
+	}
+	sessions := make(map[int]int, len(userIDs))
+	for _, userID := range userIDs {
+		sessionID, err := s.createSession(userID, gameType, selection)
+		if err != nil {
+			return nil, nil, err
+		}
+		sessions[userID] = sessionID
+	}
+	return selection, sessions, nil
+}
+
+// CreateFixedSession 用给定的单词创建游戏会话（如好友挑战中冻结的题目）
+func (s *Service) CreateFixedSession(userID int, gameType GameType, level int, words []AdventureWord) (int, error) {
+	selection := &WordSelection{
+		Band:    DifficultyBand{Min: level, Max: level},
+		Words:   words,
+		Sources: make(map[int]WordSource, len(words)),
+	}
+	for _, w := range words {
+		selection.Sources[w.ID] = WordSourceFill
+	}
+	return s.createSession(userID, gameType, selection)
+}
+
+// selectWords 按范围自适应选词（不创建会话）
+func (s *Service) selectWords(userID int, count int, level int, scope wordScope) (*WordSelection, error) {
+	band := s.difficultyBand(userID, level)
+
+	recent, err := s.recentSessionWordIDs(userID, selectorRecentSessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent session words: %w", err)
+	}
+	scope.exclude = recent
+	unrestricted := scope
+	unrestricted.exclude = nil
+
+	selection := &WordSelection{
+		Band:    band,
+		Words:   make([]AdventureWord, 0, count),
+		Sources: make(map[int]WordSource),
+	}
+	due, err := s.queryDueWords(userID, scope, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due words: %w", err)
+	}
+	weak, err := s.queryWeakWords(userID, scope, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get weak words: %w", err)
+	}
+	fresh, err := s.queryNewWords(userID, band, scope, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new words: %w", err)
+	}
+
+	selection.takeQuotas(count, due, weak, fresh)
+	// 再用区间内已学过的词补齐，最后才放开新词配额
+	if len(selection.Words) < count {
+		fill, err := s.queryBandWords(band, scope, count*2)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get fill words: %w", err)
+		}
+		selection.take(fill, WordSourceFill, count, count)
+	}
+	selection.take(fresh, WordSourceNew, count, count)
+
+	// 词库太小：放开最近几局的排除限制
+	if len(selection.Words) < count {
+		fill, err := s.queryBandWords(band, unrestricted, count*2)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get fill words: %w", err)
+		}
+		selection.take(fill, WordSourceFill, count, count)
+	}
+
+	// 兜底：按原有方式随机抽词
+	if len(selection.Words) == 0 && !scope.requireAudio && !scope.requireSentence {
+		fallback, err := s.getRandomWords(count, level, scope.category)
+		if err != nil {
+			return nil, err
+		}
+		selection.take(fallback, WordSourceFill, count, count)
+	}
+
+	return selection, nil
+}
+
+// takeQuotas 按配额依次挑选到期复习词、薄弱词和新词；配额用完仍不足 count 个时，先用剩余的复习词和薄弱词补齐
+func (sel *WordSelection) takeQuotas(count int, due, weak, fresh []AdventureWord) {
+	sel.take(due, WordSourceReview, count, shareOf(count, selectorReviewShare))
+	sel.take(weak, WordSourceWeak, count, shareOf(count, selectorWeakShare))
+	sel.take(fresh, WordSourceNew, count, shareOf(count, selectorNewShare))
+	sel.take(due, WordSourceReview, count, count)
+	sel.take(weak, WordSourceWeak, count, count)
+}
+
+// take 从候选中最多挑选 limit 个尚未选中的单词，总数不超过 count
+func (sel *WordSelection) take(candidates []AdventureWord, source WordSource, count int, limit int) {
+	taken := 0
+	for _, w := range candidates {
+		if len(sel.Words) >= count || taken >= limit {
+			return
+		}
+		if _, ok := sel.Sources[w.ID]; ok {
+			continue
+		}
+		w.Required = rand.Float32() < 0.7 // 70%的单词是必需的
+		sel.Words = append(sel.Words, w)
+		sel.Sources[w.ID] = source
+		taken++
+	}
+}
+
+// saveSelection 为选词结果创建游戏会话
+func (s *Service) saveSelection(userID int, gameType GameType, selection *WordSelection) error {
+	sessionID, err := s.createSession(userID, gameType, selection)
+	if err != nil {
+		return err
+	}
+	selection.SessionID = sessionID
+	return nil
+}
+
+// difficultyBand 根据近期正确率调整难度区间
+func (s *Service) difficultyBand(userID int, level int) DifficultyBand {
+	maxLevel := 1
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(difficulty_level), 1) FROM words").Scan(&maxLevel); err != nil {
+		maxLevel = 1
+	}
+	if level <= 0 {
+		level = 1
+	}
+	if level > maxLevel {
+		level = maxLevel
+	}
+
+	band := DifficultyBand{Min: level, Max: level}
+	accuracy, ok := s.recentAccuracy(userID, selectorRecentGames)
+	if !ok {
+		return band
+	}
+	if accuracy >= selectorRaiseAccuracy && level < maxLevel {
+		band.Max = level + 1
+	} else if accuracy < selectorLowerAccuracy && level > 1 {
+		band.Min = level - 1
+	}
+	return band
+}
+
+// recentAccuracy 最近N局游戏的答题正确率，没有答题数据时返回false
+func (s *Service) recentAccuracy(userID int, games int) (float64, bool) {
+	var correct, total int
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(correct_count), 0), COALESCE(SUM(total_count), 0)
+		FROM (
+			SELECT correct_count, total_count
+			FROM game_records
+			WHERE user_id = ? AND total_count > 0
+			ORDER BY completed_at DESC
+			LIMIT ?
+		) recent
+	`, userID, games).Scan(&correct, &total)
+	if err != nil || total == 0 {
+		return 0, false
+	}
+	return float64(correct) / float64(total), true
+}
+
+// recentSessionWordIDs 最近N局游戏出现过的单词ID
+func (s *Service) recentSessionWordIDs(userID int, sessions int) ([]int, error) {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT gsw.word_id
+		FROM game_session_words gsw
+		JOIN (
+			SELECT id FROM game_sessions
+			WHERE user_id = ?
+			ORDER BY created_at DESC, id DESC
+			LIMIT ?
+		) gs ON gs.id = gsw.session_id
+	`, userID, sessions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// queryDueWords 到期需要复习的单词：掌握度越高，复习间隔越长（1~32天）
+func (s *Service) queryDueWords(userID int, scope wordScope, limit int) ([]AdventureWord, error) {
+	query := `
+		SELECT w.id, w.english, w.chinese
+		FROM user_progress up
+		JOIN words w ON w.id = up.word_id
+		WHERE up.user_id = ?
+		  AND up.last_studied <= DATE_SUB(NOW(), INTERVAL POW(2, FLOOR(up.mastery_level * 5)) DAY)
+	`
+	args := []interface{}{userID}
+	filter, filterArgs := wordFilter(nil, scope)
+	query += filter + " ORDER BY up.last_studied ASC LIMIT ?"
+	args = append(append(args, filterArgs...), limit)
+	return s.queryWords(query, args...)
+}
+
+// queryWeakWords 薄弱词：掌握度低的单词，最近学过的优先
+func (s *Service) queryWeakWords(userID int, scope wordScope, limit int) ([]AdventureWord, error) {
+	query := `
+		SELECT w.id, w.english, w.chinese
+		FROM user_progress up
+		JOIN words w ON w.id = up.word_id
+		WHERE up.user_id = ? AND up.mastery_level < ?
+	`
+	args := []interface{}{userID, selectorWeakMastery}
+	filter, filterArgs := wordFilter(nil, scope)
+	query += filter + " ORDER BY up.mastery_level ASC, up.last_studied DESC LIMIT ?"
+	args = append(append(args, filterArgs...), limit)
+	return s.queryWords(query, args...)
+}
+
+// queryNewWords 难度区间内用户从未学过的单词
+func (s *Service) queryNewWords(userID int, band DifficultyBand, scope wordScope, limit int) ([]AdventureWord, error) {
+	query := `
+		SELECT w.id, w.english, w.chinese
+		FROM words w
+		LEFT JOIN user_progress up ON up.word_id = w.id AND up.user_id = ?
+		WHERE up.id IS NULL
+	`
+	args := []interface{}{userID}
+	filter, filterArgs := wordFilter(&band, scope)
+	query += filter + " ORDER BY RAND() LIMIT ?"
+	args = append(append(args, filterArgs...), limit)
+	return s.queryWords(query, args...)
+}
+
+// queryBandWords 难度区间内的任意单词
+func (s *Service) queryBandWords(band DifficultyBand, scope wordScope, limit int) ([]AdventureWord, error) {
+	query := `SELECT w.id, w.english, w.chinese FROM words w WHERE 1=1`
+	filter, args := wordFilter(&band, scope)
+	query += filter + " ORDER BY RAND() LIMIT ?"
+	args = append(args, limit)
+	return s.queryWords(query, args...)
+}
+
+func (s *Service) queryWords(query string, args ...interface{}) ([]AdventureWord, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var words []AdventureWord
+	for rows.Next() {
+		var word AdventureWord
+		if err := rows.Scan(&word.ID, &word.English, &word.Chinese); err != nil {
+			return nil, err
+		}
+		words = append(words, word)
+	}
+	return words, nil
+}
+
+// createSession 保存本局会话及抽到的单词
+func (s *Service) createSession(userID int, gameType GameType, selection *WordSelection) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO game_sessions (user_id, game_type, difficulty_min, difficulty_max)
+		VALUES (?, ?, ?, ?)
+	`, userID, gameType, selection.Band.Min, selection.Band.Max)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create game session: %w", err)
+	}
+	sessionID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session ID: %w", err)
+	}
+
+	if len(selection.Words) > 0 {
+		placeholders := make([]string, 0, len(selection.Words))
+		args := make([]interface{}, 0, len(selection.Words)*3)
+		for _, w := range selection.Words {
+			placeholders = append(placeholders, "(?, ?, ?)")
+			args = append(args, sessionID, w.ID, selection.Sources[w.ID])
+		}
+		_, err = tx.Exec(
+			"INSERT INTO game_session_words (session_id, word_id, source) VALUES "+strings.Join(placeholders, ", "),
+			args...,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save session words: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit game session: %w", err)
+	}
+	return int(sessionID), nil
+}
+
+// sessionBelongsTo 校验会话归属
+func (s *Service) sessionBelongsTo(sessionID int, userID int) error {
+	var owner int
+	err := s.db.QueryRow("SELECT user_id FROM game_sessions WHERE id = ?", sessionID).Scan(&owner)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("game session not found")
+		}
+		return fmt.Errorf("failed to get game session: %w", err)
+	}
+	if owner != userID {
+		return fmt.Errorf("game session not found")
+	}
+	return nil
+}
+
+// wordFilter 生成难度区间和选词范围的查询条件（单词表别名为 w）
+func wordFilter(band *DifficultyBand, scope wordScope) (string, []interface{}) {
+	var clause strings.Builder
+	var args []interface{}
+	if band != nil {
+		clause.WriteString(" AND w.difficulty_level BETWEEN ? AND ?")
+		args = append(args, band.Min, band.Max)
+	}
+	if scope.category != "" {
+		clause.WriteString(" AND w.category = ?")
+		args = append(args, scope.category)
+	}
+	if scope.requireAudio {
+		clause.WriteString(" AND w.audio_url IS NOT NULL AND w.audio_url <> ''")
+	}
+	if scope.requireMedia {
+		clause.WriteString(" AND ((w.audio_url IS NOT NULL AND w.audio_url <> '') OR (w.pronunciation IS NOT NULL AND w.pronunciation <> ''))")
+	}
+	if scope.requireSentence {
+		clause.WriteString(" AND ((w.example_sentence IS NOT NULL AND w.example_sentence <> '')" +
+			" OR w.story REGEXP '[A-Za-z]+[ ,]+[A-Za-z]+[ ,]+[A-Za-z]+')")
+	}
+	if len(scope.exclude) > 0 {
+		clause.WriteString(" AND w.id NOT IN (?" + strings.Repeat(", ?", len(scope.exclude)-1) + ")")
+		for _, id := range scope.exclude {
+			args = append(args, id)
+		}
+	}
+	return clause.String(), args
+}
+
+// shareOf 按占比计算配额，至少为1
+func shareOf(count int, share float64) int {
+	n := int(math.Round(float64(count) * share))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
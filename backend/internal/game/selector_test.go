@@ -0,0 +1,132 @@
+package game
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShareOf(t *testing.T) {
+	tests := []struct {
+		count int
+		share float64
+		want  int
+	}{
+		{10, selectorReviewShare, 3},
+		{10, selectorNewShare, 4},
+		{5, selectorReviewShare, 2}, // 1.5 四舍五入
+		{7, selectorNewShare, 3},    // 2.8
+		{3, selectorReviewShare, 1}, // 0.9
+		{1, selectorReviewShare, 1}, // 0.3 舍为0，至少为1
+		{0, selectorNewShare, 1},
+	}
+	for _, tt := range tests {
+		if got := shareOf(tt.count, tt.share); got != tt.want {
+			t.Errorf("shareOf(%d, %v) = %d, want %d", tt.count, tt.share, got, tt.want)
+		}
+	}
+}
+
+func TestWordFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		band   *DifficultyBand
+		scope  wordScope
+		clause string
+		args   []interface{}
+	}{
+		{"empty", nil, wordScope{}, "", nil},
+		{"band", &DifficultyBand{Min: 2, Max: 3}, wordScope{}, " AND w.difficulty_level BETWEEN ? AND ?", []interface{}{2, 3}},
+		{"category", nil, wordScope{category: "food"}, " AND w.category = ?", []interface{}{"food"}},
+		{"exclude", nil, wordScope{exclude: []int{4, 5, 6}}, " AND w.id NOT IN (?, ?, ?)", []interface{}{4, 5, 6}},
+		{
+			"band, category and exclude in order",
+			&DifficultyBand{Min: 1, Max: 1},
+			wordScope{category: "animals", exclude: []int{9}},
+			" AND w.difficulty_level BETWEEN ? AND ? AND w.category = ? AND w.id NOT IN (?)",
+			[]interface{}{1, 1, "animals", 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := wordFilter(tt.band, tt.scope)
+			if clause != tt.clause {
+				t.Errorf("clause = %q, want %q", clause, tt.clause)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+
+	for _, scope := range []wordScope{{requireAudio: true}, {requireMedia: true}, {requireSentence: true}} {
+		clause, args := wordFilter(nil, scope)
+		if clause == "" || strings.Contains(clause, "?") || args != nil {
+			t.Errorf("wordFilter(%+v) = %q, %v; want a condition without placeholders", scope, clause, args)
+		}
+	}
+}
+
+// words 生成ID为 from..to 的单词
+func words(from, to int) []AdventureWord {
+	var list []AdventureWord
+	for id := from; id <= to; id++ {
+		list = append(list, AdventureWord{ID: id})
+	}
+	return list
+}
+
+func TestTakeQuotas(t *testing.T) {
+	tests := []struct {
+		name             string
+		count            int
+		due, weak, fresh []AdventureWord
+		want             map[WordSource]int
+	}{
+		{
+			name: "every bucket full", count: 10,
+			due: words(1, 10), weak: words(11, 20), fresh: words(21, 30),
+			want: map[WordSource]int{WordSourceReview: 3, WordSourceWeak: 3, WordSourceNew: 4},
+		},
+		{
+			// 复习词不足，由薄弱词补齐，新词不超过配额
+			name: "review bucket short", count: 10,
+			due: words(1, 1), weak: words(11, 20), fresh: words(21, 30),
+			want: map[WordSource]int{WordSourceReview: 1, WordSourceWeak: 5, WordSourceNew: 4},
+		},
+		{
+			name: "no weak words and few new words", count: 10,
+			due: words(1, 10), fresh: words(21, 22),
+			want: map[WordSource]int{WordSourceReview: 8, WordSourceNew: 2},
+		},
+		{
+			// 同一单词只选一次，保留先选中的来源
+			name: "overlapping buckets", count: 4,
+			due: words(1, 2), weak: words(1, 3), fresh: words(21, 30),
+			want: map[WordSource]int{WordSourceReview: 1, WordSourceWeak: 1, WordSourceNew: 2},
+		},
+		{
+			// 全部来源加起来也不够，留给调用方用其他单词补齐
+			name: "everything short", count: 10,
+			due: words(1, 1), weak: words(11, 11), fresh: words(21, 22),
+			want: map[WordSource]int{WordSourceReview: 1, WordSourceWeak: 1, WordSourceNew: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := &WordSelection{Sources: make(map[int]WordSource)}
+			selection.takeQuotas(tt.count, tt.due, tt.weak, tt.fresh)
+
+			got := map[WordSource]int{}
+			for _, w := range selection.Words {
+				got[selection.Sources[w.ID]]++
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("sources = %v, want %v", got, tt.want)
+			}
+			if len(selection.Words) != len(selection.Sources) {
+				t.Fatalf("%d words but %d sources: a word was picked twice", len(selection.Words), len(selection.Sources))
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package game
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strings"
+)
+
+// 连词成句参数
+const (
+	sentenceRoundCount      = 5
+	sentenceDistractorCount = 2
+	sentenceMaxPoints       = 10
+)
+
+// 故事中的英文句子：至少三个英文单词
+var englishSentencePattern = regexp.MustCompile(`[A-Za-z][A-Za-z'’-]*(?:[ ,]+[A-Za-z][A-Za-z'’-]*){2,}[.!?]?`)
+
+// sentenceRoundData 保存在 game_session_words.round_data 中的题目数据
+type sentenceRoundData struct {
+	Sentences []string `json:"sentences"` // 第一个为标准句，其余为等价的可接受顺序
+	Tokens    []string `json:"tokens"`    // 下发给客户端的全部单词（含干扰词）
+}
+
+// StartSentenceGame 开始连词成句：例句优先取 example_sentence，没有时取故事中的英文句子
+func (s *Service) StartSentenceGame(userID int, level int) (*SentenceGame, error) {
+	if level <= 0 {
+		level = s.getUserPreferredLevel(userID)
+	}
+
+	preferred, _ := s.getUserPreferredCategory(userID)
+	selection, err := s.selectWords(userID, sentenceRoundCount, level, wordScope{category: preferred, requireSentence: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get words: %w", err)
+	}
+	if len(selection.Words) == 0 && preferred != "" {
+		// 偏好分类下没有例句时放宽到全部分类
+		selection, err = s.selectWords(userID, sentenceRoundCount, level, wordScope{requireSentence: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get words: %w", err)
+		}
+	}
+	if len(selection.Words) == 0 {
+		return nil, fmt.Errorf("no example sentences available for level %d", level)
+	}
+
+	pool, err := s.queryWords("SELECT w.id, w.english, w.chinese FROM words w WHERE w.english NOT LIKE '% %' ORDER BY RAND() LIMIT ?",
+		len(selection.Words)*sentenceDistractorCount*2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distractors: %w", err)
+	}
+
+	// 先生成题目，只有生成了题目的单词才加入本局
+	rounds := make([]SentenceRound, 0, len(selection.Words))
+	roundData := make(map[int]string, len(selection.Words))
+	words := make([]AdventureWord, 0, len(selection.Words))
+	for _, w := range selection.Words {
+		sentences, err := s.getWordSentences(w.ID, w.English)
+		if err != nil {
+			return nil, err
+		}
+		if len(sentences) == 0 {
+			continue
+		}
+
+		tokens := tokenizeSentence(sentences[0])
+		inSentence := make(map[string]bool, len(tokens))
+		for _, t := range tokens {
+			inSentence[normalizeToken(t)] = true
+		}
+		offered := append([]string{}, tokens...)
+		added := 0
+		for _, d := range pool {
+			if added >= sentenceDistractorCount {
+				break
+			}
+			token := strings.ToLower(d.English)
+			if inSentence[token] {
+				continue
+			}
+			inSentence[token] = true
+			offered = append(offered, token)
+			added++
+		}
+		rand.Shuffle(len(offered), func(i, j int) { offered[i], offered[j] = offered[j], offered[i] })
+
+		data, err := json.Marshal(sentenceRoundData{Sentences: sentences, Tokens: offered})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode round data: %w", err)
+		}
+		roundData[w.ID] = string(data)
+		words = append(words, w)
+
+		rounds = append(rounds, SentenceRound{
+			WordID:  w.ID,
+			English: w.English,
+			Chinese: w.Chinese,
+			Length:  len(tokens),
+			Tokens:  offered,
+		})
+	}
+	if len(rounds) == 0 {
+		return nil, fmt.Errorf("no example sentences available for level %d", level)
+	}
+
+	selection.Words = words
+	if err := s.saveSelection(userID, GameTypeSentence, selection); err != nil {
+		return nil, err
+	}
+	for _, w := range words {
+		_, err = s.db.Exec(`
+			UPDATE game_session_words SET round_data = ? WHERE session_id = ? AND word_id = ?
+		`, roundData[w.ID], selection.SessionID, w.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save round data: %w", err)
+		}
+	}
+
+	return &SentenceGame{
+		ID:         selection.SessionID,
+		UserID:     userID,
+		Difficulty: selection.Band,
+		Rounds:     rounds,
+	}, nil
+}
+
+// SubmitSentenceAnswer 校验重组后的句子：忽略大小写和标点，重复单词互换位置、作者提供的等价句都算正确；
+// 按最长公共子序列计算的得分记在本局单词上，全部单词作答后按总分自动结算
+func (s *Service) SubmitSentenceAnswer(userID int, req *SentenceAnswerRequest) (*SentenceAnswerResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockLiveSession(tx, req.SessionID, userID, GameTypeSentence); err != nil {
+		return nil, err
+	}
+	var raw sql.NullString
+	var answered bool
+	err = tx.QueryRow(`
+		SELECT round_data, answered_at IS NOT NULL
+		FROM game_session_words
+		WHERE session_id = ? AND word_id = ?
+		FOR UPDATE
+	`, req.SessionID, req.WordID).Scan(&raw, &answered)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("word not found in game session")
+		}
+		return nil, fmt.Errorf("failed to get session word: %w", err)
+	}
+	if answered {
+		return nil, ErrWordAnswered
+	}
+	var data sentenceRoundData
+	if !raw.Valid || json.Unmarshal([]byte(raw.String), &data) != nil || len(data.Sentences) == 0 {
+		return nil, fmt.Errorf("sentence round not found")
+	}
+
+	// 提交的单词必须来自下发的单词（每个单词最多用一次）
+	available := make(map[string]int, len(data.Tokens))
+	for _, t := range data.Tokens {
+		available[normalizeToken(t)]++
+	}
+	submitted := make([]string, 0, len(req.Tokens))
+	for _, t := range req.Tokens {
+		token := normalizeToken(t)
+		if available[token] == 0 {
+			return nil, fmt.Errorf("token %q was not offered", t)
+		}
+		available[token]--
+		submitted = append(submitted, token)
+	}
+
+	result := &SentenceAnswerResult{WordID: req.WordID, Expected: data.Sentences[0]}
+	for _, sentence := range data.Sentences {
+		expected := normalizeTokens(tokenizeSentence(sentence))
+		credit := float64(longestCommonSubsequence(submitted, expected)) / float64(max(len(expected), len(submitted)))
+		if credit > result.Credit {
+			result.Credit = credit
+		}
+		if strings.Join(submitted, " ") == strings.Join(expected, " ") {
+			result.Correct = true
+			result.Credit = 1
+			break
+		}
+	}
+	result.Points = int(math.Round(result.Credit * sentenceMaxPoints))
+	result.Credit = math.Round(result.Credit*100) / 100
+
+	// 整句正确才算目标单词答对
+	err = s.recordAnswers(tx, userID, &RecordAnswersRequest{
+		SessionID: req.SessionID,
+		GameType:  GameTypeSentence,
+		Answers: []AnswerInput{{
+			WordID:         req.WordID,
+			ChosenOption:   truncate(strings.Join(req.Tokens, " "), 200),
+			IsCorrect:      result.Correct,
+			ResponseTimeMs: req.ResponseTimeMs,
+			Points:         result.Points,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sentence answer: %w", err)
+	}
+
+	result.Summary, err = s.settleAnsweredSession(userID, req.SessionID, GameTypeSentence)
+	if err != nil {
+		return nil, err
+	}
+	result.Completed = result.Summary != nil
+	return result, nil
+}
+
+// getWordSentences 获取单词的例句；example_sentence 可用"|"分隔多个等价句，第一个为标准句
+func (s *Service) getWordSentences(wordID int, english string) ([]string, error) {
+	var example, story sql.NullString
+	err := s.db.QueryRow("SELECT example_sentence, story FROM words WHERE id = ?", wordID).Scan(&example, &story)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get example sentence: %w", err)
+	}
+
+	var sentences []string
+	for _, sentence := range strings.Split(example.String, "|") {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+	if len(sentences) > 0 {
+		return sentences, nil
+	}
+
+	// 从故事中提取英文句子，优先包含目标单词的
+	matches := englishSentencePattern.FindAllString(story.String, -1)
+	for _, m := range matches {
+		if strings.Contains(strings.ToLower(m), strings.ToLower(english)) {
+			return []string{strings.TrimSpace(m)}, nil
+		}
+	}
+	if len(matches) > 0 {
+		return []string{strings.TrimSpace(matches[0])}, nil
+	}
+	return nil, nil
+}
+
+// tokenizeSentence 按空白切分句子，去掉句末标点
+func tokenizeSentence(sentence string) []string {
+	return strings.Fields(strings.TrimRight(strings.TrimSpace(sentence), ".!?"))
+}
+
+// normalizeToken 转小写并去掉首尾标点
+func normalizeToken(token string) string {
+	return strings.Trim(strings.ToLower(token), ".,!?;:\"“”")
+}
+
+func normalizeTokens(tokens []string) []string {
+	normalized := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		normalized = append(normalized, normalizeToken(t))
+	}
+	return normalized
+}
+
+// longestCommonSubsequence 最长公共子序列长度
+func longestCommonSubsequence(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				curr[j] = prev[j-1] + 1
+			} else {
+				curr[j] = max(prev[j], curr[j-1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// truncate 按字符截断字符串
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
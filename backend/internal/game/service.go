@@ -2,28 +2,66 @@ package game
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"linguaforge/internal/events"
 	"math/rand"
 )
 
+// experiencePerLevel 每升一级所需经验
+const experiencePerLevel = 100
+
+const (
+	// dailyExperienceCap 每天通过游戏最多获得的经验
+	dailyExperienceCap = 1000
+	// dailyCoinCap 每天通过游戏最多获得的金币
+	dailyCoinCap = 500
+)
+
+// ErrGameCompleted 该局已经结算过
+var ErrGameCompleted = errors.New("game already completed")
+
+// ErrScoreRejected 客户端提交的分数不被接受（游戏类型与游戏局不符，或该游戏由服务端结算）
+var ErrScoreRejected = errors.New("score rejected")
+
+// serverScoredGameTypes 由服务端逐题判分并自动结算的游戏类型，不接受客户端提交分数
+var serverScoredGameTypes = map[GameType]bool{
+	GameTypeMatching:  true,
+	GameTypeSpelling:  true,
+	GameTypeListening: true,
+	GameTypeSentence:  true,
+	GameTypeDuel:      true,
+	GameTypeChallenge: true,
+}
+
 type Service struct {
-	db *sql.DB
+	db       *sql.DB
+	progress ProgressRecorder
 }
 
-func NewService(db *sql.DB) *Service {
+func NewService(db *sql.DB, progress ProgressRecorder) *Service {
 	return &Service{
-		db: db,
+		db:       db,
+		progress: progress,
 	}
 }
 
 // StartAdventureGame 开始冒险游戏
 func (s *Service) StartAdventureGame(userID int, level int) (*AdventureGame, error) {
-	// 获取随机单词（根据用户偏好分类筛选）
+	if level <= 0 {
+		level = s.getUserPreferredLevel(userID)
+	}
+
+	// 自适应选词（根据用户偏好分类筛选）
 	preferred, _ := s.getUserPreferredCategory(userID)
-	words, err := s.getRandomWords(10, level, preferred)
+	selection, err := s.SelectWords(userID, GameTypeAdventure, 10, level, preferred)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get words: %w", err)
 	}
+	words := selection.Words
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no words available for level %d", level)
+	}
 
 	// 构造5轮题目（每轮1个主词 + 干扰项）
 	rounds := make([]AdventureRound, 0, len(words))
@@ -53,9 +91,10 @@ func (s *Service) StartAdventureGame(userID int, level int) (*AdventureGame, err
 	}
 
 	game := &AdventureGame{
-		ID:           rand.Intn(10000),
+		ID:           selection.SessionID,
 		UserID:       userID,
 		CurrentLevel: level,
+		Difficulty:   selection.Band,
 		Score:        0,
 		Story:        rounds[0].Story,
 		Options:      rounds[0].Options,
@@ -69,16 +108,20 @@ func (s *Service) StartAdventureGame(userID int, level int) (*AdventureGame, err
 
 // StartDefenseGame 开始塔防游戏
 func (s *Service) StartDefenseGame(userID int, level int) (*DefenseGame, error) {
-	// 获取随机单词（根据用户偏好分类筛选）
+	if level <= 0 {
+		level = s.getUserPreferredLevel(userID)
+	}
+
+	// 自适应选词（根据用户偏好分类筛选）
 	preferred, _ := s.getUserPreferredCategory(userID)
-	adventureWords, err := s.getRandomWords(10, level, preferred)
+	selection, err := s.SelectWords(userID, GameTypeDefense, 10, level, preferred)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get words: %w", err)
 	}
 
 	// 转换为DefenseWord类型
 	var words []DefenseWord
-	for _, word := range adventureWords {
+	for _, word := range selection.Words {
 		words = append(words, DefenseWord{
 			ID:       word.ID,
 			English:  word.English,
@@ -93,8 +136,9 @@ func (s *Service) StartDefenseGame(userID int, level int) (*DefenseGame, error)
 	towers := s.generateTowers(level)
 
 	game := &DefenseGame{
-		ID:          rand.Intn(10000),
+		ID:          selection.SessionID,
 		UserID:      userID,
+		Difficulty:  selection.Band,
 		CurrentWave: 1,
 		Score:       0,
 		Health:      100,
@@ -128,22 +172,107 @@ func (s *Service) StartDubbingGame(userID int, sceneID int) (*DubbingGame, error
 	return game, nil
 }
 
-// SubmitScore 提交游戏分数
+// ReportScore 保存客户端提交的分数：游戏类型须与游戏局一致，服务端结算的游戏和剧情章节的局不接受客户端提交
+func (s *Service) ReportScore(userID int, req *SubmitScoreRequest) error {
+	if serverScoredGameTypes[req.GameType] {
+		return fmt.Errorf("%w: %s games are settled by the server", ErrScoreRejected, req.GameType)
+	}
+	if req.SessionID > 0 {
+		var owner int
+		var gameType GameType
+		var chapter bool
+		err := s.db.QueryRow(`
+			SELECT gs.user_id, gs.game_type,
+			       EXISTS(SELECT 1 FROM user_adventure_progress WHERE session_id = gs.id)
+			FROM game_sessions gs WHERE gs.id = ?
+		`, req.SessionID).Scan(&owner, &gameType, &chapter)
+		if err == sql.ErrNoRows || (err == nil && owner != userID) {
+			return fmt.Errorf("%w: game session not found", ErrScoreRejected)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get game session: %w", err)
+		}
+		if gameType != req.GameType {
+			return fmt.Errorf("%w: game type does not match session", ErrScoreRejected)
+		}
+		if chapter {
+			return fmt.Errorf("%w: chapter scores are settled by the server", ErrScoreRejected)
+		}
+	}
+	return s.SubmitScore(userID, req)
+}
+
+// SubmitScore 结算一局并写入游戏记录；服务端结算的游戏直接调用，客户端提交经 ReportScore 校验
 func (s *Service) SubmitScore(userID int, req *SubmitScoreRequest) error {
-	_, err := s.db.Exec(`
-		INSERT INTO game_records (user_id, game_type, score, level_reached, time_spent)
-		VALUES (?, ?, ?, ?, ?)
-	`, userID, req.GameType, req.Score, req.LevelReached, req.TimeSpent)
+	var sessionID sql.NullInt64
+	if req.SessionID > 0 {
+		if err := s.sessionBelongsTo(req.SessionID, userID); err != nil {
+			return err
+		}
+		sessionID = sql.NullInt64{Int64: int64(req.SessionID), Valid: true}
+
+		// 已逐题上报过的会话，以服务器记录的答题数为准
+		correct, total, err := s.sessionAnswerCounts(req.SessionID)
+		if err != nil {
+			return err
+		}
+		if total > 0 {
+			req.CorrectCount, req.TotalCount = correct, total
+		}
+	}
+	if req.CorrectCount < 0 || req.TotalCount < 0 || req.CorrectCount > req.TotalCount {
+		return fmt.Errorf("invalid answer counts")
+	}
+
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to save game record: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if sessionID.Valid {
+		// 每局只结算一次：并发提交时只有先标记结算的请求能继续
+		result, err := tx.Exec(`
+			UPDATE game_sessions SET finished_at = NOW() WHERE id = ? AND finished_at IS NULL
+		`, req.SessionID)
+		if err != nil {
+			return fmt.Errorf("failed to finish game session: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return ErrGameCompleted
+		}
+		// 游戏时长以服务器记录的开局到结算时间为准，客户端上报的时长只能更短
+		var elapsed int
+		if err := tx.QueryRow(`
+			SELECT TIMESTAMPDIFF(SECOND, created_at, finished_at) FROM game_sessions WHERE id = ?
+		`, req.SessionID).Scan(&elapsed); err != nil {
+			return fmt.Errorf("failed to get game session time: %w", err)
+		}
+		if req.TimeSpent <= 0 || req.TimeSpent > elapsed {
+			req.TimeSpent = elapsed
+		}
 	}
 
-	// 根据分数给予经验和金币奖励
-	expReward := req.Score / 10
-	coinReward := req.Score / 20
+	// 根据分数给予经验和金币奖励（不超过每日上限）
+	expReward, coinReward, err := cappedRewards(tx, userID, req.Score)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO game_records (user_id, session_id, game_type, score, level_reached, time_spent, correct_count, total_count, experience_gained, coins_gained)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, sessionID, req.GameType, req.Score, req.LevelReached, req.TimeSpent, req.CorrectCount, req.TotalCount, expReward, coinReward)
+	if err != nil {
+		return fmt.Errorf("failed to save game record: %w", err)
+	}
+	recordID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get game record ID: %w", err)
+	}
 
 	// 更新用户经验和金币
-	_, err = s.db.Exec(`
+	_, err = tx.Exec(`
 		UPDATE users 
 		SET experience = experience + ?, coins = coins + ?, updated_at = NOW()
 		WHERE id = ?
@@ -152,9 +281,88 @@ func (s *Service) SubmitScore(userID int, req *SubmitScoreRequest) error {
 		return fmt.Errorf("failed to update user rewards: %w", err)
 	}
 
+	experience, err := applyLevelUp(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := events.Write(tx, events.GameCompleted, userID, &events.GameCompletedPayload{
+		RecordID:         recordID,
+		SessionID:        req.SessionID,
+		GameType:         string(req.GameType),
+		Score:            req.Score,
+		LevelReached:     req.LevelReached,
+		TimeSpent:        req.TimeSpent,
+		CorrectCount:     req.CorrectCount,
+		TotalCount:       req.TotalCount,
+		ExperienceGained: expReward,
+		CoinsGained:      coinReward,
+		Experience:       experience,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit game record: %w", err)
+	}
 	return nil
 }
 
+// cappedRewards 按分数计算本局的经验和金币，扣除今天已获得的部分，使每天的总量不超过上限
+// 先锁定用户行，并发提交时依次计算，避免同时通过上限检查
+func cappedRewards(tx *sql.Tx, userID int, score int) (int, int, error) {
+	var id int
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&id); err != nil {
+		return 0, 0, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var earnedExp, earnedCoins int
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(experience_gained), 0), COALESCE(SUM(coins_gained), 0)
+		FROM game_records
+		WHERE user_id = ? AND completed_at >= CURDATE()
+	`, userID).Scan(&earnedExp, &earnedCoins)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get today's rewards: %w", err)
+	}
+
+	expReward, coinReward := rewardsWithinCaps(score, earnedExp, earnedCoins)
+	return expReward, coinReward, nil
+}
+
+// rewardsWithinCaps 每 10 分得 1 经验、每 20 分得 1 金币，加上今天已获得的部分不超过每日上限
+func rewardsWithinCaps(score int, earnedExp int, earnedCoins int) (int, int) {
+	expReward := max(0, min(score/10, dailyExperienceCap-earnedExp))
+	coinReward := max(0, min(score/20, dailyCoinCap-earnedCoins))
+	return expReward, coinReward
+}
+
+// applyLevelUp 按经验值提升等级，每升一级写入一条升级记录（用于好友动态）和升级事件；返回当前经验值
+func applyLevelUp(tx *sql.Tx, userID int) (int, error) {
+	var level, experience int
+	err := tx.QueryRow("SELECT level, experience FROM users WHERE id = ? FOR UPDATE", userID).Scan(&level, &experience)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user level: %w", err)
+	}
+	target := experience/experiencePerLevel + 1
+	if target <= level {
+		return experience, nil
+	}
+
+	if _, err := tx.Exec("UPDATE users SET level = ? WHERE id = ?", target, userID); err != nil {
+		return 0, fmt.Errorf("failed to update user level: %w", err)
+	}
+	for l := level + 1; l <= target; l++ {
+		if _, err := tx.Exec("INSERT INTO user_level_ups (user_id, level) VALUES (?, ?)", userID, l); err != nil {
+			return 0, fmt.Errorf("failed to record level up: %w", err)
+		}
+		if err := events.Write(tx, events.LevelUp, userID, &events.LevelUpPayload{Level: l}); err != nil {
+			return 0, err
+		}
+	}
+	return experience, nil
+}
+
 // SubmitDubbing 提交配音
 func (s *Service) SubmitDubbing(userID int, req *SubmitDubbingRequest) error {
 	// 这里只保存配音记录，不进行AI评分
@@ -173,7 +381,7 @@ func (s *Service) SubmitDubbing(userID int, req *SubmitDubbingRequest) error {
 // GetGameHistory 获取游戏历史
 func (s *Service) GetGameHistory(userID int, gameType GameType, limit int) ([]GameRecord, error) {
 	query := `
-		SELECT id, user_id, game_type, score, level_reached, time_spent, completed_at
+		SELECT id, user_id, game_type, score, level_reached, time_spent, correct_count, total_count, completed_at
 		FROM game_records
 		WHERE user_id = ?
 	`
@@ -199,7 +407,7 @@ func (s *Service) GetGameHistory(userID int, gameType GameType, limit int) ([]Ga
 		err := rows.Scan(
 			&record.ID, &record.UserID, &record.GameType,
 			&record.Score, &record.LevelReached, &record.TimeSpent,
-			&record.CompletedAt,
+			&record.CorrectCount, &record.TotalCount, &record.CompletedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan game record: %w", err)
@@ -358,3 +566,12 @@ func (s *Service) getUserPreferredCategory(userID int) (string, error) {
 	}
 	return "", nil
 }
+
+// getUserPreferredLevel 获取用户推荐难度（分级测试结果），默认为1
+func (s *Service) getUserPreferredLevel(userID int) int {
+	level := 1
+	if err := s.db.QueryRow("SELECT preferred_level FROM users WHERE id = ?", userID).Scan(&level); err != nil || level < 1 {
+		return 1
+	}
+	return level
+}
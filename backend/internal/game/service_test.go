@@ -0,0 +1,32 @@
+package game
+
+import "testing"
+
+func TestRewardsWithinCaps(t *testing.T) {
+	tests := []struct {
+		name                   string
+		score                  int
+		earnedExp, earnedCoins int
+		wantExp, wantCoins     int
+	}{
+		{"nothing earned yet", 1000, 0, 0, 100, 50},
+		{"rounds down", 19, 0, 0, 1, 0},
+		{"zero score", 0, 0, 0, 0, 0},
+		{"negative score", -50, 0, 0, 0, 0},
+		{"reaches the caps exactly", 5000, 500, 250, 500, 250},
+		{"capped by the remaining allowance", 5000, 950, 480, 50, 20},
+		{"caps already reached", 1000, 1000, 500, 0, 0},
+		{"over the caps from earlier records", 1000, 1200, 600, 0, 0},
+		{"only the coin cap applies", 2000, 0, 450, 200, 50},
+		{"single huge score", 1000000, 0, 0, dailyExperienceCap, dailyCoinCap},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, coins := rewardsWithinCaps(tt.score, tt.earnedExp, tt.earnedCoins)
+			if exp != tt.wantExp || coins != tt.wantCoins {
+				t.Fatalf("rewardsWithinCaps(%d, %d, %d) = %d, %d; want %d, %d",
+					tt.score, tt.earnedExp, tt.earnedCoins, exp, coins, tt.wantExp, tt.wantCoins)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package game
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 拼写挑战参数
+const (
+	spellingRoundCount  = 10
+	spellingHintCost    = 5  // 每个字母提示消耗的金币
+	spellingMaxPoints   = 10 // 每个单词满分
+	spellingHintPenalty = 2  // 每用一次提示扣除的分数
+)
+
+// StartSpellingGame 开始拼写挑战
+func (s *Service) StartSpellingGame(userID int, level int) (*SpellingGame, error) {
+	if level <= 0 {
+		level = s.getUserPreferredLevel(userID)
+	}
+
+	preferred, _ := s.getUserPreferredCategory(userID)
+	selection, err := s.SelectWords(userID, GameTypeSpelling, spellingRoundCount, level, preferred)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get words: %w", err)
+	}
+	if len(selection.Words) == 0 {
+		return nil, fmt.Errorf("no words available for level %d", level)
+	}
+
+	ids := make([]int, 0, len(selection.Words))
+	for _, w := range selection.Words {
+		ids = append(ids, w.ID)
+	}
+	media, err := s.getWordMedia(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get word media: %w", err)
+	}
+
+	rounds := make([]SpellingRound, 0, len(selection.Words))
+	for _, w := range selection.Words {
+		rounds = append(rounds, SpellingRound{
+			WordID:        w.ID,
+			Chinese:       w.Chinese,
+			Pronunciation: media[w.ID].Pronunciation,
+			AudioURL:      media[w.ID].AudioURL,
+			Pattern:       spellingPattern(w.English),
+		})
+	}
+
+	return &SpellingGame{
+		ID:         selection.SessionID,
+		UserID:     userID,
+		Difficulty: selection.Band,
+		HintCost:   spellingHintCost,
+		Rounds:     rounds,
+	}, nil
+}
+
+// GetSpellingHint 花费金币揭示下一个字母
+func (s *Service) GetSpellingHint(userID int, req *SpellingHintRequest) (*SpellingHintResponse, error) {
+	// 扣金币和累加提示次数在同一事务中完成；锁定本局单词，与作答互斥
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockLiveSession(tx, req.SessionID, userID, GameTypeSpelling); err != nil {
+		return nil, err
+	}
+	english, hintsUsed, answered, err := lockSpellingWord(tx, req.SessionID, req.WordID)
+	if err != nil {
+		return nil, err
+	}
+	if answered {
+		return nil, ErrWordAnswered
+	}
+
+	target := normalizeSpelling(english)
+	// 至少留一个字母给用户自己拼
+	if hintsUsed+1 >= countLetters(target) {
+		return nil, fmt.Errorf("no more hints available")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE game_session_words SET hints_used = hints_used + 1
+		WHERE session_id = ? AND word_id = ?
+	`, req.SessionID, req.WordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save hint: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE users SET coins = coins - ?, updated_at = NOW()
+		WHERE id = ? AND coins >= ?
+	`, spellingHintCost, userID, spellingHintCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to spend coins: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, fmt.Errorf("not enough coins")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit hint: %w", err)
+	}
+	hintsUsed++
+
+	return &SpellingHintResponse{
+		WordID:     req.WordID,
+		Revealed:   revealLetters(target, hintsUsed),
+		HintsUsed:  hintsUsed,
+		CoinsSpent: spellingHintCost,
+	}, nil
+}
+
+// SubmitSpellingAnswer 评分拼写答案：忽略大小写和多余空白，按编辑距离给部分分；
+// 得分记在本局单词上，全部单词作答后按总分自动结算
+func (s *Service) SubmitSpellingAnswer(userID int, req *SpellingAnswerRequest) (*SpellingAnswerResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockLiveSession(tx, req.SessionID, userID, GameTypeSpelling); err != nil {
+		return nil, err
+	}
+	english, hintsUsed, answered, err := lockSpellingWord(tx, req.SessionID, req.WordID)
+	if err != nil {
+		return nil, err
+	}
+	if answered {
+		return nil, ErrWordAnswered
+	}
+
+	target := normalizeSpelling(english)
+	answer := normalizeSpelling(req.Answer)
+	distance := editDistance(answer, target)
+	credit := math.Max(0, 1-float64(distance)/float64(len([]rune(target))))
+	points := max(int(math.Round(credit*spellingMaxPoints))-hintsUsed*spellingHintPenalty, 0)
+
+	err = s.recordAnswers(tx, userID, &RecordAnswersRequest{
+		SessionID: req.SessionID,
+		GameType:  GameTypeSpelling,
+		Answers: []AnswerInput{{
+			WordID:         req.WordID,
+			ChosenOption:   answer,
+			IsCorrect:      distance == 0,
+			ResponseTimeMs: req.ResponseTimeMs,
+			Points:         points,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit spelling answer: %w", err)
+	}
+
+	summary, err := s.settleAnsweredSession(userID, req.SessionID, GameTypeSpelling)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpellingAnswerResult{
+		WordID:    req.WordID,
+		Correct:   distance == 0,
+		Credit:    math.Round(credit*100) / 100,
+		Points:    points,
+		Distance:  distance,
+		HintsUsed: hintsUsed,
+		Expected:  english,
+		Completed: summary != nil,
+		Summary:   summary,
+	}, nil
+}
+
+// wordMedia 单词的音标与音频
+type wordMedia struct {
+	Pronunciation string
+	AudioURL      string
+}
+
+// getWordMedia 批量获取单词的音标与音频
+func (s *Service) getWordMedia(ids []int) (map[int]wordMedia, error) {
+	media := make(map[int]wordMedia, len(ids))
+	if len(ids) == 0 {
+		return media, nil
+	}
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := s.db.Query(
+		"SELECT id, pronunciation, audio_url FROM words WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var pronunciation, audioURL sql.NullString
+		if err := rows.Scan(&id, &pronunciation, &audioURL); err != nil {
+			return nil, err
+		}
+		media[id] = wordMedia{Pronunciation: pronunciation.String, AudioURL: audioURL.String}
+	}
+	return media, nil
+}
+
+// lockSpellingWord 锁定本局中的单词，返回英文、已用提示数和是否已作答
+func lockSpellingWord(tx *sql.Tx, sessionID int, wordID int) (string, int, bool, error) {
+	var english string
+	var hintsUsed int
+	var answered bool
+	err := tx.QueryRow(`
+		SELECT w.english, gsw.hints_used, gsw.answered_at IS NOT NULL
+		FROM game_session_words gsw JOIN words w ON w.id = gsw.word_id
+		WHERE gsw.session_id = ? AND gsw.word_id = ?
+		FOR UPDATE
+	`, sessionID, wordID).Scan(&english, &hintsUsed, &answered)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", 0, false, fmt.Errorf("word not found in game session")
+		}
+		return "", 0, false, fmt.Errorf("failed to get session word: %w", err)
+	}
+	return english, hintsUsed, answered, nil
+}
+
+// normalizeSpelling 转小写并合并多余空白
+func normalizeSpelling(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// spellingPattern 用下划线遮住字母，保留空格和标点
+func spellingPattern(english string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return '_'
+		}
+		return r
+	}, normalizeSpelling(english))
+}
+
+// revealLetters 揭示前n个字母，其余用下划线表示
+func revealLetters(target string, n int) string {
+	var b strings.Builder
+	for _, r := range target {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if n > 0 {
+				b.WriteRune(r)
+				n--
+			} else {
+				b.WriteRune('_')
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// countLetters 字母和数字的个数
+func countLetters(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// editDistance 计算两个字符串的编辑距离（Levenshtein）
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,62 @@
+package game
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "apple", 5},
+		{"apple", "", 5},
+		{"apple", "apple", 0},
+		{"aple", "apple", 1},
+		{"appel", "apple", 2},
+		{"kitten", "sitting", 3},
+		{"ice cream", "icecream", 1},
+		{"café", "cafe", 1}, // 按字符而不是字节计算
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSpelling(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Apple", "apple"},
+		{"  ice   Cream ", "ice cream"},
+		{"New\tYork\n", "new york"},
+		{"   ", ""},
+		{"T-shirt", "t-shirt"},
+	}
+	for _, tt := range tests {
+		if got := normalizeSpelling(tt.in); got != tt.want {
+			t.Errorf("normalizeSpelling(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRevealLetters(t *testing.T) {
+	tests := []struct {
+		target string
+		n      int
+		want   string
+	}{
+		{"apple", 0, "_____"},
+		{"apple", 2, "ap___"},
+		{"apple", 5, "apple"},
+		{"apple", 9, "apple"},
+		{"ice cream", 4, "ice c____"}, // 空格不占提示次数
+		{"t-shirt", 2, "t-s____"},
+	}
+	for _, tt := range tests {
+		if got := revealLetters(tt.target, tt.n); got != tt.want {
+			t.Errorf("revealLetters(%q, %d) = %q, want %q", tt.target, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package guardian
+
+import (
+	"context"
+	"linguaforge/internal/scheduler"
+	"time"
+)
+
+// RegisterJobs 注册监护相关的定时任务
+func (s *Service) RegisterJobs(jobs *scheduler.Scheduler) {
+	// 每天执行一次：周一生成上一周的报告，其余日期补发漏掉的周报并重试发送失败的报告
+	jobs.Register("guardian.weekly_reports", "0 6 * * *", "生成并发送监护人每周学习报告", 30*time.Minute,
+		func(ctx context.Context) error {
+			return s.RunWeeklyReports(ctx, time.Now())
+		})
+}
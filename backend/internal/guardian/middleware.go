@@ -0,0 +1,39 @@
+package guardian
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnforceTimeLimit 开局前检查监护人设置的每日游戏时长，需在 AuthMiddleware 之后使用
+// 查询失败时放行，避免监护功能故障影响正常游戏
+func (h *Handlers) EnforceTimeLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		status, err := h.service.TimeLimit(userID.(int))
+		if err != nil {
+			log.Printf("time limit check for user %d: %v", userID.(int), err)
+			c.Next()
+			return
+		}
+		if status.Exceeded {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":         "Daily time limit reached",
+				"limit_minutes": status.LimitMinutes,
+				"used_minutes":  status.UsedMinutes,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,95 @@
+package guardian
+
+import "time"
+
+// LinkStatus 监护关系状态
+type LinkStatus string
+
+const (
+	LinkPending  LinkStatus = "pending" // 等待学习者同意
+	LinkActive   LinkStatus = "active"
+	LinkDeclined LinkStatus = "declined"
+	LinkRevoked  LinkStatus = "revoked" // 任一方解除
+)
+
+// LinkRequest 监护人申请绑定学习者，可用ID或用户名指定
+type LinkRequest struct {
+	LearnerID       int    `json:"learner_id"`
+	LearnerUsername string `json:"learner_username"`
+}
+
+// RespondRequest 学习者处理绑定申请
+type RespondRequest struct {
+	Accept bool `json:"accept"`
+}
+
+// LimitRequest 设置每日游戏时长限制，0 表示不限制
+type LimitRequest struct {
+	DailyLimitMinutes int `json:"daily_limit_minutes" binding:"min=0,max=1440"`
+}
+
+// Link 监护关系
+type Link struct {
+	ID                int        `json:"id"`
+	GuardianID        int        `json:"guardian_id"`
+	GuardianName      string     `json:"guardian_name"`
+	LearnerID         int        `json:"learner_id"`
+	LearnerName       string     `json:"learner_name"`
+	Status            LinkStatus `json:"status"`
+	DailyLimitMinutes *int       `json:"daily_limit_minutes,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// DaySummary 单日学习情况
+type DaySummary struct {
+	Date    string `json:"date"` // 2006-01-02
+	Minutes int    `json:"minutes"`
+	Games   int    `json:"games"`
+	Answers int    `json:"answers"`
+	Correct int    `json:"correct"`
+}
+
+// LearnerOverview 监护人查看的学习者概况（只读）
+type LearnerOverview struct {
+	LearnerID         int          `json:"learner_id"`
+	Username          string       `json:"username"`
+	Level             int          `json:"level"`
+	Experience        int          `json:"experience"`
+	Streak            int          `json:"streak"` // 连续学习天数
+	WordsStudied      int          `json:"words_studied"`
+	Accuracy          float64      `json:"accuracy"` // 全部作答正确率，0-1
+	TodayMinutes      int          `json:"today_minutes"`
+	DailyLimitMinutes int          `json:"daily_limit_minutes"` // 0 表示不限制
+	LastActiveAt      *time.Time   `json:"last_active_at,omitempty"`
+	Days              []DaySummary `json:"days"` // 最近7天
+}
+
+// WeeklyReport 每周学习报告
+type WeeklyReport struct {
+	LearnerID    int          `json:"learner_id"`
+	LearnerName  string       `json:"learner_name"`
+	WeekStart    string       `json:"week_start"` // 周一
+	WeekEnd      string       `json:"week_end"`   // 周日
+	Minutes      int          `json:"minutes"`
+	Games        int          `json:"games"`
+	Answers      int          `json:"answers"`
+	Accuracy     float64      `json:"accuracy"`
+	WordsStudied int          `json:"words_studied"` // 本周学习过的不同单词数
+	ActiveDays   int          `json:"active_days"`
+	Streak       int          `json:"streak"` // 报告生成时的连续学习天数
+	Days         []DaySummary `json:"days"`
+}
+
+// Recipient 报告接收人
+type Recipient struct {
+	UserID   int
+	Username string
+	Email    string
+}
+
+// TimeLimitStatus 学习者今日游戏时长与限制
+type TimeLimitStatus struct {
+	LimitMinutes int  `json:"limit_minutes"` // 0 表示不限制
+	UsedMinutes  int  `json:"used_minutes"`
+	Exceeded     bool `json:"exceeded"`
+}
@@ -0,0 +1,62 @@
+package guardian
+
+import (
+	"context"
+	"fmt"
+	"linguaforge/config"
+	"linguaforge/internal/mail"
+	"log"
+	"strings"
+)
+
+// Notifier 发送每周学习报告，可按配置替换实现
+type Notifier interface {
+	SendWeeklyReport(ctx context.Context, to Recipient, report *WeeklyReport) error
+}
+
+// NewNotifier 按配置创建通知发送器，未配置或未知驱动时只写日志
+func NewNotifier(cfg config.NotifierConfig) Notifier {
+	if mail.Enabled(cfg) {
+		return &SMTPNotifier{cfg: cfg}
+	}
+	if cfg.Driver == "smtp" {
+		log.Printf("notifier: SMTP_HOST not set, falling back to log notifier")
+	}
+	return LogNotifier{}
+}
+
+// LogNotifier 把报告写入日志（开发环境默认）
+type LogNotifier struct{}
+
+func (LogNotifier) SendWeeklyReport(ctx context.Context, to Recipient, report *WeeklyReport) error {
+	log.Printf("weekly report for guardian %d (%s): learner %s, week %s, %d minutes, %d games, accuracy %.0f%%",
+		to.UserID, to.Username, report.LearnerName, report.WeekStart, report.Minutes, report.Games, report.Accuracy*100)
+	return nil
+}
+
+// SMTPNotifier 通过邮件发送报告
+type SMTPNotifier struct {
+	cfg config.NotifierConfig
+}
+
+func (n *SMTPNotifier) SendWeeklyReport(ctx context.Context, to Recipient, report *WeeklyReport) error {
+	if to.Email == "" {
+		return fmt.Errorf("guardian %d has no email", to.UserID)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s，您好：\r\n\r\n", to.Username)
+	fmt.Fprintf(&body, "%s 本周学习了 %d 天，共 %d 分钟，完成 %d 局游戏。\r\n",
+		report.LearnerName, report.ActiveDays, report.Minutes, report.Games)
+	fmt.Fprintf(&body, "作答 %d 题，正确率 %.0f%%，学习单词 %d 个，当前连续学习 %d 天。\r\n\r\n",
+		report.Answers, report.Accuracy*100, report.WordsStudied, report.Streak)
+	for _, d := range report.Days {
+		fmt.Fprintf(&body, "%s  %d 分钟  %d 局  %d/%d 题\r\n", d.Date, d.Minutes, d.Games, d.Correct, d.Answers)
+	}
+
+	subject := fmt.Sprintf("%s 的学习周报（%s ~ %s）", report.LearnerName, report.WeekStart, report.WeekEnd)
+	if err := mail.Send(n.cfg, to.Email, subject, body.String()); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+	return nil
+}
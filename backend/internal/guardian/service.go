@@ -0,0 +1,593 @@
+package guardian
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linguaforge/internal/common"
+	"linguaforge/internal/user"
+	"log"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type Service struct {
+	db       *sql.DB
+	notifier Notifier
+}
+
+func NewService(db *sql.DB, notifier Notifier) *Service {
+	return &Service{
+		db:       db,
+		notifier: notifier,
+	}
+}
+
+// RequestLink 监护人申请绑定学习者，学习者同意后生效
+func (s *Service) RequestLink(guardianID int, req *LinkRequest) (*Link, error) {
+	learnerID := req.LearnerID
+	if learnerID == 0 {
+		if req.LearnerUsername == "" {
+			return nil, errors.New("learner_id or learner_username is required")
+		}
+		err := s.db.QueryRow("SELECT id FROM users WHERE username = ?", req.LearnerUsername).Scan(&learnerID)
+		if err == sql.ErrNoRows {
+			return nil, errors.New("learner not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find learner: %w", err)
+		}
+	}
+	if learnerID == guardianID {
+		return nil, errors.New("cannot link to yourself")
+	}
+
+	var role user.Role
+	err := s.db.QueryRow("SELECT role FROM users WHERE id = ?", learnerID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("learner not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find learner: %w", err)
+	}
+	if role != user.RoleStudent {
+		return nil, errors.New("only student accounts can be linked")
+	}
+
+	// 已拒绝或已解除的关系可以重新申请；已生效的保持不变
+	_, err = s.db.Exec(`
+		INSERT INTO guardian_links (guardian_id, learner_id, status)
+		VALUES (?, ?, 'pending')
+		ON DUPLICATE KEY UPDATE
+		    status = IF(status = 'active', status, 'pending'),
+		    responded_at = IF(status = 'pending', NULL, responded_at),
+		    created_at = IF(status = 'pending', NOW(), created_at)
+	`, guardianID, learnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create guardian link: %w", err)
+	}
+
+	var linkID int
+	if err := s.db.QueryRow("SELECT id FROM guardian_links WHERE guardian_id = ? AND learner_id = ?",
+		guardianID, learnerID).Scan(&linkID); err != nil {
+		return nil, fmt.Errorf("failed to get guardian link: %w", err)
+	}
+	return s.getLink(linkID)
+}
+
+// ListAsGuardian 监护人的全部绑定关系
+func (s *Service) ListAsGuardian(guardianID int) ([]Link, error) {
+	return s.listLinks("l.guardian_id = ? AND l.status IN ('pending', 'active')", guardianID)
+}
+
+// ListAsLearner 学习者收到的绑定申请与已生效的监护人
+func (s *Service) ListAsLearner(learnerID int) ([]Link, error) {
+	return s.listLinks("l.learner_id = ? AND l.status IN ('pending', 'active')", learnerID)
+}
+
+// RespondLink 学习者同意或拒绝绑定申请
+func (s *Service) RespondLink(learnerID int, linkID int, accept bool) (*Link, error) {
+	status := LinkDeclined
+	if accept {
+		status = LinkActive
+	}
+	result, err := s.db.Exec(`
+		UPDATE guardian_links SET status = ?, responded_at = NOW()
+		WHERE id = ? AND learner_id = ? AND status = 'pending'
+	`, status, linkID, learnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to respond guardian link: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to respond guardian link: %w", err)
+	}
+	if affected == 0 {
+		return nil, errors.New("link request not found")
+	}
+	return s.getLink(linkID)
+}
+
+// RevokeLink 监护人或学习者解除绑定
+func (s *Service) RevokeLink(userID int, linkID int) error {
+	result, err := s.db.Exec(`
+		UPDATE guardian_links SET status = 'revoked', responded_at = NOW()
+		WHERE id = ? AND (guardian_id = ? OR learner_id = ?) AND status IN ('pending', 'active')
+	`, linkID, userID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke guardian link: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke guardian link: %w", err)
+	}
+	if affected == 0 {
+		return errors.New("link not found")
+	}
+	return nil
+}
+
+// SetDailyLimit 监护人设置每日游戏时长限制，0 表示取消限制
+func (s *Service) SetDailyLimit(guardianID int, linkID int, minutes int) (*Link, error) {
+	var limit interface{}
+	if minutes > 0 {
+		limit = minutes
+	}
+	_, err := s.db.Exec(`
+		UPDATE guardian_links SET daily_limit_minutes = ?
+		WHERE id = ? AND guardian_id = ? AND status = 'active'
+	`, limit, linkID, guardianID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set daily limit: %w", err)
+	}
+
+	// 值未变化时 RowsAffected 为 0，因此回读校验归属
+	link, err := s.getLink(linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link.GuardianID != guardianID || link.Status != LinkActive {
+		return nil, errors.New("link not found")
+	}
+	return link, nil
+}
+
+// Overview 学习者概况（需已生效的绑定关系）
+func (s *Service) Overview(guardianID int, learnerID int) (*LearnerOverview, error) {
+	if err := s.checkActiveLink(guardianID, learnerID); err != nil {
+		return nil, err
+	}
+
+	overview := &LearnerOverview{LearnerID: learnerID}
+	err := s.db.QueryRow("SELECT username, level, experience FROM users WHERE id = ?", learnerID).
+		Scan(&overview.Username, &overview.Level, &overview.Experience)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get learner: %w", err)
+	}
+
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM user_progress WHERE user_id = ?", learnerID).
+		Scan(&overview.WordsStudied); err != nil {
+		return nil, fmt.Errorf("failed to count studied words: %w", err)
+	}
+
+	var answers, correct int
+	if err := s.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(is_correct), 0) FROM answer_events WHERE user_id = ?
+	`, learnerID).Scan(&answers, &correct); err != nil {
+		return nil, fmt.Errorf("failed to count answers: %w", err)
+	}
+	if answers > 0 {
+		overview.Accuracy = float64(correct) / float64(answers)
+	}
+
+	var lastAnswer, lastGame sql.NullTime
+	if err := s.db.QueryRow(`
+		SELECT (SELECT MAX(answered_at) FROM answer_events WHERE user_id = ?),
+		       (SELECT MAX(completed_at) FROM game_records WHERE user_id = ?)
+	`, learnerID, learnerID).Scan(&lastAnswer, &lastGame); err != nil {
+		return nil, fmt.Errorf("failed to get last activity: %w", err)
+	}
+	for _, t := range []sql.NullTime{lastAnswer, lastGame} {
+		if t.Valid && (overview.LastActiveAt == nil || t.Time.After(*overview.LastActiveAt)) {
+			last := t.Time
+			overview.LastActiveAt = &last
+		}
+	}
+
+	now := time.Now()
+	overview.Streak, err = s.streak(learnerID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	today := common.StartOfDay(now)
+	overview.Days, err = s.daySummaries(learnerID, today.AddDate(0, 0, -6), today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	overview.TodayMinutes = overview.Days[len(overview.Days)-1].Minutes
+
+	status, err := s.TimeLimit(learnerID)
+	if err != nil {
+		return nil, err
+	}
+	overview.DailyLimitMinutes = status.LimitMinutes
+
+	return overview, nil
+}
+
+// sessionMaxSeconds 单局游戏最多计入的时长，避免中途放弃的局一直累计
+const sessionMaxSeconds = 30 * 60
+
+// TimeLimit 学习者今日游戏时长；多个监护人设置时取最严格的限制
+func (s *Service) TimeLimit(learnerID int) (*TimeLimitStatus, error) {
+	var limit sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT MIN(daily_limit_minutes) FROM guardian_links
+		WHERE learner_id = ? AND status = 'active' AND daily_limit_minutes IS NOT NULL
+	`, learnerID).Scan(&limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily limit: %w", err)
+	}
+
+	status := &TimeLimitStatus{}
+	if !limit.Valid {
+		return status, nil
+	}
+	status.LimitMinutes = int(limit.Int64)
+
+	// 按服务器记录的开局和结算时间计算，不依赖客户端上报的时长；未结算的局计到现在（单局最多计 sessionMaxSeconds）
+	var seconds int
+	if err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(LEAST(TIMESTAMPDIFF(SECOND, created_at, COALESCE(finished_at, NOW())), ?)), 0)
+		FROM game_sessions
+		WHERE user_id = ? AND created_at >= CURDATE()
+	`, sessionMaxSeconds, learnerID).Scan(&seconds); err != nil {
+		return nil, fmt.Errorf("failed to get today's play time: %w", err)
+	}
+	status.UsedMinutes = seconds / 60
+	status.Exceeded = status.UsedMinutes >= status.LimitMinutes
+	return status, nil
+}
+
+// Reports 监护人查看某个学习者的历史周报
+func (s *Service) Reports(guardianID int, learnerID int, limit int) ([]WeeklyReport, error) {
+	if err := s.checkActiveLink(guardianID, learnerID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 52 {
+		limit = 12
+	}
+
+	rows, err := s.db.Query(`
+		SELECT summary FROM guardian_reports
+		WHERE guardian_id = ? AND learner_id = ?
+		ORDER BY week_start DESC
+		LIMIT ?
+	`, guardianID, learnerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []WeeklyReport{}
+	for rows.Next() {
+		var summary string
+		if err := rows.Scan(&summary); err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		var report WeeklyReport
+		if err := json.Unmarshal([]byte(summary), &report); err != nil {
+			return nil, fmt.Errorf("failed to decode report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// RunWeeklyReports 生成上一周（周一至周日）的报告并发送；重复执行不会重复生成或发送
+func (s *Service) RunWeeklyReports(ctx context.Context, now time.Time) error {
+	weekStart := lastWeekStart(now)
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	rows, err := s.db.Query(`
+		SELECT l.guardian_id, l.learner_id
+		FROM guardian_links l
+		LEFT JOIN guardian_reports r
+		       ON r.guardian_id = l.guardian_id AND r.learner_id = l.learner_id AND r.week_start = ?
+		WHERE l.status = 'active' AND r.id IS NULL
+	`, weekStart.Format(dateLayout))
+	if err != nil {
+		return fmt.Errorf("failed to query guardian links: %w", err)
+	}
+	type pair struct{ guardianID, learnerID int }
+	var pending []pair
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.guardianID, &p.learnerID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan guardian link: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+
+	for _, p := range pending {
+		report, err := s.buildReport(p.learnerID, weekStart, weekEnd)
+		if err != nil {
+			return err
+		}
+		summary, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		if _, err := s.db.Exec(`
+			INSERT IGNORE INTO guardian_reports (guardian_id, learner_id, week_start, summary)
+			VALUES (?, ?, ?, ?)
+		`, p.guardianID, p.learnerID, weekStart.Format(dateLayout), string(summary)); err != nil {
+			return fmt.Errorf("failed to save report: %w", err)
+		}
+	}
+
+	return s.deliverReports(ctx)
+}
+
+// deliverReports 发送尚未送达的报告；先占位 delivered_at，避免多个实例重复发送
+func (s *Service) deliverReports(ctx context.Context) error {
+	rows, err := s.db.Query(`
+		SELECT r.id, r.summary, u.id, u.username, COALESCE(u.email, '')
+		FROM guardian_reports r
+		JOIN users u ON u.id = r.guardian_id
+		WHERE r.delivered_at IS NULL
+		ORDER BY r.id
+		LIMIT 500
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query undelivered reports: %w", err)
+	}
+	type delivery struct {
+		id      int
+		summary string
+		to      Recipient
+	}
+	var deliveries []delivery
+	for rows.Next() {
+		var d delivery
+		if err := rows.Scan(&d.id, &d.summary, &d.to.UserID, &d.to.Username, &d.to.Email); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan report: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+
+	for _, d := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result, err := s.db.Exec("UPDATE guardian_reports SET delivered_at = NOW() WHERE id = ? AND delivered_at IS NULL", d.id)
+		if err != nil {
+			return fmt.Errorf("failed to claim report: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+
+		var report WeeklyReport
+		if err := json.Unmarshal([]byte(d.summary), &report); err != nil {
+			log.Printf("guardian report %d: failed to decode: %v", d.id, err)
+			continue
+		}
+		if err := s.notifier.SendWeeklyReport(ctx, d.to, &report); err != nil {
+			log.Printf("guardian report %d: failed to send: %v", d.id, err)
+			// 发送失败则下次重试
+			if _, err := s.db.Exec("UPDATE guardian_reports SET delivered_at = NULL WHERE id = ?", d.id); err != nil {
+				log.Printf("guardian report %d: failed to release: %v", d.id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// buildReport 统计学习者在 [from, to) 内的学习情况
+func (s *Service) buildReport(learnerID int, from, to time.Time) (*WeeklyReport, error) {
+	report := &WeeklyReport{
+		LearnerID: learnerID,
+		WeekStart: from.Format(dateLayout),
+		WeekEnd:   to.AddDate(0, 0, -1).Format(dateLayout),
+	}
+	if err := s.db.QueryRow("SELECT username FROM users WHERE id = ?", learnerID).Scan(&report.LearnerName); err != nil {
+		return nil, fmt.Errorf("failed to get learner: %w", err)
+	}
+
+	days, err := s.daySummaries(learnerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	report.Days = days
+	var correct int
+	for _, d := range days {
+		report.Minutes += d.Minutes
+		report.Games += d.Games
+		report.Answers += d.Answers
+		correct += d.Correct
+		if d.Games > 0 || d.Answers > 0 {
+			report.ActiveDays++
+		}
+	}
+	if report.Answers > 0 {
+		report.Accuracy = float64(correct) / float64(report.Answers)
+	}
+
+	if err := s.db.QueryRow(`
+		SELECT COUNT(DISTINCT word_id) FROM answer_events
+		WHERE user_id = ? AND answered_at >= ? AND answered_at < ?
+	`, learnerID, from, to).Scan(&report.WordsStudied); err != nil {
+		return nil, fmt.Errorf("failed to count studied words: %w", err)
+	}
+
+	report.Streak, err = s.streak(learnerID, to.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// daySummaries 按天汇总 [from, to) 内的游戏时长与作答情况，没有活动的日期补零
+func (s *Service) daySummaries(learnerID int, from, to time.Time) ([]DaySummary, error) {
+	byDate := map[string]*DaySummary{}
+	var days []DaySummary
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		days = append(days, DaySummary{Date: d.Format(dateLayout)})
+	}
+	for i := range days {
+		byDate[days[i].Date] = &days[i]
+	}
+
+	rows, err := s.db.Query(`
+		SELECT DATE_FORMAT(completed_at, '%Y-%m-%d'), COUNT(*), COALESCE(SUM(time_spent), 0)
+		FROM game_records
+		WHERE user_id = ? AND completed_at >= ? AND completed_at < ?
+		GROUP BY 1
+	`, learnerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily games: %w", err)
+	}
+	for rows.Next() {
+		var date string
+		var games, seconds int
+		if err := rows.Scan(&date, &games, &seconds); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan daily games: %w", err)
+		}
+		if d, ok := byDate[date]; ok {
+			d.Games = games
+			d.Minutes = seconds / 60
+		}
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT DATE_FORMAT(answered_at, '%Y-%m-%d'), COUNT(*), COALESCE(SUM(is_correct), 0)
+		FROM answer_events
+		WHERE user_id = ? AND answered_at >= ? AND answered_at < ?
+		GROUP BY 1
+	`, learnerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily answers: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date string
+		var answers, correct int
+		if err := rows.Scan(&date, &answers, &correct); err != nil {
+			return nil, fmt.Errorf("failed to scan daily answers: %w", err)
+		}
+		if d, ok := byDate[date]; ok {
+			d.Answers = answers
+			d.Correct = correct
+		}
+	}
+	return days, nil
+}
+
+// streak 截至 day 的连续学习天数；当天还没学习时从前一天算起
+func (s *Service) streak(learnerID int, day time.Time) (int, error) {
+	rows, err := s.db.Query(`
+		SELECT DATE_FORMAT(answered_at, '%Y-%m-%d') AS d FROM answer_events
+		WHERE user_id = ? AND answered_at >= ? AND answered_at < ?
+		UNION
+		SELECT DATE_FORMAT(completed_at, '%Y-%m-%d') FROM game_records
+		WHERE user_id = ? AND completed_at >= ? AND completed_at < ?
+		ORDER BY d DESC
+	`, learnerID, common.StartOfDay(day).AddDate(-1, 0, 0), common.StartOfDay(day).AddDate(0, 0, 1),
+		learnerID, common.StartOfDay(day).AddDate(-1, 0, 0), common.StartOfDay(day).AddDate(0, 0, 1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query active days: %w", err)
+	}
+	defer rows.Close()
+
+	active := map[string]bool{}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return 0, fmt.Errorf("failed to scan active day: %w", err)
+		}
+		active[date] = true
+	}
+
+	cursor := common.StartOfDay(day)
+	if !active[cursor.Format(dateLayout)] {
+		cursor = cursor.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for active[cursor.Format(dateLayout)] {
+		streak++
+		cursor = cursor.AddDate(0, 0, -1)
+	}
+	return streak, nil
+}
+
+func (s *Service) checkActiveLink(guardianID int, learnerID int) error {
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM guardian_links WHERE guardian_id = ? AND learner_id = ? AND status = 'active')
+	`, guardianID, learnerID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check guardian link: %w", err)
+	}
+	if !exists {
+		return errors.New("learner is not linked to you")
+	}
+	return nil
+}
+
+func (s *Service) getLink(linkID int) (*Link, error) {
+	links, err := s.listLinks("l.id = ?", linkID)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, errors.New("link not found")
+	}
+	return &links[0], nil
+}
+
+func (s *Service) listLinks(where string, args ...interface{}) ([]Link, error) {
+	rows, err := s.db.Query(`
+		SELECT l.id, l.guardian_id, g.username, l.learner_id, u.username, l.status, l.daily_limit_minutes, l.created_at
+		FROM guardian_links l
+		JOIN users g ON g.id = l.guardian_id
+		JOIN users u ON u.id = l.learner_id
+		WHERE `+where+`
+		ORDER BY l.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query guardian links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		var link Link
+		var limit sql.NullInt64
+		if err := rows.Scan(&link.ID, &link.GuardianID, &link.GuardianName, &link.LearnerID, &link.LearnerName,
+			&link.Status, &limit, &link.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan guardian link: %w", err)
+		}
+		if limit.Valid {
+			minutes := int(limit.Int64)
+			link.DailyLimitMinutes = &minutes
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// lastWeekStart 上一个完整自然周的周一
+func lastWeekStart(now time.Time) time.Time {
+	return common.WeekStart(now).AddDate(0, 0, -7)
+}
@@ -0,0 +1,85 @@
+package homework
+
+import "time"
+
+// Kind 作业类型
+type Kind string
+
+const (
+	KindStudyWords Kind = "study_words" // 学习 target 个单词（可限定分类）
+	KindPlayGames  Kind = "play_games"  // 完成 target 局指定游戏
+	KindReachScore Kind = "reach_score" // 在指定游戏中取得至少 target 分
+)
+
+// Status 学生作业状态
+type Status string
+
+const (
+	StatusNotStarted Status = "not_started"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed" // 按时完成
+	StatusLate       Status = "late"      // 截止后完成
+	StatusOverdue    Status = "overdue"   // 已过截止时间仍未完成
+)
+
+// dueSoonWindow 截止前多久在学生作业列表中标记为即将截止
+const dueSoonWindow = 24 * time.Hour
+
+// AssignmentRequest 布置/修改作业请求
+type AssignmentRequest struct {
+	Title       string    `json:"title" binding:"required,max=200"`
+	Description string    `json:"description"`
+	Kind        Kind      `json:"kind" binding:"required,oneof=study_words play_games reach_score"`
+	GameType    string    `json:"game_type"`
+	Category    string    `json:"category"`
+	MinLevel    int       `json:"min_level" binding:"min=0"`
+	Target      int       `json:"target" binding:"required,min=1"`
+	DueAt       time.Time `json:"due_at" binding:"required"`
+}
+
+// Assignment 作业
+type Assignment struct {
+	ID          int       `json:"id"`
+	ClassID     int       `json:"class_id"`
+	ClassName   string    `json:"class_name"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Kind        Kind      `json:"kind"`
+	GameType    string    `json:"game_type,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	MinLevel    int       `json:"min_level"`
+	Target      int       `json:"target"`
+	StartsAt    time.Time `json:"starts_at"`
+	DueAt       time.Time `json:"due_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// StudentAssignment 学生视角的作业（含本人进度）
+type StudentAssignment struct {
+	Assignment
+	Progress    int        `json:"progress"`
+	Status      Status     `json:"status"`
+	DueSoon     bool       `json:"due_soon"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	RemindedAt  *time.Time `json:"reminded_at,omitempty"` // 教师最近一次催交时间
+}
+
+// StudentResult 教师视角的单个学生作业结果
+type StudentResult struct {
+	StudentID   int        `json:"student_id"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	Progress    int        `json:"progress"`
+	Status      Status     `json:"status"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	RemindedAt  *time.Time `json:"reminded_at,omitempty"`
+}
+
+// AssignmentResults 作业完成情况
+type AssignmentResults struct {
+	Assignment Assignment      `json:"assignment"`
+	Completed  int             `json:"completed"`
+	Late       int             `json:"late"`
+	Pending    int             `json:"pending"`
+	Students   []StudentResult `json:"students"`
+}
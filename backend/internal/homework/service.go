@@ -0,0 +1,524 @@
+package homework
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"linguaforge/internal/classroom"
+	"linguaforge/internal/events"
+	"linguaforge/internal/game"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assignableGameTypes 可以布置为作业的游戏类型
+var assignableGameTypes = []game.GameType{
+	game.GameTypeAdventure, game.GameTypeDefense, game.GameTypeDubbing, game.GameTypeMistakes,
+	game.GameTypeSpelling, game.GameTypeListening, game.GameTypeMatching, game.GameTypeSentence,
+	game.GameTypeDuel, game.GameTypeChallenge,
+}
+
+type Service struct {
+	db      *sql.DB
+	classes *classroom.Service
+}
+
+func NewService(db *sql.DB, classes *classroom.Service) *Service {
+	return &Service{
+		db:      db,
+		classes: classes,
+	}
+}
+
+// Create 给班级布置作业
+func (s *Service) Create(teacherID int, classID int, req *AssignmentRequest) (*Assignment, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	if err := s.classes.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO assignments (class_id, teacher_id, title, description, kind, game_type, category, min_level, target, due_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, classID, teacherID, req.Title, req.Description, req.Kind, nullString(req.GameType), nullString(req.Category),
+		req.MinLevel, req.Target, req.DueAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create assignment: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment ID: %w", err)
+	}
+	return s.getAssignment(int(id))
+}
+
+// Update 修改作业；只有完成条件（类型、目标、游戏、分类、最低难度）变化时才重新判定全部学生的完成情况，
+// 只改标题、说明或截止时间时保留已有的完成时间，不会重复发出完成事件
+func (s *Service) Update(teacherID int, assignmentID int, req *AssignmentRequest) (*Assignment, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+	old, err := s.teacherAssignment(teacherID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE assignments
+		SET title = ?, description = ?, kind = ?, game_type = ?, category = ?, min_level = ?, target = ?, due_at = ?
+		WHERE id = ?
+	`, req.Title, req.Description, req.Kind, nullString(req.GameType), nullString(req.Category),
+		req.MinLevel, req.Target, req.DueAt, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update assignment: %w", err)
+	}
+
+	a, err := s.getAssignment(assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if !goalChanged(old, a) {
+		return a, nil
+	}
+
+	// 完成时间按新目标重新判定
+	if _, err := s.db.Exec("UPDATE assignment_progress SET completed_at = NULL WHERE assignment_id = ?", assignmentID); err != nil {
+		return nil, fmt.Errorf("failed to reset assignment progress: %w", err)
+	}
+	if err := s.refreshClass(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Delete 删除作业
+func (s *Service) Delete(teacherID int, assignmentID int) error {
+	if _, err := s.teacherAssignment(teacherID, assignmentID); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("DELETE FROM assignments WHERE id = ?", assignmentID); err != nil {
+		return fmt.Errorf("failed to delete assignment: %w", err)
+	}
+	return nil
+}
+
+// ListForClass 班级的全部作业（教师）
+func (s *Service) ListForClass(teacherID int, classID int) ([]Assignment, error) {
+	if err := s.classes.CheckTeacher(teacherID, classID); err != nil {
+		return nil, err
+	}
+	return s.queryAssignments("a.class_id = ?", classID)
+}
+
+// ListForStudent 学生所在班级的作业及本人进度，未完成的排在前面
+func (s *Service) ListForStudent(studentID int) ([]StudentAssignment, error) {
+	// 事件异步投递，查看前先刷新一次；失败时仍返回已有进度
+	if err := s.refreshStudent(studentID); err != nil {
+		log.Printf("failed to refresh assignments of user %d: %v", studentID, err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+assignmentColumns+`, COALESCE(p.progress, 0), p.completed_at, p.reminded_at
+		FROM assignments a
+		JOIN classes c ON c.id = a.class_id
+		JOIN class_members m ON m.class_id = a.class_id AND m.student_id = ?
+		LEFT JOIN assignment_progress p ON p.assignment_id = a.id AND p.student_id = ?
+		ORDER BY p.completed_at IS NOT NULL, a.due_at
+	`, studentID, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assignments: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	assignments := []StudentAssignment{}
+	for rows.Next() {
+		var sa StudentAssignment
+		var completed, reminded sql.NullTime
+		dest := append(assignmentDest(&sa.Assignment), &sa.Progress, &completed, &reminded)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan assignment: %w", err)
+		}
+		sa.CompletedAt = timePtr(completed)
+		sa.RemindedAt = timePtr(reminded)
+		sa.Status = studentStatus(sa.Progress, sa.CompletedAt, sa.DueAt, now)
+		sa.DueSoon = sa.CompletedAt == nil && now.Before(sa.DueAt) && sa.DueAt.Sub(now) <= dueSoonWindow
+		assignments = append(assignments, sa)
+	}
+	return assignments, nil
+}
+
+// Results 作业完成情况（教师），返回前重新计算全部学生的进度
+func (s *Service) Results(teacherID int, assignmentID int) (*AssignmentResults, error) {
+	a, err := s.teacherAssignment(teacherID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshClass(a); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT u.id, u.username, COALESCE(u.email, ''), COALESCE(p.progress, 0), p.completed_at, p.reminded_at
+		FROM class_members m
+		JOIN users u ON u.id = m.student_id
+		LEFT JOIN assignment_progress p ON p.assignment_id = ? AND p.student_id = m.student_id
+		WHERE m.class_id = ?
+		ORDER BY u.username
+	`, a.ID, a.ClassID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assignment results: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	results := &AssignmentResults{Assignment: *a, Students: []StudentResult{}}
+	for rows.Next() {
+		var r StudentResult
+		var completed, reminded sql.NullTime
+		if err := rows.Scan(&r.StudentID, &r.Username, &r.Email, &r.Progress, &completed, &reminded); err != nil {
+			return nil, fmt.Errorf("failed to scan assignment result: %w", err)
+		}
+		r.CompletedAt = timePtr(completed)
+		r.RemindedAt = timePtr(reminded)
+		r.Status = studentStatus(r.Progress, r.CompletedAt, a.DueAt, now)
+		switch r.Status {
+		case StatusCompleted:
+			results.Completed++
+		case StatusLate:
+			results.Late++
+		default:
+			results.Pending++
+		}
+		results.Students = append(results.Students, r)
+	}
+	return results, nil
+}
+
+// Remind 催交：给尚未完成的学生记录提醒时间（学生在作业列表中看到），返回提醒人数
+func (s *Service) Remind(teacherID int, assignmentID int) (int, error) {
+	a, err := s.teacherAssignment(teacherID, assignmentID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.refreshClass(a); err != nil {
+		return 0, err
+	}
+
+	// refreshClass 之后每个学生都已有进度记录
+	result, err := s.db.Exec(`
+		UPDATE assignment_progress p
+		JOIN class_members m ON m.class_id = ? AND m.student_id = p.student_id
+		SET p.reminded_at = NOW()
+		WHERE p.assignment_id = ? AND p.completed_at IS NULL
+	`, a.ClassID, a.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remind students: %w", err)
+	}
+	reminded, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to remind students: %w", err)
+	}
+	return int(reminded), nil
+}
+
+// ExportCSV 导出班级全部作业的完成情况
+func (s *Service) ExportCSV(teacherID int, classID int, w io.Writer) error {
+	assignments, err := s.ListForClass(teacherID, classID)
+	if err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"assignment_id", "assignment", "kind", "target", "due_at",
+		"student_id", "username", "email", "progress", "status", "completed_at"})
+	for _, a := range assignments {
+		results, err := s.Results(teacherID, a.ID)
+		if err != nil {
+			return err
+		}
+		for _, r := range results.Students {
+			completed := ""
+			if r.CompletedAt != nil {
+				completed = r.CompletedAt.Format(time.RFC3339)
+			}
+			out.Write([]string{
+				strconv.Itoa(a.ID), a.Title, string(a.Kind), strconv.Itoa(a.Target), a.DueAt.Format(time.RFC3339),
+				strconv.Itoa(r.StudentID), r.Username, r.Email, strconv.Itoa(r.Progress), string(r.Status), completed,
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Subscribe 订阅游戏完成和单词学习事件，更新该学生未完成作业的进度
+func (s *Service) Subscribe(bus *events.Dispatcher) {
+	handler := func(ctx context.Context, e events.Event) error {
+		return s.refreshStudent(e.UserID)
+	}
+	bus.Subscribe(events.GameCompleted, "homework.progress", handler)
+	bus.Subscribe(events.WordReviewed, "homework.progress", handler)
+}
+
+// refreshStudent 重新计算学生全部未完成作业的进度；进度按原始记录重算，重复执行结果不变
+func (s *Service) refreshStudent(studentID int) error {
+	assignments, err := s.queryAssignments(`
+		a.class_id IN (SELECT class_id FROM class_members WHERE student_id = ?)
+		AND a.starts_at <= NOW()
+		AND NOT EXISTS (SELECT 1 FROM assignment_progress p
+		                WHERE p.assignment_id = a.id AND p.student_id = ? AND p.completed_at IS NOT NULL)
+	`, studentID, studentID)
+	if err != nil {
+		return fmt.Errorf("failed to load assignments of user %d: %w", studentID, err)
+	}
+	for i := range assignments {
+		if err := s.refresh(&assignments[i], studentID); err != nil {
+			return fmt.Errorf("failed to refresh assignment %d of user %d: %w", assignments[i].ID, studentID, err)
+		}
+	}
+	return nil
+}
+
+// refreshClass 重新计算班级全部学生的作业进度
+func (s *Service) refreshClass(a *Assignment) error {
+	studentIDs, err := s.classes.StudentIDs(a.ClassID)
+	if err != nil {
+		return err
+	}
+	for _, id := range studentIDs {
+		if err := s.refresh(a, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refresh 根据作业开始后的学习和游戏记录计算学生进度；达到目标时记录完成时间
+// 游戏类作业的完成时间取达标那局游戏的时间，以便准确判断是否逾期
+func (s *Service) refresh(a *Assignment, studentID int) error {
+	var progress int
+	var reachedAt sql.NullTime
+	var err error
+
+	switch a.Kind {
+	case KindStudyWords:
+		from := `
+			FROM user_progress up
+			JOIN words w ON w.id = up.word_id
+			WHERE up.user_id = ? AND up.last_studied >= ?
+		`
+		args := []interface{}{studentID, a.StartsAt}
+		if a.Category != "" {
+			from += " AND w.category = ?"
+			args = append(args, a.Category)
+		}
+		err = s.db.QueryRow("SELECT COUNT(*)"+from, args...).Scan(&progress)
+		if err == nil && progress >= a.Target {
+			// 完成时间取第 target 个单词的学习时间，重算时不会因为当前时间晚于截止时间而变成逾期
+			err = s.db.QueryRow("SELECT up.last_studied"+from+" ORDER BY up.last_studied LIMIT 1 OFFSET ?",
+				append(args, a.Target-1)...).Scan(&reachedAt)
+		}
+
+	case KindPlayGames, KindReachScore:
+		where := "user_id = ? AND completed_at >= ? AND level_reached >= ?"
+		args := []interface{}{studentID, a.StartsAt, a.MinLevel}
+		if a.GameType != "" {
+			where += " AND game_type = ?"
+			args = append(args, a.GameType)
+		}
+		if a.Kind == KindPlayGames {
+			err = s.db.QueryRow("SELECT COUNT(*) FROM game_records WHERE "+where, args...).Scan(&progress)
+			if err == nil && progress >= a.Target {
+				err = s.db.QueryRow(`
+					SELECT completed_at FROM game_records WHERE `+where+`
+					ORDER BY completed_at LIMIT 1 OFFSET ?
+				`, append(args, a.Target-1)...).Scan(&reachedAt)
+			}
+		} else {
+			err = s.db.QueryRow("SELECT COALESCE(MAX(score), 0) FROM game_records WHERE "+where, args...).Scan(&progress)
+			if err == nil && progress >= a.Target {
+				err = s.db.QueryRow("SELECT MIN(completed_at) FROM game_records WHERE "+where+" AND score >= ?",
+					append(args, a.Target)...).Scan(&reachedAt)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compute assignment progress: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO assignment_progress (assignment_id, student_id, progress)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE progress = VALUES(progress)
+	`, a.ID, studentID, progress)
+	if err != nil {
+		return fmt.Errorf("failed to save assignment progress: %w", err)
+	}
+
+	// 首次达标时记录完成时间并发出完成事件
+	if reachedAt.Valid {
+		result, err := tx.Exec(`
+			UPDATE assignment_progress SET completed_at = ?
+			WHERE assignment_id = ? AND student_id = ? AND completed_at IS NULL
+		`, reachedAt.Time, a.ID, studentID)
+		if err != nil {
+			return fmt.Errorf("failed to complete assignment: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			if err := events.Write(tx, events.AssignmentCompleted, studentID, &events.AssignmentCompletedPayload{
+				AssignmentID: a.ID,
+				ClassID:      a.ClassID,
+				Title:        a.Title,
+				Kind:         string(a.Kind),
+				Target:       a.Target,
+				Progress:     progress,
+				DueAt:        a.DueAt,
+				CompletedAt:  reachedAt.Time,
+				Late:         reachedAt.Time.After(a.DueAt),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit assignment progress: %w", err)
+	}
+	return nil
+}
+
+// goalChanged 作业的完成条件是否变化（开始时间不能通过修改接口更改）
+func goalChanged(old, updated *Assignment) bool {
+	return old.Kind != updated.Kind || old.Target != updated.Target || old.GameType != updated.GameType ||
+		old.Category != updated.Category || old.MinLevel != updated.MinLevel || !old.StartsAt.Equal(updated.StartsAt)
+}
+
+// teacherAssignment 获取作业并校验是该班级的教师
+func (s *Service) teacherAssignment(teacherID int, assignmentID int) (*Assignment, error) {
+	a, err := s.getAssignment(assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.classes.CheckTeacher(teacherID, a.ClassID); err != nil {
+		return nil, errors.New("assignment not found")
+	}
+	return a, nil
+}
+
+func (s *Service) getAssignment(assignmentID int) (*Assignment, error) {
+	assignments, err := s.queryAssignments("a.id = ?", assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, errors.New("assignment not found")
+	}
+	return &assignments[0], nil
+}
+
+const assignmentColumns = `a.id, a.class_id, c.name, a.title, COALESCE(a.description, ''), a.kind,
+	COALESCE(a.game_type, ''), COALESCE(a.category, ''), a.min_level, a.target, a.starts_at, a.due_at, a.created_at`
+
+func assignmentDest(a *Assignment) []interface{} {
+	return []interface{}{&a.ID, &a.ClassID, &a.ClassName, &a.Title, &a.Description, &a.Kind,
+		&a.GameType, &a.Category, &a.MinLevel, &a.Target, &a.StartsAt, &a.DueAt, &a.CreatedAt}
+}
+
+func (s *Service) queryAssignments(where string, args ...interface{}) ([]Assignment, error) {
+	rows, err := s.db.Query(`
+		SELECT `+assignmentColumns+`
+		FROM assignments a
+		JOIN classes c ON c.id = a.class_id
+		WHERE `+where+`
+		ORDER BY a.due_at
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []Assignment{}
+	for rows.Next() {
+		var a Assignment
+		if err := rows.Scan(assignmentDest(&a)...); err != nil {
+			return nil, fmt.Errorf("failed to scan assignment: %w", err)
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
+
+// studentStatus 根据进度、完成时间和截止时间判断学生作业状态
+func studentStatus(progress int, completedAt *time.Time, dueAt time.Time, now time.Time) Status {
+	switch {
+	case completedAt != nil && completedAt.After(dueAt):
+		return StatusLate
+	case completedAt != nil:
+		return StatusCompleted
+	case now.After(dueAt):
+		return StatusOverdue
+	case progress > 0:
+		return StatusInProgress
+	default:
+		return StatusNotStarted
+	}
+}
+
+// validateRequest 校验作业目标：游戏类作业的游戏类型必须有效，取得分数类作业必须指定游戏
+func validateRequest(req *AssignmentRequest) error {
+	req.GameType = strings.TrimSpace(req.GameType)
+	req.Category = strings.TrimSpace(req.Category)
+
+	switch req.Kind {
+	case KindStudyWords:
+		req.GameType, req.MinLevel = "", 0
+	case KindReachScore:
+		if req.GameType == "" {
+			return errors.New("game_type is required for reach_score assignments")
+		}
+		fallthrough
+	case KindPlayGames:
+		req.Category = ""
+		if req.GameType != "" && !isAssignableGame(req.GameType) {
+			return fmt.Errorf("invalid game type: %s", req.GameType)
+		}
+	}
+	if !req.DueAt.After(time.Now()) {
+		return errors.New("due_at must be in the future")
+	}
+	return nil
+}
+
+func isAssignableGame(gameType string) bool {
+	for _, t := range assignableGameTypes {
+		if string(t) == gameType {
+			return true
+		}
+	}
+	return false
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -22,14 +22,21 @@ const (
 	LeaderboardTypeAdventure LeaderboardType = "adventure"
 	LeaderboardTypeDefense   LeaderboardType = "defense"
 	LeaderboardTypeDubbing   LeaderboardType = "dubbing"
+	LeaderboardTypeSpelling  LeaderboardType = "spelling"
+	LeaderboardTypeListening LeaderboardType = "listening"
+	LeaderboardTypeMatching  LeaderboardType = "matching"
+	LeaderboardTypeSentence  LeaderboardType = "sentence"
+	LeaderboardTypeDuel      LeaderboardType = "duel"
+	LeaderboardTypeChallenge LeaderboardType = "challenge"
 	LeaderboardTypeWeekly    LeaderboardType = "weekly"
 	LeaderboardTypeMonthly   LeaderboardType = "monthly"
 )
 
 // LeaderboardRequest 排行榜请求
 type LeaderboardRequest struct {
-	Type  LeaderboardType `form:"type"`
-	Limit int             `form:"limit"`
+	Type    LeaderboardType `form:"type"`
+	Limit   int             `form:"limit"`
+	UserIDs []int           `form:"-"` // 限定用户范围（如班级排行榜）
 }
 
 // LeaderboardResponse 排行榜响应
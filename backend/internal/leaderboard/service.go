@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -107,34 +108,81 @@ func (s *Service) GetUserRank(userID int, leaderboardType LeaderboardType) (int,
 	return int(rank) + 1, nil // Redis排名从0开始，转换为从1开始
 }
 
-// queryLeaderboardFromDB 从数据库查询排行榜
+// GetScopedLeaderboard 获取限定用户范围内的排行榜（如班级），不走缓存
+func (s *Service) GetScopedLeaderboard(req *LeaderboardRequest, userIDs []int) (*LeaderboardResponse, error) {
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Type == "" {
+		req.Type = LeaderboardTypeOverall
+	}
+
+	response := &LeaderboardResponse{
+		Type:      req.Type,
+		Entries:   []LeaderboardEntry{},
+		UpdatedAt: time.Now(),
+	}
+	if len(userIDs) == 0 {
+		return response, nil
+	}
+
+	req.UserIDs = userIDs
+	entries, err := s.queryLeaderboardFromDB(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query leaderboard: %w", err)
+	}
+	if entries != nil {
+		response.Entries = entries
+	}
+	response.Total = len(response.Entries)
+	return response, nil
+}
+
+// queryLeaderboardFromDB 从数据库查询排行榜；req.UserIDs 非空时只统计这些用户
 func (s *Service) queryLeaderboardFromDB(req *LeaderboardRequest) ([]LeaderboardEntry, error) {
 	var query string
 	var args []interface{}
 
+	scope, scopeArgs := "", []interface{}{}
+	if len(req.UserIDs) > 0 {
+		scope = "u.id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(req.UserIDs)), ",") + ")"
+		for _, id := range req.UserIDs {
+			scopeArgs = append(scopeArgs, id)
+		}
+	}
+	// andScope 接在已有 WHERE 条件之后，whereScope 用于没有 WHERE 的查询
+	andScope, whereScope := "", ""
+	if scope != "" {
+		andScope, whereScope = " AND "+scope, "WHERE "+scope
+	}
+
 	switch req.Type {
 	case LeaderboardTypeOverall:
 		// 按总经验值排序
 		query = `
 			SELECT u.id, u.username, u.experience, u.level, u.experience
 			FROM users u
+			` + whereScope + `
 			ORDER BY u.experience DESC
 			LIMIT ?
 		`
-		args = []interface{}{req.Limit}
+		args = append(scopeArgs, req.Limit)
 
-	case LeaderboardTypeAdventure, LeaderboardTypeDefense, LeaderboardTypeDubbing:
+	case LeaderboardTypeAdventure, LeaderboardTypeDefense, LeaderboardTypeDubbing, LeaderboardTypeSpelling,
+		LeaderboardTypeListening, LeaderboardTypeMatching, LeaderboardTypeSentence, LeaderboardTypeDuel,
+		LeaderboardTypeChallenge:
 		// 按特定游戏类型的最高分排序
 		query = `
 			SELECT u.id, u.username, MAX(gr.score) as max_score, u.level, u.experience
 			FROM users u
 			JOIN game_records gr ON u.id = gr.user_id
-			WHERE gr.game_type = ?
+			WHERE gr.game_type = ?` + andScope + `
 			GROUP BY u.id, u.username, u.level, u.experience
 			ORDER BY max_score DESC
 			LIMIT ?
 		`
-		args = []interface{}{string(req.Type), req.Limit}
+		args = append([]interface{}{string(req.Type)}, scopeArgs...)
+		args = append(args, req.Limit)
 
 	case LeaderboardTypeWeekly:
 		// 本周排行榜
@@ -142,12 +190,12 @@ func (s *Service) queryLeaderboardFromDB(req *LeaderboardRequest) ([]Leaderboard
 			SELECT u.id, u.username, SUM(gr.score) as total_score, u.level, u.experience
 			FROM users u
 			JOIN game_records gr ON u.id = gr.user_id
-			WHERE gr.completed_at >= DATE_SUB(NOW(), INTERVAL 1 WEEK)
+			WHERE gr.completed_at >= DATE_SUB(NOW(), INTERVAL 1 WEEK)` + andScope + `
 			GROUP BY u.id, u.username, u.level, u.experience
 			ORDER BY total_score DESC
 			LIMIT ?
 		`
-		args = []interface{}{req.Limit}
+		args = append(scopeArgs, req.Limit)
 
 	case LeaderboardTypeMonthly:
 		// 本月排行榜
@@ -155,12 +203,12 @@ func (s *Service) queryLeaderboardFromDB(req *LeaderboardRequest) ([]Leaderboard
 			SELECT u.id, u.username, SUM(gr.score) as total_score, u.level, u.experience
 			FROM users u
 			JOIN game_records gr ON u.id = gr.user_id
-			WHERE gr.completed_at >= DATE_SUB(NOW(), INTERVAL 1 MONTH)
+			WHERE gr.completed_at >= DATE_SUB(NOW(), INTERVAL 1 MONTH)` + andScope + `
 			GROUP BY u.id, u.username, u.level, u.experience
 			ORDER BY total_score DESC
 			LIMIT ?
 		`
-		args = []interface{}{req.Limit}
+		args = append(scopeArgs, req.Limit)
 
 	default:
 		return nil, fmt.Errorf("invalid leaderboard type: %s", req.Type)
@@ -0,0 +1,38 @@
+// Package mail 通过 SMTP 发送纯文本邮件（周报、学习提醒等共用）
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"linguaforge/config"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Enabled 是否配置了 SMTP 发信
+func Enabled(cfg config.NotifierConfig) bool {
+	return cfg.Driver == "smtp" && cfg.SMTPHost != ""
+}
+
+// Send 发送一封 UTF-8 纯文本邮件；主题按 RFC 2047 编码，中文主题在各客户端都能正常显示
+func Send(cfg config.NotifierConfig, to string, subject string, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient address %q", to)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(body)
+
+	var auth smtp.Auth
+	if cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return smtp.SendMail(cfg.SMTPHost+":"+cfg.SMTPPort, auth, cfg.From, []string{to}, msg.Bytes())
+}
@@ -0,0 +1,188 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval 推送连接的心跳间隔，防止代理因空闲断开
+const heartbeatInterval = 25 * time.Second
+
+type Handlers struct {
+	service *Service
+}
+
+func NewHandlers(service *Service) *Handlers {
+	return &Handlers{
+		service: service,
+	}
+}
+
+// List 通知列表
+func (h *Handlers) List(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	unreadOnly := c.Query("unread") == "true"
+
+	response, err := h.service.List(userID.(int), page, limit, unreadOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UnreadCount 未读通知数
+func (h *Handlers) UnreadCount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	count, err := h.service.UnreadCount(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+// MarkRead 标记一条通知为已读
+func (h *Handlers) MarkRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	if err := h.service.MarkRead(userID.(int), notificationID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotificationNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllRead 全部标记为已读
+func (h *Handlers) MarkAllRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	updated, err := h.service.MarkAllRead(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// Stream 通过 Server-Sent Events 实时推送新通知
+// 连接建立时发送 ready 事件（含未读数）；带 Last-Event-ID 重连时先补发断线期间的通知
+func (h *Handlers) Stream(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	lastID, _ := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64)
+	if lastID == 0 {
+		lastID, _ = strconv.ParseInt(c.Query("last_event_id"), 10, 64)
+	}
+
+	// 先注册再补发，避免补发期间产生的通知丢失；重复的由 ID 过滤
+	live, closeStream, err := h.service.hub.open(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	defer closeStream()
+
+	var missed []Notification
+	if lastID > 0 {
+		if missed, err = h.service.since(userID.(int), lastID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	unread, err := h.service.UnreadCount(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: 5000\n\n")
+	writeEvent(w, 0, "ready", gin.H{"unread_count": unread})
+	for i := range missed {
+		writeEvent(w, missed[i].ID, "notification", &missed[i])
+		lastID = missed[i].ID
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case n := <-live:
+			if n.ID <= lastID {
+				continue // 已在补发中发送过
+			}
+			writeEvent(w, n.ID, "notification", n)
+			w.Flush()
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": ping\n\n")
+			w.Flush()
+		}
+	}
+}
+
+// writeEvent 写入一条 SSE 事件；id 为 0 时不设置，避免覆盖客户端记录的 Last-Event-ID
+func writeEvent(w io.Writer, id int64, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if id > 0 {
+		fmt.Fprintf(w, "id: %d\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
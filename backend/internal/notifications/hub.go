@@ -0,0 +1,107 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// channel 各实例共用的 Redis 广播频道
+	channel = "notifications:live"
+	// maxStreamsPerUser 每个用户同时打开的推送连接数
+	maxStreamsPerUser = 5
+	// streamBuffer 每个连接缓冲的通知数，客户端过慢时丢弃，重连后按 Last-Event-ID 补发
+	streamBuffer = 16
+)
+
+var ErrTooManyStreams = errors.New("too many open notification streams")
+
+// hub 管理本实例的推送连接；配置 Redis 时通过发布订阅把通知广播给所有实例
+type hub struct {
+	redis *redis.Client
+
+	mu      sync.Mutex
+	streams map[int]map[chan *Notification]struct{}
+	once    sync.Once
+}
+
+func newHub(redis *redis.Client) *hub {
+	return &hub{
+		redis:   redis,
+		streams: make(map[int]map[chan *Notification]struct{}),
+	}
+}
+
+// open 为用户打开一个推送连接，返回接收通道和关闭函数
+func (h *hub) open(userID int) (<-chan *Notification, func(), error) {
+	if h.redis != nil {
+		// 有连接时才需要接收广播
+		h.once.Do(func() { go h.listen(context.Background()) })
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.streams[userID]) >= maxStreamsPerUser {
+		return nil, nil, ErrTooManyStreams
+	}
+	ch := make(chan *Notification, streamBuffer)
+	if h.streams[userID] == nil {
+		h.streams[userID] = make(map[chan *Notification]struct{})
+	}
+	h.streams[userID][ch] = struct{}{}
+
+	closeFn := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.streams[userID], ch)
+		if len(h.streams[userID]) == 0 {
+			delete(h.streams, userID)
+		}
+	}
+	return ch, closeFn, nil
+}
+
+// publish 广播通知；未配置 Redis 或发布失败时只推送给本实例的连接
+func (h *hub) publish(ctx context.Context, userID int, n *Notification) {
+	if h.redis != nil {
+		data, err := json.Marshal(&message{UserID: userID, Notification: n})
+		if err == nil {
+			if err = h.redis.Publish(ctx, channel, data).Err(); err == nil {
+				return
+			}
+		}
+		log.Printf("notifications: failed to publish notification %d: %v", n.ID, err)
+	}
+	h.deliver(userID, n)
+}
+
+// deliver 推送给本实例上该用户的连接
+func (h *hub) deliver(userID int, n *Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.streams[userID] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+// listen 接收其他实例（包括自身）发布的通知；连接断开后由客户端自动重连
+func (h *hub) listen(ctx context.Context) {
+	sub := h.redis.Subscribe(ctx, channel)
+	defer sub.Close()
+	for msg := range sub.Channel() {
+		var m message
+		if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil || m.Notification == nil {
+			log.Printf("notifications: invalid broadcast message: %v", err)
+			continue
+		}
+		h.deliver(m.UserID, m.Notification)
+	}
+}
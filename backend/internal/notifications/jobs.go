@@ -0,0 +1,11 @@
+package notifications
+
+import (
+	"linguaforge/internal/scheduler"
+	"time"
+)
+
+// RegisterJobs 注册通知相关的定时任务
+func (s *Service) RegisterJobs(jobs *scheduler.Scheduler) {
+	jobs.Register("notifications.prune", "0 4 * * *", "清理90天前的已读通知", 5*time.Minute, s.prune)
+}
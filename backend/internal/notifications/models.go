@@ -0,0 +1,42 @@
+package notifications
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Kind 通知类型
+type Kind string
+
+const (
+	KindLevelUp   Kind = "level_up"              // 升级
+	KindOvertaken Kind = "leaderboard_overtaken" // 在总排行榜前列被其他玩家超越
+	KindChallenge Kind = "challenge_received"    // 收到好友挑战
+	KindReminder  Kind = "study_reminder"        // 学习提醒
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        int64           `json:"id"`
+	Kind      Kind            `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ListResponse 通知分页响应
+type ListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	Page          int            `json:"page"`
+	Limit         int            `json:"limit"`
+	HasMore       bool           `json:"has_more"`
+}
+
+// message 通过 Redis 广播给各实例的通知
+type message struct {
+	UserID       int           `json:"user_id"`
+	Notification *Notification `json:"notification"`
+}
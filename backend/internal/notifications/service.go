@@ -0,0 +1,289 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linguaforge/internal/events"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// listMaxLimit 每页最多返回的通知数
+	listMaxLimit = 50
+	// replayLimit 重连时最多补发的通知数
+	replayLimit = 100
+	// overtakeTopN 只在总排行榜前 N 名内的超越才通知
+	overtakeTopN = 100
+	// overtakeMaxNotified 一局游戏最多通知的被超越人数
+	overtakeMaxNotified = 20
+	// readRetentionDays 已读通知的保留天数
+	readRetentionDays = 90
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type Service struct {
+	db  *sql.DB
+	hub *hub
+}
+
+func NewService(db *sql.DB, redis *redis.Client) *Service {
+	return &Service{
+		db:  db,
+		hub: newHub(redis),
+	}
+}
+
+// Subscribe 订阅需要通知用户的事件
+func (s *Service) Subscribe(bus *events.Dispatcher) {
+	bus.Subscribe(events.LevelUp, "notifications.level_up", s.onLevelUp)
+	bus.Subscribe(events.GameCompleted, "notifications.overtaken", s.onGameCompleted)
+	bus.Subscribe(events.ChallengeSent, "notifications.challenge", s.onChallengeSent)
+}
+
+// Send 保存通知并实时推送；dedupeKey 非空时同一用户同一 key 只通知一次
+func (s *Service) Send(ctx context.Context, userID int, kind Kind, title, body string, data interface{}, dedupeKey string) error {
+	var encoded, key interface{}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification data: %w", err)
+		}
+		encoded = string(raw)
+	}
+	if dedupeKey != "" {
+		key = dedupeKey
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT IGNORE INTO notifications (user_id, kind, title, body, data, dedupe_key) VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, kind, title, body, encoded, key)
+	if err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get notification ID: %w", err)
+	}
+
+	n := &Notification{ID: id, Kind: kind, Title: title, Body: body, CreatedAt: time.Now()}
+	if encoded != nil {
+		n.Data = json.RawMessage(encoded.(string))
+	}
+	s.hub.publish(ctx, userID, n)
+	return nil
+}
+
+// List 通知列表（最新在前）
+func (s *Service) List(userID int, page int, limit int, unreadOnly bool) (*ListResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > listMaxLimit {
+		limit = 20
+	}
+
+	where := "user_id = ?"
+	if unreadOnly {
+		where += " AND read_at IS NULL"
+	}
+	// 多取一条判断是否还有下一页
+	notifications, err := s.query(where+" ORDER BY id DESC LIMIT ? OFFSET ?", userID, limit+1, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.UnreadCount(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListResponse{Notifications: notifications, UnreadCount: unread, Page: page, Limit: limit}
+	if len(notifications) > limit {
+		response.Notifications = notifications[:limit]
+		response.HasMore = true
+	}
+	return response, nil
+}
+
+// UnreadCount 未读通知数
+func (s *Service) UnreadCount(userID int) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead 标记一条通知为已读
+func (s *Service) MarkRead(userID int, notificationID int64) error {
+	var exists bool
+	err := s.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND user_id = ?)", notificationID, userID,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get notification: %w", err)
+	}
+	if !exists {
+		return ErrNotificationNotFound
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE notifications SET read_at = NOW() WHERE id = ? AND user_id = ? AND read_at IS NULL
+	`, notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return nil
+}
+
+// MarkAllRead 全部标记为已读，返回标记的数量
+func (s *Service) MarkAllRead(userID int) (int, error) {
+	result, err := s.db.Exec("UPDATE notifications SET read_at = NOW() WHERE user_id = ? AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// since ID 大于 afterID 的通知（按时间正序），用于断线重连后补发
+func (s *Service) since(userID int, afterID int64) ([]Notification, error) {
+	return s.query("user_id = ? AND id > ? ORDER BY id LIMIT ?", userID, afterID, replayLimit)
+}
+
+func (s *Service) query(where string, args ...interface{}) ([]Notification, error) {
+	rows, err := s.db.Query(`
+		SELECT id, kind, title, body, data, read_at, created_at
+		FROM notifications
+		WHERE `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		var data sql.NullString
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.Kind, &n.Title, &n.Body, &data, &readAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if data.Valid {
+			n.Data = json.RawMessage(data.String)
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+func (s *Service) onLevelUp(ctx context.Context, e events.Event) error {
+	var p events.LevelUpPayload
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	return s.Send(ctx, e.UserID, KindLevelUp,
+		fmt.Sprintf("升级到 %d 级", p.Level), "继续保持，解锁更多关卡和挑战！",
+		map[string]int{"level": p.Level}, fmt.Sprintf("event:%d", e.ID))
+}
+
+// onGameCompleted 本局经验越过的玩家收到被超越通知
+// 以事件中的经验值为准，只比较对方当前的经验值，处理延迟期间对方又反超的情况不再通知
+func (s *Service) onGameCompleted(ctx context.Context, e events.Event) error {
+	var p events.GameCompletedPayload
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	if p.ExperienceGained <= 0 || p.Experience <= 0 {
+		return nil
+	}
+	before := p.Experience - p.ExperienceGained
+
+	var ahead int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE experience > ?", p.Experience).Scan(&ahead); err != nil {
+		return fmt.Errorf("failed to get leaderboard rank: %w", err)
+	}
+	if ahead >= overtakeTopN {
+		return nil
+	}
+
+	var username string
+	if err := s.db.QueryRowContext(ctx, "SELECT username FROM users WHERE id = ?", e.UserID).Scan(&username); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("failed to get username: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM users
+		WHERE experience > ? AND experience < ? AND id <> ?
+		ORDER BY experience DESC
+		LIMIT ?
+	`, before, p.Experience, e.UserID, overtakeMaxNotified)
+	if err != nil {
+		return fmt.Errorf("failed to query overtaken users: %w", err)
+	}
+	var overtaken []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan overtaken user: %w", err)
+		}
+		overtaken = append(overtaken, id)
+	}
+	rows.Close()
+
+	for _, userID := range overtaken {
+		err := s.Send(ctx, userID, KindOvertaken,
+			fmt.Sprintf("%s 在总排行榜上超过了你", username), "玩一局夺回你的名次吧！",
+			map[string]interface{}{"user_id": e.UserID, "username": username, "experience": p.Experience},
+			fmt.Sprintf("event:%d", e.ID))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) onChallengeSent(ctx context.Context, e events.Event) error {
+	var p events.ChallengeSentPayload
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	var username string
+	if err := s.db.QueryRowContext(ctx, "SELECT username FROM users WHERE id = ?", e.UserID).Scan(&username); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("failed to get username: %w", err)
+	}
+	return s.Send(ctx, p.OpponentID, KindChallenge,
+		fmt.Sprintf("%s 向你发起了挑战", username), fmt.Sprintf("对方得了 %d 分，快来应战吧！", p.Score),
+		map[string]interface{}{"challenge_id": p.ChallengeID, "user_id": e.UserID, "username": username, "expires_at": p.ExpiresAt},
+		fmt.Sprintf("event:%d", e.ID))
+}
+
+// prune 删除已读的旧通知
+func (s *Service) prune(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM notifications WHERE read_at IS NOT NULL AND created_at < NOW() - INTERVAL ? DAY
+	`, readRetentionDays)
+	if err != nil {
+		return fmt.Errorf("failed to prune notifications: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package placement
+
+import (
+	"time"
+)
+
+// TestStatus 分级测试状态
+type TestStatus string
+
+const (
+	TestStatusInProgress TestStatus = "in_progress"
+	TestStatusCompleted  TestStatus = "completed"
+)
+
+// Question 分级测试题目：看英文选中文释义
+type Question struct {
+	TestID     int      `json:"test_id"`
+	Number     int      `json:"number"`
+	Total      int      `json:"total"`
+	WordID     int      `json:"word_id"`
+	English    string   `json:"english"`
+	Difficulty int      `json:"difficulty"`
+	Options    []string `json:"options"`
+}
+
+// Result 分级测试结果
+type Result struct {
+	TestID             int        `json:"test_id"`
+	Status             TestStatus `json:"status"`
+	QuestionCount      int        `json:"question_count"`
+	CorrectCount       int        `json:"correct_count"`
+	RecommendedLevel   int        `json:"recommended_level"`
+	VocabularyEstimate int        `json:"vocabulary_estimate"`
+	StartedAt          time.Time  `json:"started_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+}
+
+// AnswerRequest 提交答案请求
+type AnswerRequest struct {
+	TestID int    `json:"test_id" binding:"required"`
+	WordID int    `json:"word_id" binding:"required"`
+	Answer string `json:"answer"`
+}
+
+// AnswerResponse 提交答案响应：测试未结束时返回下一题，结束时返回结果
+type AnswerResponse struct {
+	Correct      bool      `json:"correct"`
+	Completed    bool      `json:"completed"`
+	NextQuestion *Question `json:"next_question,omitempty"`
+	Result       *Result   `json:"result,omitempty"`
+}
@@ -0,0 +1,378 @@
+package placement
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// 每次分级测试的题目数
+const testQuestionCount = 15
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// Start 开始分级测试，从中间难度出第一题；未完成的旧测试作废
+func (s *Service) Start(userID int) (*Question, error) {
+	_, err := s.db.Exec("DELETE FROM placement_tests WHERE user_id = ? AND status = ?", userID, TestStatusInProgress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset placement test: %w", err)
+	}
+
+	maxLevel := s.maxDifficulty()
+	start := startDifficulty(maxLevel)
+
+	result, err := s.db.Exec(`
+		INSERT INTO placement_tests (user_id, status, current_difficulty)
+		VALUES (?, ?, ?)
+	`, userID, TestStatusInProgress, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create placement test: %w", err)
+	}
+	testID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get placement test ID: %w", err)
+	}
+
+	question, err := s.nextQuestion(int(testID), start, 1, maxLevel)
+	if err != nil {
+		return nil, err
+	}
+	if question == nil {
+		return nil, errors.New("no words available for placement test")
+	}
+	return question, nil
+}
+
+// Answer 提交当前题目的答案：答对升一级难度，答错降一级
+// 锁住测试记录后再判题，重复提交同一题时后到的请求会因当前题目已清空而被拒绝
+func (s *Service) Answer(userID int, req *AnswerRequest) (*AnswerResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owner, difficulty, questionCount, correctCount int
+	var status TestStatus
+	var currentWordID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT user_id, status, current_difficulty, current_word_id, question_count, correct_count
+		FROM placement_tests WHERE id = ? FOR UPDATE
+	`, req.TestID).Scan(&owner, &status, &difficulty, &currentWordID, &questionCount, &correctCount)
+	if err != nil || owner != userID {
+		if err == nil || err == sql.ErrNoRows {
+			return nil, errors.New("placement test not found")
+		}
+		return nil, fmt.Errorf("failed to get placement test: %w", err)
+	}
+	if status != TestStatusInProgress {
+		return nil, errors.New("placement test already completed")
+	}
+	if !currentWordID.Valid || int(currentWordID.Int64) != req.WordID {
+		return nil, errors.New("answer does not match current question")
+	}
+
+	var chinese string
+	if err := tx.QueryRow("SELECT chinese FROM words WHERE id = ?", req.WordID).Scan(&chinese); err != nil {
+		return nil, fmt.Errorf("failed to get word: %w", err)
+	}
+	correct := strings.TrimSpace(req.Answer) == chinese
+
+	_, err = tx.Exec(`
+		INSERT INTO placement_answers (test_id, word_id, difficulty_level, is_correct)
+		VALUES (?, ?, ?, ?)
+	`, req.TestID, req.WordID, difficulty, correct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save placement answer: %w", err)
+	}
+
+	questionCount++
+	maxLevel := s.maxDifficulty()
+	if correct {
+		correctCount++
+	}
+	difficulty = nextDifficulty(difficulty, correct, maxLevel)
+
+	_, err = tx.Exec(`
+		UPDATE placement_tests
+		SET question_count = ?, correct_count = ?, current_difficulty = ?, current_word_id = NULL
+		WHERE id = ?
+	`, questionCount, correctCount, difficulty, req.TestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update placement test: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit placement answer: %w", err)
+	}
+
+	response := &AnswerResponse{Correct: correct}
+	if questionCount < testQuestionCount {
+		next, err := s.nextQuestion(req.TestID, difficulty, questionCount+1, maxLevel)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			response.NextQuestion = next
+			return response, nil
+		}
+	}
+
+	// 题目做完（或题库已用尽）：结算
+	result, err := s.finish(userID, req.TestID, maxLevel)
+	if err != nil {
+		return nil, err
+	}
+	response.Completed = true
+	response.Result = result
+	return response, nil
+}
+
+// GetResult 获取最近一次完成的分级测试结果
+func (s *Service) GetResult(userID int) (*Result, error) {
+	result := &Result{}
+	var completedAt sql.NullTime
+	var recommended, estimate sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT id, status, question_count, correct_count, recommended_level, vocabulary_estimate, started_at, completed_at
+		FROM placement_tests
+		WHERE user_id = ? AND status = ?
+		ORDER BY completed_at DESC, id DESC
+		LIMIT 1
+	`, userID, TestStatusCompleted).Scan(
+		&result.TestID, &result.Status, &result.QuestionCount, &result.CorrectCount,
+		&recommended, &estimate, &result.StartedAt, &completedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("placement result not found")
+		}
+		return nil, fmt.Errorf("failed to get placement result: %w", err)
+	}
+	result.RecommendedLevel = int(recommended.Int64)
+	result.VocabularyEstimate = int(estimate.Int64)
+	if completedAt.Valid {
+		result.CompletedAt = &completedAt.Time
+	}
+	return result, nil
+}
+
+// nextQuestion 在指定难度出一道未出过的题；该难度没有可用单词时向相邻难度扩展
+func (s *Service) nextQuestion(testID int, difficulty int, number int, maxLevel int) (*Question, error) {
+	var wordID, level int
+	var english, chinese string
+	candidates := []int{difficulty}
+	for offset := 1; offset < maxLevel; offset++ {
+		candidates = append(candidates, difficulty-offset, difficulty+offset)
+	}
+
+	found := false
+	for _, d := range candidates {
+		if d < 1 || d > maxLevel {
+			continue
+		}
+		err := s.db.QueryRow(`
+			SELECT id, english, chinese, difficulty_level FROM words
+			WHERE difficulty_level = ?
+			  AND id NOT IN (SELECT word_id FROM placement_answers WHERE test_id = ?)
+			ORDER BY RAND() LIMIT 1
+		`, d, testID).Scan(&wordID, &english, &chinese, &level)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get placement word: %w", err)
+		}
+		found = true
+		break
+	}
+	if !found {
+		return nil, nil
+	}
+
+	// 干扰项优先取同难度的其他释义
+	rows, err := s.db.Query(`
+		SELECT chinese FROM words
+		WHERE chinese <> ?
+		GROUP BY chinese
+		ORDER BY MAX(difficulty_level = ?) DESC, RAND()
+		LIMIT 3
+	`, chinese, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get placement options: %w", err)
+	}
+	defer rows.Close()
+
+	options := []string{chinese}
+	for rows.Next() {
+		var option string
+		if err := rows.Scan(&option); err != nil {
+			return nil, fmt.Errorf("failed to scan placement option: %w", err)
+		}
+		options = append(options, option)
+	}
+	rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+
+	_, err = s.db.Exec(`
+		UPDATE placement_tests SET current_word_id = ?, current_difficulty = ? WHERE id = ?
+	`, wordID, level, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update placement test: %w", err)
+	}
+
+	return &Question{
+		TestID:     testID,
+		Number:     number,
+		Total:      testQuestionCount,
+		WordID:     wordID,
+		English:    english,
+		Difficulty: level,
+		Options:    options,
+	}, nil
+}
+
+// finish 结算分级测试，并把推荐难度写回用户
+func (s *Service) finish(userID int, testID int, maxLevel int) (*Result, error) {
+	rows, err := s.db.Query(`
+		SELECT difficulty_level, is_correct FROM placement_answers
+		WHERE test_id = ? ORDER BY id
+	`, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query placement answers: %w", err)
+	}
+	defer rows.Close()
+
+	var levels []int
+	asked := make(map[int]int)
+	correct := make(map[int]int)
+	for rows.Next() {
+		var level int
+		var isCorrect bool
+		if err := rows.Scan(&level, &isCorrect); err != nil {
+			return nil, fmt.Errorf("failed to scan placement answer: %w", err)
+		}
+		levels = append(levels, level)
+		asked[level]++
+		if isCorrect {
+			correct[level]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read placement answers: %w", err)
+	}
+
+	recommended := recommendLevel(levels, maxLevel)
+	estimate, err := s.estimateVocabulary(asked, correct, recommended)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE placement_tests
+		SET status = ?, recommended_level = ?, vocabulary_estimate = ?, completed_at = NOW()
+		WHERE id = ?
+	`, TestStatusCompleted, recommended, estimate, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete placement test: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE users SET preferred_level = ?, vocabulary_estimate = ?, updated_at = NOW()
+		WHERE id = ?
+	`, recommended, estimate, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user level: %w", err)
+	}
+
+	result, err := s.GetResult(userID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// estimateVocabulary 按各难度的正确率估算词库中已掌握的单词数
+func (s *Service) estimateVocabulary(asked, correct map[int]int, recommended int) (int, error) {
+	rows, err := s.db.Query("SELECT difficulty_level, COUNT(*) FROM words GROUP BY difficulty_level")
+	if err != nil {
+		return 0, fmt.Errorf("failed to count words: %w", err)
+	}
+	defer rows.Close()
+
+	wordCounts := make(map[int]int)
+	for rows.Next() {
+		var level, count int
+		if err := rows.Scan(&level, &count); err != nil {
+			return 0, fmt.Errorf("failed to scan word count: %w", err)
+		}
+		wordCounts[level] = count
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read word counts: %w", err)
+	}
+	return vocabularyEstimate(wordCounts, asked, correct, recommended), nil
+}
+
+// vocabularyEstimate 各难度的单词数乘以该难度的正确率再求和
+// 未测到的难度：低于推荐难度视为已掌握，等于推荐难度算一半，高于推荐难度视为未掌握
+func vocabularyEstimate(wordCounts, asked, correct map[int]int, recommended int) int {
+	estimate := 0.0
+	for level, count := range wordCounts {
+		var known float64
+		switch {
+		case asked[level] > 0:
+			known = float64(correct[level]) / float64(asked[level])
+		case level < recommended:
+			known = 1
+		case level == recommended:
+			known = 0.5
+		}
+		estimate += known * float64(count)
+	}
+	return int(math.Round(estimate))
+}
+
+// startDifficulty 第一题从中间难度开始
+func startDifficulty(maxLevel int) int {
+	return (maxLevel + 1) / 2
+}
+
+// nextDifficulty 阶梯法：答对升一级难度，答错降一级，不超出 1~maxLevel
+func nextDifficulty(difficulty int, correct bool, maxLevel int) int {
+	if correct {
+		return min(difficulty+1, maxLevel)
+	}
+	return max(difficulty-1, 1)
+}
+
+// recommendLevel 取后半程题目的平均难度（阶梯法收敛到的难度）
+func recommendLevel(levels []int, maxLevel int) int {
+	if len(levels) == 0 {
+		return 1
+	}
+	tail := levels[len(levels)/2:]
+	sum := 0
+	for _, l := range tail {
+		sum += l
+	}
+	level := int(math.Round(float64(sum) / float64(len(tail))))
+	return min(max(level, 1), maxLevel)
+}
+
+func (s *Service) maxDifficulty() int {
+	maxLevel := 1
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(difficulty_level), 1) FROM words").Scan(&maxLevel); err != nil || maxLevel < 1 {
+		return 1
+	}
+	return maxLevel
+}
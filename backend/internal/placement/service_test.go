@@ -0,0 +1,118 @@
+package placement
+
+import "testing"
+
+// staircase 按 knows 模拟一次完整测试，返回每题的难度
+func staircase(maxLevel int, knows func(level int) bool) []int {
+	levels := make([]int, 0, testQuestionCount)
+	difficulty := startDifficulty(maxLevel)
+	for i := 0; i < testQuestionCount; i++ {
+		levels = append(levels, difficulty)
+		difficulty = nextDifficulty(difficulty, knows(difficulty), maxLevel)
+	}
+	return levels
+}
+
+func TestNextDifficulty(t *testing.T) {
+	tests := []struct {
+		difficulty int
+		correct    bool
+		maxLevel   int
+		want       int
+	}{
+		{3, true, 5, 4},
+		{3, false, 5, 2},
+		{5, true, 5, 5},  // 不超过最高难度
+		{1, false, 5, 1}, // 不低于1级
+		{1, true, 1, 1},
+		{1, false, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := nextDifficulty(tt.difficulty, tt.correct, tt.maxLevel); got != tt.want {
+			t.Errorf("nextDifficulty(%d, %v, %d) = %d, want %d", tt.difficulty, tt.correct, tt.maxLevel, got, tt.want)
+		}
+	}
+}
+
+func TestRecommendLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxLevel int
+		knows    func(level int) bool
+		want     int
+	}{
+		{"all correct", 5, func(int) bool { return true }, 5},
+		{"all wrong", 5, func(int) bool { return false }, 1},
+		{"only the lowest level exists", 1, func(int) bool { return true }, 1},
+		{"only the lowest level exists, all wrong", 1, func(int) bool { return false }, 1},
+		// 在第3、4级之间来回，后半程平均3.5四舍五入
+		{"knows up to level 3", 5, func(l int) bool { return l <= 3 }, 4},
+		{"knows up to level 4 of 6", 6, func(l int) bool { return l <= 4 }, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels := staircase(tt.maxLevel, tt.knows)
+			if got := recommendLevel(levels, tt.maxLevel); got != tt.want {
+				t.Fatalf("recommendLevel(%v, %d) = %d, want %d", levels, tt.maxLevel, got, tt.want)
+			}
+		})
+	}
+
+	if got := recommendLevel(nil, 5); got != 1 {
+		t.Errorf("recommendLevel(nil) = %d, want 1", got)
+	}
+	// 只取后半程：前半程的高难度不影响结果
+	if got := recommendLevel([]int{5, 5, 5, 1, 1, 1}, 5); got != 1 {
+		t.Errorf("recommendLevel ignores the first half: got %d, want 1", got)
+	}
+	// 数据中的难度超过当前最高难度时截断
+	if got := recommendLevel([]int{8, 8}, 5); got != 5 {
+		t.Errorf("recommendLevel clamps to maxLevel: got %d, want 5", got)
+	}
+}
+
+func TestVocabularyEstimate(t *testing.T) {
+	counts := map[int]int{1: 100, 2: 100, 3: 100, 4: 100, 5: 100}
+	tests := []struct {
+		name           string
+		wordCounts     map[int]int
+		asked, correct map[int]int
+		recommended    int
+		want           int
+	}{
+		{
+			// 未测到的1、2级低于推荐难度，视为全部掌握
+			name: "all correct", wordCounts: counts,
+			asked: map[int]int{3: 1, 4: 1, 5: 13}, correct: map[int]int{3: 1, 4: 1, 5: 13},
+			recommended: 5, want: 500,
+		},
+		{
+			name: "all wrong", wordCounts: counts,
+			asked: map[int]int{1: 13, 2: 1, 3: 1}, correct: map[int]int{},
+			recommended: 1, want: 0,
+		},
+		{
+			// 1级视为掌握，2级按正确率，未测到的推荐难度算一半
+			name: "untested recommended level counts half", wordCounts: counts,
+			asked: map[int]int{2: 2}, correct: map[int]int{2: 1},
+			recommended: 3, want: 200,
+		},
+		{
+			name: "only the lowest level", wordCounts: map[int]int{1: 5},
+			asked: map[int]int{1: 3}, correct: map[int]int{1: 1},
+			recommended: 1, want: 2, // 5 × 1/3 四舍五入
+		},
+		{
+			name: "empty word bank", wordCounts: map[int]int{},
+			asked: map[int]int{1: 1}, correct: map[int]int{1: 1},
+			recommended: 1, want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vocabularyEstimate(tt.wordCounts, tt.asked, tt.correct, tt.recommended); got != tt.want {
+				t.Fatalf("vocabularyEstimate = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
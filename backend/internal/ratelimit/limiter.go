@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyFunc 从请求中取出限流维度（如客户端 IP、用户 ID）
+type KeyFunc func(c *gin.Context) string
+
+// PerIP 按客户端 IP 限流（反向代理后需配置 TRUSTED_PROXIES 才能取到真实 IP）
+func PerIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// PerUser 按登录用户限流，需在 AuthMiddleware 之后使用；未登录时退化为按 IP
+func PerUser(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%d", userID.(int))
+	}
+	return PerIP(c)
+}
+
+// Rule 一条令牌桶规则：每个 key 的桶容量为 Burst，每 Per 时间匀速补充 Limit 个令牌
+// 同一 Name 的规则共享配额，可以挂在多个路由组上
+type Rule struct {
+	Name  string
+	Limit int
+	Per   time.Duration
+	Burst int // 为 0 时等于 Limit
+	By    KeyFunc
+}
+
+// tokenBucket 原子地补充并取出一个令牌；时间取 Redis 服务器时间，多实例之间一致
+// 返回 {是否允许, 需要等待的毫秒数, 剩余令牌数}
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, wait, math.floor(tokens)}
+`)
+
+// Limiter 基于 Redis 的令牌桶限流器，多个实例共享配额
+type Limiter struct {
+	redis *redis.Client
+}
+
+func NewLimiter(redis *redis.Client) *Limiter {
+	return &Limiter{
+		redis: redis,
+	}
+}
+
+// Allow 为 key 取一个令牌；不允许时返回需要等待的时间
+func (l *Limiter) Allow(ctx context.Context, rule Rule, key string) (bool, time.Duration, error) {
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.Limit
+	}
+	perMilli := float64(rule.Limit) / float64(rule.Per.Milliseconds())
+
+	result, err := tokenBucket.Run(ctx, l.redis, []string{"ratelimit:" + rule.Name + ":" + key},
+		strconv.FormatFloat(perMilli, 'g', -1, 64), burst).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// Limit 限流中间件，按顺序检查每条规则；超出时返回 429 和 Retry-After
+// Redis 不可用时放行（只写日志），避免限流器故障导致整站不可用
+func (l *Limiter) Limit(rules ...Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, rule := range rules {
+			allowed, wait, err := l.Allow(c.Request.Context(), rule, rule.By(c))
+			if err != nil {
+				log.Printf("ratelimit %s: %v", rule.Name, err)
+				continue
+			}
+			if !allowed {
+				TooManyRequests(c, wait)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// TooManyRequests 写入 429 响应和 Retry-After 头（秒，向上取整）并中止
+func TooManyRequests(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests", "retry_after": seconds})
+}
@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestLimiter 使用进程内 Redis 的限流器；脚本中的 TIME 取 miniredis 设置的时间
+func newTestLimiter(t *testing.T) (*miniredis.Miniredis, *Limiter) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Unix(1700000000, 0))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, NewLimiter(client)
+}
+
+func TestTokenBucket(t *testing.T) {
+	mr, l := newTestLimiter(t)
+	ctx := context.Background()
+	rule := Rule{Name: "test", Limit: 2, Per: time.Minute, Burst: 3}
+
+	// 桶满时可以突发 Burst 次
+	for i := 1; i <= 3; i++ {
+		if allowed, _, err := l.Allow(ctx, rule, "a"); err != nil || !allowed {
+			t.Fatalf("request %d: allowed=%v err=%v", i, allowed, err)
+		}
+	}
+	allowed, wait, err := l.Allow(ctx, rule, "a")
+	if err != nil || allowed {
+		t.Fatalf("request over burst: allowed=%v err=%v", allowed, err)
+	}
+	// 每分钟补充 2 个，攒够 1 个令牌需要 30 秒
+	if wait != 30*time.Second {
+		t.Fatalf("wait = %v, want 30s", wait)
+	}
+
+	// 其他 key 不受影响
+	if allowed, _, _ := l.Allow(ctx, rule, "b"); !allowed {
+		t.Fatal("another key was limited")
+	}
+
+	mr.SetTime(time.Unix(1700000000, 0).Add(29 * time.Second))
+	if allowed, wait, _ := l.Allow(ctx, rule, "a"); allowed || wait != time.Second {
+		t.Fatalf("after 29s: allowed=%v wait=%v, want denied with 1s left", allowed, wait)
+	}
+	mr.SetTime(time.Unix(1700000000, 0).Add(30 * time.Second))
+	if allowed, _, _ := l.Allow(ctx, rule, "a"); !allowed {
+		t.Fatal("after 30s: still limited")
+	}
+	if allowed, _, _ := l.Allow(ctx, rule, "a"); allowed {
+		t.Fatal("refill gave more than one token")
+	}
+
+	// 长时间空闲后最多补满到 Burst
+	mr.SetTime(time.Unix(1700000000, 0).Add(time.Hour))
+	for i := 1; i <= 3; i++ {
+		if allowed, _, _ := l.Allow(ctx, rule, "a"); !allowed {
+			t.Fatalf("after idle, request %d limited", i)
+		}
+	}
+	if allowed, _, _ := l.Allow(ctx, rule, "a"); allowed {
+		t.Fatal("bucket refilled beyond burst")
+	}
+}
+
+func TestBurstDefaultsToLimit(t *testing.T) {
+	_, l := newTestLimiter(t)
+	ctx := context.Background()
+	rule := Rule{Name: "test", Limit: 2, Per: time.Second}
+	for i := 1; i <= 2; i++ {
+		if allowed, _, _ := l.Allow(ctx, rule, "a"); !allowed {
+			t.Fatalf("request %d limited", i)
+		}
+	}
+	if allowed, wait, _ := l.Allow(ctx, rule, "a"); allowed || wait != 500*time.Millisecond {
+		t.Fatalf("third request: allowed=%v wait=%v, want denied with 500ms", allowed, wait)
+	}
+}
+
+func newTestRouter(l *Limiter, rules ...Rule) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", l.Limit(rules...), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func get(router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestLimitMiddleware(t *testing.T) {
+	_, l := newTestLimiter(t)
+	router := newTestRouter(l,
+		Rule{Name: "loose", Limit: 100, Per: time.Minute, By: PerIP},
+		Rule{Name: "strict", Limit: 1, Per: 90 * time.Second, By: PerIP},
+	)
+
+	if w := get(router); w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d", w.Code)
+	}
+	w := get(router)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "90" {
+		t.Fatalf("Retry-After = %q, want 90", got)
+	}
+	if body := w.Body.String(); body != `{"error":"Too many requests","retry_after":90}` {
+		t.Fatalf("body = %s", body)
+	}
+}
+
+func TestTooManyRequestsRoundsUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for wait, want := range map[time.Duration]string{0: "1", 200 * time.Millisecond: "1", 1500 * time.Millisecond: "2", time.Minute: "60"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		TooManyRequests(c, wait)
+		if got := w.Header().Get("Retry-After"); got != want {
+			t.Errorf("TooManyRequests(%v) Retry-After = %q, want %q", wait, got, want)
+		}
+	}
+}
+
+func TestLimitFailsOpen(t *testing.T) {
+	mr, l := newTestLimiter(t)
+	router := newTestRouter(l, Rule{Name: "strict", Limit: 1, Per: time.Minute, By: PerIP})
+	mr.Close()
+
+	// Redis 不可用时放行
+	for i := 1; i <= 3; i++ {
+		if w := get(router); w.Code != http.StatusOK {
+			t.Fatalf("request %d with Redis down: status %d, want 200", i, w.Code)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package reminders
+
+import (
+	"context"
+	"linguaforge/internal/scheduler"
+	"time"
+)
+
+// RegisterJobs 注册学习提醒的定时任务
+func (s *Service) RegisterJobs(jobs *scheduler.Scheduler) {
+	jobs.Register("reminders.send", "*/5 * * * *", "按用户时区发送学习提醒", 4*time.Minute, func(ctx context.Context) error {
+		return s.RunReminders(ctx, time.Now())
+	})
+}
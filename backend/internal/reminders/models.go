@@ -0,0 +1,74 @@
+package reminders
+
+import "time"
+
+// Channel 提醒渠道
+type Channel string
+
+const (
+	ChannelInApp  Channel = "in_app" // 站内通知
+	ChannelEmail  Channel = "email"
+	ChannelWeChat Channel = "wechat" // 微信订阅消息
+)
+
+// Reason 提醒原因
+type Reason string
+
+const (
+	ReasonNotStudied Reason = "not_studied" // 今天还没有学习
+	ReasonDueReviews Reason = "due_reviews" // 今天学过，但还有到期复习的单词
+)
+
+// 没有设置记录的学生使用的默认设置
+const (
+	defaultRemindAt = "19:00"
+	defaultTimezone = "Asia/Shanghai"
+)
+
+// PreferencesRequest 修改提醒设置请求；时间为用户时区的 HH:MM
+type PreferencesRequest struct {
+	Enabled    bool      `json:"enabled"`
+	RemindAt   string    `json:"remind_at" binding:"required"`
+	Timezone   string    `json:"timezone" binding:"required,max=64"`
+	QuietStart string    `json:"quiet_start"` // 与 quiet_end 同时为空表示不设免打扰
+	QuietEnd   string    `json:"quiet_end"`
+	Channels   []Channel `json:"channels" binding:"required,min=1,dive,oneof=in_app email wechat"`
+}
+
+// Preferences 提醒设置
+type Preferences struct {
+	Enabled    bool      `json:"enabled"`
+	RemindAt   string    `json:"remind_at"`
+	Timezone   string    `json:"timezone"`
+	QuietStart string    `json:"quiet_start,omitempty"`
+	QuietEnd   string    `json:"quiet_end,omitempty"`
+	Channels   []Channel `json:"channels"`
+	IsDefault  bool      `json:"is_default"` // 尚未保存过设置
+}
+
+// Reminder 一次提醒记录
+type Reminder struct {
+	ID         int64     `json:"id"`
+	LocalDate  string    `json:"local_date"`
+	Reason     Reason    `json:"reason"`
+	DueReviews int       `json:"due_reviews"`
+	Channels   []Channel `json:"channels"` // 发送成功的渠道
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Recipient 提醒接收人
+type Recipient struct {
+	UserID   int
+	Username string
+	Email    string
+}
+
+// Message 提醒内容
+type Message struct {
+	Reason     Reason
+	DueReviews int
+	LocalDate  string
+	Title      string
+	Body       string
+}
@@ -0,0 +1,65 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"linguaforge/config"
+	"linguaforge/internal/mail"
+	"linguaforge/internal/notifications"
+	"log"
+)
+
+// Sender 某个渠道的提醒发送器，可按部署环境替换实现
+type Sender interface {
+	Send(ctx context.Context, to Recipient, msg *Message) error
+}
+
+// DefaultSenders 站内通知 + 按配置发送邮件（未配置 SMTP 时只写日志）
+// 微信订阅消息尚未接入，不注册该渠道：选择微信的提醒会记录为发送失败，而不是假装已送达
+func DefaultSenders(cfg config.NotifierConfig, notices *notifications.Service) map[Channel]Sender {
+	var email Sender = LogSender{Channel: ChannelEmail}
+	if mail.Enabled(cfg) {
+		email = &SMTPSender{cfg: cfg}
+	}
+	return map[Channel]Sender{
+		ChannelInApp: &InAppSender{notices: notices},
+		ChannelEmail: email,
+	}
+}
+
+// InAppSender 发送站内通知（会通过 SSE 实时推送）
+type InAppSender struct {
+	notices *notifications.Service
+}
+
+func (s *InAppSender) Send(ctx context.Context, to Recipient, msg *Message) error {
+	data := map[string]interface{}{"reason": msg.Reason, "due_reviews": msg.DueReviews}
+	return s.notices.Send(ctx, to.UserID, notifications.KindReminder, msg.Title, msg.Body, data, "reminder:"+msg.LocalDate)
+}
+
+// LogSender 把提醒写入日志（开发环境）
+type LogSender struct {
+	Channel Channel
+}
+
+func (s LogSender) Send(ctx context.Context, to Recipient, msg *Message) error {
+	log.Printf("reminder via %s to user %d (%s): %s", s.Channel, to.UserID, to.Username, msg.Title)
+	return nil
+}
+
+// SMTPSender 通过邮件发送提醒
+type SMTPSender struct {
+	cfg config.NotifierConfig
+}
+
+func (s *SMTPSender) Send(ctx context.Context, to Recipient, msg *Message) error {
+	if to.Email == "" {
+		return fmt.Errorf("user %d has no email", to.UserID)
+	}
+
+	body := fmt.Sprintf("%s，你好：\r\n\r\n%s\r\n", to.Username, msg.Body)
+	if err := mail.Send(s.cfg, to.Email, msg.Title, body); err != nil {
+		return fmt.Errorf("failed to send reminder email: %w", err)
+	}
+	return nil
+}
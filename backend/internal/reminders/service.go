@@ -0,0 +1,414 @@
+package reminders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	_ "time/tzdata" // 内置时区数据，容器镜像中没有 zoneinfo 时也能解析用户时区
+)
+
+const (
+	// sendWindow 到达提醒时间后多久内仍会补发（任务延迟或免打扰结束后）
+	sendWindow = 3 * time.Hour
+	// maxPerWeek 最近 7 天最多提醒次数
+	maxPerWeek = 5
+	// maxIgnored 连续多少次提醒后仍未学习就暂停提醒，直到用户重新学习
+	maxIgnored = 3
+	// historyMaxLimit 提醒记录每次最多返回的条数
+	historyMaxLimit = 100
+)
+
+type Service struct {
+	db      *sql.DB
+	senders map[Channel]Sender
+}
+
+func NewService(db *sql.DB, senders map[Channel]Sender) *Service {
+	return &Service{
+		db:      db,
+		senders: senders,
+	}
+}
+
+// GetPreferences 提醒设置；未保存过时返回默认设置（学生默认开启站内提醒）
+func (s *Service) GetPreferences(userID int) (*Preferences, error) {
+	var p Preferences
+	var remindAt string
+	var quietStart, quietEnd sql.NullString
+	var channels string
+	err := s.db.QueryRow(`
+		SELECT enabled, remind_at, timezone, quiet_start, quiet_end, channels
+		FROM reminder_preferences WHERE user_id = ?
+	`, userID).Scan(&p.Enabled, &remindAt, &p.Timezone, &quietStart, &quietEnd, &channels)
+	if err == sql.ErrNoRows {
+		var role string
+		if err := s.db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("user not found")
+			}
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		return &Preferences{
+			Enabled:   role == "student",
+			RemindAt:  defaultRemindAt,
+			Timezone:  defaultTimezone,
+			Channels:  []Channel{ChannelInApp},
+			IsDefault: true,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminder preferences: %w", err)
+	}
+
+	p.RemindAt = clock(remindAt)
+	if quietStart.Valid && quietEnd.Valid {
+		p.QuietStart, p.QuietEnd = clock(quietStart.String), clock(quietEnd.String)
+	}
+	p.Channels = parseChannels(channels)
+	return &p, nil
+}
+
+// UpdatePreferences 保存提醒设置
+func (s *Service) UpdatePreferences(userID int, req *PreferencesRequest) (*Preferences, error) {
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone: %s", req.Timezone)
+	}
+	remindAt, err := parseClock(req.RemindAt)
+	if err != nil {
+		return nil, errors.New("remind_at must be HH:MM")
+	}
+
+	var quietStart, quietEnd interface{}
+	if req.QuietStart != "" || req.QuietEnd != "" {
+		start, errStart := parseClock(req.QuietStart)
+		end, errEnd := parseClock(req.QuietEnd)
+		if errStart != nil || errEnd != nil {
+			return nil, errors.New("quiet_start and quiet_end must both be HH:MM")
+		}
+		if start == end {
+			return nil, errors.New("quiet hours must not be empty")
+		}
+		if inQuietHours(remindAt, start, end) {
+			return nil, errors.New("remind_at falls within quiet hours")
+		}
+		quietStart, quietEnd = formatClock(start), formatClock(end)
+	}
+
+	var channels []string
+	seen := map[Channel]bool{}
+	for _, ch := range req.Channels {
+		if !seen[ch] {
+			seen[ch] = true
+			channels = append(channels, string(ch))
+		}
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO reminder_preferences (user_id, enabled, remind_at, timezone, quiet_start, quiet_end, channels)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE enabled = VALUES(enabled), remind_at = VALUES(remind_at), timezone = VALUES(timezone),
+		    quiet_start = VALUES(quiet_start), quiet_end = VALUES(quiet_end), channels = VALUES(channels)
+	`, userID, req.Enabled, formatClock(remindAt), req.Timezone, quietStart, quietEnd, strings.Join(channels, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save reminder preferences: %w", err)
+	}
+	return s.GetPreferences(userID)
+}
+
+// History 最近的提醒记录
+func (s *Service) History(userID int, limit int) ([]Reminder, error) {
+	if limit <= 0 || limit > historyMaxLimit {
+		limit = 30
+	}
+	rows, err := s.db.Query(`
+		SELECT id, local_date, reason, due_reviews, channels, COALESCE(error, ''), created_at
+		FROM study_reminders
+		WHERE user_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []Reminder{}
+	for rows.Next() {
+		var r Reminder
+		var localDate time.Time
+		var channels string
+		if err := rows.Scan(&r.ID, &localDate, &r.Reason, &r.DueReviews, &channels, &r.Error, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		r.LocalDate = localDate.Format("2006-01-02")
+		r.Channels = parseChannels(channels)
+		reminders = append(reminders, r)
+	}
+	return reminders, nil
+}
+
+// candidate 开启了提醒的用户
+type candidate struct {
+	recipient  Recipient
+	remindAt   int // 当天第几分钟
+	location   *time.Location
+	quietStart int
+	quietEnd   int
+	hasQuiet   bool
+	channels   []Channel
+}
+
+// RunReminders 提醒已到提醒时间、不在免打扰时段、今天还没学习或有到期复习的用户
+// 每个用户每个本地日期最多提醒一次，重复执行不会重复提醒
+func (s *Service) RunReminders(ctx context.Context, now time.Time) error {
+	candidates, err := s.dueCandidates(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range candidates {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.remind(ctx, &candidates[i], now); err != nil {
+			log.Printf("reminders: failed to remind user %d: %v", candidates[i].recipient.UserID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to remind %d of %d users", failed, len(candidates))
+	}
+	return nil
+}
+
+// dueCandidates 本地时间处于提醒窗口内且不在免打扰时段的用户
+func (s *Service) dueCandidates(ctx context.Context, now time.Time) ([]candidate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id, u.username, COALESCE(u.email, ''),
+		       COALESCE(p.remind_at, ?), COALESCE(p.timezone, ?), p.quiet_start, p.quiet_end, COALESCE(p.channels, ?)
+		FROM users u
+		LEFT JOIN reminder_preferences p ON p.user_id = u.id
+		WHERE (p.user_id IS NULL AND u.role = 'student') OR p.enabled = TRUE
+	`, defaultRemindAt, defaultTimezone, string(ChannelInApp))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reminder preferences: %w", err)
+	}
+	defer rows.Close()
+
+	locations := map[string]*time.Location{}
+	var due []candidate
+	for rows.Next() {
+		var c candidate
+		var remindAt, timezone, channels string
+		var quietStart, quietEnd sql.NullString
+		if err := rows.Scan(&c.recipient.UserID, &c.recipient.Username, &c.recipient.Email,
+			&remindAt, &timezone, &quietStart, &quietEnd, &channels); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder preferences: %w", err)
+		}
+
+		loc, ok := locations[timezone]
+		if !ok {
+			if loc, err = time.LoadLocation(timezone); err != nil {
+				loc = time.UTC
+			}
+			locations[timezone] = loc
+		}
+		c.location = loc
+		if c.remindAt, err = parseClock(clock(remindAt)); err != nil {
+			continue
+		}
+		if quietStart.Valid && quietEnd.Valid {
+			start, errStart := parseClock(clock(quietStart.String))
+			end, errEnd := parseClock(clock(quietEnd.String))
+			c.quietStart, c.quietEnd, c.hasQuiet = start, end, errStart == nil && errEnd == nil
+		}
+		c.channels = parseChannels(channels)
+
+		local := now.In(loc)
+		minute := local.Hour()*60 + local.Minute()
+		if minute < c.remindAt || minute >= c.remindAt+int(sendWindow/time.Minute) {
+			continue
+		}
+		if c.hasQuiet && inQuietHours(minute, c.quietStart, c.quietEnd) {
+			continue
+		}
+		due = append(due, c)
+	}
+	return due, nil
+}
+
+// remind 判断是否需要提醒并发送
+func (s *Service) remind(ctx context.Context, c *candidate, now time.Time) error {
+	local := now.In(c.location)
+	localDate := local.Format("2006-01-02")
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+	userID := c.recipient.UserID
+
+	var reminded bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM study_reminders WHERE user_id = ? AND local_date = ?)
+	`, userID, localDate).Scan(&reminded)
+	if err != nil {
+		return fmt.Errorf("failed to check reminder: %w", err)
+	}
+	if reminded {
+		return nil
+	}
+
+	lastActivity, err := s.lastActivity(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// 到期规则与游戏选词一致：掌握度越高，复习间隔越长（1~32天）
+	var dueReviews int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM user_progress
+		WHERE user_id = ? AND last_studied <= DATE_SUB(NOW(), INTERVAL POW(2, FLOOR(mastery_level * 5)) DAY)
+	`, userID).Scan(&dueReviews)
+	if err != nil {
+		return fmt.Errorf("failed to count due reviews: %w", err)
+	}
+
+	reason := ReasonNotStudied
+	if !lastActivity.Before(dayStart) {
+		if dueReviews == 0 {
+			return nil
+		}
+		reason = ReasonDueReviews
+	}
+
+	// 频率限制：最近 7 天的次数，以及上次学习之后已被忽略的次数
+	var lastWeek, ignored int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(created_at > ?), 0)
+		FROM study_reminders
+		WHERE user_id = ? AND created_at >= ? - INTERVAL 7 DAY
+	`, lastActivity, userID, now).Scan(&lastWeek, &ignored)
+	if err != nil {
+		return fmt.Errorf("failed to count recent reminders: %w", err)
+	}
+	if lastWeek >= maxPerWeek || ignored >= maxIgnored {
+		return nil
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT IGNORE INTO study_reminders (user_id, local_date, reason, due_reviews, channels) VALUES (?, ?, ?, ?, '')
+	`, userID, localDate, reason, dueReviews)
+	if err != nil {
+		return fmt.Errorf("failed to save reminder: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil
+	}
+	reminderID, _ := result.LastInsertId()
+
+	msg := buildMessage(reason, dueReviews, localDate)
+	var sent, failures []string
+	for _, ch := range c.channels {
+		sender, ok := s.senders[ch]
+		if !ok {
+			failures = append(failures, string(ch)+": channel not available")
+			continue
+		}
+		if err := sender.Send(ctx, c.recipient, msg); err != nil {
+			failures = append(failures, string(ch)+": "+err.Error())
+			continue
+		}
+		sent = append(sent, string(ch))
+	}
+
+	var errText interface{}
+	if len(failures) > 0 {
+		errText = strings.Join(failures, "; ")
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE study_reminders SET channels = ?, error = ? WHERE id = ?
+	`, strings.Join(sent, ","), errText, reminderID); err != nil {
+		return fmt.Errorf("failed to update reminder: %w", err)
+	}
+	if len(sent) == 0 && len(failures) > 0 {
+		return errors.New(errText.(string))
+	}
+	return nil
+}
+
+// lastActivity 最近一次学习或游戏的时间
+func (s *Service) lastActivity(ctx context.Context, userID int) (time.Time, error) {
+	var game, answer, study sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT (SELECT MAX(completed_at) FROM game_records WHERE user_id = ?),
+		       (SELECT MAX(answered_at) FROM answer_events WHERE user_id = ?),
+		       (SELECT MAX(last_studied) FROM user_progress WHERE user_id = ?)
+	`, userID, userID, userID).Scan(&game, &answer, &study)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last activity: %w", err)
+	}
+
+	var last time.Time
+	for _, t := range []sql.NullTime{game, answer, study} {
+		if t.Valid && t.Time.After(last) {
+			last = t.Time
+		}
+	}
+	return last, nil
+}
+
+func buildMessage(reason Reason, dueReviews int, localDate string) *Message {
+	msg := &Message{Reason: reason, DueReviews: dueReviews, LocalDate: localDate}
+	switch {
+	case reason == ReasonDueReviews:
+		msg.Title = fmt.Sprintf("有 %d 个单词该复习了", dueReviews)
+		msg.Body = "趁记忆还没淡忘，花几分钟复习一下吧。"
+	case dueReviews > 0:
+		msg.Title = "今天还没有学习哦"
+		msg.Body = fmt.Sprintf("还有 %d 个单词等你复习，来玩一局保持连续学习吧！", dueReviews)
+	default:
+		msg.Title = "今天还没有学习哦"
+		msg.Body = "每天几分钟，词汇量稳步增长，来玩一局吧！"
+	}
+	return msg
+}
+
+// inQuietHours 判断当天第 minute 分钟是否在免打扰时段内，时段可跨零点
+func inQuietHours(minute, start, end int) bool {
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// parseClock 把 HH:MM 解析为当天第几分钟
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// clock 把数据库 TIME 值（HH:MM:SS）截为 HH:MM
+func clock(value string) string {
+	if len(value) > 5 {
+		return value[:5]
+	}
+	return value
+}
+
+func parseChannels(value string) []Channel {
+	channels := []Channel{}
+	for _, ch := range strings.Split(value, ",") {
+		if ch != "" {
+			channels = append(channels, Channel(ch))
+		}
+	}
+	return channels
+}
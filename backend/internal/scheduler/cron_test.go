@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func at(layout string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", layout, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@yearly",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		after string
+		want  string // 空表示一年内不会执行
+	}{
+		{"every 15 minutes", "*/15 * * * *", "2024-10-16 10:07", "2024-10-16 10:15"},
+		{"strictly after", "*/15 * * * *", "2024-10-16 10:15", "2024-10-16 10:30"},
+		{"hour rollover", "0 * * * *", "2024-10-16 10:59", "2024-10-16 11:00"},
+		{"stepped range", "0-30/10 * * * *", "2024-10-16 10:05", "2024-10-16 10:10"},
+		{"stepped range wraps to next hour", "0-30/10 * * * *", "2024-10-16 10:31", "2024-10-16 11:00"},
+		{"start with step", "5/20 * * * *", "2024-10-16 10:26", "2024-10-16 10:45"},
+		{"list", "0 6,18 * * *", "2024-10-16 07:00", "2024-10-16 18:00"},
+		{"month rollover", "0 0 1 * *", "2024-01-31 12:00", "2024-02-01 00:00"},
+		{"year rollover", "30 23 31 12 *", "2024-12-31 23:30", "2025-12-31 23:30"},
+		{"skips short months", "0 0 31 * *", "2024-04-01 00:00", "2024-05-31 00:00"},
+		{"leap day", "0 0 29 2 *", "2023-03-01 00:00", "2024-02-29 00:00"},
+		{"never", "0 0 30 2 *", "2024-01-01 00:00", ""},
+		{"weekday range", "0 9 * * 1-5", "2024-10-18 09:00", "2024-10-21 09:00"},
+		{"7 is sunday", "0 8 * * 7", "2024-10-14 00:00", "2024-10-20 08:00"},
+		{"0 is sunday", "0 8 * * 0", "2024-10-14 00:00", "2024-10-20 08:00"},
+		{"sunday range ending in 7", "0 8 * * 6-7", "2024-10-14 00:00", "2024-10-19 08:00"},
+		{"dom or dow: weekday first", "0 9 13 * 5", "2024-10-01 00:00", "2024-10-04 09:00"},
+		{"dom or dow: day of month first", "0 9 13 * 5", "2024-10-12 00:00", "2024-10-13 09:00"},
+		{"dom with dow star", "0 9 13 * *", "2024-10-01 00:00", "2024-10-13 09:00"},
+		{"dow with dom star", "0 9 * * 5", "2024-10-12 00:00", "2024-10-18 09:00"},
+		{"daily descriptor", "@daily", "2024-10-16 00:00", "2024-10-17 00:00"},
+		{"weekly descriptor is monday", "@weekly", "2024-10-16 12:00", "2024-10-21 00:00"},
+		{"monthly descriptor", "@monthly", "2024-12-15 00:00", "2025-01-01 00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+			}
+			got := s.Next(at(tt.after))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Fatalf("Next(%s) = %s, want zero", tt.after, got)
+				}
+				return
+			}
+			if want := at(tt.want); !got.Equal(want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.after, got.Format("2006-01-02 15:04 Mon"), want.Format("2006-01-02 15:04 Mon"))
+			}
+			if !s.Matches(got) {
+				t.Fatalf("Matches(%s) = false for the time returned by Next", got)
+			}
+		})
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	s, err := ParseSchedule("*/10 9-17 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		at   string
+		want bool
+	}{
+		{"2024-10-16 09:00", true},
+		{"2024-10-16 17:50", true},
+		{"2024-10-16 09:05", false},
+		{"2024-10-16 18:00", false},
+		{"2024-10-19 10:00", false}, // 周六
+	}
+	for _, tt := range tests {
+		if got := s.Matches(at(tt.at)); got != tt.want {
+			t.Errorf("Matches(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handlers struct {
+	scheduler *Scheduler
+}
+
+func NewHandlers(scheduler *Scheduler) *Handlers {
+	return &Handlers{
+		scheduler: scheduler,
+	}
+}
+
+// ListJobs 全部定时任务
+func (h *Handlers) ListJobs(c *gin.Context) {
+	jobs, err := h.scheduler.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// ListRuns 任务执行记录
+func (h *Handlers) ListRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	runs, err := h.scheduler.Runs(c.Param("name"), limit)
+	if err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// TriggerJob 立即执行一次
+func (h *Handlers) TriggerJob(c *gin.Context) {
+	if err := h.scheduler.Trigger(c.Param("name")); err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Job triggered"})
+}
+
+// PauseJob 暂停计划执行
+func (h *Handlers) PauseJob(c *gin.Context) {
+	if err := h.scheduler.SetPaused(c.Param("name"), true); err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job paused"})
+}
+
+// ResumeJob 恢复计划执行
+func (h *Handlers) ResumeJob(c *gin.Context) {
+	if err := h.scheduler.SetPaused(c.Param("name"), false); err != nil {
+		c.JSON(statusFor(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job resumed"})
+}
+
+func statusFor(err error) int {
+	if errors.Is(err, ErrJobNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
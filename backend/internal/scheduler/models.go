@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// JobFunc 任务函数；ctx 在任务超时或服务停止时取消
+type JobFunc func(ctx context.Context) error
+
+// Trigger 触发方式
+type Trigger string
+
+const (
+	TriggerSchedule Trigger = "schedule"
+	TriggerManual   Trigger = "manual"
+)
+
+// RunStatus 执行状态
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunSkipped   RunStatus = "skipped" // 上一次执行尚未结束
+)
+
+// Job 已注册的任务
+type Job struct {
+	Name        string
+	Spec        string
+	Description string
+	Timeout     time.Duration
+	schedule    *Schedule
+	run         JobFunc
+}
+
+// JobInfo 任务及其状态
+type JobInfo struct {
+	Name        string     `json:"name"`
+	Spec        string     `json:"spec"`
+	Description string     `json:"description"`
+	Timeout     string     `json:"timeout"`
+	Paused      bool       `json:"paused"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	LastRun     *Run       `json:"last_run,omitempty"`
+}
+
+// Run 一次执行记录
+type Run struct {
+	ID         int64      `json:"id"`
+	JobName    string     `json:"job_name"`
+	Trigger    Trigger    `json:"trigger"`
+	Instance   string     `json:"instance"`
+	Status     RunStatus  `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs *int       `json:"duration_ms,omitempty"`
+}
@@ -0,0 +1,438 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"linguaforge/internal/common"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultTimeout 未指定超时的任务最长执行时间
+	defaultTimeout = 10 * time.Minute
+	// occurrenceTTL 记录某次计划执行已被认领的时长，需大于各实例之间的时钟偏差
+	occurrenceTTL = time.Hour
+	// runRetentionDays 执行记录保留天数
+	runRetentionDays = 30
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Scheduler 进程内定时任务调度器
+// 每个任务每次计划执行只由一个实例认领（Redis），同一任务不会并发执行；
+// 未配置 Redis 时只在本进程内去重
+type Scheduler struct {
+	db       *sql.DB
+	redis    *redis.Client
+	instance string
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	running map[string]bool // 未配置 Redis 时使用的本地锁
+	ctx     context.Context // Start 传入的上下文，手动触发的任务也随之取消
+}
+
+func New(db *sql.DB, redis *redis.Client) *Scheduler {
+	s := &Scheduler{
+		db:       db,
+		redis:    redis,
+		instance: instanceName(),
+		jobs:     make(map[string]*Job),
+		running:  make(map[string]bool),
+		ctx:      context.Background(),
+	}
+	s.Register("scheduler.prune_runs", "30 3 * * *", "清理过期的任务执行记录", time.Minute, s.pruneRuns)
+	return s
+}
+
+// Register 注册任务；表达式无效或名称重复时 panic（属于编码错误）
+// timeout 为 0 时使用默认超时
+func (s *Scheduler) Register(name, spec, description string, timeout time.Duration, fn JobFunc) {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		panic(fmt.Sprintf("scheduler: job %s: %v", name, err))
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		panic(fmt.Sprintf("scheduler: job %s registered twice", name))
+	}
+	s.jobs[name] = &Job{
+		Name:        name,
+		Spec:        spec,
+		Description: description,
+		Timeout:     timeout,
+		schedule:    schedule,
+		run:         fn,
+	}
+}
+
+// Start 在后台按分钟检查并执行到期的任务，ctx 取消后停止
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute()+1, 0, 0, now.Location())
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			s.tick(ctx, next)
+		}
+	}()
+}
+
+func (s *Scheduler) tick(ctx context.Context, at time.Time) {
+	paused, err := s.pausedJobs()
+	if err != nil {
+		log.Printf("scheduler: %v", err)
+		return
+	}
+	for _, job := range s.sortedJobs() {
+		if paused[job.Name] || !job.schedule.Matches(at) {
+			continue
+		}
+		go s.execute(ctx, job, TriggerSchedule, at)
+	}
+}
+
+// Trigger 立即在后台执行一次任务（暂停的任务也可手动执行）
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	ctx := s.ctx
+	s.mu.Unlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+	go s.execute(ctx, job, TriggerManual, time.Now())
+	return nil
+}
+
+// SetPaused 暂停或恢复任务的计划执行，对所有实例生效
+func (s *Scheduler) SetPaused(name string, paused bool) error {
+	if s.job(name) == nil {
+		return ErrJobNotFound
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO scheduled_jobs (name, paused) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE paused = VALUES(paused)
+	`, name, paused)
+	if err != nil {
+		return fmt.Errorf("failed to update job state: %w", err)
+	}
+	return nil
+}
+
+// List 全部任务及暂停状态、下次执行时间和最近一次执行
+func (s *Scheduler) List() ([]JobInfo, error) {
+	paused, err := s.pausedJobs()
+	if err != nil {
+		return nil, err
+	}
+	lastRuns, err := s.lastRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	jobs := []JobInfo{}
+	for _, job := range s.sortedJobs() {
+		info := JobInfo{
+			Name:        job.Name,
+			Spec:        job.Spec,
+			Description: job.Description,
+			Timeout:     job.Timeout.String(),
+			Paused:      paused[job.Name],
+			LastRun:     lastRuns[job.Name],
+		}
+		if !info.Paused {
+			if next := job.schedule.Next(now); !next.IsZero() {
+				info.NextRunAt = &next
+			}
+		}
+		jobs = append(jobs, info)
+	}
+	return jobs, nil
+}
+
+// Runs 任务的执行记录（最新在前）
+func (s *Scheduler) Runs(name string, limit int) ([]Run, error) {
+	if s.job(name) == nil {
+		return nil, ErrJobNotFound
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, job_name, trigger_type, instance, status, COALESCE(error, ''), started_at, finished_at, duration_ms
+		FROM job_runs
+		WHERE job_name = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []Run{}
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+// execute 认领并执行一次任务，记录执行结果
+func (s *Scheduler) execute(ctx context.Context, job *Job, trigger Trigger, at time.Time) {
+	if trigger == TriggerSchedule {
+		claimed, err := s.claimOccurrence(ctx, job, at)
+		if err != nil {
+			log.Printf("scheduler: job %s: %v", job.Name, err)
+			return
+		}
+		if !claimed {
+			return // 其他实例已执行
+		}
+	}
+
+	release, locked, err := s.lock(ctx, job)
+	if err != nil {
+		log.Printf("scheduler: job %s: %v", job.Name, err)
+		return
+	}
+	if !locked {
+		s.recordSkipped(job, trigger)
+		return
+	}
+	defer release()
+
+	started := time.Now()
+	runID, err := s.startRun(job, trigger)
+	if err != nil {
+		log.Printf("scheduler: job %s: %v", job.Name, err)
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	runErr := safeRun(jobCtx, job.run)
+	cancel()
+
+	if runErr != nil {
+		log.Printf("scheduler: job %s failed: %v", job.Name, runErr)
+	}
+	if runID != 0 {
+		s.finishRun(runID, started, runErr)
+	}
+}
+
+// claimOccurrence 认领某一分钟的计划执行，保证多个实例中只有一个执行
+func (s *Scheduler) claimOccurrence(ctx context.Context, job *Job, at time.Time) (bool, error) {
+	if s.redis == nil {
+		return true, nil
+	}
+	key := fmt.Sprintf("scheduler:occurrence:%s:%d", job.Name, at.Unix())
+	ok, err := s.redis.SetNX(ctx, key, s.instance, occurrenceTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim occurrence: %w", err)
+	}
+	return ok, nil
+}
+
+// lock 防止同一任务并发执行（上一次尚未结束或与手动触发重叠）
+func (s *Scheduler) lock(ctx context.Context, job *Job) (func(), bool, error) {
+	if s.redis == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.running[job.Name] {
+			return nil, false, nil
+		}
+		s.running[job.Name] = true
+		return func() {
+			s.mu.Lock()
+			delete(s.running, job.Name)
+			s.mu.Unlock()
+		}, true, nil
+	}
+
+	key := "scheduler:lock:" + job.Name
+	token := common.RandomToken(16)
+	// 锁的有效期略长于任务超时，实例崩溃后锁会自动过期
+	ok, err := s.redis.SetNX(ctx, key, token, job.Timeout+time.Minute).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	return func() {
+		common.ReleaseLock(context.Background(), s.redis, key, token)
+	}, true, nil
+}
+
+func (s *Scheduler) startRun(job *Job, trigger Trigger) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO job_runs (job_name, trigger_type, instance, status) VALUES (?, ?, ?, ?)
+	`, job.Name, trigger, s.instance, RunRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record job run: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+func (s *Scheduler) finishRun(runID int64, started time.Time, runErr error) {
+	status := RunSucceeded
+	var errText interface{}
+	if runErr != nil {
+		status = RunFailed
+		errText = runErr.Error()
+	}
+	_, err := s.db.Exec(`
+		UPDATE job_runs SET status = ?, error = ?, finished_at = NOW(3), duration_ms = ? WHERE id = ?
+	`, status, errText, time.Since(started).Milliseconds(), runID)
+	if err != nil {
+		log.Printf("scheduler: failed to finish job run %d: %v", runID, err)
+	}
+}
+
+func (s *Scheduler) recordSkipped(job *Job, trigger Trigger) {
+	_, err := s.db.Exec(`
+		INSERT INTO job_runs (job_name, trigger_type, instance, status, error, finished_at, duration_ms)
+		VALUES (?, ?, ?, ?, ?, NOW(3), 0)
+	`, job.Name, trigger, s.instance, RunSkipped, "previous run still in progress")
+	if err != nil {
+		log.Printf("scheduler: failed to record skipped run of %s: %v", job.Name, err)
+	}
+}
+
+// pruneRuns 删除过期的执行记录，并把长时间停留在执行中的记录（实例崩溃）标记为失败
+func (s *Scheduler) pruneRuns(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE job_runs SET status = ?, error = 'interrupted', finished_at = NOW(3)
+		WHERE status = ? AND started_at < NOW() - INTERVAL 1 DAY
+	`, RunFailed, RunRunning); err != nil {
+		return fmt.Errorf("failed to mark interrupted runs: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM job_runs WHERE started_at < NOW() - INTERVAL ? DAY
+	`, runRetentionDays); err != nil {
+		return fmt.Errorf("failed to prune job runs: %w", err)
+	}
+	return nil
+}
+
+func (s *Scheduler) pausedJobs() (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT name FROM scheduled_jobs WHERE paused = TRUE")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paused jobs: %w", err)
+	}
+	defer rows.Close()
+
+	paused := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan paused job: %w", err)
+		}
+		paused[name] = true
+	}
+	return paused, nil
+}
+
+func (s *Scheduler) lastRuns() (map[string]*Run, error) {
+	rows, err := s.db.Query(`
+		SELECT id, job_name, trigger_type, instance, status, COALESCE(error, ''), started_at, finished_at, duration_ms
+		FROM job_runs
+		WHERE id IN (SELECT MAX(id) FROM job_runs GROUP BY job_name)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := map[string]*Run{}
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs[run.JobName] = run
+	}
+	return runs, nil
+}
+
+func scanRun(rows *sql.Rows) (*Run, error) {
+	var run Run
+	var finishedAt sql.NullTime
+	var duration sql.NullInt64
+	if err := rows.Scan(&run.ID, &run.JobName, &run.Trigger, &run.Instance, &run.Status, &run.Error,
+		&run.StartedAt, &finishedAt, &duration); err != nil {
+		return nil, fmt.Errorf("failed to scan job run: %w", err)
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	if duration.Valid {
+		ms := int(duration.Int64)
+		run.DurationMs = &ms
+	}
+	return &run, nil
+}
+
+func (s *Scheduler) job(name string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[name]
+}
+
+func (s *Scheduler) sortedJobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// safeRun 执行任务并把 panic 转为错误，避免拖垮整个进程
+func safeRun(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return host + "-" + common.RandomToken(6)[:6]
+}
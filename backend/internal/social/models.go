@@ -0,0 +1,98 @@
+package social
+
+import "time"
+
+// Relationship 查看者与对方的关系
+type Relationship string
+
+const (
+	RelationshipNone            Relationship = "none"
+	RelationshipFriend          Relationship = "friend"
+	RelationshipRequestSent     Relationship = "request_sent"     // 查看者已发出好友申请
+	RelationshipRequestReceived Relationship = "request_received" // 对方已发来好友申请
+	RelationshipBlocked         Relationship = "blocked"          // 查看者已拉黑对方
+)
+
+// Visibility 动态可见范围
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"  // 好友和关注者可见
+	VisibilityFriends Visibility = "friends" // 仅好友可见
+	VisibilityPrivate Visibility = "private" // 不出现在任何人的动态中
+)
+
+// RequestStatus 好友申请状态
+type RequestStatus string
+
+const (
+	RequestPending  RequestStatus = "pending"
+	RequestAccepted RequestStatus = "accepted"
+	RequestDeclined RequestStatus = "declined"
+)
+
+// ActivityKind 动态类型
+type ActivityKind string
+
+const (
+	ActivityGameCompleted ActivityKind = "game_completed"
+	ActivityAchievement   ActivityKind = "achievement"
+	ActivityLevelUp       ActivityKind = "level_up"
+)
+
+// UserSummary 用户搜索结果与好友/关注列表项
+type UserSummary struct {
+	ID           int          `json:"id"`
+	Username     string       `json:"username"`
+	Level        int          `json:"level"`
+	Relationship Relationship `json:"relationship"`
+	Following    bool         `json:"following"`
+	Since        *time.Time   `json:"since,omitempty"` // 成为好友或关注的时间
+}
+
+// TargetRequest 指定目标用户，可用ID或用户名
+type TargetRequest struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// FriendRequest 好友申请
+type FriendRequest struct {
+	ID           int           `json:"id"`
+	SenderID     int           `json:"sender_id"`
+	SenderName   string        `json:"sender_name"`
+	ReceiverID   int           `json:"receiver_id"`
+	ReceiverName string        `json:"receiver_name"`
+	Status       RequestStatus `json:"status"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// RespondRequest 处理好友申请
+type RespondRequest struct {
+	Accept bool `json:"accept"`
+}
+
+// PrivacyRequest 更新动态可见范围
+type PrivacyRequest struct {
+	ActivityVisibility Visibility `json:"activity_visibility" binding:"required,oneof=public friends private"`
+}
+
+// Activity 动态条目
+type Activity struct {
+	Kind       ActivityKind `json:"kind"`
+	UserID     int          `json:"user_id"`
+	Username   string       `json:"username"`
+	GameType   string       `json:"game_type,omitempty"`
+	Score      int          `json:"score,omitempty"`
+	Title      string       `json:"title,omitempty"` // 成就名称
+	Level      int          `json:"level,omitempty"` // 升到的等级
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// FeedResponse 动态分页响应
+type FeedResponse struct {
+	Activities []Activity `json:"activities"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	HasMore    bool       `json:"has_more"`
+}
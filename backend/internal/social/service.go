@@ -0,0 +1,561 @@
+package social
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	searchMinLength = 2
+	feedMaxLimit    = 50
+)
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// SearchUsers 按用户名前缀搜索用户；拉黑了查看者的用户不会出现在结果中
+func (s *Service) SearchUsers(viewerID int, query string, limit int) ([]UserSummary, error) {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < searchMinLength {
+		return nil, fmt.Errorf("query must be at least %d characters", searchMinLength)
+	}
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	rows, err := s.db.Query(`
+		SELECT u.id, u.username, u.level,
+		       EXISTS(SELECT 1 FROM friendships WHERE user_id = ? AND friend_id = u.id),
+		       EXISTS(SELECT 1 FROM friend_requests WHERE sender_id = ? AND receiver_id = u.id AND status = 'pending'),
+		       EXISTS(SELECT 1 FROM friend_requests WHERE sender_id = u.id AND receiver_id = ? AND status = 'pending'),
+		       EXISTS(SELECT 1 FROM user_blocks WHERE blocker_id = ? AND blocked_id = u.id),
+		       EXISTS(SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = u.id)
+		FROM users u
+		WHERE u.username LIKE ? AND u.id <> ?
+		  AND NOT EXISTS(SELECT 1 FROM user_blocks WHERE blocker_id = u.id AND blocked_id = ?)
+		ORDER BY u.username
+		LIMIT ?
+	`, viewerID, viewerID, viewerID, viewerID, viewerID, escapeLike(query)+"%", viewerID, viewerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		var u UserSummary
+		var friend, sent, received, blocked bool
+		if err := rows.Scan(&u.ID, &u.Username, &u.Level, &friend, &sent, &received, &blocked, &u.Following); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		switch {
+		case blocked:
+			u.Relationship = RelationshipBlocked
+		case friend:
+			u.Relationship = RelationshipFriend
+		case sent:
+			u.Relationship = RelationshipRequestSent
+		case received:
+			u.Relationship = RelationshipRequestReceived
+		default:
+			u.Relationship = RelationshipNone
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// SendFriendRequest 发送好友申请；对方已向自己发过申请时直接成为好友
+func (s *Service) SendFriendRequest(userID int, req *TargetRequest) (*FriendRequest, error) {
+	targetID, err := s.resolveTarget(req)
+	if err != nil {
+		return nil, err
+	}
+	if targetID == userID {
+		return nil, errors.New("cannot add yourself as a friend")
+	}
+	if err := s.CheckNotBlocked(userID, targetID); err != nil {
+		return nil, err
+	}
+	friends, err := s.IsFriend(userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if friends {
+		return nil, errors.New("already friends")
+	}
+
+	var reverseID int
+	err = s.db.QueryRow(`
+		SELECT id FROM friend_requests WHERE sender_id = ? AND receiver_id = ? AND status = 'pending'
+	`, targetID, userID).Scan(&reverseID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get friend request: %w", err)
+	}
+	if reverseID > 0 {
+		if err := s.RespondFriendRequest(userID, reverseID, true); err != nil {
+			return nil, err
+		}
+		return s.getRequest(reverseID)
+	}
+
+	// 之前被拒绝或解除好友后可以重新申请
+	_, err = s.db.Exec(`
+		INSERT INTO friend_requests (sender_id, receiver_id, status) VALUES (?, ?, 'pending')
+		ON DUPLICATE KEY UPDATE status = 'pending', created_at = NOW(), responded_at = NULL
+	`, userID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send friend request: %w", err)
+	}
+
+	var requestID int
+	err = s.db.QueryRow(`
+		SELECT id FROM friend_requests WHERE sender_id = ? AND receiver_id = ?
+	`, userID, targetID).Scan(&requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friend request: %w", err)
+	}
+	return s.getRequest(requestID)
+}
+
+// RespondFriendRequest 接受或拒绝收到的好友申请
+func (s *Service) RespondFriendRequest(userID int, requestID int, accept bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var senderID int
+	var status RequestStatus
+	err = tx.QueryRow(`
+		SELECT sender_id, status FROM friend_requests WHERE id = ? AND receiver_id = ? FOR UPDATE
+	`, requestID, userID).Scan(&senderID, &status)
+	if err == sql.ErrNoRows {
+		return errors.New("friend request not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get friend request: %w", err)
+	}
+	if status != RequestPending {
+		return errors.New("friend request already handled")
+	}
+
+	status = RequestDeclined
+	if accept {
+		status = RequestAccepted
+		_, err = tx.Exec(`
+			INSERT IGNORE INTO friendships (user_id, friend_id) VALUES (?, ?), (?, ?)
+		`, userID, senderID, senderID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to add friend: %w", err)
+		}
+	}
+	_, err = tx.Exec(`
+		UPDATE friend_requests SET status = ?, responded_at = NOW() WHERE id = ?
+	`, status, requestID)
+	if err != nil {
+		return fmt.Errorf("failed to update friend request: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit friend request: %w", err)
+	}
+	return nil
+}
+
+// ListFriendRequests 待处理的好友申请，incoming 为收到的，outgoing 为发出的
+func (s *Service) ListFriendRequests(userID int, incoming bool) ([]FriendRequest, error) {
+	column := "r.sender_id"
+	if incoming {
+		column = "r.receiver_id"
+	}
+	rows, err := s.db.Query(`
+		SELECT r.id, r.sender_id, su.username, r.receiver_id, ru.username, r.status, r.created_at
+		FROM friend_requests r
+		JOIN users su ON su.id = r.sender_id
+		JOIN users ru ON ru.id = r.receiver_id
+		WHERE `+column+` = ? AND r.status = 'pending'
+		ORDER BY r.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query friend requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []FriendRequest{}
+	for rows.Next() {
+		var r FriendRequest
+		if err := rows.Scan(&r.ID, &r.SenderID, &r.SenderName, &r.ReceiverID, &r.ReceiverName, &r.Status, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan friend request: %w", err)
+		}
+		requests = append(requests, r)
+	}
+	return requests, nil
+}
+
+// ListFriends 好友列表
+func (s *Service) ListFriends(userID int) ([]UserSummary, error) {
+	return s.listUsers(`
+		SELECT u.id, u.username, u.level, f.created_at,
+		       EXISTS(SELECT 1 FROM follows WHERE follower_id = f.user_id AND followee_id = u.id), TRUE
+		FROM friendships f
+		JOIN users u ON u.id = f.friend_id
+		WHERE f.user_id = ?
+		ORDER BY u.username
+	`, userID)
+}
+
+// RemoveFriend 解除好友关系
+func (s *Service) RemoveFriend(userID int, friendID int) error {
+	result, err := s.db.Exec(`
+		DELETE FROM friendships WHERE (user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)
+	`, userID, friendID, friendID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove friend: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("friend not found")
+	}
+	return nil
+}
+
+// IsFriend 两个用户是否为好友
+func (s *Service) IsFriend(userID int, otherID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM friendships WHERE user_id = ? AND friend_id = ?)
+	`, userID, otherID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check friendship: %w", err)
+	}
+	return exists, nil
+}
+
+// Follow 关注用户（单向，无需对方同意）
+func (s *Service) Follow(userID int, req *TargetRequest) error {
+	targetID, err := s.resolveTarget(req)
+	if err != nil {
+		return err
+	}
+	if targetID == userID {
+		return errors.New("cannot follow yourself")
+	}
+	if err := s.CheckNotBlocked(userID, targetID); err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT IGNORE INTO follows (follower_id, followee_id) VALUES (?, ?)", userID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to follow user: %w", err)
+	}
+	return nil
+}
+
+// Unfollow 取消关注
+func (s *Service) Unfollow(userID int, targetID int) error {
+	result, err := s.db.Exec("DELETE FROM follows WHERE follower_id = ? AND followee_id = ?", userID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("not following this user")
+	}
+	return nil
+}
+
+// ListFollowing 我关注的用户
+func (s *Service) ListFollowing(userID int) ([]UserSummary, error) {
+	return s.listUsers(`
+		SELECT u.id, u.username, u.level, fo.created_at, TRUE,
+		       EXISTS(SELECT 1 FROM friendships WHERE user_id = fo.follower_id AND friend_id = u.id)
+		FROM follows fo
+		JOIN users u ON u.id = fo.followee_id
+		WHERE fo.follower_id = ?
+		ORDER BY fo.created_at DESC
+	`, userID)
+}
+
+// ListFollowers 关注我的用户
+func (s *Service) ListFollowers(userID int) ([]UserSummary, error) {
+	return s.listUsers(`
+		SELECT u.id, u.username, u.level, fo.created_at,
+		       EXISTS(SELECT 1 FROM follows WHERE follower_id = fo.followee_id AND followee_id = u.id),
+		       EXISTS(SELECT 1 FROM friendships WHERE user_id = fo.followee_id AND friend_id = u.id)
+		FROM follows fo
+		JOIN users u ON u.id = fo.follower_id
+		WHERE fo.followee_id = ?
+		ORDER BY fo.created_at DESC
+	`, userID)
+}
+
+// Block 拉黑用户：同时解除好友、撤销双方的好友申请和关注
+func (s *Service) Block(userID int, req *TargetRequest) error {
+	targetID, err := s.resolveTarget(req)
+	if err != nil {
+		return err
+	}
+	if targetID == userID {
+		return errors.New("cannot block yourself")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"INSERT IGNORE INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)",
+		"DELETE FROM friendships WHERE (user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)",
+		"DELETE FROM friend_requests WHERE (sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+		"DELETE FROM follows WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)",
+	}
+	for i, stmt := range statements {
+		args := []interface{}{userID, targetID}
+		if i > 0 {
+			args = append(args, targetID, userID)
+		}
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return fmt.Errorf("failed to block user: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit block: %w", err)
+	}
+	return nil
+}
+
+// Unblock 取消拉黑
+func (s *Service) Unblock(userID int, targetID int) error {
+	result, err := s.db.Exec("DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?", userID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("user not blocked")
+	}
+	return nil
+}
+
+// ListBlocked 我拉黑的用户
+func (s *Service) ListBlocked(userID int) ([]UserSummary, error) {
+	users, err := s.listUsers(`
+		SELECT u.id, u.username, u.level, b.created_at, FALSE, FALSE
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = ?
+		ORDER BY b.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].Relationship = RelationshipBlocked
+	}
+	return users, nil
+}
+
+// UpdatePrivacy 更新动态可见范围
+func (s *Service) UpdatePrivacy(userID int, visibility Visibility) error {
+	_, err := s.db.Exec(`
+		UPDATE users SET activity_visibility = ?, updated_at = NOW() WHERE id = ?
+	`, visibility, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update privacy: %w", err)
+	}
+	return nil
+}
+
+// GetFeed 好友与关注者的动态（完成游戏、获得成就、升级），按时间倒序分页
+// 好友的动态在对方可见范围为 public 或 friends 时可见；仅关注的用户须为 public
+func (s *Service) GetFeed(userID int, page int, limit int) (*FeedResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > feedMaxLimit {
+		limit = 20
+	}
+	response := &FeedResponse{Activities: []Activity{}, Page: page, Limit: limit}
+
+	actors, err := s.visibleActors(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(actors) == 0 {
+		return response, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(actors)), ",")
+	var args []interface{}
+	for i := 0; i < 3; i++ {
+		for _, id := range actors {
+			args = append(args, id)
+		}
+	}
+	// 多取一条判断是否还有下一页
+	args = append(args, limit+1, (page-1)*limit)
+
+	rows, err := s.db.Query(`
+		SELECT a.kind, a.user_id, u.username, a.game_type, a.score, a.title, a.level, a.occurred_at
+		FROM (
+			SELECT 'game_completed' AS kind, gr.user_id, gr.game_type AS game_type, gr.score AS score,
+			       NULL AS title, NULL AS level, gr.completed_at AS occurred_at
+			FROM game_records gr WHERE gr.user_id IN (`+placeholders+`)
+			UNION ALL
+			SELECT 'achievement', ua.user_id, NULL, NULL, ua.achievement_name, NULL, ua.earned_at
+			FROM user_achievements ua WHERE ua.user_id IN (`+placeholders+`)
+			UNION ALL
+			SELECT 'level_up', lu.user_id, NULL, NULL, NULL, lu.level, lu.reached_at
+			FROM user_level_ups lu WHERE lu.user_id IN (`+placeholders+`)
+		) a
+		JOIN users u ON u.id = a.user_id
+		ORDER BY a.occurred_at DESC
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity feed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a Activity
+		var gameType, title sql.NullString
+		var score, level sql.NullInt64
+		if err := rows.Scan(&a.Kind, &a.UserID, &a.Username, &gameType, &score, &title, &level, &a.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		a.GameType = gameType.String
+		a.Score = int(score.Int64)
+		a.Title = title.String
+		a.Level = int(level.Int64)
+		response.Activities = append(response.Activities, a)
+	}
+	if len(response.Activities) > limit {
+		response.Activities = response.Activities[:limit]
+		response.HasMore = true
+	}
+	return response, nil
+}
+
+// visibleActors 动态对查看者可见的用户
+func (s *Service) visibleActors(userID int) ([]int, error) {
+	rows, err := s.db.Query(`
+		SELECT f.friend_id FROM friendships f
+		JOIN users u ON u.id = f.friend_id
+		WHERE f.user_id = ? AND u.activity_visibility IN ('public', 'friends')
+		UNION
+		SELECT fo.followee_id FROM follows fo
+		JOIN users u ON u.id = fo.followee_id
+		WHERE fo.follower_id = ? AND u.activity_visibility = 'public'
+	`, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan feed user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// listUsers 查询用户列表，查询需返回 id, username, level, since, following, friend
+func (s *Service) listUsers(query string, args ...interface{}) ([]UserSummary, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		var u UserSummary
+		var since sql.NullTime
+		var friend bool
+		if err := rows.Scan(&u.ID, &u.Username, &u.Level, &since, &u.Following, &friend); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		u.Relationship = RelationshipNone
+		if friend {
+			u.Relationship = RelationshipFriend
+		}
+		if since.Valid {
+			u.Since = &since.Time
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// getRequest 获取好友申请
+func (s *Service) getRequest(requestID int) (*FriendRequest, error) {
+	r := &FriendRequest{}
+	err := s.db.QueryRow(`
+		SELECT r.id, r.sender_id, su.username, r.receiver_id, ru.username, r.status, r.created_at
+		FROM friend_requests r
+		JOIN users su ON su.id = r.sender_id
+		JOIN users ru ON ru.id = r.receiver_id
+		WHERE r.id = ?
+	`, requestID).Scan(&r.ID, &r.SenderID, &r.SenderName, &r.ReceiverID, &r.ReceiverName, &r.Status, &r.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friend request: %w", err)
+	}
+	return r, nil
+}
+
+// CheckNotBlocked 任意一方拉黑了另一方时拒绝建立关系
+func (s *Service) CheckNotBlocked(userID int, targetID int) error {
+	var blocked bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_blocks
+		              WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?))
+	`, userID, targetID, targetID, userID).Scan(&blocked)
+	if err != nil {
+		return fmt.Errorf("failed to check block: %w", err)
+	}
+	if blocked {
+		return errors.New("user not available")
+	}
+	return nil
+}
+
+// resolveTarget 按ID或用户名找到目标用户
+func (s *Service) resolveTarget(req *TargetRequest) (int, error) {
+	var id int
+	var err error
+	switch {
+	case req.UserID > 0:
+		err = s.db.QueryRow("SELECT id FROM users WHERE id = ?", req.UserID).Scan(&id)
+	case req.Username != "":
+		err = s.db.QueryRow("SELECT id FROM users WHERE username = ?", req.Username).Scan(&id)
+	default:
+		return 0, errors.New("user_id or username is required")
+	}
+	if err == sql.ErrNoRows {
+		return 0, errors.New("user not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	return id, nil
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
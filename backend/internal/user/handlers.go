@@ -1,7 +1,13 @@
 package user
 
 import (
+	"crypto/subtle"
+	"errors"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -44,8 +50,12 @@ func (h *Handlers) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.Login(&req)
+	req.ClientIP = c.ClientIP()
+	response, err := h.service.Login(c.Request.Context(), &req)
 	if err != nil {
+		if writeLocked(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
@@ -91,6 +101,533 @@ func (h *Handlers) UpdateProfile(c *gin.Context) {
 	if pref, ok := body["preferred_category"]; ok {
 		h.service.DB().Exec("UPDATE users SET preferred_category = ?, updated_at = NOW() WHERE id = ?", pref, userID.(int))
 	}
+	if lvl, ok := body["preferred_level"]; ok {
+		level, err := strconv.Atoi(lvl)
+		if err != nil || level < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid preferred level"})
+			return
+		}
+		h.service.DB().Exec("UPDATE users SET preferred_level = ?, updated_at = NOW() WHERE id = ?", level, userID.(int))
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
+
+// SetRole 设置用户角色（管理员）
+func (h *Handlers) SetRole(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.SetRole(targetID, req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": targetID, "role": req.Role})
+}
+
+// WeChatLogin 微信小程序登录
+func (h *Handlers) WeChatLogin(c *gin.Context) {
+	var req WeChatLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.ClientIP = c.ClientIP()
+	response, err := h.service.WeChatLogin(c.Request.Context(), &req)
+	if err != nil {
+		if writeLocked(c, err) {
+			return
+		}
+		c.JSON(wechatErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWeChatBinding 获取微信绑定状态
+func (h *Handlers) GetWeChatBinding(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	binding, err := h.service.GetWeChatBinding(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, binding)
+}
+
+// BindWeChat 绑定微信
+func (h *Handlers) BindWeChat(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req BindWeChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.BindWeChat(c.Request.Context(), userID.(int), req.Code); err != nil {
+		c.JSON(wechatErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "WeChat bound successfully"})
+}
+
+// UnbindWeChat 解绑微信
+func (h *Handlers) UnbindWeChat(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.service.UnbindWeChat(userID.(int)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "WeChat unbound successfully"})
+}
+
+// wechatErrorStatus 微信登录/绑定错误对应的状态码：未配置 503，code 无效或密码错误 401，绑定冲突 409
+func wechatErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrWeChatNotConfigured):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrInvalidWeChatCode), err.Error() == "invalid credentials":
+		return http.StatusUnauthorized
+	case strings.Contains(err.Error(), "already bound"):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// OIDCProviders 可用的第三方登录方式
+func (h *Handlers) OIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.service.OIDCProviders()})
+}
+
+// OIDCLogin 跳转到第三方登录页
+func (h *Handlers) OIDCLogin(c *gin.Context) {
+	authURL, state, err := h.service.BeginOIDC(c.Request.Context(), c.Param("provider"), 0)
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setOIDCStateCookie(c, oidcStateHash(state), int(oidcStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 第三方登录回调
+// 配置了 OIDC_FRONTEND_URL 时跳转到前端并附带一次性 login_code（绑定时为 linked，失败时为 error），否则直接返回 JSON
+func (h *Handlers) OIDCCallback(c *gin.Context) {
+	provider := c.Param("provider")
+	frontendURL := h.service.cfg.OIDC.FrontendURL
+	fail := func(status int, message string) {
+		if frontendURL != "" {
+			c.Redirect(http.StatusFound, withQuery(frontendURL, "error", message))
+			return
+		}
+		c.JSON(status, gin.H{"error": message})
+	}
+
+	// state 必须与发起登录的浏览器 Cookie 中的哈希一致，无论成功与否都清除 Cookie
+	stateHash, _ := c.Cookie(oidcStateCookie)
+	h.setOIDCStateCookie(c, "", -1)
+
+	if errCode := c.Query("error"); errCode != "" {
+		fail(http.StatusUnauthorized, strings.TrimSpace(errCode+" "+c.Query("error_description")))
+		return
+	}
+	state := c.Query("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(stateHash), []byte(oidcStateHash(state))) != 1 {
+		fail(http.StatusBadRequest, ErrInvalidOIDCState.Error())
+		return
+	}
+
+	result, err := h.service.CompleteOIDC(c.Request.Context(), provider, c.Query("code"), state)
+	if err != nil {
+		fail(oidcErrorStatus(err), err.Error())
+		return
+	}
+
+	if result.Linked {
+		if frontendURL != "" {
+			c.Redirect(http.StatusFound, withQuery(frontendURL, "linked", provider))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Identity linked successfully", "provider": provider})
+		return
+	}
+	if frontendURL == "" {
+		response, err := h.service.oidcLoginResponse(c.Request.Context(), result)
+		if err != nil {
+			fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	code, err := h.service.issueLoginCode(c.Request.Context(), result)
+	if err != nil {
+		fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Redirect(http.StatusFound, withQuery(frontendURL, "login_code", code))
+}
+
+// ExchangeLoginCode 前端用回调附带的 login_code 换取 token
+func (h *Handlers) ExchangeLoginCode(c *gin.Context) {
+	var req ExchangeLoginCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.ExchangeLoginCode(c.Request.Context(), req.LoginCode)
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListIdentities 当前账号绑定的第三方身份
+func (h *Handlers) ListIdentities(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	identities, err := h.service.ListIdentities(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// LinkIdentity 为当前账号绑定第三方身份：返回授权地址，由前端跳转，回调后完成绑定
+func (h *Handlers) LinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	authURL, state, err := h.service.BeginOIDC(c.Request.Context(), c.Param("provider"), userID.(int))
+	if err != nil {
+		c.JSON(oidcErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setOIDCStateCookie(c, oidcStateHash(state), int(oidcStateTTL.Seconds()))
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// UnlinkIdentity 解绑第三方身份（包括微信）
+func (h *Handlers) UnlinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.service.UnlinkIdentity(userID.(int), c.Param("provider")); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrIdentityNotLinked) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
+
+// setOIDCStateCookie 设置（maxAge < 0 时清除）绑定 state 的 Cookie；只在 OIDC 回调路径下发送，
+// SameSite=Lax 保证从提供方跳转回来的顶级 GET 请求会带上它
+func (h *Handlers) setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/v1/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.service.cfg.Environment == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// oidcErrorStatus 第三方登录错误对应的状态码
+func oidcErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrOIDCProviderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidOIDCState), errors.Is(err, ErrInvalidLoginCode):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrOIDCAuthFailed):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrOIDCEmailInUse), strings.Contains(err.Error(), "already bound"):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// withQuery 在地址上追加一个查询参数
+func withQuery(rawURL, key, value string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// VerifyTwoFactor 登录第二步：提交验证码或恢复码
+func (h *Handlers) VerifyTwoFactor(c *gin.Context) {
+	var req VerifyTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.ClientIP = c.ClientIP()
+	response, err := h.service.VerifyTwoFactor(c.Request.Context(), &req)
+	if err != nil {
+		if writeLocked(c, err) {
+			return
+		}
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTwoFactorStatus 两步验证状态
+func (h *Handlers) GetTwoFactorStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	status, err := h.service.TwoFactorStatus(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupTwoFactor 生成 TOTP 密钥
+func (h *Handlers) SetupTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	setup, err := h.service.SetupTwoFactor(userID.(int))
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmTwoFactor 确认验证码，开启两步验证
+func (h *Handlers) ConfirmTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.ConfirmTwoFactor(userID.(int), req.Code)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (h *Handlers) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(userID.(int), req.Code)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor 关闭两步验证
+func (h *Handlers) DisableTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.DisableTwoFactor(userID.(int), &req); err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// GetTwoFactorPolicy 获取两步验证策略（管理员）
+func (h *Handlers) GetTwoFactorPolicy(c *gin.Context) {
+	policy, err := h.service.GetTwoFactorPolicy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetTwoFactorPolicy 设置要求开启两步验证的角色（管理员）
+func (h *Handlers) SetTwoFactorPolicy(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SetTwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.service.SetTwoFactorPolicy(userID.(int), req.RequiredRoles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// ResetTwoFactor 重置用户的两步验证（管理员，用于用户丢失设备和恢复码时）
+func (h *Handlers) ResetTwoFactor(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.service.ResetTwoFactor(targetID); err != nil {
+		c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// twoFactorErrorStatus 两步验证错误对应的状态码
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidChallenge), errors.Is(err, ErrInvalidTwoFactorCode), err.Error() == "invalid password":
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrTwoFactorRequired):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTwoFactorNotEnabled):
+		return http.StatusNotFound
+	case errors.Is(err, ErrTwoFactorAlreadyEnabled), errors.Is(err, ErrTwoFactorNotSetUp):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeLocked 账号因登录失败过多被锁定时，返回 429 和 Retry-After（秒）
+func writeLocked(c *gin.Context, err error) bool {
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
+
+// StreamTicket 签发 SSE 连接用的一次性凭证（30 秒内有效）
+func (h *Handlers) StreamTicket(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ticket, err := h.service.IssueStreamTicket(c.Request.Context(), userID.(int), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires_in": int(streamTicketTTL.Seconds())})
+}
@@ -0,0 +1,209 @@
+package user
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"linguaforge/internal/events"
+	"strings"
+)
+
+var ErrIdentityNotLinked = errors.New("identity is not linked")
+
+// externalIdentity 第三方登录返回的身份
+type externalIdentity struct {
+	Provider string
+	Subject  string
+	UnionID  string // 仅微信
+	Email    string
+}
+
+// identityOwner 查找已绑定该身份的用户，未绑定时返回 0
+func (s *Service) identityOwner(provider, subject string) (int, error) {
+	var userID int
+	err := s.db.QueryRow(`
+		SELECT user_id FROM identities WHERE provider = ? AND subject = ?
+	`, provider, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return userID, nil
+}
+
+// emailOwner 查找使用该邮箱的用户，没有时返回 0
+func (s *Service) emailOwner(email string) (int, error) {
+	var userID int
+	err := s.db.QueryRow("SELECT id FROM users WHERE email = ?", email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check email: %w", err)
+	}
+	return userID, nil
+}
+
+// linkIdentity 为用户绑定第三方身份；身份已被其他账号绑定或账号已绑定同一提供方的其他身份时报错
+func (s *Service) linkIdentity(userID int, identity *externalIdentity) error {
+	owner, err := s.identityOwner(identity.Provider, identity.Subject)
+	if err != nil {
+		return err
+	}
+	if owner == userID {
+		return nil
+	}
+	if owner != 0 {
+		return fmt.Errorf("this %s account is already bound to another user", identity.Provider)
+	}
+
+	if _, err := s.db.Exec(`
+		INSERT INTO identities (user_id, provider, subject, union_id, email) VALUES (?, ?, ?, ?, ?)
+	`, userID, identity.Provider, identity.Subject, nullIfEmpty(identity.UnionID), nullIfEmpty(identity.Email)); err != nil {
+		if isDuplicate(err) {
+			return fmt.Errorf("account is already bound to another %s account", identity.Provider)
+		}
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// touchIdentity 记录最近一次通过该身份登录的时间
+func (s *Service) touchIdentity(identity *externalIdentity) error {
+	if _, err := s.db.Exec(`
+		UPDATE identities SET last_login_at = NOW(), email = COALESCE(?, email) WHERE provider = ? AND subject = ?
+	`, nullIfEmpty(identity.Email), identity.Provider, identity.Subject); err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	return nil
+}
+
+// provisionUser 为第三方身份自动注册学生账号（没有密码）并绑定该身份
+// 用户名优先使用 preferred，被占用时使用 prefix 加随机后缀
+func (s *Service) provisionUser(preferred, prefix, email string, identity *externalIdentity) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var username string
+	for attempt := 0; ; attempt++ {
+		username = prefix + randomSuffix()
+		if attempt == 0 && preferred != "" {
+			username = preferred
+		}
+		result, err := tx.Exec(`
+			INSERT INTO users (username, email, role, password_hash, level, experience, coins)
+			VALUES (?, ?, ?, '', 1, 0, 0)
+		`, username, nullIfEmpty(email), RoleStudent)
+		if err == nil {
+			if userID, err = result.LastInsertId(); err != nil {
+				return 0, fmt.Errorf("failed to get user ID: %w", err)
+			}
+			break
+		}
+		if !isDuplicate(err) || attempt >= 3 {
+			return 0, fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO identities (user_id, provider, subject, union_id, email) VALUES (?, ?, ?, ?, ?)
+	`, userID, identity.Provider, identity.Subject, nullIfEmpty(identity.UnionID), nullIfEmpty(identity.Email)); err != nil {
+		if isDuplicate(err) {
+			// 并发的首次登录已经创建了账号
+			tx.Rollback()
+			return s.identityOwner(identity.Provider, identity.Subject)
+		}
+		return 0, fmt.Errorf("failed to link identity: %w", err)
+	}
+	if err := events.Write(tx, events.UserRegistered, int(userID), &events.UserRegisteredPayload{
+		Username: username,
+		Role:     string(RoleStudent),
+		Source:   identity.Provider,
+	}); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit user: %w", err)
+	}
+	return int(userID), nil
+}
+
+// ListIdentities 当前账号绑定的第三方身份
+func (s *Service) ListIdentities(userID int) ([]Identity, error) {
+	rows, err := s.db.Query(`
+		SELECT provider, COALESCE(email, ''), created_at, last_login_at
+		FROM identities WHERE user_id = ? ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []Identity{}
+	for rows.Next() {
+		var identity Identity
+		var lastLogin sql.NullTime
+		if err := rows.Scan(&identity.Provider, &identity.Email, &identity.CreatedAt, &lastLogin); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		if lastLogin.Valid {
+			identity.LastLoginAt = &lastLogin.Time
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity 解绑第三方身份；解绑后账号必须仍能登录（设置了密码或还绑定了其他身份）
+func (s *Service) UnlinkIdentity(userID int, provider string) error {
+	var passwordHash string
+	var others int
+	err := s.db.QueryRow(`
+		SELECT u.password_hash, (SELECT COUNT(*) FROM identities i WHERE i.user_id = u.id AND i.provider <> ?)
+		FROM users u WHERE u.id = ?
+	`, provider, userID).Scan(&passwordHash, &others)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if passwordHash == "" && others == 0 {
+		return errors.New("set a password before unlinking your only sign-in method")
+	}
+
+	result, err := s.db.Exec("DELETE FROM identities WHERE user_id = ? AND provider = ?", userID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrIdentityNotLinked
+	}
+	return nil
+}
+
+// isDuplicate 是否为 MySQL 唯一键冲突（1062）
+func isDuplicate(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "1062")
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func randomSuffix() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// lockoutThreshold 同一用户名在同一 IP 上连续失败多少次后锁定该 IP 的登录
+	lockoutThreshold = 5
+	// accountLockoutThreshold 同一用户名在所有 IP 上合计失败多少次后锁定账号；
+	// 远高于单 IP 阈值，别人无法只凭用户名把账号锁住，分布式猜密码仍会被挡下
+	accountLockoutThreshold = 50
+	// lockoutBase 第一次锁定的时长，之后每多失败一次翻倍
+	lockoutBase = time.Minute
+	// lockoutMax 单次锁定的最长时间
+	lockoutMax = time.Hour
+	// failureWindow 失败次数的统计窗口，期间没有新的失败则清零
+	failureWindow = 24 * time.Hour
+)
+
+// LockedError 登录失败次数过多，暂时锁定
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again in %d seconds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// lockoutScope 失败计数的范围：用户名 + IP，或 IP 为空时的用户名全局计数
+type lockoutScope struct {
+	ip        string
+	threshold int64
+}
+
+// lockoutScopes clientIP 为空（无法取得客户端地址）时只按用户名全局计数
+func lockoutScopes(clientIP string) []lockoutScope {
+	scopes := []lockoutScope{{ip: "", threshold: accountLockoutThreshold}}
+	if clientIP != "" {
+		scopes = append(scopes, lockoutScope{ip: clientIP, threshold: lockoutThreshold})
+	}
+	return scopes
+}
+
+// checkPassword 校验用户名和密码；该用户名在该 IP 上（或全局）锁定期间直接拒绝，失败会计入锁定次数
+func (s *Service) checkPassword(ctx context.Context, username, password, clientIP string) (*User, error) {
+	if err := s.checkLockout(ctx, username, clientIP); err != nil {
+		return nil, err
+	}
+	user, err := s.GetByUsername(username)
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		if err := s.recordLoginFailure(ctx, username, clientIP); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid credentials")
+	}
+	return user, nil
+}
+
+// checkLockout 用户名在该 IP 上或全局处于锁定期时返回 LockedError（取较长的剩余时间）
+func (s *Service) checkLockout(ctx context.Context, username, clientIP string) error {
+	var retryAfter time.Duration
+	for _, scope := range lockoutScopes(clientIP) {
+		ttl, err := s.redis.PTTL(ctx, lockKey(username, scope.ip)).Result()
+		if err != nil {
+			// Redis 不可用时不锁定，由路由上的限流兜底
+			log.Printf("login lockout check failed: %v", err)
+			return nil
+		}
+		retryAfter = max(retryAfter, ttl)
+	}
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次失败（密码或两步验证码错误），同时计入用户名 + IP 和用户名全局两个计数；
+// 任一计数达到阈值后锁定对应范围，锁定时长从 1 分钟起每次翻倍，最长 1 小时；本次失败触发锁定时返回 LockedError
+func (s *Service) recordLoginFailure(ctx context.Context, username, clientIP string) error {
+	var lockout time.Duration
+	for _, scope := range lockoutScopes(clientIP) {
+		key := failureKey(username, scope.ip)
+		failures, err := s.redis.Incr(ctx, key).Result()
+		if err != nil {
+			log.Printf("failed to record login failure: %v", err)
+			return nil
+		}
+		s.redis.Expire(ctx, key, failureWindow)
+		if failures < scope.threshold {
+			continue
+		}
+
+		duration := lockoutMax
+		if shift := failures - scope.threshold; shift < 16 {
+			duration = min(lockoutBase<<shift, lockoutMax)
+		}
+		if err := s.redis.Set(ctx, lockKey(username, scope.ip), failures, duration).Err(); err != nil {
+			log.Printf("failed to lock login: %v", err)
+			continue
+		}
+		lockout = max(lockout, duration)
+	}
+	if lockout > 0 {
+		return &LockedError{RetryAfter: lockout}
+	}
+	return nil
+}
+
+// clearLoginFailures 登录成功后清除用户名的全局失败记录和该 IP 上的失败记录（clientIP 为空时只清除全局记录）
+func (s *Service) clearLoginFailures(ctx context.Context, username, clientIP string) {
+	keys := []string{failureKey(username, ""), lockKey(username, "")}
+	if clientIP != "" {
+		keys = append(keys, failureKey(username, clientIP), lockKey(username, clientIP))
+	}
+	if err := s.redis.Del(ctx, keys...).Err(); err != nil && err != redis.Nil {
+		log.Printf("failed to clear login failures: %v", err)
+	}
+}
+
+// failureKey 失败计数的 key；ip 为空时为用户名的全局计数
+func failureKey(username, ip string) string {
+	return lockoutKey("login:failures:", username, ip)
+}
+
+// lockKey 锁定标记的 key；ip 为空时为账号锁定
+func lockKey(username, ip string) string {
+	return lockoutKey("login:lock:", username, ip)
+}
+
+func lockoutKey(prefix, username, ip string) string {
+	key := prefix + strings.ToLower(username)
+	if ip != "" {
+		key += ":ip:" + ip
+	}
+	return key
+}
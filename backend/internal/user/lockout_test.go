@@ -0,0 +1,127 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLockoutKeys(t *testing.T) {
+	if got := lockKey("Alice", ""); got != "login:lock:alice" {
+		t.Fatalf("account lock key = %q", got)
+	}
+	if got := failureKey("Alice", "203.0.113.7"); got != "login:failures:alice:ip:203.0.113.7" {
+		t.Fatalf("per-IP failure key = %q", got)
+	}
+	if lockKey("alice", "203.0.113.7") == lockKey("alice", "198.51.100.1") {
+		t.Fatal("different IPs share a lock")
+	}
+}
+
+func TestLockoutScopes(t *testing.T) {
+	scopes := lockoutScopes("203.0.113.7")
+	if len(scopes) != 2 || scopes[0].ip != "" || scopes[0].threshold != accountLockoutThreshold ||
+		scopes[1].ip != "203.0.113.7" || scopes[1].threshold != lockoutThreshold {
+		t.Fatalf("unexpected scopes %+v", scopes)
+	}
+	// 取不到客户端 IP 时只有账号范围，不会把同一个 key 计两次
+	if scopes := lockoutScopes(""); len(scopes) != 1 || scopes[0].ip != "" {
+		t.Fatalf("unexpected scopes without IP %+v", scopes)
+	}
+	if accountLockoutThreshold < 5*lockoutThreshold {
+		t.Fatal("account threshold should be much higher than the per-IP threshold")
+	}
+}
+
+// lockedFor 返回 err 中的锁定时长，err 不是 LockedError 时为 0
+func lockedFor(err error) time.Duration {
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		return locked.RetryAfter
+	}
+	return 0
+}
+
+func TestLockoutPerIP(t *testing.T) {
+	mr, client := newTestRedis(t)
+	s := &Service{redis: client}
+	ctx := context.Background()
+	const ip = "203.0.113.7"
+
+	for i := 1; i < lockoutThreshold; i++ {
+		if err := s.recordLoginFailure(ctx, "Alice", ip); err != nil {
+			t.Fatalf("failure %d: %v", i, err)
+		}
+	}
+	if err := s.checkLockout(ctx, "alice", ip); err != nil {
+		t.Fatalf("locked before the threshold: %v", err)
+	}
+	if got := lockedFor(s.recordLoginFailure(ctx, "alice", ip)); got != lockoutBase {
+		t.Fatalf("failure %d locked for %v, want %v", lockoutThreshold, got, lockoutBase)
+	}
+	if got := lockedFor(s.checkLockout(ctx, "ALICE", ip)); got <= 0 || got > lockoutBase {
+		t.Fatalf("check on the same IP: locked for %v", got)
+	}
+	// 其他 IP 上的同一用户名不受影响
+	if err := s.checkLockout(ctx, "alice", "198.51.100.1"); err != nil {
+		t.Fatalf("other IP locked: %v", err)
+	}
+
+	// 锁定期间继续失败，锁定时长翻倍
+	if got := lockedFor(s.recordLoginFailure(ctx, "alice", ip)); got != 2*lockoutBase {
+		t.Fatalf("next failure locked for %v, want %v", got, 2*lockoutBase)
+	}
+
+	// 锁定到期后可以再试
+	mr.FastForward(2 * lockoutBase)
+	if err := s.checkLockout(ctx, "alice", ip); err != nil {
+		t.Fatalf("still locked after expiry: %v", err)
+	}
+
+	// 登录成功清除该 IP 和全局的失败记录
+	s.clearLoginFailures(ctx, "alice", ip)
+	if mr.Exists(failureKey("alice", ip)) || mr.Exists(failureKey("alice", "")) {
+		t.Fatal("failures not cleared after a successful login")
+	}
+}
+
+func TestLockoutAccountWide(t *testing.T) {
+	_, client := newTestRedis(t)
+	s := &Service{redis: client}
+	ctx := context.Background()
+
+	// 每个 IP 只失败一次，不触发单 IP 锁定
+	for i := 1; i < accountLockoutThreshold; i++ {
+		if err := s.recordLoginFailure(ctx, "alice", fmt.Sprintf("10.0.0.%d", i)); err != nil {
+			t.Fatalf("failure %d: %v", i, err)
+		}
+	}
+	if err := s.checkLockout(ctx, "alice", "10.0.1.1"); err != nil {
+		t.Fatalf("account locked before the threshold: %v", err)
+	}
+	if got := lockedFor(s.recordLoginFailure(ctx, "alice", "10.0.0.250")); got != lockoutBase {
+		t.Fatalf("failure %d locked for %v, want %v", accountLockoutThreshold, got, lockoutBase)
+	}
+	// 账号锁定对所有 IP 生效，其他用户名不受影响
+	if lockedFor(s.checkLockout(ctx, "alice", "10.0.1.1")) <= 0 {
+		t.Fatal("account lock not applied to a new IP")
+	}
+	if err := s.checkLockout(ctx, "bob", "10.0.1.1"); err != nil {
+		t.Fatalf("another user locked: %v", err)
+	}
+}
+
+func TestLockoutFailsOpenWithoutRedis(t *testing.T) {
+	mr, client := newTestRedis(t)
+	s := &Service{redis: client}
+	mr.Close()
+	ctx := context.Background()
+	if err := s.recordLoginFailure(ctx, "alice", "203.0.113.7"); err != nil {
+		t.Fatalf("recordLoginFailure with Redis down: %v", err)
+	}
+	if err := s.checkLockout(ctx, "alice", "203.0.113.7"); err != nil {
+		t.Fatalf("checkLockout with Redis down: %v", err)
+	}
+}
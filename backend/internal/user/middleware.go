@@ -8,6 +8,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// twoFactorSetupPath 受限 token（角色要求两步验证但尚未开启）可以访问的路由前缀
+const twoFactorSetupPath = "/api/v1/profile/2fa"
+
 // AuthMiddleware JWT认证中间件
 func (h *Handlers) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -26,46 +29,122 @@ func (h *Handlers) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 验证token
-		token, err := h.service.ValidateToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		if !h.authenticate(c, tokenString) {
 			return
 		}
+		c.Next()
+	}
+}
 
-		// 检查token是否有效
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is not valid"})
-			c.Abort()
+// StreamAuthMiddleware 用于 SSE 等浏览器无法设置请求头的长连接：
+// 优先使用 Authorization 头，没有时读取 ticket 查询参数（由 POST /notifications/stream-ticket 签发的一次性凭证）
+func (h *Handlers) StreamAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); tokenString != "" {
+			if !h.authenticate(c, tokenString) {
+				return
+			}
+			c.Next()
 			return
 		}
 
-		// 提取用户信息
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header or stream ticket required"})
 			c.Abort()
 			return
 		}
+		t, err := h.service.redeemStreamTicket(c.Request.Context(), ticket)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Set("user_id", t.UserID)
+		c.Set("username", t.Username)
+		c.Next()
+	}
+}
+
+// authenticate 校验 token 并把用户信息写入上下文；失败时已写入响应并中止
+func (h *Handlers) authenticate(c *gin.Context, tokenString string) bool {
+	// 验证token
+	token, err := h.service.ValidateToken(tokenString)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
+	}
 
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+	// 检查token是否有效
+	if !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is not valid"})
+		c.Abort()
+		return false
+	}
+
+	// 提取用户信息
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		c.Abort()
+		return false
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+		c.Abort()
+		return false
+	}
+
+	username, ok := claims["username"].(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username in token"})
+		c.Abort()
+		return false
+	}
+
+	// 受限 token 只能用于开启两步验证
+	if scope, _ := claims["scope"].(string); scope == scopeTwoFactorSetup && !strings.HasPrefix(c.FullPath(), twoFactorSetupPath) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication setup required", "two_factor_setup_required": true})
+		c.Abort()
+		return false
+	}
+
+	// 将用户信息存储到上下文中
+	c.Set("user_id", int(userID))
+	c.Set("username", username)
+	return true
+}
+
+// RequireRole 角色校验中间件，需在 AuthMiddleware 之后使用
+// 角色每次从数据库读取，调整角色后无需重新登录即可生效
+func (h *Handlers) RequireRole(roles ...Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 			c.Abort()
 			return
 		}
 
-		username, ok := claims["username"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username in token"})
+		role, err := h.service.GetRole(userID.(int))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		// 将用户信息存储到上下文中
-		c.Set("user_id", int(userID))
-		c.Set("username", username)
-		c.Next()
+		for _, r := range roles {
+			if role == r {
+				c.Set("role", role)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
 	}
 }
@@ -6,16 +6,28 @@ import (
 	"time"
 )
 
+// Role 用户角色
+type Role string
+
+const (
+	RoleStudent  Role = "student"
+	RoleTeacher  Role = "teacher"
+	RoleAdmin    Role = "admin"
+	RoleGuardian Role = "guardian" // 家长/监护人
+)
+
 // User 用户模型
 type User struct {
 	ID                int            `json:"id" db:"id"`
 	Username          string         `json:"username" db:"username"`
 	Email             string         `json:"email" db:"email"`
+	Role              Role           `json:"role" db:"role"`
 	PasswordHash      string         `json:"-" db:"password_hash"`
 	Level             int            `json:"level" db:"level"`
 	Experience        int            `json:"experience" db:"experience"`
 	Coins             int            `json:"coins" db:"coins"`
 	PreferredCategory sql.NullString `json:"-" db:"preferred_category"`
+	PreferredLevel    int            `json:"preferred_level" db:"preferred_level"`
 	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at" db:"updated_at"`
 }
@@ -37,18 +49,129 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	Role     Role   `json:"role" binding:"omitempty,oneof=student guardian"` // 只能自行注册为学生或监护人
 }
 
 // LoginRequest 登录请求
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	ClientIP string `json:"-"` // 由处理函数填入，用于登录失败锁定
+}
+
+// SetRoleRequest 设置用户角色请求
+type SetRoleRequest struct {
+	Role Role `json:"role" binding:"required,oneof=student teacher admin guardian"`
 }
 
 // LoginResponse 登录响应
+// 开启了两步验证时不返回 token，需用 challenge_token 和验证码调用 /auth/2fa/verify；
+// 角色被要求开启两步验证但尚未开启时，返回的 token 只能用于开启两步验证
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token                  string `json:"token,omitempty"`
+	User                   *User  `json:"user,omitempty"`
+	TwoFactorRequired      bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken         string `json:"challenge_token,omitempty"`
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"`
+}
+
+// WeChatLoginRequest 微信登录请求；code 来自 wx.login，同时提供用户名和密码时绑定到已有账号
+type WeChatLoginRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Username string `json:"username"`
+	Password string `json:"password" binding:"required_with=Username"`
+	ClientIP string `json:"-"`
+}
+
+// WeChatLoginResponse 微信登录响应；created 表示本次自动注册了新账号
+type WeChatLoginResponse struct {
+	LoginResponse
+	Created bool `json:"created"`
+}
+
+// BindWeChatRequest 绑定微信请求
+type BindWeChatRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// WeChatBinding 微信绑定状态
+type WeChatBinding struct {
+	Bound   bool       `json:"bound"`
+	BoundAt *time.Time `json:"bound_at,omitempty"`
+}
+
+// OIDCProvider 可用的第三方登录方式
+type OIDCProvider struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// OIDCLoginResponse 第三方登录响应；created 表示本次自动注册了新账号
+type OIDCLoginResponse struct {
+	LoginResponse
+	Provider string `json:"provider"`
+	Created  bool   `json:"created"`
+}
+
+// ExchangeLoginCodeRequest 用回调跳转时附带的 login_code 换取 token
+type ExchangeLoginCodeRequest struct {
+	LoginCode string `json:"login_code" binding:"required"`
+}
+
+// Identity 已绑定的第三方登录身份
+type Identity struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// VerifyTwoFactorRequest 登录第二步；code 为 6 位验证码或恢复码
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	ClientIP       string `json:"-"`
+}
+
+// TwoFactorCodeRequest 需要验证码的两步验证操作
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest 关闭两步验证；没有设置密码的账号 password 可为空
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TwoFactorStatus 两步验证状态；required 表示当前角色被要求开启
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	Required               bool       `json:"required"`
+}
+
+// TwoFactorSetup 开启两步验证的密钥；provisioning_uri 用于生成认证器扫描的二维码
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorConfirmResponse 确认开启后的恢复码（只展示这一次）和新的 token
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Token         string   `json:"token"`
+}
+
+// TwoFactorPolicy 被要求开启两步验证的角色
+type TwoFactorPolicy struct {
+	RequiredRoles []Role `json:"required_roles"`
+}
+
+// SetTwoFactorPolicyRequest 设置两步验证策略
+type SetTwoFactorPolicyRequest struct {
+	RequiredRoles []Role `json:"required_roles" binding:"dive,oneof=student teacher admin guardian"`
 }
 
 // UserProfile 用户资料
@@ -56,10 +179,12 @@ type UserProfile struct {
 	ID                int            `json:"id"`
 	Username          string         `json:"username"`
 	Email             string         `json:"email"`
+	Role              Role           `json:"role"`
 	Level             int            `json:"level"`
 	Experience        int            `json:"experience"`
 	Coins             int            `json:"coins"`
 	PreferredCategory sql.NullString `json:"-"`
+	PreferredLevel    int            `json:"preferred_level"`
 }
 
 // MarshalJSON 自定义 JSON 序列化
@@ -0,0 +1,535 @@
+package user
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"linguaforge/config"
+	"linguaforge/internal/common"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// oidcStateTTL 发起登录到回调的最长时间
+	oidcStateTTL = 10 * time.Minute
+	// oidcLoginCodeTTL 回调跳转前端后换取 token 的一次性 code 有效期
+	oidcLoginCodeTTL = 2 * time.Minute
+	// oidcDiscoveryTTL 发现文档的缓存时间
+	oidcDiscoveryTTL = 24 * time.Hour
+	// jwksMinRefresh 遇到未知 kid 时重新拉取 JWKS 的最小间隔
+	jwksMinRefresh = time.Minute
+	// oidcResponseLimit 提供方响应的最大字节数
+	oidcResponseLimit = 1 << 20
+	// oidcStateCookie 发起登录（或绑定）的浏览器保存 state 的哈希，回调时必须带回同一个值，
+	// 防止把别人发起的授权回调送进受害者的浏览器（登录 CSRF、绑定劫持）
+	oidcStateCookie = "oidc_state"
+)
+
+var (
+	ErrOIDCProviderNotFound = errors.New("login provider not found")
+	ErrInvalidOIDCState     = errors.New("invalid or expired login state")
+	ErrInvalidLoginCode     = errors.New("invalid or expired login code")
+	ErrOIDCAuthFailed       = errors.New("external sign-in failed")
+	ErrOIDCEmailInUse       = errors.New("an account with this email already exists, sign in and link this provider from your profile")
+)
+
+// idTokenAlgs 接受的 ID Token 签名算法（不接受 none 和对称算法）
+var idTokenAlgs = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// oidcProvider 单个 OIDC 提供方；发现文档和 JWKS 按需获取并缓存
+type oidcProvider struct {
+	cfg         config.OIDCProviderConfig
+	redirectURL string
+	client      *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	discoveredAt  time.Time
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcState 发起登录时保存在 Redis 中，回调时一次性取出
+type oidcState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	UserID   int    `json:"user_id,omitempty"` // 非 0 表示为该用户绑定身份
+}
+
+// idTokenClaims ID Token 中用到的声明
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	TenantID          string   `json:"tid"` // Microsoft 多租户 Issuer 中的 {tenantid}
+}
+
+// flexBool 兼容部分提供方把布尔值编码为字符串
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// oidcResult 回调处理结果；登录结果可由 issueLoginCode 暂存，前端用一次性 code 换取 token 时再签发
+type oidcResult struct {
+	UserID   int    `json:"user_id"`
+	Provider string `json:"provider"`
+	Created  bool   `json:"created"`
+	Linked   bool   `json:"-"` // 为已登录用户绑定身份，而不是登录
+}
+
+func newOIDCProviders(cfg config.OIDCConfig) map[string]*oidcProvider {
+	providers := make(map[string]*oidcProvider, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		if p.ID == ProviderWeChat {
+			continue // 微信使用 code2session，不是 OIDC
+		}
+		providers[p.ID] = &oidcProvider{
+			cfg:         p,
+			redirectURL: cfg.RedirectBase + "/api/v1/auth/oidc/" + p.ID + "/callback",
+			client:      &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+	return providers
+}
+
+// OIDCProviders 已配置的第三方登录方式
+func (s *Service) OIDCProviders() []OIDCProvider {
+	providers := []OIDCProvider{}
+	for _, p := range s.cfg.OIDC.Providers {
+		if _, ok := s.oidc[p.ID]; ok {
+			providers = append(providers, OIDCProvider{ID: p.ID, Name: p.Name})
+		}
+	}
+	return providers
+}
+
+// BeginOIDC 生成 state、nonce 和 PKCE 参数，返回提供方的授权地址和 state；linkUserID 非 0 时回调后为该用户绑定身份
+// 调用方需要用 oidcStateHash(state) 把 state 绑定到发起请求的浏览器
+func (s *Service) BeginOIDC(ctx context.Context, providerID string, linkUserID int) (string, string, error) {
+	provider, ok := s.oidc[providerID]
+	if !ok {
+		return "", "", ErrOIDCProviderNotFound
+	}
+	discovery, err := provider.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state := &oidcState{Provider: providerID, Nonce: common.RandomToken(32), Verifier: common.RandomToken(32), UserID: linkUserID}
+	stateKey := common.RandomToken(32)
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode login state: %w", err)
+	}
+	if err := s.redis.Set(ctx, "oidc:state:"+stateKey, encoded, oidcStateTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("failed to save login state: %w", err)
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.cfg.ClientID)
+	query.Set("redirect_uri", provider.redirectURL)
+	query.Set("scope", strings.Join(provider.cfg.Scopes, " "))
+	query.Set("state", stateKey)
+	query.Set("nonce", state.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), stateKey, nil
+}
+
+// oidcStateHash state 的哈希，保存在发起登录的浏览器的 Cookie 中
+func oidcStateHash(stateKey string) string {
+	sum := sha256.Sum256([]byte(stateKey))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// CompleteOIDC 处理提供方回调：校验 state，用 code 和 PKCE verifier 换取 ID Token 并验证，然后登录或绑定
+func (s *Service) CompleteOIDC(ctx context.Context, providerID, code, stateKey string) (*oidcResult, error) {
+	provider, ok := s.oidc[providerID]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+	if stateKey == "" || code == "" {
+		return nil, ErrInvalidOIDCState
+	}
+	raw, err := s.redis.GetDel(ctx, "oidc:state:"+stateKey).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login state: %w", err)
+	}
+	var state oidcState
+	if err := json.Unmarshal(raw, &state); err != nil || state.Provider != providerID {
+		return nil, ErrInvalidOIDCState
+	}
+
+	idToken, err := provider.exchange(ctx, code, state.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.verifyIDToken(ctx, idToken, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	identity := &externalIdentity{Provider: providerID, Subject: claims.Subject, Email: claims.Email}
+
+	if state.UserID != 0 {
+		if err := s.linkIdentity(state.UserID, identity); err != nil {
+			return nil, err
+		}
+		return &oidcResult{UserID: state.UserID, Provider: providerID, Linked: true}, nil
+	}
+
+	userID, created, err := s.oidcLogin(provider, identity, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &oidcResult{UserID: userID, Provider: providerID, Created: created}, nil
+}
+
+// oidcLoginResponse 为第三方登录结果签发 token（开启了两步验证时为挑战 token）
+func (s *Service) oidcLoginResponse(ctx context.Context, result *oidcResult) (*OIDCLoginResponse, error) {
+	response, err := s.loginAs(ctx, result.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLoginResponse{LoginResponse: *response, Provider: result.Provider, Created: result.Created}, nil
+}
+
+// issueLoginCode 保存登录结果并返回一次性 code，供回调跳转前端后换取 token（避免 token 出现在地址栏）
+func (s *Service) issueLoginCode(ctx context.Context, result *oidcResult) (string, error) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode login: %w", err)
+	}
+	code := common.RandomToken(32)
+	if err := s.redis.Set(ctx, "oidc:login:"+code, encoded, oidcLoginCodeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to save login code: %w", err)
+	}
+	return code, nil
+}
+
+// ExchangeLoginCode 用一次性 code 换取 token
+func (s *Service) ExchangeLoginCode(ctx context.Context, code string) (*OIDCLoginResponse, error) {
+	raw, err := s.redis.GetDel(ctx, "oidc:login:"+code).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidLoginCode
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login code: %w", err)
+	}
+	var result oidcResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode login: %w", err)
+	}
+	return s.oidcLoginResponse(ctx, &result)
+}
+
+// oidcLogin 已绑定的直接登录；已验证邮箱与已有账号相同时，按提供方配置自动绑定或要求用户先登录再绑定；否则自动注册学生账号
+// 返回用户 ID 和是否新注册
+func (s *Service) oidcLogin(provider *oidcProvider, identity *externalIdentity, claims *idTokenClaims) (int, bool, error) {
+	userID, err := s.identityOwner(identity.Provider, identity.Subject)
+	if err != nil {
+		return 0, false, err
+	}
+
+	verifiedEmail := ""
+	if claims.EmailVerified {
+		verifiedEmail = strings.ToLower(claims.Email)
+	}
+	created := false
+	if userID == 0 && verifiedEmail != "" {
+		owner, err := s.emailOwner(verifiedEmail)
+		if err != nil {
+			return 0, false, err
+		}
+		if owner != 0 {
+			if !provider.cfg.TrustEmail {
+				return 0, false, ErrOIDCEmailInUse
+			}
+			if err := s.linkIdentity(owner, identity); err != nil {
+				return 0, false, err
+			}
+			userID = owner
+		}
+	}
+	if userID == 0 {
+		preferred := usernameFrom(claims.PreferredUsername, claims.Email, claims.Name)
+		prefix := identity.Provider + "_"
+		if preferred != "" {
+			prefix = preferred + "_"
+		}
+		if userID, err = s.provisionUser(preferred, prefix, verifiedEmail, identity); err != nil {
+			return 0, false, err
+		}
+		created = true
+	}
+
+	if err := s.touchIdentity(identity); err != nil {
+		return 0, false, err
+	}
+	return userID, created, nil
+}
+
+// discover 获取（并缓存）提供方的发现文档
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil && time.Since(p.discoveredAt) < oidcDiscoveryTTL {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %w", p.cfg.ID, err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("incomplete discovery document for %s", p.cfg.ID)
+	}
+	// Microsoft 多租户的 Issuer 为模板（含 {tenantid}），无法与配置直接比较
+	if !strings.Contains(discovery.Issuer, "{") && strings.TrimSuffix(discovery.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("issuer mismatch for %s: %s", p.cfg.ID, discovery.Issuer)
+	}
+	p.discovery = &discovery
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+// exchange 用授权码和 PKCE verifier 换取 ID Token
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcResponseLimit)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		return "", fmt.Errorf("%w: token exchange failed: %s %s", ErrOIDCAuthFailed, result.Error, result.ErrorDescription)
+	}
+	if result.IDToken == "" {
+		return "", fmt.Errorf("%w: token response has no id_token", ErrOIDCAuthFailed)
+	}
+	return result.IDToken, nil
+}
+
+// verifyIDToken 用 JWKS 验证 ID Token 的签名，并校验 iss、aud、azp、exp、iat 和 nonce
+func (p *oidcProvider) verifyIDToken(ctx context.Context, raw, nonce string) (*idTokenClaims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, discovery.JWKSURI, kid)
+	}, jwt.WithValidMethods(idTokenAlgs), jwt.WithAudience(p.cfg.ClientID), jwt.WithIssuedAt(), jwt.WithLeeway(time.Minute))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ID token: %v", ErrOIDCAuthFailed, err)
+	}
+
+	issuer := strings.ReplaceAll(discovery.Issuer, "{tenantid}", claims.TenantID)
+	switch {
+	case claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("%w: ID token has no expiry", ErrOIDCAuthFailed)
+	case claims.Issuer != issuer:
+		return nil, fmt.Errorf("%w: unexpected ID token issuer %s", ErrOIDCAuthFailed, claims.Issuer)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: unexpected ID token azp", ErrOIDCAuthFailed)
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return nil, fmt.Errorf("%w: ID token nonce mismatch", ErrOIDCAuthFailed)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: ID token has no subject", ErrOIDCAuthFailed)
+	}
+	return claims, nil
+}
+
+// key 按 kid 查找签名公钥；缓存中没有时（密钥轮换）重新拉取 JWKS，但最多每分钟一次
+func (p *oidcProvider) key(ctx context.Context, jwksURI, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < jwksMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	p.keysFetchedAt = time.Now()
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	p.keys = keys
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, target)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcResponseLimit)).Decode(v)
+}
+
+// jsonWebKey JWKS 中的一个公钥（支持 RSA 和 EC）
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// usernameFrom 从第一个可用的候选值（preferred_username、邮箱前缀、姓名）生成用户名，都不可用时返回空
+func usernameFrom(candidates ...string) string {
+	for _, candidate := range candidates {
+		if i := strings.Index(candidate, "@"); i >= 0 {
+			candidate = candidate[:i]
+		}
+		candidate = strings.Trim(usernameInvalidChars.ReplaceAllString(candidate, "_"), "_")
+		if len(candidate) > 30 {
+			candidate = candidate[:30]
+		}
+		if len(candidate) >= 3 {
+			return candidate
+		}
+	}
+	return ""
+}
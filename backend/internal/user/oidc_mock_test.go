@@ -0,0 +1,196 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"linguaforge/internal/common"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCGrant 模拟提供方签发的授权码
+type mockOIDCGrant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	subject     string
+	expiresAt   time.Time
+}
+
+type mockOIDC struct {
+	server *httptest.Server
+
+	mu           sync.Mutex
+	key          *rsa.PrivateKey
+	kid          string
+	keyVersion   int
+	grants       map[string]*mockOIDCGrant
+	jwksRequests int
+}
+
+// newMockOIDC 启动模拟 OIDC 提供方，Issuer 为 server.URL
+// 授权端点不显示登录页而是直接同意并跳回，用户由 login_hint 指定（默认 mock-user），
+// 邮箱为 <login_hint>@example.com 且已验证；令牌端点会校验 client_id、redirect_uri 和 PKCE，授权码只能使用一次
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+	m := &mockOIDC{grants: make(map[string]*mockOIDCGrant)}
+	m.rotateKey(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/jwks", m.jwks)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// rotateKey 换用新的签名密钥，JWKS 只发布新密钥
+func (m *mockOIDC) rotateKey(t *testing.T) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keyVersion++
+	m.key = key
+	m.kid = "mock-" + strconv.Itoa(m.keyVersion)
+}
+
+func (m *mockOIDC) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := mockIssuer(r)
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *mockOIDC) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	subject := query.Get("login_hint")
+	if subject == "" {
+		subject = "mock-user"
+	}
+
+	code := common.RandomToken(32)
+	m.mu.Lock()
+	m.grants[code] = &mockOIDCGrant{
+		clientID:    query.Get("client_id"),
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		subject:     subject,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	m.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	m.mu.Lock()
+	grant := m.grants[r.PostForm.Get("code")]
+	delete(m.grants, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if grant == nil || time.Now().After(grant.expiresAt) ||
+		grant.clientID != r.PostForm.Get("client_id") || grant.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                mockIssuer(r),
+		"sub":                grant.subject,
+		"aud":                grant.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              grant.nonce,
+		"email":              grant.subject + "@example.com",
+		"email_verified":     true,
+		"name":               grant.subject,
+		"preferred_username": grant.subject,
+	})
+	m.mu.Lock()
+	token.Header["kid"] = m.kid
+	idToken, err := token.SignedString(m.key)
+	m.mu.Unlock()
+	if err != nil {
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": common.RandomToken(32),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (m *mockOIDC) jwks(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jwksRequests++
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": m.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+func mockIssuer(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,200 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"linguaforge/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestOIDCProvider(t *testing.T, m *mockOIDC) *oidcProvider {
+	t.Helper()
+	providers := newOIDCProviders(config.OIDCConfig{
+		RedirectBase: "http://app.test",
+		Providers: []config.OIDCProviderConfig{{
+			ID: "mock", Name: "Mock", Issuer: m.server.URL, ClientID: "client", Scopes: []string{"openid", "email"},
+		}},
+	})
+	return providers["mock"]
+}
+
+// authorize 走一遍授权端点，返回授权码
+func authorize(t *testing.T, p *oidcProvider, verifier, nonce, subject string) string {
+	t.Helper()
+	discovery, err := p.discover(context.Background())
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.redirectURL},
+		"state":                 {"state"},
+		"nonce":                 {nonce},
+		"login_hint":            {subject},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(discovery.AuthorizationEndpoint + "?" + query.Encode())
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Query().Get("code") == "" {
+		t.Fatalf("authorize: unexpected redirect %q (status %d)", resp.Header.Get("Location"), resp.StatusCode)
+	}
+	return location.Query().Get("code")
+}
+
+// idToken 换取一个 nonce 为 nonce 的 ID Token
+func idToken(t *testing.T, p *oidcProvider, nonce, subject string) string {
+	t.Helper()
+	code := authorize(t, p, "verifier", nonce, subject)
+	token, err := p.exchange(context.Background(), code, "verifier")
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	return token
+}
+
+func TestOIDCExchangeAndVerify(t *testing.T) {
+	p := newTestOIDCProvider(t, newMockOIDC(t))
+	claims, err := p.verifyIDToken(context.Background(), idToken(t, p, "nonce-1", "alice"), "nonce-1")
+	if err != nil {
+		t.Fatalf("verifyIDToken: %v", err)
+	}
+	if claims.Subject != "alice" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestOIDCPKCEMismatch(t *testing.T) {
+	p := newTestOIDCProvider(t, newMockOIDC(t))
+	code := authorize(t, p, "verifier", "nonce", "alice")
+	if _, err := p.exchange(context.Background(), code, "another-verifier"); !errors.Is(err, ErrOIDCAuthFailed) {
+		t.Fatalf("exchange with wrong verifier: got %v, want ErrOIDCAuthFailed", err)
+	}
+}
+
+func TestOIDCCodeReuse(t *testing.T) {
+	p := newTestOIDCProvider(t, newMockOIDC(t))
+	ctx := context.Background()
+	code := authorize(t, p, "verifier", "nonce", "alice")
+	if _, err := p.exchange(ctx, code, "verifier"); err != nil {
+		t.Fatalf("first exchange: %v", err)
+	}
+	if _, err := p.exchange(ctx, code, "verifier"); !errors.Is(err, ErrOIDCAuthFailed) {
+		t.Fatalf("reused code: got %v, want ErrOIDCAuthFailed", err)
+	}
+}
+
+func TestOIDCNonceMismatch(t *testing.T) {
+	p := newTestOIDCProvider(t, newMockOIDC(t))
+	token := idToken(t, p, "nonce-1", "alice")
+	for _, nonce := range []string{"nonce-2", ""} {
+		if _, err := p.verifyIDToken(context.Background(), token, nonce); !errors.Is(err, ErrOIDCAuthFailed) {
+			t.Fatalf("verify with nonce %q: got %v, want ErrOIDCAuthFailed", nonce, err)
+		}
+	}
+}
+
+func TestOIDCRejectsWrongAudience(t *testing.T) {
+	m := newMockOIDC(t)
+	p := newTestOIDCProvider(t, m)
+	token := idToken(t, p, "nonce", "alice")
+
+	other := newTestOIDCProvider(t, m)
+	other.cfg.ClientID = "other-client"
+	if _, err := other.verifyIDToken(context.Background(), token, "nonce"); !errors.Is(err, ErrOIDCAuthFailed) {
+		t.Fatalf("verify for another client: got %v, want ErrOIDCAuthFailed", err)
+	}
+}
+
+func TestOIDCJWKSRotation(t *testing.T) {
+	m := newMockOIDC(t)
+	p := newTestOIDCProvider(t, m)
+	ctx := context.Background()
+
+	oldToken := idToken(t, p, "nonce", "alice")
+	if _, err := p.verifyIDToken(ctx, oldToken, "nonce"); err != nil {
+		t.Fatalf("verify before rotation: %v", err)
+	}
+
+	m.rotateKey(t)
+	newToken := idToken(t, p, "nonce", "alice")
+
+	// 刚拉取过 JWKS 时遇到未知 kid 不会立即重新拉取
+	if _, err := p.verifyIDToken(ctx, newToken, "nonce"); !errors.Is(err, ErrOIDCAuthFailed) {
+		t.Fatalf("verify right after refresh: got %v, want ErrOIDCAuthFailed", err)
+	}
+	if m.jwksRequests != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", m.jwksRequests)
+	}
+
+	p.keysFetchedAt = time.Now().Add(-jwksMinRefresh)
+	if _, err := p.verifyIDToken(ctx, newToken, "nonce"); err != nil {
+		t.Fatalf("verify after rotation: %v", err)
+	}
+	if m.jwksRequests != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", m.jwksRequests)
+	}
+	// 旧密钥已不在 JWKS 中
+	if _, err := p.verifyIDToken(ctx, oldToken, "nonce"); !errors.Is(err, ErrOIDCAuthFailed) {
+		t.Fatalf("verify with retired key: got %v, want ErrOIDCAuthFailed", err)
+	}
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newMockOIDC(t)
+	cfg := &config.Config{OIDC: config.OIDCConfig{
+		RedirectBase: "http://app.test",
+		Providers:    []config.OIDCProviderConfig{{ID: "mock", Issuer: m.server.URL, ClientID: "client"}},
+	}}
+	// 没有 Redis：Cookie 校验失败时不会读取 state
+	h := NewHandlers(&Service{cfg: cfg, oidc: newOIDCProviders(cfg.OIDC)})
+	router := gin.New()
+	router.GET("/api/v1/auth/oidc/:provider/callback", h.OIDCCallback)
+
+	tests := []struct {
+		name   string
+		cookie string
+	}{
+		{"missing cookie", ""},
+		{"cookie for another state", oidcStateHash("another-state")},
+		{"raw state instead of hash", "victim-state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/mock/callback?code=abc&state=victim-state", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+			cleared := false
+			for _, c := range w.Result().Cookies() {
+				if c.Name == oidcStateCookie && c.MaxAge < 0 {
+					cleared = true
+				}
+			}
+			if !cleared {
+				t.Fatal("state cookie was not cleared")
+			}
+		})
+	}
+}
@@ -1,28 +1,42 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"linguaforge/config"
+	"linguaforge/internal/events"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-	db  *sql.DB
-	cfg *config.Config
+	db     *sql.DB
+	redis  *redis.Client
+	cfg    *config.Config
+	wechat WeChatClient
+	oidc   map[string]*oidcProvider
 }
 
-func NewService(db *sql.DB, cfg *config.Config) *Service {
+func NewService(db *sql.DB, redis *redis.Client, cfg *config.Config) *Service {
 	return &Service{
-		db:  db,
-		cfg: cfg,
+		db:     db,
+		redis:  redis,
+		cfg:    cfg,
+		wechat: NewWeChatClient(cfg.WeChat, cfg.Environment),
+		oidc:   newOIDCProviders(cfg.OIDC),
 	}
 }
 
+// SetWeChatClient 替换微信登录客户端（用于接入模拟服务）
+func (s *Service) SetWeChatClient(client WeChatClient) {
+	s.wechat = client
+}
+
 // DB 暴露底层数据库（仅用于简单更新场景）
 func (s *Service) DB() *sql.DB {
 	return s.db
@@ -55,11 +69,22 @@ func (s *Service) Register(req *RegisterRequest) (*User, error) {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	role := req.Role
+	if role == "" {
+		role = RoleStudent
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// 创建用户
-	result, err := s.db.Exec(`
-		INSERT INTO users (username, email, password_hash, level, experience, coins)
-		VALUES (?, ?, ?, 1, 0, 0)
-	`, req.Username, req.Email, string(hashedPassword))
+	result, err := tx.Exec(`
+		INSERT INTO users (username, email, role, password_hash, level, experience, coins)
+		VALUES (?, ?, ?, ?, 1, 0, 0)
+	`, req.Username, req.Email, role, string(hashedPassword))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -69,6 +94,16 @@ func (s *Service) Register(req *RegisterRequest) (*User, error) {
 		return nil, fmt.Errorf("failed to get user ID: %w", err)
 	}
 
+	if err := events.Write(tx, events.UserRegistered, int(userID), &events.UserRegisteredPayload{
+		Username: req.Username,
+		Role:     string(role),
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user: %w", err)
+	}
+
 	// 获取创建的用户
 	user, err := s.GetByID(int(userID))
 	if err != nil {
@@ -78,41 +113,35 @@ func (s *Service) Register(req *RegisterRequest) (*User, error) {
 	return user, nil
 }
 
-// Login 用户登录
-func (s *Service) Login(req *LoginRequest) (*LoginResponse, error) {
-	// 查找用户
-	user, err := s.GetByUsername(req.Username)
+// Login 用户登录；开启了两步验证时返回挑战 token，需再调用 VerifyTwoFactor
+// 连续失败过多时账号暂时锁定（返回 LockedError）
+func (s *Service) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+	user, err := s.checkPassword(ctx, req.Username, req.Password, req.ClientIP)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, err
 	}
 
-	// 验证密码
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
-	if err != nil {
-		return nil, errors.New("invalid credentials")
-	}
+	return s.issueLogin(ctx, user, req.ClientIP)
+}
 
-	// 生成JWT token
-	token, err := s.generateToken(user.ID, user.Username)
+// loginAs 为已通过第三方身份验证的用户签发 token
+func (s *Service) loginAs(ctx context.Context, userID int) (*LoginResponse, error) {
+	user, err := s.GetByID(userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
-
-	return &LoginResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	return s.issueLogin(ctx, user, "")
 }
 
 // GetByID 根据ID获取用户
 func (s *Service) GetByID(id int) (*User, error) {
 	user := &User{}
 	err := s.db.QueryRow(`
-		SELECT id, username, email, password_hash, level, experience, coins, preferred_category, created_at, updated_at
+		SELECT id, username, COALESCE(email, ''), role, password_hash, level, experience, coins, preferred_category, preferred_level, created_at, updated_at
 		FROM users WHERE id = ?
 	`, id).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Level, &user.Experience, &user.Coins, &user.PreferredCategory, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Username, &user.Email, &user.Role, &user.PasswordHash,
+		&user.Level, &user.Experience, &user.Coins, &user.PreferredCategory, &user.PreferredLevel, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -127,11 +156,11 @@ func (s *Service) GetByID(id int) (*User, error) {
 func (s *Service) GetByUsername(username string) (*User, error) {
 	user := &User{}
 	err := s.db.QueryRow(`
-		SELECT id, username, email, password_hash, level, experience, coins, preferred_category, created_at, updated_at
+		SELECT id, username, COALESCE(email, ''), role, password_hash, level, experience, coins, preferred_category, preferred_level, created_at, updated_at
 		FROM users WHERE username = ?
 	`, username).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Level, &user.Experience, &user.Coins, &user.PreferredCategory, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Username, &user.Email, &user.Role, &user.PasswordHash,
+		&user.Level, &user.Experience, &user.Coins, &user.PreferredCategory, &user.PreferredLevel, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -146,11 +175,11 @@ func (s *Service) GetByUsername(username string) (*User, error) {
 func (s *Service) GetProfile(userID int) (*UserProfile, error) {
 	profile := &UserProfile{}
 	err := s.db.QueryRow(`
-		SELECT id, username, email, level, experience, coins, preferred_category
+		SELECT id, username, COALESCE(email, ''), role, level, experience, coins, preferred_category, preferred_level
 		FROM users WHERE id = ?
 	`, userID).Scan(
-		&profile.ID, &profile.Username, &profile.Email,
-		&profile.Level, &profile.Experience, &profile.Coins, &profile.PreferredCategory,
+		&profile.ID, &profile.Username, &profile.Email, &profile.Role,
+		&profile.Level, &profile.Experience, &profile.Coins, &profile.PreferredCategory, &profile.PreferredLevel,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -161,6 +190,31 @@ func (s *Service) GetProfile(userID int) (*UserProfile, error) {
 	return profile, nil
 }
 
+// GetRole 获取用户角色
+func (s *Service) GetRole(userID int) (Role, error) {
+	var role Role
+	err := s.db.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New("user not found")
+		}
+		return "", fmt.Errorf("failed to get user role: %w", err)
+	}
+	return role, nil
+}
+
+// SetRole 设置用户角色
+func (s *Service) SetRole(userID int, role Role) error {
+	result, err := s.db.Exec("UPDATE users SET role = ?, updated_at = NOW() WHERE id = ?", role, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
 // AddExperience 增加经验值
 func (s *Service) AddExperience(userID int, exp int) error {
 	_, err := s.db.Exec(`
@@ -187,13 +241,20 @@ func (s *Service) AddCoins(userID int, coins int) error {
 
 // generateToken 生成JWT token
 func (s *Service) generateToken(userID int, username string) (string, error) {
+	return s.signToken(userID, username, time.Duration(s.cfg.JWT.ExpireHours)*time.Hour, "")
+}
+
+// signToken 签发 JWT；scope 非空时为受限 token
+func (s *Service) signToken(userID int, username string, ttl time.Duration, scope string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id":  userID,
 		"username": username,
-		"exp":      time.Now().Add(time.Duration(s.cfg.JWT.ExpireHours) * time.Hour).Unix(),
+		"exp":      time.Now().Add(ttl).Unix(),
 		"iat":      time.Now().Unix(),
 	}
-
+	if scope != "" {
+		claims["scope"] = scope
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.cfg.JWT.Secret))
 }
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linguaforge/internal/common"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// streamTicketTTL 推送连接凭证的有效期：客户端拿到后应立即建立连接
+const streamTicketTTL = 30 * time.Second
+
+var ErrInvalidStreamTicket = errors.New("invalid or expired stream ticket")
+
+// streamTicket 保存在 Redis 中的推送连接凭证
+type streamTicket struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// IssueStreamTicket 为 SSE 连接签发一次性凭证，代替把 JWT 放进查询参数（查询参数会写入访问日志）
+func (s *Service) IssueStreamTicket(ctx context.Context, userID int, username string) (string, error) {
+	data, err := json.Marshal(&streamTicket{UserID: userID, Username: username})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode stream ticket: %w", err)
+	}
+	ticket := common.RandomToken(32)
+	if err := s.redis.Set(ctx, "stream:ticket:"+ticket, data, streamTicketTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to save stream ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+// redeemStreamTicket 校验并作废推送连接凭证
+func (s *Service) redeemStreamTicket(ctx context.Context, ticket string) (*streamTicket, error) {
+	raw, err := s.redis.GetDel(ctx, "stream:ticket:"+ticket).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidStreamTicket
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream ticket: %w", err)
+	}
+	var t streamTicket
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, ErrInvalidStreamTicket
+	}
+	return &t, nil
+}
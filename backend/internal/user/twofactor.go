@@ -0,0 +1,500 @@
+package user
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"linguaforge/internal/common"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// totpIssuer 认证器 App 中显示的发行方
+	totpIssuer = "LinguaForge"
+	// totpPeriod 验证码的时间步长（秒）
+	totpPeriod = 30
+	// totpSkew 允许前后偏差的时间步数，兼容手机时钟误差
+	totpSkew = 1
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// twoFactorChallengeTTL 密码验证通过后输入验证码的时限
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts 每个挑战最多尝试的次数
+	twoFactorMaxAttempts = 5
+	// setupTokenTTL 只能用于开启两步验证的 token 有效期
+	setupTokenTTL = 30 * time.Minute
+	// scopeTwoFactorSetup 受限 token 的 scope：只能访问 /profile/2fa 相关接口
+	scopeTwoFactorSetup = "2fa_setup"
+)
+
+var (
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp       = errors.New("start two-factor setup first")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for your role")
+	ErrInvalidTwoFactorCode    = errors.New("invalid verification code")
+	ErrInvalidChallenge        = errors.New("invalid or expired two-factor challenge")
+)
+
+// TwoFactorStatus 当前账号的两步验证状态
+func (s *Service) TwoFactorStatus(userID int) (*TwoFactorStatus, error) {
+	role, err := s.GetRole(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{}
+	if status.Required, err = s.twoFactorRequired(role); err != nil {
+		return nil, err
+	}
+
+	var confirmedAt sql.NullTime
+	err = s.db.QueryRow("SELECT confirmed_at FROM user_totp WHERE user_id = ?", userID).Scan(&confirmedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get two-factor status: %w", err)
+	}
+	if !confirmedAt.Valid {
+		return status, nil
+	}
+	status.Enabled = true
+	status.EnabledAt = &confirmedAt.Time
+	err = s.db.QueryRow(`
+		SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL
+	`, userID).Scan(&status.RecoveryCodesRemaining)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return status, nil
+}
+
+// SetupTwoFactor 生成新的 TOTP 密钥（覆盖未确认的旧密钥），返回密钥和用于生成二维码的 otpauth:// 地址
+func (s *Service) SetupTwoFactor(userID int) (*TwoFactorSetup, error) {
+	user, err := s.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, confirmed, err := s.totpSecret(userID); err != nil {
+		return nil, err
+	} else if confirmed {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+	if _, err := s.db.Exec(`
+		INSERT INTO user_totp (user_id, secret) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE secret = VALUES(secret), last_used_step = NULL, created_at = NOW()
+	`, userID, secret); err != nil {
+		return nil, fmt.Errorf("failed to save secret: %w", err)
+	}
+
+	label := url.PathEscape(totpIssuer + ":" + user.Username)
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {totpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {"6"},
+		"period":    {strconv.Itoa(totpPeriod)},
+	}
+	return &TwoFactorSetup{Secret: secret, ProvisioningURI: "otpauth://totp/" + label + "?" + query.Encode()}, nil
+}
+
+// ConfirmTwoFactor 用认证器中的验证码确认绑定，开启两步验证并生成恢复码；
+// 同时签发新的完整 token（受限 token 的用户开启后可直接继续使用）
+func (s *Service) ConfirmTwoFactor(userID int, code string) (*TwoFactorConfirmResponse, error) {
+	secret, confirmed, err := s.totpSecret(userID)
+	if err != nil {
+		return nil, err
+	}
+	if confirmed {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if secret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	if ok, err := s.verifyTOTP(userID, secret, code); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE user_totp SET confirmed_at = NOW() WHERE user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit two-factor authentication: %w", err)
+	}
+
+	user, err := s.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.generateToken(user.ID, user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return &TwoFactorConfirmResponse{RecoveryCodes: codes, Token: token}, nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（旧的全部作废），需要当前的 TOTP 验证码
+func (s *Service) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	secret, confirmed, err := s.totpSecret(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if ok, err := s.verifyTOTP(userID, secret, code); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor 关闭两步验证：需要密码（设置过时）和验证码或恢复码；角色被要求开启时不能关闭
+func (s *Service) DisableTwoFactor(userID int, req *DisableTwoFactorRequest) error {
+	user, err := s.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if required, err := s.twoFactorRequired(user.Role); err != nil {
+		return err
+	} else if required {
+		return ErrTwoFactorRequired
+	}
+	if user.PasswordHash != "" && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return errors.New("invalid password")
+	}
+	if ok, err := s.verifySecondFactor(userID, req.Code); err != nil {
+		return err
+	} else if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	return s.ResetTwoFactor(userID)
+}
+
+// ResetTwoFactor 删除用户的 TOTP 密钥和恢复码（用户丢失设备时由管理员重置）
+func (s *Service) ResetTwoFactor(userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec("DELETE FROM user_totp WHERE user_id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete two-factor secret: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTwoFactorNotEnabled
+	}
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit two-factor reset: %w", err)
+	}
+	return nil
+}
+
+// VerifyTwoFactor 登录第二步：用挑战 token 和验证码（或恢复码）换取正式 token
+func (s *Service) VerifyTwoFactor(ctx context.Context, req *VerifyTwoFactorRequest) (*LoginResponse, error) {
+	key := "2fa:challenge:" + req.ChallengeToken
+	userID, err := s.redis.Get(ctx, key).Int()
+	if err == redis.Nil {
+		return nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor challenge: %w", err)
+	}
+
+	if err := s.countChallengeAttempt(ctx, key); err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLockout(ctx, user.Username, req.ClientIP); err != nil {
+		return nil, err
+	}
+	ok, err := s.verifySecondFactor(userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 验证码错误与密码错误一样计入锁定次数，避免换新挑战无限尝试
+		if err := s.recordLoginFailure(ctx, user.Username, req.ClientIP); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+	// 挑战只能使用一次；并发请求中只有删除成功的一方能登录
+	if n, err := s.redis.Del(ctx, key, key+":attempts").Result(); err != nil || n == 0 {
+		return nil, ErrInvalidChallenge
+	}
+
+	s.clearLoginFailures(ctx, user.Username, req.ClientIP)
+	token, err := s.generateToken(user.ID, user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return &LoginResponse{Token: token, User: user}, nil
+}
+
+// countChallengeAttempt 记录一次验证码尝试；超过 twoFactorMaxAttempts 次后作废挑战
+func (s *Service) countChallengeAttempt(ctx context.Context, key string) error {
+	attempts, err := s.redis.Incr(ctx, key+":attempts").Result()
+	if err != nil {
+		return fmt.Errorf("failed to count two-factor attempts: %w", err)
+	}
+	if attempts == 1 {
+		s.redis.Expire(ctx, key+":attempts", twoFactorChallengeTTL)
+	}
+	if attempts > twoFactorMaxAttempts {
+		s.redis.Del(ctx, key, key+":attempts")
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// GetTwoFactorPolicy 被要求开启两步验证的角色
+func (s *Service) GetTwoFactorPolicy() (*TwoFactorPolicy, error) {
+	rows, err := s.db.Query("SELECT role FROM two_factor_policy ORDER BY role")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query two-factor policy: %w", err)
+	}
+	defer rows.Close()
+
+	policy := &TwoFactorPolicy{RequiredRoles: []Role{}}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("failed to scan two-factor policy: %w", err)
+		}
+		policy.RequiredRoles = append(policy.RequiredRoles, role)
+	}
+	return policy, nil
+}
+
+// SetTwoFactorPolicy 设置被要求开启两步验证的角色；对之后的登录生效
+func (s *Service) SetTwoFactorPolicy(adminID int, roles []Role) (*TwoFactorPolicy, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM two_factor_policy"); err != nil {
+		return nil, fmt.Errorf("failed to clear two-factor policy: %w", err)
+	}
+	for _, role := range roles {
+		if _, err := tx.Exec(`
+			INSERT IGNORE INTO two_factor_policy (role, updated_by) VALUES (?, ?)
+		`, role, adminID); err != nil {
+			return nil, fmt.Errorf("failed to save two-factor policy: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit two-factor policy: %w", err)
+	}
+	return s.GetTwoFactorPolicy()
+}
+
+// issueLogin 身份验证通过后签发 token：开启了两步验证时只返回挑战 token；
+// 角色被要求开启但尚未开启时返回只能用于开启两步验证的受限 token；clientIP 为空时只清除用户名的全局失败记录
+func (s *Service) issueLogin(ctx context.Context, user *User, clientIP string) (*LoginResponse, error) {
+	_, enabled, err := s.totpSecret(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		challenge := common.RandomToken(32)
+		if err := s.redis.Set(ctx, "2fa:challenge:"+challenge, user.ID, twoFactorChallengeTTL).Err(); err != nil {
+			return nil, fmt.Errorf("failed to save two-factor challenge: %w", err)
+		}
+		return &LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	s.clearLoginFailures(ctx, user.Username, clientIP)
+	required, err := s.twoFactorRequired(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		token, err := s.signToken(user.ID, user.Username, setupTokenTTL, scopeTwoFactorSetup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		return &LoginResponse{Token: token, User: user, TwoFactorSetupRequired: true}, nil
+	}
+
+	token, err := s.generateToken(user.ID, user.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return &LoginResponse{Token: token, User: user}, nil
+}
+
+// twoFactorRequired 该角色是否被要求开启两步验证
+func (s *Service) twoFactorRequired(role Role) (bool, error) {
+	var required bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM two_factor_policy WHERE role = ?)", role).Scan(&required)
+	if err != nil {
+		return false, fmt.Errorf("failed to get two-factor policy: %w", err)
+	}
+	return required, nil
+}
+
+// totpSecret 用户的 TOTP 密钥及是否已确认；没有时返回空字符串
+func (s *Service) totpSecret(userID int) (string, bool, error) {
+	var secret string
+	var confirmedAt sql.NullTime
+	err := s.db.QueryRow("SELECT secret, confirmed_at FROM user_totp WHERE user_id = ?", userID).Scan(&secret, &confirmedAt)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get two-factor secret: %w", err)
+	}
+	return secret, confirmedAt.Valid, nil
+}
+
+// verifySecondFactor 校验 6 位 TOTP 验证码，其他格式按恢复码处理（使用后作废）
+func (s *Service) verifySecondFactor(userID int, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		secret, confirmed, err := s.totpSecret(userID)
+		if err != nil {
+			return false, err
+		}
+		if !confirmed {
+			return false, ErrTwoFactorNotEnabled
+		}
+		return s.verifyTOTP(userID, secret, code)
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE user_recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`, userID, hashRecoveryCode(code))
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n == 1, nil
+}
+
+// verifyTOTP 校验验证码并记录使用的时间步，同一时间步（及更早）的验证码不能再次使用
+func (s *Service) verifyTOTP(userID int, secret, code string) (bool, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return false, fmt.Errorf("invalid two-factor secret: %w", err)
+	}
+	step, ok := matchTOTPStep(key, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	result, err := s.db.Exec(`
+		UPDATE user_totp SET last_used_step = ?
+		WHERE user_id = ? AND (last_used_step IS NULL OR last_used_step < ?)
+	`, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor code: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n == 1, nil
+}
+
+// matchTOTPStep 在 now 所在时间步前后 totpSkew 步内查找与验证码一致的时间步
+func matchTOTPStep(key []byte, code string, now time.Time) (int64, bool) {
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode RFC 6238 TOTP（HMAC-SHA1，6 位）
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// replaceRecoveryCodes 作废旧恢复码并生成新的一组，返回明文（只在此时展示给用户）
+func replaceRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := hex.EncodeToString(raw)
+		code = code[:5] + "-" + code[5:]
+		if _, err := tx.Exec(`
+			INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)
+		`, userID, hashRecoveryCode(code)); err != nil {
+			return nil, fmt.Errorf("failed to save recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode 恢复码去掉分隔符、转小写后取 SHA-256
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,208 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/redis/go-redis/v9"
+)
+
+// rfc6238Key RFC 6238 附录 B 中 SHA1 的密钥
+var rfc6238Key = []byte("12345678901234567890")
+
+// newTestRedis 进程内的 Redis
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+// newTestMySQL 连接 TEST_MYSQL_DSN 指定的 MySQL，未设置或连不上时跳过；
+// 只保留一个连接，测试在该连接上创建同名临时表，不会读写库中已有的数据
+func newTestMySQL(t *testing.T, schema ...string) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	if err := db.Ping(); err != nil {
+		t.Skipf("mysql not available: %v", err)
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("create schema: %v", err)
+		}
+	}
+	return db
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// 附录 B 的 8 位验证码取后 6 位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTPStepSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	tests := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"one step behind", -1, true},
+		{"one step ahead", 1, true},
+		{"two steps behind", -2, false},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := matchTOTPStep(rfc6238Key, totpCode(rfc6238Key, current+tt.offset), now)
+			if ok != tt.ok {
+				t.Fatalf("matchTOTPStep ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && step != current+tt.offset {
+				t.Fatalf("matched step %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestChallengeAttemptLimit(t *testing.T) {
+	mr, client := newTestRedis(t)
+	s := &Service{redis: client}
+	ctx := context.Background()
+	key := "2fa:challenge:token"
+	mr.Set(key, "42")
+
+	for i := 1; i <= twoFactorMaxAttempts; i++ {
+		if err := s.countChallengeAttempt(ctx, key); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	if ttl := mr.TTL(key + ":attempts"); ttl <= 0 || ttl > twoFactorChallengeTTL {
+		t.Fatalf("attempts counter TTL = %v", ttl)
+	}
+	if err := s.countChallengeAttempt(ctx, key); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("attempt %d: got %v, want ErrInvalidChallenge", twoFactorMaxAttempts+1, err)
+	}
+	// 超过次数后挑战作废，换不到 token
+	if mr.Exists(key) || mr.Exists(key+":attempts") {
+		t.Fatal("challenge not deleted after too many attempts")
+	}
+	if _, err := s.VerifyTwoFactor(ctx, &VerifyTwoFactorRequest{ChallengeToken: "token", Code: "123456"}); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("verify with a burned challenge: got %v, want ErrInvalidChallenge", err)
+	}
+}
+
+const (
+	testTOTPTable = `CREATE TEMPORARY TABLE user_totp (
+		user_id INT PRIMARY KEY, secret VARCHAR(64) NOT NULL, confirmed_at TIMESTAMP NULL, last_used_step BIGINT NULL
+	)`
+	testRecoveryTable = `CREATE TEMPORARY TABLE user_recovery_codes (
+		id INT AUTO_INCREMENT PRIMARY KEY, user_id INT NOT NULL, code_hash CHAR(64) NOT NULL, used_at TIMESTAMP NULL,
+		UNIQUE KEY uk_user_code (user_id, code_hash)
+	)`
+)
+
+func TestVerifyTOTPRejectsReusedStep(t *testing.T) {
+	db := newTestMySQL(t, testTOTPTable)
+	s := &Service{db: db}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(rfc6238Key)
+	if _, err := db.Exec("INSERT INTO user_totp (user_id, secret, confirmed_at) VALUES (1, ?, NOW())", secret); err != nil {
+		t.Fatal(err)
+	}
+
+	current := time.Now().Unix() / totpPeriod
+	code := totpCode(rfc6238Key, current)
+	if ok, err := s.verifyTOTP(1, secret, code); err != nil || !ok {
+		t.Fatalf("first use: ok=%v err=%v", ok, err)
+	}
+	if ok, err := s.verifyTOTP(1, secret, code); err != nil || ok {
+		t.Fatalf("reused code: ok=%v err=%v, want rejected", ok, err)
+	}
+	// 上一个时间步的验证码虽在偏差范围内，但早于已使用的时间步
+	if ok, err := s.verifyTOTP(1, secret, totpCode(rfc6238Key, current-1)); err != nil || ok {
+		t.Fatalf("older step: ok=%v err=%v, want rejected", ok, err)
+	}
+	var last int64
+	if err := db.QueryRow("SELECT last_used_step FROM user_totp WHERE user_id = 1").Scan(&last); err != nil || last != current {
+		t.Fatalf("last_used_step = %d (%v), want %d", last, err, current)
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	db := newTestMySQL(t, testRecoveryTable)
+	s := &Service{db: db}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := replaceRecoveryCodes(tx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	var stored string
+	if err := db.QueryRow("SELECT code_hash FROM user_recovery_codes WHERE user_id = 1 LIMIT 1").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if stored == code {
+			t.Fatal("recovery code stored in plain text")
+		}
+	}
+
+	// 输入时忽略大小写和分隔符
+	if ok, err := s.verifySecondFactor(1, "  "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))+" "); err != nil || !ok {
+		t.Fatalf("first use: ok=%v err=%v", ok, err)
+	}
+	if ok, err := s.verifySecondFactor(1, codes[0]); err != nil || ok {
+		t.Fatalf("reused recovery code: ok=%v err=%v, want rejected", ok, err)
+	}
+	if ok, err := s.verifySecondFactor(2, codes[1]); err != nil || ok {
+		t.Fatalf("another user's recovery code: ok=%v err=%v, want rejected", ok, err)
+	}
+	if ok, err := s.verifySecondFactor(1, codes[1]); err != nil || !ok {
+		t.Fatalf("second code: ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,231 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linguaforge/config"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProviderWeChat 微信小程序身份
+const ProviderWeChat = "wechat"
+
+var (
+	ErrWeChatNotConfigured = errors.New("WeChat login is not configured")
+	ErrInvalidWeChatCode   = errors.New("invalid or expired WeChat login code")
+)
+
+// WeChatSession code2session 的结果
+type WeChatSession struct {
+	OpenID     string
+	UnionID    string // 小程序绑定到开放平台时才有
+	SessionKey string
+}
+
+// WeChatClient 用 wx.login 得到的 code 换取 openid，可替换为模拟实现
+type WeChatClient interface {
+	Code2Session(ctx context.Context, code string) (*WeChatSession, error)
+}
+
+// NewWeChatClient 按配置创建客户端：配置了 AppID 时调用微信接口（APIBase 可指向本地模拟服务）；
+// 显式开启 Fake 且不是生产环境时使用 FakeWeChatClient；其余情况返回 nil（关闭微信登录）
+func NewWeChatClient(cfg config.WeChatConfig, environment string) WeChatClient {
+	if cfg.AppID != "" {
+		return &HTTPWeChatClient{
+			appID:   cfg.AppID,
+			secret:  cfg.AppSecret,
+			apiBase: strings.TrimSuffix(cfg.APIBase, "/"),
+			client:  &http.Client{Timeout: 5 * time.Second},
+		}
+	}
+	if !cfg.Fake {
+		return nil
+	}
+	if environment == "production" {
+		log.Printf("wechat: WECHAT_FAKE is ignored in production, WeChat login disabled")
+		return nil
+	}
+	log.Printf("wechat: WECHAT_FAKE enabled, using fake WeChat client")
+	return FakeWeChatClient{}
+}
+
+// HTTPWeChatClient 调用 auth.code2Session 接口
+type HTTPWeChatClient struct {
+	appID   string
+	secret  string
+	apiBase string
+	client  *http.Client
+}
+
+func (c *HTTPWeChatClient) Code2Session(ctx context.Context, code string) (*WeChatSession, error) {
+	query := url.Values{
+		"appid":      {c.appID},
+		"secret":     {c.secret},
+		"js_code":    {code},
+		"grant_type": {"authorization_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiBase+"/sns/jscode2session?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build code2session request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call code2session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OpenID     string `json:"openid"`
+		UnionID    string `json:"unionid"`
+		SessionKey string `json:"session_key"`
+		ErrCode    int    `json:"errcode"`
+		ErrMsg     string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode code2session response (status %d): %w", resp.StatusCode, err)
+	}
+	switch result.ErrCode {
+	case 0:
+	case 40029, 40163: // code 无效 / 已被使用
+		return nil, ErrInvalidWeChatCode
+	default:
+		return nil, fmt.Errorf("code2session failed: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	if result.OpenID == "" {
+		return nil, errors.New("code2session returned no openid")
+	}
+	return &WeChatSession{OpenID: result.OpenID, UnionID: result.UnionID, SessionKey: result.SessionKey}, nil
+}
+
+// FakeWeChatClient 开发环境的模拟实现：同一个 code 总是得到同一个 openid
+type FakeWeChatClient struct{}
+
+func (FakeWeChatClient) Code2Session(ctx context.Context, code string) (*WeChatSession, error) {
+	if code == "" || code == "invalid" {
+		return nil, ErrInvalidWeChatCode
+	}
+	return &WeChatSession{OpenID: fakeOpenID(code), SessionKey: "fake"}, nil
+}
+
+func fakeOpenID(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return "fake_" + hex.EncodeToString(sum[:12])
+}
+
+// WeChatLogin 微信登录：已绑定的直接登录；unionid 已关联其他应用账号的自动绑定；
+// 提供用户名和密码时绑定到该账号；否则自动注册一个学生账号
+func (s *Service) WeChatLogin(ctx context.Context, req *WeChatLoginRequest) (*WeChatLoginResponse, error) {
+	session, err := s.code2Session(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	identity := session.identity()
+
+	userID, err := s.wechatUser(session)
+	if err != nil {
+		return nil, err
+	}
+	created := false
+	if userID == 0 && req.Username != "" {
+		user, err := s.checkPassword(ctx, req.Username, req.Password, req.ClientIP)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.linkIdentity(user.ID, identity); err != nil {
+			return nil, err
+		}
+		userID = user.ID
+	}
+	if userID == 0 {
+		if userID, err = s.provisionUser("", "wx_", "", identity); err != nil {
+			return nil, err
+		}
+		created = true
+	}
+
+	if err := s.touchIdentity(identity); err != nil {
+		return nil, err
+	}
+	response, err := s.loginAs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &WeChatLoginResponse{LoginResponse: *response, Created: created}, nil
+}
+
+// GetWeChatBinding 当前账号的微信绑定状态
+func (s *Service) GetWeChatBinding(userID int) (*WeChatBinding, error) {
+	binding := &WeChatBinding{}
+	var boundAt time.Time
+	err := s.db.QueryRow(`
+		SELECT created_at FROM identities WHERE user_id = ? AND provider = ?
+	`, userID, ProviderWeChat).Scan(&boundAt)
+	if err == sql.ErrNoRows {
+		return binding, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WeChat binding: %w", err)
+	}
+	binding.Bound = true
+	binding.BoundAt = &boundAt
+	return binding, nil
+}
+
+// BindWeChat 为已登录的账号绑定微信
+func (s *Service) BindWeChat(ctx context.Context, userID int, code string) error {
+	session, err := s.code2Session(ctx, code)
+	if err != nil {
+		return err
+	}
+	return s.linkIdentity(userID, session.identity())
+}
+
+// UnbindWeChat 解绑微信
+func (s *Service) UnbindWeChat(userID int) error {
+	return s.UnlinkIdentity(userID, ProviderWeChat)
+}
+
+func (s *Service) code2Session(ctx context.Context, code string) (*WeChatSession, error) {
+	if s.wechat == nil {
+		return nil, ErrWeChatNotConfigured
+	}
+	return s.wechat.Code2Session(ctx, code)
+}
+
+// wechatUser 按 openid 查找已绑定的用户；没有时按 unionid 查找同一开放平台下已绑定的用户并自动绑定
+func (s *Service) wechatUser(session *WeChatSession) (int, error) {
+	userID, err := s.identityOwner(ProviderWeChat, session.OpenID)
+	if err != nil || userID != 0 || session.UnionID == "" {
+		return userID, err
+	}
+
+	err = s.db.QueryRow(`
+		SELECT user_id FROM identities WHERE provider = ? AND union_id = ? LIMIT 1
+	`, ProviderWeChat, session.UnionID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get identity: %w", err)
+	}
+	// 同一用户每个 provider 只有一条身份，已有其他 openid 时不再绑定，只登录
+	if _, err := s.db.Exec(`
+		INSERT IGNORE INTO identities (user_id, provider, subject, union_id) VALUES (?, ?, ?, ?)
+	`, userID, ProviderWeChat, session.OpenID, session.UnionID); err != nil {
+		return 0, fmt.Errorf("failed to link identity: %w", err)
+	}
+	return userID, nil
+}
+
+func (session *WeChatSession) identity() *externalIdentity {
+	return &externalIdentity{Provider: ProviderWeChat, Subject: session.OpenID, UnionID: session.UnionID}
+}
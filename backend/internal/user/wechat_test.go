@@ -0,0 +1,130 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"linguaforge/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeWeChatServer 模拟 code2session 接口：校验 appid/secret，code 只能使用一次；
+// code 为 invalid 时返回 40029，形如 xxx:union 时同时返回 unionid，为 broken 时返回非 JSON 的 502
+func fakeWeChatServer(t *testing.T, appID, secret string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	used := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sns/jscode2session" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		code := q.Get("js_code")
+		if code == "broken" {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if q.Get("appid") != appID || q.Get("secret") != secret || q.Get("grant_type") != "authorization_code" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 40013, "errmsg": "invalid appid"})
+			return
+		}
+		mu.Lock()
+		reused := used[code]
+		used[code] = true
+		mu.Unlock()
+		switch {
+		case code == "" || code == "invalid":
+			json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 40029, "errmsg": "invalid code"})
+			return
+		case reused:
+			json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 40163, "errmsg": "code been used"})
+			return
+		}
+		result := map[string]interface{}{"openid": fakeOpenID(code), "session_key": "key"}
+		if i := strings.LastIndex(code, ":"); i >= 0 {
+			result["unionid"] = "union_" + code[i+1:]
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPWeChatClient(t *testing.T) {
+	server := fakeWeChatServer(t, "wx-app", "wx-secret")
+	client := NewWeChatClient(config.WeChatConfig{AppID: "wx-app", AppSecret: "wx-secret", APIBase: server.URL + "/"}, "production")
+	ctx := context.Background()
+
+	session, err := client.Code2Session(ctx, "abc:42")
+	if err != nil {
+		t.Fatalf("Code2Session: %v", err)
+	}
+	if session.OpenID != fakeOpenID("abc:42") || session.UnionID != "union_42" || session.SessionKey != "key" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	if _, err := client.Code2Session(ctx, "abc:42"); !errors.Is(err, ErrInvalidWeChatCode) {
+		t.Fatalf("reused code: got %v, want ErrInvalidWeChatCode", err)
+	}
+	if _, err := client.Code2Session(ctx, "invalid"); !errors.Is(err, ErrInvalidWeChatCode) {
+		t.Fatalf("invalid code: got %v, want ErrInvalidWeChatCode", err)
+	}
+	if _, err := client.Code2Session(ctx, "broken"); err == nil || errors.Is(err, ErrInvalidWeChatCode) {
+		t.Fatalf("broken upstream: got %v, want a non-code error", err)
+	}
+
+	wrongSecret := NewWeChatClient(config.WeChatConfig{AppID: "wx-app", AppSecret: "other", APIBase: server.URL}, "production")
+	if _, err := wrongSecret.Code2Session(ctx, "fresh"); err == nil || errors.Is(err, ErrInvalidWeChatCode) {
+		t.Fatalf("wrong secret: got %v, want a configuration error", err)
+	}
+}
+
+func TestNewWeChatClientFakeIsOptIn(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.WeChatConfig
+		environment string
+		want        string // http / fake / 空表示关闭
+	}{
+		{"no app id defaults to disabled", config.WeChatConfig{}, "development", ""},
+		{"fake opt-in", config.WeChatConfig{Fake: true}, "development", "fake"},
+		{"fake ignored in production", config.WeChatConfig{Fake: true}, "production", ""},
+		{"app id wins over fake", config.WeChatConfig{AppID: "wx", Fake: true}, "development", "http"},
+		{"app id in production", config.WeChatConfig{AppID: "wx"}, "production", "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			switch NewWeChatClient(tt.cfg, tt.environment).(type) {
+			case *HTTPWeChatClient:
+				got = "http"
+			case FakeWeChatClient:
+				got = "fake"
+			}
+			if got != tt.want {
+				t.Fatalf("NewWeChatClient(%+v, %q) = %q, want %q", tt.cfg, tt.environment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeWeChatClientIsStable(t *testing.T) {
+	ctx := context.Background()
+	a, err := FakeWeChatClient{}.Code2Session(ctx, "dev-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := FakeWeChatClient{}.Code2Session(ctx, "dev-user")
+	if a.OpenID != b.OpenID {
+		t.Fatalf("same code gave different openids %q and %q", a.OpenID, b.OpenID)
+	}
+	if _, err := (FakeWeChatClient{}).Code2Session(ctx, "invalid"); !errors.Is(err, ErrInvalidWeChatCode) {
+		t.Fatalf("invalid code: got %v", err)
+	}
+}
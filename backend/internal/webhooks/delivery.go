@@ -0,0 +1,333 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"linguaforge/config"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// batchSize 每批认领的投递数
+	batchSize = 50
+	// concurrency 同时进行的请求数
+	concurrency = 8
+	// pollInterval 没有待投递记录时的轮询间隔
+	pollInterval = 2 * time.Second
+	// maxAttempts 超过后标记为失败，等待手动重放
+	maxAttempts = 8
+	// baseBackoff 第一次失败后的重试间隔，之后每次翻倍
+	baseBackoff = 30 * time.Second
+	// maxBackoff 重试间隔上限
+	maxBackoff = 6 * time.Hour
+	// maxResponseBody 保存的响应体长度（只保存 2xx 响应的）
+	maxResponseBody = 1000
+
+	userAgent       = "LinguaForge-Webhooks/1.0"
+	signatureHeader = "X-LinguaForge-Signature"
+	eventHeader     = "X-LinguaForge-Event"
+	deliveryHeader  = "X-LinguaForge-Delivery"
+)
+
+var errPrivateAddress = errors.New("destination resolves to a private or loopback address")
+
+// Worker 在后台发送待投递的 Webhook，失败按指数退避重试
+type Worker struct {
+	db           *sql.DB
+	client       *http.Client
+	lockDuration time.Duration
+	wake         chan struct{}
+}
+
+func NewWorker(db *sql.DB, cfg config.WebhooksConfig) *Worker {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 || timeout > time.Minute {
+		timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !cfg.AllowPrivateNetworks {
+		// 在连接已解析的地址时检查，防止通过 DNS 指向内网
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if addr, err := netip.ParseAddr(host); err != nil || isBlockedAddr(addr) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &Worker{
+		db: db,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, MaxIdleConnsPerHost: 2},
+			// 不跟随重定向，3xx 视为失败
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		// 一批内的请求分组并发，认领时间需覆盖整批最坏情况
+		lockDuration: timeout*time.Duration(batchSize/concurrency+1) + time.Minute,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Notify 提示有新的投递，减少延迟
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start 在后台持续投递，ctx 取消后停止
+func (w *Worker) Start(ctx context.Context) {
+	go func() {
+		for {
+			n, err := w.DeliverBatch(ctx)
+			if err != nil {
+				log.Printf("webhooks: %v", err)
+			}
+			if n > 0 && err == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.wake:
+			case <-time.After(pollInterval):
+			}
+		}
+	}()
+}
+
+type pendingDelivery struct {
+	id        int64
+	eventType string
+	payload   string
+	attempts  int
+	url       string
+	secret    string
+	isActive  bool
+}
+
+// DeliverBatch 认领一批到期的投递并发送，返回处理的数量
+func (w *Worker) DeliverBatch(ctx context.Context) (int, error) {
+	token := randomHex(16)
+	result, err := w.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET locked_by = ?, locked_until = NOW(3) + INTERVAL ? SECOND
+		WHERE status = 'pending' AND next_attempt_at <= NOW(3)
+		  AND (locked_until IS NULL OR locked_until < NOW(3))
+		ORDER BY next_attempt_at
+		LIMIT ?
+	`, token, int(w.lockDuration.Seconds()), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+
+	rows, err := w.db.QueryContext(ctx, `
+		SELECT d.id, d.event_type, d.payload, d.attempts, wh.url, wh.secret, wh.is_active
+		FROM webhook_deliveries d
+		JOIN webhooks wh ON wh.id = d.webhook_id
+		WHERE d.locked_by = ?
+	`, token)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load claimed deliveries: %w", err)
+	}
+	var batch []pendingDelivery
+	for rows.Next() {
+		var d pendingDelivery
+		if err := rows.Scan(&d.id, &d.eventType, &d.payload, &d.attempts, &d.url, &d.secret, &d.isActive); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		batch = append(batch, d)
+	}
+	rows.Close()
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range batch {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(d *pendingDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.attempt(ctx, d)
+		}(&batch[i])
+	}
+	wg.Wait()
+	return len(batch), ctx.Err()
+}
+
+// attempt 发送一次并记录结果
+func (w *Worker) attempt(ctx context.Context, d *pendingDelivery) {
+	if !d.isActive {
+		// 订阅已停用：直接标记失败，重新启用后可手动重放
+		w.finish(d, nil, 0, "", errors.New("webhook is disabled"), true)
+		return
+	}
+
+	started := time.Now()
+	statusCode, body, err := w.send(ctx, d)
+	duration := time.Since(started)
+	var code *int
+	if err == nil {
+		code = &statusCode
+		if statusCode < 200 || statusCode >= 300 {
+			err = fmt.Errorf("unexpected status %d", statusCode)
+		}
+	}
+	w.finish(d, code, duration, body, err, false)
+}
+
+func (w *Worker) send(ctx context.Context, d *pendingDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader([]byte(d.payload)))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to build request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(eventHeader, d.eventType)
+	req.Header.Set(deliveryHeader, strconv.FormatInt(d.id, 10))
+	req.Header.Set(signatureHeader, Sign(d.secret, timestamp, []byte(d.payload)))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	// 非 2xx 响应不保存响应体：投递记录对订阅者可见，避免把错误页等内容回显给订阅者
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, "", nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(body), nil
+}
+
+// finish 保存请求记录并更新投递状态；giveUp 时不再重试
+func (w *Worker) finish(d *pendingDelivery, code *int, duration time.Duration, body string, cause error, giveUp bool) {
+	var errText interface{}
+	if cause != nil {
+		errText = cause.Error()
+	}
+	if _, err := w.db.Exec(`
+		INSERT INTO webhook_delivery_attempts (delivery_id, status_code, duration_ms, error, response_body)
+		VALUES (?, ?, ?, ?, ?)
+	`, d.id, code, duration.Milliseconds(), errText, body); err != nil {
+		log.Printf("webhooks: failed to record attempt of delivery %d: %v", d.id, err)
+	}
+
+	attempts := d.attempts + 1
+	if cause == nil {
+		_, err := w.db.Exec(`
+			UPDATE webhook_deliveries
+			SET status = 'succeeded', attempts = ?, last_status_code = ?, last_error = NULL,
+			    delivered_at = NOW(3), locked_by = NULL, locked_until = NULL
+			WHERE id = ?
+		`, attempts, code, d.id)
+		if err != nil {
+			log.Printf("webhooks: failed to mark delivery %d succeeded: %v", d.id, err)
+		}
+		return
+	}
+
+	status := StatusPending
+	if giveUp || attempts >= maxAttempts {
+		status = StatusFailed
+	}
+	backoff := retryBackoff(attempts)
+	_, err := w.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, last_status_code = ?, last_error = ?,
+		    next_attempt_at = NOW(3) + INTERVAL ? SECOND, locked_by = NULL, locked_until = NULL
+		WHERE id = ?
+	`, status, attempts, code, cause.Error(), int(backoff.Seconds()), d.id)
+	if err != nil {
+		log.Printf("webhooks: failed to record failure of delivery %d: %v", d.id, err)
+	}
+}
+
+// retryBackoff 第 attempts 次失败后的重试间隔：从 baseBackoff 开始翻倍，不超过 maxBackoff
+func retryBackoff(attempts int) time.Duration {
+	backoff := baseBackoff << uint(min(max(attempts-1, 0), 10))
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// Sign 生成签名头：t=<时间戳>,v1=<HMAC-SHA256(secret, "<时间戳>.<请求体>") 的十六进制>
+// 接收方应按同样方式计算并用常量时间比较，同时拒绝时间戳过旧的请求以防重放
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// blockedPrefixes 不允许投递的地址段：私有网络、运营商级 NAT（含阿里云元数据 100.100.100.200）、
+// 基准测试、文档示例、NAT64 和 6to4/Teredo 等可能转到内网的 IPv6 过渡地址
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fec0::/10"),
+}
+
+// isBlockedAddr 只允许公网单播地址；IPv4 映射的 IPv6 地址按 IPv4 判断
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	if !addr.IsGlobalUnicast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"linguaforge/config"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verify 按文档中的接收方步骤校验签名头
+func verify(t *testing.T, secret string, header string, body []byte, now time.Time) error {
+	t.Helper()
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			signature = v
+		}
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad timestamp %q", timestamp)
+	}
+	if now.Sub(time.Unix(ts, 0)) > 5*time.Minute {
+		return errors.New("timestamp too old")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	expected, _ := hex.DecodeString(signature)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"user.level_up"}`)
+	header := Sign("whsec_test", 1700000000, body)
+	// 固定向量：printf '1700000000.<body>' | openssl dgst -sha256 -hmac whsec_test
+	if want := "t=1700000000,v1=10f5aa235ae2284a671a3969255d8f76eb2671d6124da9a1b3d0546344d1ae36"; header != want {
+		t.Fatalf("Sign = %q, want %q", header, want)
+	}
+
+	now := time.Unix(1700000000, 0)
+	if err := verify(t, "whsec_test", header, body, now); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := verify(t, "whsec_other", header, body, now); err == nil {
+		t.Fatal("signature verified with the wrong secret")
+	}
+	if err := verify(t, "whsec_test", header, append(body, ' '), now); err == nil {
+		t.Fatal("signature verified for a modified body")
+	}
+	if err := verify(t, "whsec_test", header, body, now.Add(10*time.Minute)); err == nil {
+		t.Fatal("stale timestamp accepted")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, 64 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestIsBlockedAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:4700::6810:84e5", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"100.64.0.1", true},
+		{"0.1.2.3", true},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:100.100.100.200", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"2002:a9fe:a9fe::1", true},
+		{"2001:0:4136:e378::1", true},
+		{"fd00::1", true},
+		{"fe80::1%eth0", true},
+		{"ff02::1", true},
+	}
+	for _, tt := range tests {
+		if got := isBlockedAddr(netip.MustParseAddr(tt.addr)); got != tt.blocked {
+			t.Errorf("isBlockedAddr(%s) = %v, want %v", tt.addr, got, tt.blocked)
+		}
+	}
+}
+
+func TestSendSignsRequest(t *testing.T) {
+	payload := `{"id":"evt_42","type":"game.completed"}`
+	received := make(chan *http.Request, 1)
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedBody, _ = io.ReadAll(r.Body)
+		received <- r
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	w := NewWorker(nil, config.WebhooksConfig{AllowPrivateNetworks: true})
+	code, body, err := w.send(context.Background(), &pendingDelivery{
+		id: 42, eventType: "game.completed", payload: payload, url: server.URL, secret: "whsec_test", isActive: true,
+	})
+	if err != nil || code != http.StatusOK || body != "ok" {
+		t.Fatalf("send = %d %q %v", code, body, err)
+	}
+
+	r := <-received
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+	}
+	if r.Header.Get(eventHeader) != "game.completed" || r.Header.Get(deliveryHeader) != "42" {
+		t.Fatalf("unexpected headers %v", r.Header)
+	}
+	if string(receivedBody) != payload {
+		t.Fatalf("body = %s", receivedBody)
+	}
+	if err := verify(t, "whsec_test", r.Header.Get(signatureHeader), receivedBody, time.Now()); err != nil {
+		t.Fatalf("signature: %v", err)
+	}
+}
+
+func TestSendDropsErrorBodiesAndRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("secret internal error page"))
+	}))
+	defer server.Close()
+
+	w := NewWorker(nil, config.WebhooksConfig{AllowPrivateNetworks: true})
+	code, body, err := w.send(context.Background(), &pendingDelivery{id: 1, payload: "{}", url: server.URL, secret: "s"})
+	if err != nil || code != http.StatusInternalServerError || body != "" {
+		t.Fatalf("send = %d %q %v, want 500 with no body", code, body, err)
+	}
+	code, _, err = w.send(context.Background(), &pendingDelivery{id: 1, payload: "{}", url: server.URL + "/redirect", secret: "s"})
+	if err != nil || code != http.StatusFound {
+		t.Fatalf("send followed redirect: %d %v", code, err)
+	}
+}
+
+func TestSendBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback receiver")
+	}))
+	defer server.Close()
+
+	w := NewWorker(nil, config.WebhooksConfig{})
+	_, _, err := w.send(context.Background(), &pendingDelivery{id: 1, payload: "{}", url: server.URL, secret: "s"})
+	if !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("send to %s: %v, want errPrivateAddress", server.URL, err)
+	}
+}
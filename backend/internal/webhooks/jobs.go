@@ -0,0 +1,11 @@
+package webhooks
+
+import (
+	"linguaforge/internal/scheduler"
+	"time"
+)
+
+// RegisterJobs 注册 Webhook 相关的定时任务
+func (s *Service) RegisterJobs(jobs *scheduler.Scheduler) {
+	jobs.Register("webhooks.prune", "15 4 * * *", "清理已结束的旧 Webhook 投递记录", 5*time.Minute, s.prune)
+}
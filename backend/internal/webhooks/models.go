@@ -0,0 +1,93 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"linguaforge/internal/events"
+	"time"
+)
+
+// 投递状态
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed" // 超过重试次数，可手动重放
+)
+
+// PingEvent 测试投递的事件类型
+const PingEvent = "ping"
+
+// SupportedEvents 可以订阅的事件
+var SupportedEvents = []events.Type{
+	events.GameCompleted,
+	events.AssignmentCompleted,
+	events.LevelUp,
+}
+
+// WebhookRequest 创建/修改订阅请求
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=500"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1"`
+	ClassID     *int     `json:"class_id"`                                  // 只推送该班级学生的事件；管理员可不填
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=100"` // 不填时自动生成
+	Description string   `json:"description" binding:"max=200"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// Webhook 订阅；Secret 仅在创建或更换时完整返回
+type Webhook struct {
+	ID          int       `json:"id"`
+	OwnerID     int       `json:"owner_id"`
+	ClassID     *int      `json:"class_id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Delivery 一次事件投递（含重试）
+type Delivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int        `json:"webhook_id"`
+	EventID        *int64     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"` // 仅待投递时有值
+	LastStatusCode *int       `json:"last_status_code"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// DeliveryDetail 投递详情，包括请求体和每次请求的结果
+type DeliveryDetail struct {
+	Delivery
+	Payload json.RawMessage `json:"payload"`
+	History []Attempt       `json:"history"`
+}
+
+// Attempt 一次 HTTP 请求的结果
+type Attempt struct {
+	StatusCode   *int      `json:"status_code"` // 网络错误时为空
+	DurationMs   int       `json:"duration_ms"`
+	Error        string    `json:"error,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// envelope 发送给接收方的请求体
+type envelope struct {
+	ID        string      `json:"id"` // 事件 ID，接收方可用于去重；重放时不变
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	User      *eventUser  `json:"user,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+type eventUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
@@ -0,0 +1,527 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linguaforge/internal/classroom"
+	"linguaforge/internal/events"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxWebhooksPerOwner 每个用户最多创建的订阅数
+	maxWebhooksPerOwner = 20
+	// fanoutSubscriber 事件总线上的订阅者名称
+	fanoutSubscriber = "webhooks.fanout"
+	// deliveryRetentionDays 已结束的投递记录保留天数
+	deliveryRetentionDays = 30
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type Service struct {
+	db         *sql.DB
+	classrooms *classroom.Service
+	worker     *Worker
+}
+
+func NewService(db *sql.DB, classrooms *classroom.Service, worker *Worker) *Service {
+	return &Service{
+		db:         db,
+		classrooms: classrooms,
+		worker:     worker,
+	}
+}
+
+// Subscribe 订阅可推送的事件，为匹配的 Webhook 生成投递记录
+func (s *Service) Subscribe(bus *events.Dispatcher) {
+	for _, eventType := range SupportedEvents {
+		bus.Subscribe(eventType, fanoutSubscriber, s.fanout)
+	}
+}
+
+// Create 创建订阅；教师只能订阅自己任教的班级，管理员可以订阅全部学生
+func (s *Service) Create(ownerID int, isAdmin bool, req *WebhookRequest) (*Webhook, error) {
+	if err := s.validate(ownerID, isAdmin, req); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM webhooks WHERE owner_id = ?", ownerID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxWebhooksPerOwner {
+		return nil, fmt.Errorf("at most %d webhooks are allowed", maxWebhooksPerOwner)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = generateSecret()
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO webhooks (owner_id, class_id, url, secret, event_types, description, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, ownerID, req.ClassID, req.URL, secret, strings.Join(req.EventTypes, ","), req.Description, isActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	id, _ := result.LastInsertId()
+
+	webhook, err := s.get(ownerID, int(id))
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+// Update 修改订阅；提供 secret 时更换签名密钥并完整返回
+func (s *Service) Update(ownerID int, isAdmin bool, webhookID int, req *WebhookRequest) (*Webhook, error) {
+	existing, err := s.get(ownerID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(ownerID, isAdmin, req); err != nil {
+		return nil, err
+	}
+
+	isActive := existing.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+	_, err = s.db.Exec(`
+		UPDATE webhooks
+		SET class_id = ?, url = ?, event_types = ?, description = ?, is_active = ?,
+		    secret = IF(? = '', secret, ?)
+		WHERE id = ? AND owner_id = ?
+	`, req.ClassID, req.URL, strings.Join(req.EventTypes, ","), req.Description, isActive,
+		req.Secret, req.Secret, webhookID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	webhook, err := s.get(ownerID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	return webhook, nil
+}
+
+// Delete 删除订阅及其投递记录
+func (s *Service) Delete(ownerID int, webhookID int) error {
+	result, err := s.db.Exec("DELETE FROM webhooks WHERE id = ? AND owner_id = ?", webhookID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// List 我的订阅
+func (s *Service) List(ownerID int) ([]Webhook, error) {
+	rows, err := s.db.Query(`
+		SELECT id, owner_id, class_id, url, secret, event_types, description, is_active, created_at, updated_at
+		FROM webhooks
+		WHERE owner_id = ?
+		ORDER BY id
+	`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, nil
+}
+
+// Get 订阅详情（密钥已打码）
+func (s *Service) Get(ownerID int, webhookID int) (*Webhook, error) {
+	return s.get(ownerID, webhookID)
+}
+
+// Ping 发送一条测试事件，用于确认接收端和签名校验
+func (s *Service) Ping(ownerID int, webhookID int) (*Delivery, error) {
+	webhook, err := s.get(ownerID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(&envelope{
+		ID:        "ping_" + randomHex(8),
+		Type:      PingEvent,
+		CreatedAt: time.Now().UTC(),
+		Data:      map[string]interface{}{"webhook_id": webhook.ID, "event_types": webhook.EventTypes},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ping: %w", err)
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload) VALUES (?, ?, ?)
+	`, webhook.ID, PingEvent, string(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue ping: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	s.worker.Notify()
+
+	detail, err := s.delivery(webhook.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &detail.Delivery, nil
+}
+
+// Deliveries 订阅的投递记录（最新在前），可按状态筛选
+func (s *Service) Deliveries(ownerID int, webhookID int, status string, limit int) ([]Delivery, error) {
+	if _, err := s.get(ownerID, webhookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	query := `
+		SELECT id, webhook_id, event_id, event_type, status, attempts, next_attempt_at,
+		       last_status_code, COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = ?`
+	args := []interface{}{webhookID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+// Delivery 投递详情
+func (s *Service) Delivery(ownerID int, webhookID int, deliveryID int64) (*DeliveryDetail, error) {
+	if _, err := s.get(ownerID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.delivery(webhookID, deliveryID)
+}
+
+// Replay 重新投递（原样发送当时的请求体），已在队列中的投递不能重放
+func (s *Service) Replay(ownerID int, webhookID int, deliveryID int64) (*Delivery, error) {
+	if _, err := s.get(ownerID, webhookID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(3), locked_by = NULL, locked_until = NULL
+		WHERE id = ? AND webhook_id = ? AND status <> 'pending'
+	`, deliveryID, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay delivery: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := s.delivery(webhookID, deliveryID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("delivery is already queued")
+	}
+	s.worker.Notify()
+
+	detail, err := s.delivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return &detail.Delivery, nil
+}
+
+// fanout 为匹配事件类型和班级范围的启用订阅生成投递记录；(webhook_id, event_id) 唯一，重复处理不会重复投递
+func (s *Service) fanout(ctx context.Context, e events.Event) error {
+	// 作业事件只推送给布置作业的班级，其他事件推送给学生所在的全部班级
+	scope := "class_id IN (SELECT class_id FROM class_members WHERE student_id = ?)"
+	scopeArg := interface{}(e.UserID)
+	if e.Type == events.AssignmentCompleted {
+		var p events.AssignmentCompletedPayload
+		if err := e.Decode(&p); err != nil {
+			return err
+		}
+		scope = "class_id = ?"
+		scopeArg = p.ClassID
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM webhooks
+		WHERE is_active = TRUE AND FIND_IN_SET(?, event_types) > 0
+		  AND (class_id IS NULL OR `+scope+`)
+	`, string(e.Type), scopeArg)
+	if err != nil {
+		return fmt.Errorf("failed to match webhooks: %w", err)
+	}
+	var webhookIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhookIDs = append(webhookIDs, id)
+	}
+	rows.Close()
+	if len(webhookIDs) == 0 {
+		return nil
+	}
+
+	body, err := s.buildBody(ctx, e)
+	if err != nil {
+		return err
+	}
+	for _, id := range webhookIDs {
+		if _, err := s.db.ExecContext(ctx, `
+			INSERT IGNORE INTO webhook_deliveries (webhook_id, event_id, event_type, payload) VALUES (?, ?, ?, ?)
+		`, id, e.ID, string(e.Type), string(body)); err != nil {
+			return fmt.Errorf("failed to queue delivery: %w", err)
+		}
+	}
+	s.worker.Notify()
+	return nil
+}
+
+// buildBody 生成请求体，附带学生的用户名方便接收方对应账号
+func (s *Service) buildBody(ctx context.Context, e events.Event) ([]byte, error) {
+	env := &envelope{
+		ID:        fmt.Sprintf("evt_%d", e.ID),
+		Type:      string(e.Type),
+		CreatedAt: e.OccurredAt.UTC(),
+		Data:      e.Payload,
+	}
+	if e.UserID > 0 {
+		u := &eventUser{ID: e.UserID}
+		err := s.db.QueryRowContext(ctx, "SELECT username FROM users WHERE id = ?", e.UserID).Scan(&u.Username)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		env.User = u
+	}
+	body, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook body: %w", err)
+	}
+	return body, nil
+}
+
+// validate 校验地址、事件类型和班级权限
+func (s *Service) validate(ownerID int, isAdmin bool, req *WebhookRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+
+	seen := map[string]bool{}
+	for _, t := range req.EventTypes {
+		if !isSupported(t) {
+			return fmt.Errorf("unsupported event type: %s", t)
+		}
+		if seen[t] {
+			return fmt.Errorf("duplicate event type: %s", t)
+		}
+		seen[t] = true
+	}
+
+	if req.ClassID == nil {
+		if !isAdmin {
+			return errors.New("class_id is required")
+		}
+		return nil
+	}
+	return s.classrooms.CheckTeacher(ownerID, *req.ClassID)
+}
+
+func (s *Service) get(ownerID int, webhookID int) (*Webhook, error) {
+	row := s.db.QueryRow(`
+		SELECT id, owner_id, class_id, url, secret, event_types, description, is_active, created_at, updated_at
+		FROM webhooks
+		WHERE id = ? AND owner_id = ?
+	`, webhookID, ownerID)
+	w, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	return w, err
+}
+
+func (s *Service) delivery(webhookID int, deliveryID int64) (*DeliveryDetail, error) {
+	var payload string
+	row := s.db.QueryRow(`
+		SELECT id, webhook_id, event_id, event_type, status, attempts, next_attempt_at,
+		       last_status_code, COALESCE(last_error, ''), created_at, delivered_at, payload
+		FROM webhook_deliveries
+		WHERE id = ? AND webhook_id = ?
+	`, deliveryID, webhookID)
+	d, err := scanDelivery(row, &payload)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("delivery not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	detail := &DeliveryDetail{Delivery: *d, Payload: json.RawMessage(payload), History: []Attempt{}}
+
+	rows, err := s.db.Query(`
+		SELECT status_code, duration_ms, COALESCE(error, ''), COALESCE(response_body, ''), created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ?
+		ORDER BY id
+	`, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delivery attempts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a Attempt
+		var code sql.NullInt64
+		if err := rows.Scan(&code, &a.DurationMs, &a.Error, &a.ResponseBody, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery attempt: %w", err)
+		}
+		if code.Valid {
+			c := int(code.Int64)
+			a.StatusCode = &c
+		}
+		detail.History = append(detail.History, a)
+	}
+	return detail, nil
+}
+
+// prune 删除已结束的旧投递记录
+func (s *Service) prune(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < NOW() - INTERVAL ? DAY
+	`, deliveryRetentionDays)
+	if err != nil {
+		return fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row scanner) (*Webhook, error) {
+	var w Webhook
+	var classID sql.NullInt64
+	var eventTypes string
+	err := row.Scan(&w.ID, &w.OwnerID, &classID, &w.URL, &w.Secret, &eventTypes,
+		&w.Description, &w.IsActive, &w.CreatedAt, &w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook: %w", err)
+	}
+	if classID.Valid {
+		id := int(classID.Int64)
+		w.ClassID = &id
+	}
+	w.EventTypes = strings.Split(eventTypes, ",")
+	w.Secret = maskSecret(w.Secret)
+	return &w, nil
+}
+
+func scanDelivery(row scanner, extra ...interface{}) (*Delivery, error) {
+	var d Delivery
+	var eventID, statusCode sql.NullInt64
+	var nextAttempt, deliveredAt sql.NullTime
+	dest := []interface{}{&d.ID, &d.WebhookID, &eventID, &d.EventType, &d.Status, &d.Attempts, &nextAttempt,
+		&statusCode, &d.LastError, &d.CreatedAt, &deliveredAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan delivery: %w", err)
+	}
+	if eventID.Valid {
+		d.EventID = &eventID.Int64
+	}
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		d.LastStatusCode = &code
+	}
+	if nextAttempt.Valid && d.Status == StatusPending {
+		d.NextAttemptAt = &nextAttempt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+func isSupported(eventType string) bool {
+	for _, t := range SupportedEvents {
+		if string(t) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSecret() string {
+	return "whsec_" + randomHex(24)
+}
+
+// maskSecret 只保留前缀和末尾 4 位
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	prefix := ""
+	if strings.HasPrefix(secret, "whsec_") {
+		prefix = "whsec_"
+	}
+	return prefix + "****" + secret[len(secret)-4:]
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	v1 "linguaforge/api/v1"
 	"linguaforge/config"
+	"linguaforge/internal/events"
+	"linguaforge/internal/scheduler"
+	"linguaforge/internal/webhooks"
 	"linguaforge/storage"
 	"log"
 
@@ -32,12 +36,17 @@ func main() {
 
 	// 创建Gin路由器
 	router := gin.Default()
+	// 未配置 TRUSTED_PROXIES 时不信任任何代理，客户端 IP 取连接的对端地址
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// 设置CORS中间件
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, Last-Event-ID")
+		c.Header("Access-Control-Expose-Headers", "Retry-After")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -47,8 +56,16 @@ func main() {
 		c.Next()
 	})
 
-	// 初始化API路由
-	v1.SetupRoutes(router, db, redisClient, cfg)
+	// 初始化API路由，各模块同时注册自己的定时任务和事件订阅
+	jobs := scheduler.New(db, redisClient)
+	bus := events.NewDispatcher(db, redisClient, cfg.Events)
+	hooks := webhooks.NewWorker(db, cfg.Webhooks)
+	v1.SetupRoutes(router, db, redisClient, cfg, jobs, bus, hooks)
+
+	// 启动定时任务、事件投递和 Webhook 发送
+	jobs.Start(context.Background())
+	bus.Start(context.Background())
+	hooks.Start(context.Background())
 
 	// 启动服务器
 	log.Printf("Server starting on port %s", cfg.Port)
@@ -0,0 +1,34 @@
+-- 003_adaptive_word_selection.sql
+-- 自适应选词：记录每局游戏抽到的单词，以及每局的答题正确数
+USE linguaforge;
+
+-- 1. 游戏会话表（每次开始游戏生成一条）
+CREATE TABLE IF NOT EXISTS game_sessions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    game_type VARCHAR(20) NOT NULL,
+    difficulty_min INT DEFAULT 1,
+    difficulty_max INT DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_created (user_id, created_at)
+);
+
+-- 2. 会话单词表（用于避免最近几局重复出题）
+CREATE TABLE IF NOT EXISTS game_session_words (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    word_id INT NOT NULL,
+    source VARCHAR(20) NOT NULL, -- review / weak / new / fill
+    FOREIGN KEY (session_id) REFERENCES game_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_session_word (session_id, word_id),
+    INDEX idx_word_id (word_id)
+);
+
+-- 3. 游戏记录关联会话，并记录答题正确数（用于计算近期正确率）
+ALTER TABLE game_records
+  ADD COLUMN session_id INT NULL AFTER user_id,
+  ADD COLUMN correct_count INT DEFAULT 0 AFTER time_spent,
+  ADD COLUMN total_count INT DEFAULT 0 AFTER correct_count,
+  ADD INDEX idx_session_id (session_id);
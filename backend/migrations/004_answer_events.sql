@@ -0,0 +1,22 @@
+-- 004_answer_events.sql
+-- 逐题作答记录：记录每一轮的选择、对错与反应时间
+USE linguaforge;
+
+CREATE TABLE IF NOT EXISTS answer_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    session_id INT NULL,
+    word_id INT NOT NULL,
+    game_type VARCHAR(20) NOT NULL,
+    chosen_option VARCHAR(200),
+    is_correct BOOLEAN NOT NULL DEFAULT FALSE,
+    response_time_ms INT DEFAULT 0,
+    answered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    FOREIGN KEY (session_id) REFERENCES game_sessions(id) ON DELETE SET NULL,
+    INDEX idx_user_answered (user_id, answered_at),
+    INDEX idx_session_id (session_id),
+    INDEX idx_user_word (user_id, word_id)
+);
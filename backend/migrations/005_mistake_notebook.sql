@@ -0,0 +1,23 @@
+-- 005_mistake_notebook.sql
+-- 错题本：记录用户答错的单词，连续答对若干次后自动移出
+USE linguaforge;
+
+CREATE TABLE IF NOT EXISTS user_mistakes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    word_id INT NOT NULL,
+    wrong_count INT DEFAULT 0,
+    correct_streak INT DEFAULT 0, -- 上次答错之后的连续答对次数
+    last_wrong_answer VARCHAR(200),
+    last_wrong_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_user_word (user_id, word_id),
+    INDEX idx_user_last_wrong (user_id, last_wrong_at)
+);
+
+-- 错题复习模式
+ALTER TABLE game_records
+  MODIFY COLUMN game_type ENUM('adventure', 'defense', 'dubbing', 'mistakes') NOT NULL;
@@ -0,0 +1,38 @@
+-- 006_placement_test.sql
+-- 分级测试：自适应出题，估算词汇量并给出推荐难度
+USE linguaforge;
+
+-- 1. 用户推荐难度与词汇量估计
+ALTER TABLE users
+  ADD COLUMN preferred_level INT NOT NULL DEFAULT 1 AFTER preferred_category,
+  ADD COLUMN vocabulary_estimate INT NULL AFTER preferred_level;
+
+-- 2. 分级测试表
+CREATE TABLE IF NOT EXISTS placement_tests (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    status ENUM('in_progress', 'completed') NOT NULL DEFAULT 'in_progress',
+    current_difficulty INT NOT NULL DEFAULT 1,
+    current_word_id INT NULL,
+    question_count INT DEFAULT 0,
+    correct_count INT DEFAULT 0,
+    recommended_level INT NULL,
+    vocabulary_estimate INT NULL,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_status (user_id, status)
+);
+
+-- 3. 分级测试作答记录
+CREATE TABLE IF NOT EXISTS placement_answers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    test_id INT NOT NULL,
+    word_id INT NOT NULL,
+    difficulty_level INT NOT NULL,
+    is_correct BOOLEAN NOT NULL DEFAULT FALSE,
+    answered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (test_id) REFERENCES placement_tests(id) ON DELETE CASCADE,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE CASCADE,
+    INDEX idx_test_id (test_id)
+);
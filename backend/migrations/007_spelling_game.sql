@@ -0,0 +1,9 @@
+-- 007_spelling_game.sql
+-- 拼写挑战：新增游戏类型，并记录每个单词使用的字母提示次数
+USE linguaforge;
+
+ALTER TABLE game_records
+  MODIFY COLUMN game_type ENUM('adventure', 'defense', 'dubbing', 'mistakes', 'spelling') NOT NULL;
+
+ALTER TABLE game_session_words
+  ADD COLUMN hints_used INT DEFAULT 0 AFTER source;
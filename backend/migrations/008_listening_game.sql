@@ -0,0 +1,9 @@
+-- 008_listening_game.sql
+-- 听力练习：新增游戏类型，并记录每轮的作答方式（选释义 / 拼写）
+USE linguaforge;
+
+ALTER TABLE game_records
+  MODIFY COLUMN game_type ENUM('adventure', 'defense', 'dubbing', 'mistakes', 'spelling', 'listening') NOT NULL;
+
+ALTER TABLE game_session_words
+  ADD COLUMN round_mode VARCHAR(10) NULL AFTER hints_used;
@@ -0,0 +1,11 @@
+-- 009_matching_game.sql
+-- 连连看：新增游戏类型，并记录每个单词对应的中文卡片、配对错误次数和配对时间
+USE linguaforge;
+
+ALTER TABLE game_records
+  MODIFY COLUMN game_type ENUM('adventure', 'defense', 'dubbing', 'mistakes', 'spelling', 'listening', 'matching') NOT NULL;
+
+ALTER TABLE game_session_words
+  ADD COLUMN pair_card INT NULL AFTER round_mode,
+  ADD COLUMN mismatches INT DEFAULT 0 AFTER pair_card,
+  ADD COLUMN matched_at TIMESTAMP NULL AFTER mismatches;
@@ -0,0 +1,38 @@
+-- 010_sentence_builder.sql
+-- 连词成句：为单词增加英文例句，新增游戏类型，并保存每轮题目数据
+USE linguaforge;
+
+-- 1. 单词例句
+ALTER TABLE words
+  ADD COLUMN example_sentence TEXT NULL AFTER story;
+
+-- 2. 新增游戏类型
+ALTER TABLE game_records
+  MODIFY COLUMN game_type ENUM('adventure', 'defense', 'dubbing', 'mistakes', 'spelling', 'listening', 'matching', 'sentence') NOT NULL;
+
+-- 3. 每轮题目数据（JSON，如例句与干扰词）
+ALTER TABLE game_session_words
+  ADD COLUMN round_data TEXT NULL AFTER matched_at;
+
+-- 4. 基础例句
+UPDATE words SET example_sentence = 'Hello, my name is Alex.' WHERE english = 'hello';
+UPDATE words SET example_sentence = 'She wants to travel around the world.' WHERE english = 'world';
+UPDATE words SET example_sentence = 'The sunset over the lake is beautiful.' WHERE english = 'beautiful';
+UPDATE words SET example_sentence = 'Learning English is a great adventure.' WHERE english = 'adventure';
+UPDATE words SET example_sentence = 'Her pronunciation is very clear.' WHERE english = 'pronunciation';
+UPDATE words SET example_sentence = 'Reading books helps you build vocabulary.' WHERE english = 'vocabulary';
+UPDATE words SET example_sentence = 'Good grammar makes your writing clear.' WHERE english = 'grammar';
+UPDATE words SET example_sentence = 'We had a long conversation after dinner.' WHERE english = 'conversation';
+UPDATE words SET example_sentence = 'You need to practice every day.' WHERE english = 'practice';
+UPDATE words SET example_sentence = 'Learning a language takes time.' WHERE english = 'learning';
+UPDATE words SET example_sentence = 'My phone battery is almost dead.' WHERE english = 'battery';
+UPDATE words SET example_sentence = 'Did you bring your phone charger?' WHERE english = 'charger';
+UPDATE words SET example_sentence = 'The screen is too dark to read.' WHERE english = 'screen';
+UPDATE words SET example_sentence = 'I will call you after the meeting.' WHERE english = 'call';
+UPDATE words SET example_sentence = 'He sent me a message last night.' WHERE english = 'message';
+UPDATE words SET example_sentence = 'Please turn on the camera.' WHERE english = 'camera';
+UPDATE words SET example_sentence = 'I set an alarm for six o''clock.' WHERE english = 'alarm';
+UPDATE words SET example_sentence = 'You can install the app for free.' WHERE english = 'install';
+UPDATE words SET example_sentence = 'Never share your password with anyone.' WHERE english = 'password';
+UPDATE words SET example_sentence = 'Let me download the file first.' WHERE english = 'download';
+UPDATE words SET example_sentence = 'Use the search box to find a song.' WHERE english = 'search';
@@ -0,0 +1,83 @@
+-- 011_adventure_campaigns.sql
+-- 冒险剧情：章节与剧情节点（按答对/答错分支），用户进度可断点续玩；新增用户角色用于内容编辑权限
+USE linguaforge;
+
+-- 1. 用户角色（剧情编辑等管理接口仅 admin 可用）
+ALTER TABLE users
+  ADD COLUMN role ENUM('student', 'teacher', 'admin') NOT NULL DEFAULT 'student' AFTER email;
+
+-- 2. 冒险章节
+CREATE TABLE IF NOT EXISTS adventure_chapters (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    description TEXT,
+    sort_order INT NOT NULL DEFAULT 0,
+    start_node_key VARCHAR(50) NOT NULL,
+    unlock_chapter_id INT NULL,           -- 需先通关的章节
+    unlock_level INT NOT NULL DEFAULT 1,  -- 需达到的推荐难度（users.preferred_level）
+    is_published BOOLEAN DEFAULT FALSE,
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (unlock_chapter_id) REFERENCES adventure_chapters(id) ON DELETE SET NULL,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_published_order (is_published, sort_order)
+);
+
+-- 3. 剧情节点：narration 旁白（next_key 继续）、question 答题（答对 next_key，答错 wrong_key）、ending 结局
+CREATE TABLE IF NOT EXISTS adventure_nodes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    chapter_id INT NOT NULL,
+    node_key VARCHAR(50) NOT NULL,
+    kind ENUM('narration', 'question', 'ending') NOT NULL,
+    story TEXT NOT NULL,
+    word_id INT NULL,
+    next_key VARCHAR(50) NULL,
+    wrong_key VARCHAR(50) NULL,
+    reward_score INT DEFAULT 0,
+    FOREIGN KEY (chapter_id) REFERENCES adventure_chapters(id) ON DELETE CASCADE,
+    FOREIGN KEY (word_id) REFERENCES words(id) ON DELETE RESTRICT,
+    UNIQUE KEY unique_chapter_node (chapter_id, node_key)
+);
+
+-- 4. 用户章节进度
+CREATE TABLE IF NOT EXISTS user_adventure_progress (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    chapter_id INT NOT NULL,
+    session_id INT NULL,
+    current_node_key VARCHAR(50) NOT NULL,
+    status ENUM('in_progress', 'completed') NOT NULL DEFAULT 'in_progress',
+    score INT DEFAULT 0,
+    best_score INT DEFAULT 0,
+    completions INT DEFAULT 0,
+    ending_key VARCHAR(50) NULL,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chapter_id) REFERENCES adventure_chapters(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_user_chapter (user_id, chapter_id)
+);
+
+-- 5. 示例章节
+INSERT INTO adventure_chapters (title, description, sort_order, start_node_key, is_published)
+VALUES ('第一章：出门前的手机检查', '帮小李在出门前检查好手机，顺利赶上早班车。', 1, 'start', TRUE);
+
+SET @chapter_id = LAST_INSERT_ID();
+
+INSERT INTO adventure_nodes (chapter_id, node_key, kind, story, word_id, next_key, wrong_key, reward_score)
+SELECT @chapter_id, 'start', 'narration', '清晨六点，小李被闹钟叫醒，今天要赶早班车去外地出差。', NULL, 'battery', NULL, 0
+UNION ALL
+SELECT @chapter_id, 'battery', 'question', '他拿起手机，屏幕右上角的图标显示只剩10%。这个图标代表什么？', id, 'wifi', 'charger', 0
+FROM words WHERE english = 'battery'
+UNION ALL
+SELECT @chapter_id, 'charger', 'question', '电量快耗尽了，小李翻遍背包，想找到能给手机补电的东西。', id, 'wifi', 'late', 0
+FROM words WHERE english = 'charger'
+UNION ALL
+SELECT @chapter_id, 'wifi', 'question', '出门前他想用家里的网络下载离线地图，需要先连上什么？', id, 'good_end', 'late', 0
+FROM words WHERE english = 'wifi'
+UNION ALL
+SELECT @chapter_id, 'late', 'ending', '一番折腾后，小李只能冲向车站，勉强赶上了车，但手机一路都在低电量提醒。', NULL, NULL, NULL, 10
+UNION ALL
+SELECT @chapter_id, 'good_end', 'ending', '手机电量充足、地图已下载，小李从容地登上了早班车。', NULL, NULL, NULL, 30;
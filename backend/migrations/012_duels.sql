@@ -0,0 +1,36 @@
+-- 012_duels.sql
+-- 实时1v1对战：新增游戏类型、对战记录与对战积分（Elo）
+USE linguaforge;
+
+ALTER TABLE game_records
+  MODIFY COLUMN game_type ENUM('adventure', 'defense', 'dubbing', 'mistakes', 'spelling', 'listening', 'matching', 'sentence', 'duel') NOT NULL;
+
+-- 1. 对战记录
+CREATE TABLE IF NOT EXISTS duel_matches (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    player1_id INT NOT NULL,
+    player2_id INT NOT NULL,
+    level INT NOT NULL DEFAULT 1,
+    status ENUM('playing', 'finished') NOT NULL DEFAULT 'playing',
+    player1_score INT DEFAULT 0,
+    player2_score INT DEFAULT 0,
+    winner_id INT NULL,            -- 平局时为空
+    forfeit_by INT NULL,           -- 认输或掉线超时的一方
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP NULL,
+    FOREIGN KEY (player1_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (player2_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_player1 (player1_id, started_at),
+    INDEX idx_player2 (player2_id, started_at)
+);
+
+-- 2. 对战积分
+CREATE TABLE IF NOT EXISTS duel_ratings (
+    user_id INT PRIMARY KEY,
+    rating INT NOT NULL DEFAULT 1200,
+    wins INT DEFAULT 0,
+    losses INT DEFAULT 0,
+    draws INT DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,54 @@
+-- 013_friend_challenges.sql
+-- 好友挑战：发起者先玩一局，题目（单词、选项顺序、种子）冻结后发给对方，对方稍后玩同一局
+USE linguaforge;
+
+ALTER TABLE game_records
+  MODIFY COLUMN game_type ENUM('adventure', 'defense', 'dubbing', 'mistakes', 'spelling', 'listening', 'matching', 'sentence', 'duel', 'challenge') NOT NULL;
+
+-- 1. 挑战
+CREATE TABLE IF NOT EXISTS challenges (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    challenger_id INT NOT NULL,
+    opponent_id INT NOT NULL,
+    level INT NOT NULL DEFAULT 1,
+    seed BIGINT NOT NULL,
+    rounds TEXT NOT NULL,           -- 冻结的题目 JSON（单词、选项顺序、答案）
+    status ENUM('pending', 'sent', 'accepted', 'completed', 'declined', 'expired') NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    FOREIGN KEY (challenger_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (opponent_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_challenger (challenger_id, created_at),
+    INDEX idx_opponent_status (opponent_id, status)
+);
+
+-- 2. 双方各自的挑战记录
+CREATE TABLE IF NOT EXISTS challenge_attempts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    challenge_id INT NOT NULL,
+    user_id INT NOT NULL,
+    session_id INT NOT NULL,
+    score INT DEFAULT 0,
+    correct_count INT DEFAULT 0,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP NULL,
+    FOREIGN KEY (challenge_id) REFERENCES challenges(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_challenge_user (challenge_id, user_id)
+);
+
+-- 3. 逐题作答（用于对比双方每一题）
+CREATE TABLE IF NOT EXISTS challenge_answers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    attempt_id INT NOT NULL,
+    round INT NOT NULL,
+    answer VARCHAR(200),
+    is_correct BOOLEAN NOT NULL DEFAULT FALSE,
+    points INT DEFAULT 0,
+    response_time_ms INT DEFAULT 0,
+    answered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (attempt_id) REFERENCES challenge_attempts(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_attempt_round (attempt_id, round)
+);
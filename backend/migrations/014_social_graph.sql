@@ -0,0 +1,67 @@
+-- 014_social_graph.sql
+-- 社交关系：好友申请、好友、关注、拉黑、动态可见范围与升级记录
+USE linguaforge;
+
+-- 1. 动态可见范围：public 关注者和好友可见，friends 仅好友可见，private 不出现在任何人的动态中
+ALTER TABLE users
+  ADD COLUMN activity_visibility ENUM('public', 'friends', 'private') NOT NULL DEFAULT 'friends' AFTER role;
+
+-- 2. 好友申请
+CREATE TABLE IF NOT EXISTS friend_requests (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    sender_id INT NOT NULL,
+    receiver_id INT NOT NULL,
+    status ENUM('pending', 'accepted', 'declined') NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP NULL,
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (receiver_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_sender_receiver (sender_id, receiver_id),
+    INDEX idx_receiver_status (receiver_id, status)
+);
+
+-- 3. 好友关系（双向各存一行，便于联表查询）
+CREATE TABLE IF NOT EXISTS friendships (
+    user_id INT NOT NULL,
+    friend_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, friend_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (friend_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 4. 关注（单向）
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id INT NOT NULL,
+    followee_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_followee (followee_id)
+);
+
+-- 5. 拉黑
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id INT NOT NULL,
+    blocked_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_blocked (blocked_id)
+);
+
+-- 6. 升级记录（用于好友动态）
+CREATE TABLE IF NOT EXISTS user_level_ups (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    level INT NOT NULL,
+    reached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_reached (user_id, reached_at)
+);
+
+-- 动态查询按时间倒序扫描游戏记录和成就
+ALTER TABLE game_records ADD INDEX idx_user_completed (user_id, completed_at);
+ALTER TABLE user_achievements ADD INDEX idx_user_earned (user_id, earned_at);
@@ -0,0 +1,29 @@
+-- 015_classrooms.sql
+-- 班级：教师创建班级，学生通过邀请码加入
+USE linguaforge;
+
+-- 1. 班级
+CREATE TABLE IF NOT EXISTS classes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    teacher_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    join_code VARCHAR(12) NOT NULL,
+    is_archived BOOLEAN NOT NULL DEFAULT FALSE, -- 归档后不能再加入
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_join_code (join_code),
+    INDEX idx_teacher (teacher_id)
+);
+
+-- 2. 班级成员
+CREATE TABLE IF NOT EXISTS class_members (
+    class_id INT NOT NULL,
+    student_id INT NOT NULL,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (class_id, student_id),
+    FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_student (student_id)
+);
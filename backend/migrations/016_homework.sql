@@ -0,0 +1,40 @@
+-- 016_homework.sql
+-- 作业：教师给班级布置带目标和截止时间的作业，学生进度根据学习和游戏记录自动更新
+USE linguaforge;
+
+-- 1. 作业
+-- study_words: 学习 target 个单词（可限定分类）
+-- play_games:  完成 target 局指定游戏（可限定最低难度）
+-- reach_score: 在指定游戏中取得至少 target 分（可限定最低难度）
+CREATE TABLE IF NOT EXISTS assignments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    class_id INT NOT NULL,
+    teacher_id INT NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    description TEXT,
+    kind ENUM('study_words', 'play_games', 'reach_score') NOT NULL,
+    game_type VARCHAR(20) NULL,
+    category VARCHAR(50) NULL,
+    min_level INT NOT NULL DEFAULT 0,
+    target INT NOT NULL,
+    starts_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- 只统计此时间之后的学习和游戏记录
+    due_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE,
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_class_due (class_id, due_at)
+);
+
+-- 2. 学生作业进度
+CREATE TABLE IF NOT EXISTS assignment_progress (
+    assignment_id INT NOT NULL,
+    student_id INT NOT NULL,
+    progress INT NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP NULL,
+    reminded_at TIMESTAMP NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (assignment_id, student_id),
+    FOREIGN KEY (assignment_id) REFERENCES assignments(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_student (student_id)
+);
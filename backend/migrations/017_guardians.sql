@@ -0,0 +1,36 @@
+-- 017_guardians.sql
+-- 监护人：绑定学习者账号（需学习者同意）、每日游戏时长限制、每周学习报告
+USE linguaforge;
+
+ALTER TABLE users
+  MODIFY COLUMN role ENUM('student', 'teacher', 'admin', 'guardian') NOT NULL DEFAULT 'student';
+
+-- 1. 监护关系
+CREATE TABLE IF NOT EXISTS guardian_links (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    guardian_id INT NOT NULL,
+    learner_id INT NOT NULL,
+    status ENUM('pending', 'active', 'declined', 'revoked') NOT NULL DEFAULT 'pending',
+    daily_limit_minutes INT NULL, -- 为空表示不限制
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP NULL,
+    FOREIGN KEY (guardian_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (learner_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_guardian_learner (guardian_id, learner_id),
+    INDEX idx_learner_status (learner_id, status)
+);
+
+-- 2. 每周学习报告（每个监护人、学习者、周一条）
+CREATE TABLE IF NOT EXISTS guardian_reports (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    guardian_id INT NOT NULL,
+    learner_id INT NOT NULL,
+    week_start DATE NOT NULL,
+    summary TEXT NOT NULL, -- JSON
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP NULL,
+    FOREIGN KEY (guardian_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (learner_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_guardian_learner_week (guardian_id, learner_id, week_start),
+    INDEX idx_delivered (delivered_at)
+);
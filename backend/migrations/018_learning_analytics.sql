@@ -0,0 +1,47 @@
+-- 018_learning_analytics.sql
+-- 学习分析：按天预聚合的统计表，由后台任务增量刷新，避免分析查询扫描全部游戏和作答记录
+USE linguaforge;
+
+-- 1. 每日学习统计
+CREATE TABLE IF NOT EXISTS user_daily_stats (
+    user_id INT NOT NULL,
+    stat_date DATE NOT NULL,
+    games INT NOT NULL DEFAULT 0,
+    time_spent INT NOT NULL DEFAULT 0, -- 秒
+    total_score INT NOT NULL DEFAULT 0,
+    answers INT NOT NULL DEFAULT 0,
+    correct INT NOT NULL DEFAULT 0,
+    new_words INT NOT NULL DEFAULT 0, -- 当天首次学习的单词数
+    PRIMARY KEY (user_id, stat_date),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 2. 每日作答统计（按游戏类型和单词分类）
+CREATE TABLE IF NOT EXISTS user_answer_stats (
+    user_id INT NOT NULL,
+    stat_date DATE NOT NULL,
+    game_type VARCHAR(20) NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT '', -- 未分类的单词记为空字符串
+    answers INT NOT NULL DEFAULT 0,
+    correct INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, stat_date, game_type, category),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 3. 记忆保持统计：按当前掌握程度分档、按距上次作答的间隔分档的正确率
+CREATE TABLE IF NOT EXISTS user_retention_stats (
+    user_id INT NOT NULL,
+    mastery_bucket TINYINT NOT NULL,  -- 0-4，对应掌握程度 0-20% ... 80-100%
+    interval_bucket TINYINT NOT NULL, -- 0-5，对应 1小时-1天 ... 30天以上
+    answers INT NOT NULL DEFAULT 0,
+    correct INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, mastery_bucket, interval_bucket),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 4. 刷新进度
+CREATE TABLE IF NOT EXISTS analytics_rollup_state (
+    name VARCHAR(50) PRIMARY KEY,
+    watermark TIMESTAMP NULL, -- 已聚合到的时间点
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
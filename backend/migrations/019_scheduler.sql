@@ -0,0 +1,25 @@
+-- 019_scheduler.sql
+-- 后台定时任务：暂停状态与执行记录（多个实例共享）
+USE linguaforge;
+
+-- 1. 任务状态（只记录被暂停等需要持久化的状态，任务定义在代码中注册）
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name VARCHAR(100) PRIMARY KEY,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- 2. 执行记录
+CREATE TABLE IF NOT EXISTS job_runs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    job_name VARCHAR(100) NOT NULL,
+    trigger_type ENUM('schedule', 'manual') NOT NULL,
+    instance VARCHAR(100) NOT NULL, -- 执行的实例
+    status ENUM('running', 'succeeded', 'failed', 'skipped') NOT NULL DEFAULT 'running',
+    error TEXT NULL,
+    started_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    finished_at TIMESTAMP(3) NULL,
+    duration_ms INT NULL,
+    INDEX idx_job_started (job_name, started_at),
+    INDEX idx_started (started_at)
+);
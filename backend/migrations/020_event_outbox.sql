@@ -0,0 +1,31 @@
+-- 020_event_outbox.sql
+-- 领域事件发件箱：事件与业务数据在同一事务中写入，由后台投递给订阅者（至少一次，失败重试，超过次数进入死信）
+USE linguaforge;
+
+-- 1. 发件箱
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    user_id INT NULL,
+    payload TEXT NOT NULL, -- JSON
+    status ENUM('pending', 'delivered', 'dead') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    locked_by VARCHAR(64) NULL,     -- 正在投递的批次
+    locked_until TIMESTAMP(3) NULL, -- 超过该时间未完成则可被重新认领
+    last_error TEXT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    delivered_at TIMESTAMP(3) NULL,
+    INDEX idx_status_next (status, next_attempt_at),
+    INDEX idx_locked_by (locked_by),
+    INDEX idx_delivered (delivered_at)
+);
+
+-- 2. 已成功处理事件的订阅者（重试时跳过，避免重复处理）
+CREATE TABLE IF NOT EXISTS event_consumptions (
+    event_id BIGINT NOT NULL,
+    subscriber VARCHAR(100) NOT NULL,
+    consumed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, subscriber),
+    FOREIGN KEY (event_id) REFERENCES event_outbox(id) ON DELETE CASCADE
+);
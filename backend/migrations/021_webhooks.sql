@@ -0,0 +1,57 @@
+-- 021_webhooks.sql
+-- 外发 Webhook：合作学校订阅学生完成游戏、作业等事件，投递带 HMAC 签名，失败按指数退避重试
+USE linguaforge;
+
+-- 1. 订阅
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    owner_id INT NOT NULL,
+    class_id INT NULL, -- 只接收该班级学生的事件；为空表示全部（仅管理员）
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    event_types VARCHAR(255) NOT NULL, -- 逗号分隔
+    description VARCHAR(200) NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE,
+    INDEX idx_owner (owner_id),
+    INDEX idx_active (is_active)
+);
+
+-- 2. 投递（每个订阅、每个事件一条；event_id 为空表示测试投递）
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id INT NOT NULL,
+    event_id BIGINT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL, -- 发送的请求体，重放时原样发送
+    status ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    locked_by VARCHAR(64) NULL,
+    locked_until TIMESTAMP(3) NULL,
+    last_status_code INT NULL,
+    last_error TEXT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    delivered_at TIMESTAMP(3) NULL,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_webhook_event (webhook_id, event_id),
+    INDEX idx_status_next (status, next_attempt_at),
+    INDEX idx_locked_by (locked_by),
+    INDEX idx_webhook_created (webhook_id, created_at)
+);
+
+-- 3. 每次请求的记录
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    delivery_id BIGINT NOT NULL,
+    status_code INT NULL, -- 网络错误时为空
+    duration_ms INT NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    response_body VARCHAR(1000) NULL, -- 截断保存
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    INDEX idx_delivery (delivery_id)
+);
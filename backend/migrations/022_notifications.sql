@@ -0,0 +1,19 @@
+-- 022_notifications.sql
+-- 站内通知：升级、排行榜被超越、收到好友挑战等，通过 SSE 实时推送
+USE linguaforge;
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    kind VARCHAR(50) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body VARCHAR(1000) NOT NULL DEFAULT '',
+    data TEXT NULL, -- JSON，供前端跳转
+    dedupe_key VARCHAR(100) NULL, -- 由事件生成时用于去重，重复处理同一事件不会重复通知
+    read_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_user_dedupe (user_id, dedupe_key),
+    INDEX idx_user_id (user_id, id),
+    INDEX idx_user_unread (user_id, read_at)
+);
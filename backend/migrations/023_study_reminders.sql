@@ -0,0 +1,32 @@
+-- 023_study_reminders.sql
+-- 学习提醒：按用户时区在设定时间提醒当天还没学习或有到期复习的用户，支持免打扰时段和多种渠道
+USE linguaforge;
+
+-- 1. 提醒设置（没有记录的学生按默认设置：每天 19:00（北京时间）站内提醒）
+CREATE TABLE IF NOT EXISTS reminder_preferences (
+    user_id INT PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    remind_at TIME NOT NULL DEFAULT '19:00:00', -- 用户时区的本地时间
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Shanghai',
+    quiet_start TIME NULL, -- 免打扰开始，可跨零点（如 22:00 ~ 07:00）
+    quiet_end TIME NULL,
+    channels VARCHAR(100) NOT NULL DEFAULT 'in_app', -- 逗号分隔：in_app, email, wechat
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_enabled (enabled)
+);
+
+-- 2. 提醒记录（每个用户每个本地日期最多一条）
+CREATE TABLE IF NOT EXISTS study_reminders (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    local_date DATE NOT NULL,
+    reason ENUM('not_studied', 'due_reviews') NOT NULL,
+    due_reviews INT NOT NULL DEFAULT 0,
+    channels VARCHAR(100) NOT NULL, -- 发送成功的渠道
+    error TEXT NULL, -- 发送失败的渠道及原因
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_user_date (user_id, local_date),
+    INDEX idx_user_created (user_id, created_at)
+);
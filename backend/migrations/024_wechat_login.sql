@@ -0,0 +1,21 @@
+-- 024_wechat_login.sql
+-- 微信小程序登录：外部身份表（按 provider + subject 关联用户），微信自动注册的账号可以没有邮箱和密码
+USE linguaforge;
+
+-- 1. 邮箱改为可空（UNIQUE 允许多个 NULL）；password_hash 为空字符串表示未设置密码
+ALTER TABLE users MODIFY email VARCHAR(100) NULL;
+
+-- 2. 外部身份
+CREATE TABLE IF NOT EXISTS identities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    provider VARCHAR(30) NOT NULL, -- wechat
+    subject VARCHAR(255) NOT NULL, -- 微信为小程序 openid
+    union_id VARCHAR(64) NULL, -- 微信开放平台 unionid，同一主体下的多个应用相同
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_provider_subject (provider, subject),
+    UNIQUE KEY uk_user_provider (user_id, provider),
+    INDEX idx_provider_union (provider, union_id)
+);
@@ -0,0 +1,5 @@
+-- 025_oidc_identities.sql
+-- 第三方 OIDC 登录：identities 表记录提供方返回的邮箱（subject 为 ID Token 的 sub）
+USE linguaforge;
+
+ALTER TABLE identities ADD COLUMN email VARCHAR(100) NULL AFTER union_id;
@@ -0,0 +1,31 @@
+-- 026_two_factor.sql
+-- 两步验证：TOTP 密钥、一次性恢复码，以及按角色强制开启两步验证的策略
+USE linguaforge;
+
+-- 1. TOTP 密钥（confirmed_at 为空表示尚未完成绑定）
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INT PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL, -- base32
+    confirmed_at TIMESTAMP NULL,
+    last_used_step BIGINT NULL, -- 最近一次使用的时间步，防止验证码被重放
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 2. 恢复码（只保存 SHA-256）
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_user_code (user_id, code_hash)
+);
+
+-- 3. 强制两步验证的角色（没有记录表示不强制）
+CREATE TABLE IF NOT EXISTS two_factor_policy (
+    role ENUM('student', 'teacher', 'admin', 'guardian') PRIMARY KEY,
+    updated_by INT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
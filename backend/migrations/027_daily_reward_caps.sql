@@ -0,0 +1,7 @@
+-- 027_daily_reward_caps.sql
+-- 游戏奖励每日上限：记录每局实际发放的经验和金币，用于统计当天已获得的奖励
+USE linguaforge;
+
+ALTER TABLE game_records
+  ADD COLUMN experience_gained INT NOT NULL DEFAULT 0 AFTER total_count,
+  ADD COLUMN coins_gained INT NOT NULL DEFAULT 0 AFTER experience_gained;
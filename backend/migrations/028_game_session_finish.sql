@@ -0,0 +1,12 @@
+-- 028_game_session_finish.sql
+-- 游戏局结算标记：每局只能结算一次，并发提交时只有一个请求能写入游戏记录
+USE linguaforge;
+
+ALTER TABLE game_sessions
+  ADD COLUMN finished_at TIMESTAMP NULL AFTER created_at;
+
+-- 已有游戏记录的局视为已结算
+UPDATE game_sessions gs
+JOIN game_records gr ON gr.session_id = gs.id
+SET gs.finished_at = gr.completed_at
+WHERE gs.finished_at IS NULL;
@@ -0,0 +1,18 @@
+-- 029_class_invites.sql
+-- 班级邀请：教师按用户名或ID添加学生时改为发送邀请，学生同意后才加入班级
+USE linguaforge;
+
+CREATE TABLE IF NOT EXISTS class_invites (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    class_id INT NOT NULL,
+    student_id INT NOT NULL,
+    invited_by INT NOT NULL,
+    status ENUM('pending', 'accepted', 'declined', 'revoked') NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP NULL,
+    FOREIGN KEY (class_id) REFERENCES classes(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_class_student (class_id, student_id),
+    INDEX idx_student_status (student_id, status)
+);
@@ -0,0 +1,20 @@
+-- 030_session_word_answers.sql
+-- 每局每个单词只作答一次：作答时间和得分记录在局内单词上，作答记录按局和单词去重
+USE linguaforge;
+
+ALTER TABLE game_session_words
+  ADD COLUMN answered_at TIMESTAMP NULL AFTER round_data,
+  ADD COLUMN points INT NULL AFTER answered_at;
+
+-- 同一局同一单词的重复作答只保留第一条
+DELETE a FROM answer_events a
+JOIN answer_events b ON b.session_id = a.session_id AND b.word_id = a.word_id AND b.id < a.id;
+
+ALTER TABLE answer_events
+  ADD UNIQUE KEY unique_session_word (session_id, word_id);
+
+-- 已有作答记录的局内单词视为已作答
+UPDATE game_session_words sw
+JOIN answer_events ae ON ae.session_id = sw.session_id AND ae.word_id = sw.word_id
+SET sw.answered_at = ae.answered_at
+WHERE sw.answered_at IS NULL;
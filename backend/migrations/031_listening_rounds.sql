@@ -0,0 +1,7 @@
+-- 031_listening_rounds.sql
+-- 听力题由服务端下发播放：记录首次播放时间和播放次数，重播和作答用时以服务器为准
+USE linguaforge;
+
+ALTER TABLE game_session_words
+  ADD COLUMN issued_at TIMESTAMP(3) NULL AFTER points,
+  ADD COLUMN plays INT DEFAULT 0 AFTER issued_at;
@@ -68,6 +68,69 @@ App({
     })
   },
 
+  // 微信登录：首次登录自动注册；传入用户名和密码时绑定到已有账号
+  wxLogin(username, password, callback) {
+    wx.login({
+      success: ({ code }) => {
+        const data = { code: code }
+        if (username) {
+          data.username = username
+          data.password = password
+        }
+        wx.request({
+          url: `${this.globalData.baseUrl}/auth/wechat`,
+          method: 'POST',
+          data: data,
+          success: (res) => {
+            if (res.statusCode === 200) {
+              this.globalData.token = res.data.token
+              this.globalData.userInfo = res.data.user
+              wx.setStorageSync('token', res.data.token)
+              callback && callback(true, res.data)
+            } else {
+              callback && callback(false, res.data.error || '微信登录失败')
+            }
+          },
+          fail: () => {
+            callback && callback(false, '网络请求失败')
+          }
+        })
+      },
+      fail: () => {
+        callback && callback(false, '获取微信登录凭证失败')
+      }
+    })
+  },
+
+  // 为当前账号绑定微信
+  bindWechat(callback) {
+    wx.login({
+      success: ({ code }) => {
+        wx.request({
+          url: `${this.globalData.baseUrl}/profile/wechat`,
+          method: 'POST',
+          header: {
+            'Authorization': `Bearer ${this.globalData.token}`
+          },
+          data: { code: code },
+          success: (res) => {
+            if (res.statusCode === 200) {
+              callback && callback(true, res.data)
+            } else {
+              callback && callback(false, res.data.error || '绑定失败')
+            }
+          },
+          fail: () => {
+            callback && callback(false, '网络请求失败')
+          }
+        })
+      },
+      fail: () => {
+        callback && callback(false, '获取微信登录凭证失败')
+      }
+    })
+  },
+
   // 注册
   register(username, email, password, callback) {
     wx.request({